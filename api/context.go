@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/flanksource/commons/logger"
 	v1 "github.com/flanksource/config-db/api/v1"
@@ -41,6 +42,11 @@ type ScrapeContext interface {
 
 	HydrateConnection(connectionIdentifier string) (*models.Connection, error)
 	HydrateConnectionModel(models.Connection) (*models.Connection, error)
+
+	// GetCursor & SaveCursor persist the progress of incremental scrapers
+	// per scraper type and scope (e.g. region + account) across runs.
+	GetCursor(scraperType, scope string) (string, error)
+	SaveCursor(scraperType, scope, value string) error
 }
 
 type scrapeContext struct {
@@ -55,6 +61,10 @@ type scrapeContext struct {
 
 	jobHistory   *models.JobHistory
 	scrapeConfig *v1.ScrapeConfig
+
+	// cursors holds the cursors of the scrapers when there's no database.
+	// It's shared by the contexts derived from this one.
+	cursors *sync.Map
 }
 
 func NewScrapeContext(ctx context.Context, db *gorm.DB, pool *pgxpool.Pool) ScrapeContext {
@@ -65,6 +75,7 @@ func NewScrapeContext(ctx context.Context, db *gorm.DB, pool *pgxpool.Pool) Scra
 		kubernetesRestConfig: KubernetesRestConfig,
 		db:                   db,
 		pool:                 pool,
+		cursors:              &sync.Map{},
 	}
 }
 
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/flanksource/config-db/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (ctx scrapeContext) cursorScraperID() string {
+	if id := ctx.scrapeConfig.GetPersistedID(); id != nil {
+		return id.String()
+	}
+	return fmt.Sprintf("%s/%s", ctx.scrapeConfig.Namespace, ctx.scrapeConfig.Name)
+}
+
+// GetCursor returns the position the scraper has reached within the given scope
+// on a previous run. An empty value is returned when there is no cursor.
+func (ctx scrapeContext) GetCursor(scraperType, scope string) (string, error) {
+	cursor := models.ScrapeCursor{
+		ScraperID:   ctx.cursorScraperID(),
+		ScraperType: scraperType,
+		Scope:       scope,
+	}
+
+	if ctx.db == nil {
+		if value, ok := ctx.cursors.Load(cursor); ok {
+			return value.(string), nil
+		}
+		return "", nil
+	}
+
+	err := ctx.db.WithContext(ctx).
+		Where("scraper_id = ? AND scraper_type = ? AND scope = ?", cursor.ScraperID, scraperType, scope).
+		First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return cursor.Value, err
+}
+
+// SaveCursor persists the position the scraper has reached within the given scope.
+func (ctx scrapeContext) SaveCursor(scraperType, scope, value string) error {
+	cursor := models.ScrapeCursor{
+		ScraperID:   ctx.cursorScraperID(),
+		ScraperType: scraperType,
+		Scope:       scope,
+	}
+
+	if ctx.db == nil {
+		ctx.cursors.Store(cursor, value)
+		return nil
+	}
+
+	cursor.Value = value
+	return ctx.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scraper_id"}, {Name: "scraper_type"}, {Name: "scope"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&cursor).Error
+}
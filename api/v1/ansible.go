@@ -0,0 +1,12 @@
+package v1
+
+// Ansible scrapes the hosts & groups of Ansible inventories and the facts cached by the jsonfile fact cache plugin
+type Ansible struct {
+	BaseScraper `json:",inline"`
+	// Inventory files (INI or YAML) or directories of inventory files, supports globs
+	Inventory []string `yaml:"inventory,omitempty" json:"inventory,omitempty"`
+	// FactCache is the directory of the jsonfile fact cache (fact_caching_connection)
+	FactCache string `yaml:"factCache,omitempty" json:"factCache,omitempty"`
+	// FactCachePrefix is the prefix of the fact cache files (fact_caching_prefix)
+	FactCachePrefix string `yaml:"factCachePrefix,omitempty" json:"factCachePrefix,omitempty"`
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -16,15 +17,110 @@ type AWS struct {
 	Inventory           bool          `json:"inventory,omitempty"`
 	Compliance          bool          `json:"compliance,omitempty"`
 	CloudTrail          CloudTrail    `json:"cloudtrail,omitempty"`
+	ConfigHistory       ConfigHistory `json:"config_history,omitempty"`
 	TrustedAdvisorCheck bool          `json:"trusted_advisor_check,omitempty"`
 	Include             []string      `json:"include,omitempty"`
 	Exclude             []string      `json:"exclude,omitempty"`
 	CostReporting       CostReporting `json:"cost_reporting,omitempty"`
+	// Organization scrapes the member accounts of an AWS organization
+	// instead of the account of the credentials.
+	Organization *AWSOrganizationAccounts `json:"organization,omitempty"`
+}
+
+// AWSOrganizationAccounts scrapes several accounts by assuming a role in each of them.
+type AWSOrganizationAccounts struct {
+	// Accounts to scrape. Defaults to all the active accounts of the organization,
+	// which are listed with the credentials of the scraper and hence requires them
+	// to belong to the management account or to a delegated administrator.
+	Accounts []string `json:"accounts,omitempty"`
+	// Exclude is a list of account ids to skip.
+	Exclude []string `json:"exclude,omitempty"`
+	// Role is the name of the role assumed in every account.
+	// Defaults to OrganizationAccountAccessRole
+	Role string `json:"role,omitempty"`
+}
+
+// GetRoleARN returns the arn of the role to assume in the given account of the partition, e.g. aws or aws-us-gov.
+func (o AWSOrganizationAccounts) GetRoleARN(partition, account string) string {
+	role := o.Role
+	if role == "" {
+		role = "OrganizationAccountAccessRole"
+	}
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, account, strings.TrimPrefix(role, "/"))
 }
 
 type CloudTrail struct {
 	Exclude []string `json:"exclude,omitempty"`
 	MaxAge  string   `json:"max_age,omitempty"`
+
+	// S3, Athena and SQS are alternate sources of events to the LookupEvents api,
+	// which is rate limited and only returns the management events of a single region.
+	S3     *CloudTrailS3     `json:"s3,omitempty"`
+	Athena *CloudTrailAthena `json:"athena,omitempty"`
+	SQS    *CloudTrailSQS    `json:"sqs,omitempty"`
+}
+
+// CloudTrailS3 reads the gzipped log files a trail delivers to an S3 bucket.
+type CloudTrailS3 struct {
+	Bucket string `json:"bucket"`
+	// Prefix is the key prefix of the trail, before AWSLogs/
+	Prefix string `json:"prefix,omitempty"`
+	// Region of the bucket. Defaults to the first region of the scraper.
+	Region string `json:"region,omitempty"`
+	// OrganizationID is set for organization trails, which deliver
+	// the logs of every account under AWSLogs/<organization id>/
+	OrganizationID string `json:"organization_id,omitempty"`
+	// Accounts to read the logs of. Defaults to the account of the scraper,
+	// or to every account found in the bucket for organization trails.
+	Accounts []string `json:"accounts,omitempty"`
+	// Regions to read the logs of. Defaults to the regions of the scraper.
+	Regions []string `json:"regions,omitempty"`
+}
+
+// CloudTrailAthena queries a table created over the log files of a trail.
+type CloudTrailAthena struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	// Region of the Athena workgroup. Defaults to the first region of the scraper.
+	Region string `json:"region,omitempty"`
+	// S3BucketPath is where query results are written to e.g. s3://bucket/path
+	S3BucketPath string `json:"s3_bucket_path"`
+	// DatePartition is the partition column holding the date of the events as yyyy/MM/dd,
+	// so that a query only scans the partitions since the last scrape. Defaults to timestamp,
+	// the date partition of the partition projection AWS documents for CloudTrail tables.
+	DatePartition string `json:"date_partition,omitempty"`
+	// MaxEvents is the maximum number of events read per scrape. Defaults to 10000.
+	MaxEvents int `json:"max_events,omitempty"`
+}
+
+func (c CloudTrailAthena) GetDatePartition() string {
+	if c.DatePartition == "" {
+		return "timestamp"
+	}
+	return c.DatePartition
+}
+
+func (c CloudTrailAthena) GetMaxEvents() int {
+	if c.MaxEvents <= 0 {
+		return 10000
+	}
+	return c.MaxEvents
+}
+
+// CloudTrailSQS consumes the "AWS API Call via CloudTrail" events
+// an EventBridge rule sends to an SQS queue.
+type CloudTrailSQS struct {
+	QueueURL string `json:"queue_url"`
+	// Region of the queue. Defaults to the first region of the scraper.
+	Region string `json:"region,omitempty"`
+	// MaxMessages is the maximum number of messages consumed per scrape.
+	MaxMessages int `json:"max_messages,omitempty"`
+}
+
+// UsesAlternateSource returns true when events are read from S3, Athena or SQS
+// instead of the LookupEvents api.
+func (c CloudTrail) UsesAlternateSource() bool {
+	return c.S3 != nil || c.Athena != nil || c.SQS != nil
 }
 
 func (c CloudTrail) GetMaxAge() time.Duration {
@@ -39,6 +135,30 @@ func (c CloudTrail) GetMaxAge() time.Duration {
 	return d
 }
 
+// ConfigHistory configures the ingestion of the configuration timeline
+// recorded by AWS Config as changes.
+type ConfigHistory struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// ResourceTypes limits the history to the given resource types e.g. AWS::EC2::Instance.
+	// Defaults to all the resource types discovered by AWS Config.
+	ResourceTypes []string `json:"resource_types,omitempty"`
+	// MaxAge limits the history read on the first run, later runs continue
+	// from the last configuration item captured. Defaults to 168h.
+	MaxAge string `json:"max_age,omitempty"`
+}
+
+func (c ConfigHistory) GetMaxAge() time.Duration {
+	if c.MaxAge == "" {
+		return 7 * 24 * time.Hour
+	}
+	d, err := time.ParseDuration(c.MaxAge)
+	if err != nil {
+		logger.Warnf("Invalid config history max age %s: %v", c.MaxAge, err)
+		return 7 * 24 * time.Hour
+	}
+	return d
+}
+
 type CostReporting struct {
 	S3BucketPath string `json:"s3_bucket_path,omitempty"`
 	Table        string `json:"table,omitempty"`
@@ -67,6 +187,11 @@ const (
 	AWSIAMInstanceProfile = "AWS::IAM::InstanceProfile"
 	AWSEC2AMI             = "AWS::EC2::AMI"
 	AWSEC2DHCPOptions     = "AWS::EC2::DHCPOptions"
+
+	AWSCloudFormationStack = "AWS::CloudFormation::Stack"
+
+	AWSOrganization       = "AWS::Organizations::Organization"
+	AWSOrganizationalUnit = "AWS::Organizations::OrganizationalUnit"
 )
 
 func (aws AWS) Includes(resource string) bool {
@@ -1,6 +1,9 @@
 package v1
 
 import (
+	"time"
+
+	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/duty/types"
 )
 
@@ -11,17 +14,91 @@ type AzureDevops struct {
 	PersonalAccessToken types.EnvVar `yaml:"personalAccessToken,omitempty" json:"personalAccessToken,omitempty"`
 	Projects            []string     `yaml:"projects" json:"projects"`
 	Pipelines           []string     `yaml:"pipelines" json:"pipelines"`
+	// Repositories to scrape along with their branch policies & completed pull requests.
+	// Supports wildcards e.g. "*"
+	Repositories []string `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+	// Releases are the classic release definitions to scrape along with their releases.
+	// Supports wildcards e.g. "*"
+	Releases []string `yaml:"releases,omitempty" json:"releases,omitempty"`
+	// Environments to scrape along with their approvals & checks.
+	// Supports wildcards e.g. "*"
+	Environments []string `yaml:"environments,omitempty" json:"environments,omitempty"`
+	// MaxAge limits the pipeline runs fetched on the first run, later runs continue
+	// from the last run queued. Defaults to 168h.
+	MaxAge string `yaml:"maxAge,omitempty" json:"maxAge,omitempty"`
+}
+
+func (ado AzureDevops) GetMaxAge() time.Duration {
+	if ado.MaxAge == "" {
+		return 7 * 24 * time.Hour
+	}
+	d, err := time.ParseDuration(ado.MaxAge)
+	if err != nil {
+		logger.Warnf("Invalid azure devops max age %s: %v", ado.MaxAge, err)
+		return 7 * 24 * time.Hour
+	}
+	return d
 }
 
 type Azure struct {
 	BaseScraper    `json:",inline"`
 	ConnectionName string           `yaml:"connection,omitempty" json:"connection,omitempty"`
-	SubscriptionID string           `yaml:"subscriptionID" json:"subscriptionID"`
+	SubscriptionID string           `yaml:"subscriptionID,omitempty" json:"subscriptionID,omitempty"`
 	Organisation   string           `yaml:"organisation" json:"organisation"`
 	ClientID       types.EnvVar     `yaml:"clientID,omitempty" json:"clientID,omitempty"`
 	ClientSecret   types.EnvVar     `yaml:"clientSecret,omitempty" json:"clientSecret,omitempty"`
 	TenantID       string           `yaml:"tenantID,omitempty" json:"tenantID,omitempty"`
 	Exclusions     *AzureExclusions `yaml:"exclusions,omitempty" json:"exclusions,omitempty"`
+	// ResourceGraph discovers all the resources of the subscription with a single
+	// Azure Resource Graph query, in addition to the resources of the typed fetchers.
+	ResourceGraph *AzureResourceGraph `yaml:"resourceGraph,omitempty" json:"resourceGraph,omitempty"`
+	// Discovery scrapes all the subscriptions visible to the credential,
+	// or the ones under a management group, instead of SubscriptionID.
+	Discovery *AzureDiscovery `yaml:"discovery,omitempty" json:"discovery,omitempty"`
+	// Identity is the kind of credential to authenticate with:
+	//  "clientSecret" (default) uses ClientID & ClientSecret
+	//  "managed" uses the managed identity of the host, ClientID selects a user-assigned identity
+	//  "workload" uses the federated token of an AKS workload identity
+	Identity string `yaml:"identity,omitempty" json:"identity,omitempty"`
+}
+
+const (
+	AzureIdentityClientSecret = "clientSecret"
+	AzureIdentityManaged      = "managed"
+	AzureIdentityWorkload     = "workload"
+)
+
+type AzureDiscovery struct {
+	// ManagementGroup limits the subscriptions to the ones under the
+	// management group, including its nested management groups.
+	ManagementGroup string `yaml:"managementGroup,omitempty" json:"managementGroup,omitempty"`
+	// Exclude is a list of subscription ids to skip.
+	Exclude []string `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	// Concurrency is the number of subscriptions scraped at the same time.
+	// Defaults to 4
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+}
+
+func (d AzureDiscovery) GetConcurrency() int {
+	if d.Concurrency <= 0 {
+		return 4
+	}
+	return d.Concurrency
+}
+
+type AzureResourceGraph struct {
+	// Query is the Resource Graph query that returns the resources.
+	// Defaults to "Resources"
+	// Example:
+	//  "Resources | where type !~ 'microsoft.compute/snapshots'"
+	Query string `yaml:"query,omitempty" json:"query,omitempty"`
+}
+
+func (r AzureResourceGraph) GetQuery() string {
+	if r.Query == "" {
+		return "Resources"
+	}
+	return r.Query
 }
 
 type AzureExclusions struct {
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"github.com/flanksource/duty/types"
+)
+
+// Consul scrapes the services, nodes, health checks and KV prefixes of a Consul datacenter.
+type Consul struct {
+	BaseScraper `json:",inline"`
+	URL         string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Token is the ACL token, it requires read access to the services, nodes and KV prefixes
+	Token types.EnvVar `yaml:"token,omitempty" json:"token,omitempty"`
+	// ConnectionName, if provided, will be used to populate the url and the token (password)
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// Datacenter to scrape, defaults to the datacenter of the agent
+	Datacenter string `yaml:"datacenter,omitempty" json:"datacenter,omitempty"`
+	// KV prefixes to scrape, each prefix is scraped as a config with the keys & values under it
+	KV []string `yaml:"kv,omitempty" json:"kv,omitempty"`
+}
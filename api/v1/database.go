@@ -0,0 +1,22 @@
+package v1
+
+import "time"
+
+// Database scrapes the catalog of a Postgres, MySQL or SQL Server database:
+// its schemas, tables, views, roles, grants, extensions and settings.
+type Database struct {
+	BaseScraper `json:",inline"`
+	Connection  `json:",inline"`
+	// Schemas to scrape. Supports wildcards and exclusions (!name).
+	// All the schemas, except the system ones, are scraped when empty.
+	Schemas []string `json:"schemas,omitempty"`
+	// Timeout of each catalog query, defaults to 1m
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func (d Database) GetTimeout() time.Duration {
+	if t, err := time.ParseDuration(d.Timeout); err == nil && t > 0 {
+		return t
+	}
+	return time.Minute
+}
@@ -0,0 +1,17 @@
+package v1
+
+// Docker scrapes the containers, images, volumes, networks and compose projects of a
+// Docker or Podman engine. Container events are scraped as changes.
+type Docker struct {
+	BaseScraper `json:",inline"`
+	// Host is the address of the engine, e.g. unix:///run/podman/podman.sock or tcp://10.0.0.1:2375.
+	// Defaults to unix:///var/run/docker.sock
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+}
+
+func (d Docker) GetHost() string {
+	if d.Host != "" {
+		return d.Host
+	}
+	return "unix:///var/run/docker.sock"
+}
@@ -1,6 +1,12 @@
 package v1
 
-import "github.com/flanksource/duty/types"
+import (
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/duty/types"
+)
 
 type GitHubActions struct {
 	BaseScraper         `json:",inline"`
@@ -10,4 +16,60 @@ type GitHubActions struct {
 	// ConnectionName, if provided, will be used to populate personalAccessToken
 	ConnectionName string   `yaml:"connection,omitempty" json:"connection,omitempty"`
 	Workflows      []string `yaml:"workflows" json:"workflows"`
+	// MaxAge limits the runs fetched on the first run, later runs continue
+	// from the last run created. Deployments older than it are not scraped.
+	// Defaults to 168h.
+	MaxAge string `yaml:"maxAge,omitempty" json:"maxAge,omitempty"`
+}
+
+func (gh GitHubActions) GetMaxAge() time.Duration {
+	if gh.MaxAge == "" {
+		return 7 * 24 * time.Hour
+	}
+	d, err := time.ParseDuration(gh.MaxAge)
+	if err != nil {
+		logger.Warnf("Invalid github actions max age %s: %v", gh.MaxAge, err)
+		return 7 * 24 * time.Hour
+	}
+	return d
+}
+
+// GitHub scrapes the repositories, teams and security alerts of a GitHub organization.
+type GitHub struct {
+	BaseScraper         `json:",inline"`
+	Organization        string       `yaml:"organization" json:"organization"`
+	PersonalAccessToken types.EnvVar `yaml:"personalAccessToken,omitempty" json:"personalAccessToken,omitempty"`
+	// ConnectionName, if provided, will be used to populate personalAccessToken
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// URL of the GitHub API, defaults to https://api.github.com.
+	// Set it to https://<host>/api/v3 for GitHub Enterprise Server.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Repositories to scrape, by name. Supports wildcards and exclusions (!name).
+	// All the repositories of the organization are scraped when empty.
+	Repositories []string `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+	// IncludeArchived scrapes archived repositories too
+	IncludeArchived bool `yaml:"includeArchived,omitempty" json:"includeArchived,omitempty"`
+	// Alerts are the kinds of security alerts to scrape as analysis:
+	// dependabot, code-scanning and secret-scanning. All of them are scraped when empty.
+	Alerts []string `yaml:"alerts,omitempty" json:"alerts,omitempty"`
+}
+
+func (gh GitHub) GetURL() string {
+	if gh.URL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(gh.URL, "/")
+}
+
+// IncludesAlerts returns true if the given kind of security alerts is to be scraped
+func (gh GitHub) IncludesAlerts(kind string) bool {
+	if len(gh.Alerts) == 0 {
+		return true
+	}
+	for _, alert := range gh.Alerts {
+		if strings.EqualFold(alert, kind) {
+			return true
+		}
+	}
+	return false
 }
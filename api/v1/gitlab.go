@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/duty/types"
+)
+
+// GitLab scrapes the projects, pipelines, environments and vulnerabilities of a GitLab instance.
+type GitLab struct {
+	BaseScraper `json:",inline"`
+	// URL of the GitLab instance, defaults to https://gitlab.com
+	URL                 string       `yaml:"url,omitempty" json:"url,omitempty"`
+	PersonalAccessToken types.EnvVar `yaml:"personalAccessToken,omitempty" json:"personalAccessToken,omitempty"`
+	// ConnectionName, if provided, will be used to populate url and personalAccessToken
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// Group is the full path of the group whose projects (including the ones of its subgroups) are scraped.
+	// The projects the token is a member of are scraped when empty.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
+	// Projects to scrape, by path with namespace. Supports wildcards and exclusions (!path).
+	Projects []string `yaml:"projects,omitempty" json:"projects,omitempty"`
+	// IncludeArchived scrapes archived projects too
+	IncludeArchived bool `yaml:"includeArchived,omitempty" json:"includeArchived,omitempty"`
+	// MaxAge limits the pipelines and merge requests fetched on the first run,
+	// later runs continue from the last one updated. Defaults to 168h.
+	MaxAge string `yaml:"maxAge,omitempty" json:"maxAge,omitempty"`
+}
+
+func (gl GitLab) GetMaxAge() time.Duration {
+	if gl.MaxAge == "" {
+		return 7 * 24 * time.Hour
+	}
+	d, err := time.ParseDuration(gl.MaxAge)
+	if err != nil {
+		logger.Warnf("Invalid gitlab max age %s: %v", gl.MaxAge, err)
+		return 7 * 24 * time.Hour
+	}
+	return d
+}
+
+func (gl GitLab) GetURL() string {
+	if gl.URL == "" {
+		return "https://gitlab.com"
+	}
+	return strings.TrimSuffix(gl.URL, "/")
+}
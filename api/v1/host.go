@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/flanksource/duty/types"
+)
+
+// HostScraper scrapes the OS release, packages, services, listening ports, users, groups and files of hosts.
+// The commands are run locally, or over SSH when hosts are provided.
+type HostScraper struct {
+	BaseScraper `json:",inline"`
+	// Hosts to connect to over SSH (host or host:port). The host config-db runs on is scraped when empty.
+	Hosts []string `yaml:"hosts,omitempty" json:"hosts,omitempty"`
+	// ConnectionName, if provided, will be used to populate the username, password and private key (certificate)
+	ConnectionName string       `yaml:"connection,omitempty" json:"connection,omitempty"`
+	Username       types.EnvVar `yaml:"username,omitempty" json:"username,omitempty"`
+	Password       types.EnvVar `yaml:"password,omitempty" json:"password,omitempty"`
+	// PrivateKey in PEM format
+	PrivateKey types.EnvVar `yaml:"privateKey,omitempty" json:"privateKey,omitempty"`
+	// KnownHosts in the format of ~/.ssh/known_hosts, required to connect over SSH
+	KnownHosts types.EnvVar `yaml:"knownHosts,omitempty" json:"knownHosts,omitempty"`
+	// InsecureSkipHostKeyVerification connects without knownHosts, without verifying the host keys
+	InsecureSkipHostKeyVerification bool `yaml:"insecureSkipHostKeyVerification,omitempty" json:"insecureSkipHostKeyVerification,omitempty"`
+	// Files to scrape, by absolute path. Supports globs (e.g. /etc/nginx/conf.d/*.conf)
+	// but not quotes, variables or whitespace.
+	Files []string `yaml:"files,omitempty" json:"files,omitempty"`
+	// Timeout of the SSH connection & of each command, defaults to 1m
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+func (h HostScraper) GetTimeout() time.Duration {
+	if t, err := time.ParseDuration(h.Timeout); err == nil && t > 0 {
+		return t
+	}
+	return time.Minute
+}
@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/flanksource/duty/types"
+)
+
+// HTTP scrapes the responses of a HTTP/REST API.
+// Every page of the response is a config, from which configs are extracted using items, id and type.
+type HTTP struct {
+	BaseScraper `json:",inline"`
+	// URL to request, takes precedence over the url of the connection
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// ConnectionName, if provided, will be used to populate url, username and password
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// Authentication is sent as basic auth
+	Authentication *Authentication `yaml:"auth,omitempty" json:"auth,omitempty"`
+	// Method defaults to GET
+	Method  string       `yaml:"method,omitempty" json:"method,omitempty"`
+	Headers []HTTPHeader `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Body is a template of the request body. The cursor and page of the request are available as {{.cursor}} and {{.page}}.
+	Body string `yaml:"body,omitempty" json:"body,omitempty"`
+	// Bearer token sent in the Authorization header
+	Bearer types.EnvVar `yaml:"bearer,omitempty" json:"bearer,omitempty"`
+	// OAuth fetches a token using the client credentials flow
+	OAuth      *OAuth          `yaml:"oauth,omitempty" json:"oauth,omitempty"`
+	Pagination *HTTPPagination `yaml:"pagination,omitempty" json:"pagination,omitempty"`
+	Retry      *HTTPRetry      `yaml:"retry,omitempty" json:"retry,omitempty"`
+}
+
+func (h HTTP) GetMethod() string {
+	if h.Method == "" {
+		return "GET"
+	}
+	return h.Method
+}
+
+type HTTPHeader struct {
+	Name         string `yaml:"name" json:"name"`
+	types.EnvVar `yaml:",inline" json:",inline"`
+}
+
+// OAuth are the settings of the OAuth2 client credentials flow
+type OAuth struct {
+	ClientID     types.EnvVar      `yaml:"clientID,omitempty" json:"clientID,omitempty"`
+	ClientSecret types.EnvVar      `yaml:"clientSecret,omitempty" json:"clientSecret,omitempty"`
+	TokenURL     string            `yaml:"tokenURL" json:"tokenURL"`
+	Scopes       []string          `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	Params       map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
+}
+
+const (
+	// PaginationLink follows the rel="next" link of the Link header
+	PaginationLink = "link"
+	// PaginationCursor sends the cursor found in the previous response
+	PaginationCursor = "cursor"
+	// PaginationPage increments a page number until a page without items,
+	// or the same page as the previous one, is returned
+	PaginationPage = "page"
+)
+
+type HTTPPagination struct {
+	// Type is one of link, cursor or page
+	Type string `yaml:"type" json:"type"`
+	// Cursor is the JSONPath of the next cursor in the response, e.g. $.meta.next_cursor.
+	// A cursor that is a URL is requested as is.
+	Cursor string `yaml:"cursor,omitempty" json:"cursor,omitempty"`
+	// Param is the query parameter of the cursor or page number, defaults to cursor or page
+	Param string `yaml:"param,omitempty" json:"param,omitempty"`
+	// StartPage is the number of the first page, defaults to 1
+	StartPage *int `yaml:"startPage,omitempty" json:"startPage,omitempty"`
+	// PageSize, if set, is sent in the query parameter SizeParam
+	PageSize  int    `yaml:"pageSize,omitempty" json:"pageSize,omitempty"`
+	SizeParam string `yaml:"sizeParam,omitempty" json:"sizeParam,omitempty"`
+	// MaxPages limits the number of requests, defaults to 100
+	MaxPages int `yaml:"maxPages,omitempty" json:"maxPages,omitempty"`
+}
+
+func (p HTTPPagination) GetParam() string {
+	if p.Param != "" {
+		return p.Param
+	}
+	if p.Type == PaginationPage {
+		return "page"
+	}
+	return "cursor"
+}
+
+func (p HTTPPagination) GetStartPage() int {
+	if p.StartPage == nil {
+		return 1
+	}
+	return *p.StartPage
+}
+
+func (p HTTPPagination) GetMaxPages() int {
+	if p.MaxPages <= 0 {
+		return 100
+	}
+	return p.MaxPages
+}
+
+// HTTPRetry retries requests that fail, or return 429 or 5xx, with an exponential backoff
+type HTTPRetry struct {
+	// Attempts defaults to 3
+	Attempts int `yaml:"attempts,omitempty" json:"attempts,omitempty"`
+	// Backoff is the initial wait between attempts, defaults to 1s
+	Backoff string `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+	// MaxBackoff defaults to 30s
+	MaxBackoff string `yaml:"maxBackoff,omitempty" json:"maxBackoff,omitempty"`
+}
+
+func (r HTTPRetry) GetAttempts() int {
+	if r.Attempts <= 0 {
+		return 3
+	}
+	return r.Attempts
+}
+
+func (r HTTPRetry) GetBackoff() time.Duration {
+	if d, err := time.ParseDuration(r.Backoff); err == nil && d > 0 {
+		return d
+	}
+	return time.Second
+}
+
+func (r HTTPRetry) GetMaxBackoff() time.Duration {
+	if d, err := time.ParseDuration(r.MaxBackoff); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
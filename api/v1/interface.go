@@ -2,6 +2,7 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -159,6 +160,32 @@ func (t *ScrapeResults) Add(r ...ScrapeResult) {
 	*t = append(*t, r...)
 }
 
+// Saved calls the OnSave hooks of the results once they have been persisted.
+func (t ScrapeResults) Saved() error {
+	var errs []error
+	for _, r := range t {
+		if r.OnSave != nil {
+			if err := r.OnSave(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ResolvesAnalysis reports whether the analysis that weren't observed again can be resolved.
+// Errors of the scrape don't prevent it, unless the scraper couldn't fetch the analysis.
+func (t ScrapeResults) ResolvesAnalysis() bool {
+	for _, r := range t {
+		if r.KeepAnalysis {
+			return false
+		}
+	}
+
+	return true
+}
+
 type RelationshipResult struct {
 	// Config ID of the parent
 	ConfigID string
@@ -196,6 +223,20 @@ func (s *ScrapeResults) Analysis(analyzer string, configType string, id string)
 	return &result
 }
 
+// OnSave adds a hook that's called only after the results have been saved,
+// e.g. to advance the cursor of an incremental scraper.
+func (s *ScrapeResults) OnSave(fn func() error) *ScrapeResults {
+	*s = append(*s, ScrapeResult{OnSave: fn})
+	return s
+}
+
+// KeepAnalysis keeps the analysis that weren't observed again from being resolved,
+// e.g. when the alerts couldn't be fetched.
+func (s *ScrapeResults) KeepAnalysis() *ScrapeResults {
+	*s = append(*s, ScrapeResult{KeepAnalysis: true})
+	return s
+}
+
 func (s *ScrapeResults) Errorf(e error, msg string, args ...interface{}) ScrapeResults {
 	logger.Errorf("%s: %v", fmt.Sprintf(msg, args...), e)
 	*s = append(*s, ScrapeResult{Error: e})
@@ -241,6 +282,16 @@ type ScrapeResult struct {
 	Properties          types.Properties    `json:"properties,omitempty"`
 	LastScrapedTime     *time.Time          `json:"last_scraped_time"`
 
+	// Person is saved to the people table (matched by email) so that the changes created
+	// by the person can be attributed to them, e.g. the users of a directory.
+	Person *models.Person `json:"-"`
+
+	// OnSave is called once all the results of the scrape have been saved.
+	OnSave func() error `json:"-"`
+
+	// KeepAnalysis keeps the analysis of the scrape config from being resolved when they aren't observed again.
+	KeepAnalysis bool `json:"-"`
+
 	// RelationshipSelectors are used to form relationship of this scraped item with other items.
 	// Unlike `RelationshipResults`, selectors give you the flexibility to form relationship without
 	// knowing the external ids of the item to be linked.
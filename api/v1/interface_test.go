@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScrapeResults_ResolvesAnalysis(t *testing.T) {
+	var results ScrapeResults
+	results = append(results, ScrapeResult{ID: "i-1", Config: map[string]any{}})
+	results.Analysis("public-bucket", "AWS::S3::Bucket", "bucket-1")
+
+	// e.g. a region or a subscription that the scraper isn't allowed to read
+	results.Errorf(errors.New("access denied"), "failed to scrape eu-west-1")
+	if !results.HasErr() || !results.ResolvesAnalysis() {
+		t.Errorf("expected the analysis to be resolved despite the partial error")
+	}
+
+	results.KeepAnalysis()
+	if results.ResolvesAnalysis() {
+		t.Errorf("expected the analysis to be kept when the scraper couldn't fetch them")
+	}
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"github.com/flanksource/duty/types"
+)
+
+// Jenkins scrapes the folders, jobs and builds of a Jenkins controller.
+type Jenkins struct {
+	BaseScraper `json:",inline"`
+	URL         string       `yaml:"url,omitempty" json:"url,omitempty"`
+	Username    types.EnvVar `yaml:"username,omitempty" json:"username,omitempty"`
+	// Token is the API token of the user
+	Token types.EnvVar `yaml:"token,omitempty" json:"token,omitempty"`
+	// ConnectionName, if provided, will be used to populate url, username and token
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// Jobs to scrape, by full name (e.g. folder/job). Supports wildcards and exclusions (!name).
+	// Folders are always traversed.
+	Jobs []string `yaml:"jobs,omitempty" json:"jobs,omitempty"`
+	// MaxBuilds is the number of most recent builds of each job scraped as changes, defaults to 50
+	MaxBuilds int `yaml:"maxBuilds,omitempty" json:"maxBuilds,omitempty"`
+}
+
+func (j Jenkins) GetMaxBuilds() int {
+	if j.MaxBuilds <= 0 {
+		return 50
+	}
+	return j.MaxBuilds
+}
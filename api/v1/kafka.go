@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/flanksource/duty/types"
+)
+
+// Kafka scrapes the brokers, topics, ACLs and consumer groups of a Kafka cluster.
+type Kafka struct {
+	BaseScraper `json:",inline"`
+	// Brokers to bootstrap from (host:port), the other brokers of the cluster are discovered
+	Brokers []string `yaml:"brokers,omitempty" json:"brokers,omitempty"`
+	// ConnectionName, if provided, will be used to populate the brokers (comma separated url), username and password
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// Username & Password for SASL authentication
+	Username types.EnvVar `yaml:"username,omitempty" json:"username,omitempty"`
+	Password types.EnvVar `yaml:"password,omitempty" json:"password,omitempty"`
+	// SASLMechanism is one of PLAIN (default), SCRAM-SHA-256, SCRAM-SHA-512 or AWS_MSK_IAM.
+	// AWS_MSK_IAM uses the username & password as the access & secret key, or the default credentials of the environment.
+	SASLMechanism string `yaml:"saslMechanism,omitempty" json:"saslMechanism,omitempty"`
+	TLS           bool   `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// InsecureSkipVerify disables the verification of the certificates of the brokers
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify,omitempty" json:"insecureSkipVerify,omitempty"`
+	// Topics to scrape, supports wildcards and exclusions (!name). Internal topics are only scraped when they're listed.
+	Topics []string `yaml:"topics,omitempty" json:"topics,omitempty"`
+	// ConsumerGroups to scrape, supports wildcards and exclusions (!name)
+	ConsumerGroups []string `yaml:"consumerGroups,omitempty" json:"consumerGroups,omitempty"`
+	// Timeout of each request, defaults to 30s
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+const (
+	KafkaSASLPlain       = "PLAIN"
+	KafkaSASLScramSHA256 = "SCRAM-SHA-256"
+	KafkaSASLScramSHA512 = "SCRAM-SHA-512"
+	KafkaSASLAWSMSKIAM   = "AWS_MSK_IAM"
+)
+
+func (k Kafka) GetTimeout() time.Duration {
+	if t, err := time.ParseDuration(k.Timeout); err == nil && t > 0 {
+		return t
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/flanksource/commons/duration"
+	"github.com/flanksource/duty/types"
+)
+
+// LDAP scrapes the users, groups, group membership and organizational units of an LDAP directory
+// or Active Directory. Users with an email are also saved as people.
+type LDAP struct {
+	BaseScraper `json:",inline"`
+	// URL of the directory, e.g. ldaps://dc01.example.com:636
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// ConnectionName, if provided, will be used to populate the url, the bind DN (username) and the password
+	ConnectionName string       `yaml:"connection,omitempty" json:"connection,omitempty"`
+	BindDN         types.EnvVar `yaml:"bindDN,omitempty" json:"bindDN,omitempty"`
+	Password       types.EnvVar `yaml:"password,omitempty" json:"password,omitempty"`
+	// InsecureSkipVerify disables the verification of the certificate of ldaps:// urls
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify,omitempty" json:"insecureSkipVerify,omitempty"`
+	// BaseDN to search from, e.g. dc=example,dc=com
+	BaseDN string `yaml:"baseDN" json:"baseDN"`
+	// UserFilter defaults to (|(objectClass=person)(objectClass=inetOrgPerson)) excluding computer accounts
+	UserFilter string `yaml:"userFilter,omitempty" json:"userFilter,omitempty"`
+	// GroupFilter defaults to groups, groupOfNames, groupOfUniqueNames and posixGroups
+	GroupFilter string `yaml:"groupFilter,omitempty" json:"groupFilter,omitempty"`
+	// PrivilegedGroups are the names (cn) of the groups whose disabled & stale members are reported,
+	// supports wildcards. Defaults to the administrator groups of Active Directory.
+	PrivilegedGroups []string `yaml:"privilegedGroups,omitempty" json:"privilegedGroups,omitempty"`
+	// StaleAfter is the time since the last logon after which accounts are stale, defaults to 90d
+	StaleAfter string `yaml:"staleAfter,omitempty" json:"staleAfter,omitempty"`
+	// Timeout of each request, defaults to 1m
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+func (l LDAP) GetUserFilter() string {
+	if l.UserFilter != "" {
+		return l.UserFilter
+	}
+	return "(&(|(objectClass=person)(objectClass=inetOrgPerson))(!(objectClass=computer)))"
+}
+
+func (l LDAP) GetGroupFilter() string {
+	if l.GroupFilter != "" {
+		return l.GroupFilter
+	}
+	return "(|(objectClass=group)(objectClass=groupOfNames)(objectClass=groupOfUniqueNames)(objectClass=posixGroup))"
+}
+
+func (l LDAP) GetPrivilegedGroups() []string {
+	if len(l.PrivilegedGroups) > 0 {
+		return l.PrivilegedGroups
+	}
+	return []string{"Domain Admins", "Enterprise Admins", "Schema Admins", "Administrators"}
+}
+
+func (l LDAP) GetStaleAfter() time.Duration {
+	if d, err := duration.ParseDuration(l.StaleAfter); err == nil && d > 0 {
+		return time.Duration(d)
+	}
+	return 90 * 24 * time.Hour
+}
+
+func (l LDAP) GetTimeout() time.Duration {
+	if t, err := time.ParseDuration(l.Timeout); err == nil && t > 0 {
+		return t
+	}
+	return time.Minute
+}
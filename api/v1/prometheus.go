@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"github.com/flanksource/duty/types"
+)
+
+// Prometheus scrapes the targets and rule groups of a Prometheus server, and the active alerts
+// of Alertmanager (or of Prometheus when no Alertmanager is provided) as analysis.
+type Prometheus struct {
+	BaseScraper `json:",inline"`
+	URL         string       `yaml:"url,omitempty" json:"url,omitempty"`
+	Username    types.EnvVar `yaml:"username,omitempty" json:"username,omitempty"`
+	Password    types.EnvVar `yaml:"password,omitempty" json:"password,omitempty"`
+	// BearerToken is sent in the Authorization header, instead of basic auth
+	BearerToken types.EnvVar `yaml:"bearerToken,omitempty" json:"bearerToken,omitempty"`
+	// ConnectionName, if provided, will be used to populate url, username and password
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// Alertmanager is the url of the Alertmanager the alerts are read from, it uses the same credentials as Prometheus
+	Alertmanager string `yaml:"alertmanager,omitempty" json:"alertmanager,omitempty"`
+}
@@ -1,8 +1,69 @@
 package v1
 
+import "time"
+
 type SQL struct {
 	BaseScraper `json:",inline"`
 	Connection  `json:",inline"`
 	Driver      string `json:"driver,omitempty"`
 	Query       string `json:"query"`
+	// Changes is a query whose rows are changes, using the columns external_id, config_type,
+	// external_change_id, change_type, summary, severity, source, created_by, created_at & details (a JSON object).
+	// Any other column is added to the details.
+	Changes string `json:"changes,omitempty"`
+	// Analysis is a query whose rows are analysis, using the columns external_id, config_type,
+	// analyzer, analysis_type, severity, summary, source, status, messages (a JSON list) & analysis (a JSON object).
+	// Any other column is added to the analysis.
+	Analysis string `json:"analysis,omitempty"`
+	// Cursor scrapes the queries incrementally
+	Cursor *SQLCursor `json:"cursor,omitempty"`
+	// Timeout of each query, defaults to 1m
+	Timeout string `json:"timeout,omitempty"`
+	// MaxRows is the maximum number of rows read from each query, defaults to 10000
+	MaxRows int `json:"maxRows,omitempty"`
+}
+
+// SQLCursor binds the greatest value of a column from the previous run to a parameter of the queries
+// (e.g. WHERE updated_at > :last_updated), so that only new rows are scraped.
+// Each query keeps its own cursor, and the rows of the queries are ordered by the column:
+// the queries are wrapped as SELECT * FROM (<query>) cursor_rows ORDER BY <column>,
+// so on SQL Server they can't have a top-level ORDER BY or a WITH clause.
+//
+// Configs are not deleted as stale by scrapers with an incremental query,
+// as the configs that didn't change are not returned.
+type SQLCursor struct {
+	// Column of the results the cursor is read from, e.g. updated_at
+	Column string `json:"column"`
+	// Param is the name of the parameter in the queries, defaults to last_updated
+	Param string `json:"param,omitempty"`
+	// Initial is the value bound on the first run, defaults to 1970-01-01T00:00:00Z
+	Initial string `json:"initial,omitempty"`
+}
+
+func (s SQL) GetTimeout() time.Duration {
+	if d, err := time.ParseDuration(s.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return time.Minute
+}
+
+func (s SQL) GetMaxRows() int {
+	if s.MaxRows <= 0 {
+		return 10000
+	}
+	return s.MaxRows
+}
+
+func (c SQLCursor) GetParam() string {
+	if c.Param == "" {
+		return "last_updated"
+	}
+	return c.Param
+}
+
+func (c SQLCursor) GetInitial() string {
+	if c.Initial == "" {
+		return "1970-01-01T00:00:00Z"
+	}
+	return c.Initial
 }
@@ -11,15 +11,28 @@ import (
 )
 
 var AllScraperConfigs = map[string]any{
+	"ansible":        Ansible{},
 	"aws":            AWS{},
 	"azure":          Azure{},
 	"azuredevops":    AzureDevops{},
+	"consul":         Consul{},
+	"database":       Database{},
+	"docker":         Docker{},
 	"file":           File{},
+	"github":         GitHub{},
 	"githubactions":  GitHubActions{},
+	"gitlab":         GitLab{},
+	"host":           HostScraper{},
+	"http":           HTTP{},
+	"jenkins":        Jenkins{},
+	"kafka":          Kafka{},
 	"kubernetes":     Kubernetes{},
 	"kubernetesfile": KubernetesFile{},
+	"ldap":           LDAP{},
+	"prometheus":     Prometheus{},
 	"sql":            SQL{},
 	"trivy":          Trivy{},
+	"vault":          Vault{},
 }
 
 type ChangeRetentionSpec struct {
@@ -50,9 +63,22 @@ type ScraperSpec struct {
 	Kubernetes     []Kubernetes     `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
 	KubernetesFile []KubernetesFile `json:"kubernetesFile,omitempty" yaml:"kubernetesFile,omitempty"`
 	AzureDevops    []AzureDevops    `json:"azureDevops,omitempty" yaml:"azureDevops,omitempty"`
+	GitHub         []GitHub         `json:"github,omitempty" yaml:"github,omitempty"`
 	GithubActions  []GitHubActions  `json:"githubActions,omitempty" yaml:"githubActions,omitempty"`
+	GitLab         []GitLab         `json:"gitlab,omitempty" yaml:"gitlab,omitempty"`
+	Jenkins        []Jenkins        `json:"jenkins,omitempty" yaml:"jenkins,omitempty"`
+	HTTP           []HTTP           `json:"http,omitempty" yaml:"http,omitempty"`
+	Host           []HostScraper    `json:"host,omitempty" yaml:"host,omitempty"`
+	Ansible        []Ansible        `json:"ansible,omitempty" yaml:"ansible,omitempty"`
+	Prometheus     []Prometheus     `json:"prometheus,omitempty" yaml:"prometheus,omitempty"`
+	Consul         []Consul         `json:"consul,omitempty" yaml:"consul,omitempty"`
+	Vault          []Vault          `json:"vault,omitempty" yaml:"vault,omitempty"`
+	Kafka          []Kafka          `json:"kafka,omitempty" yaml:"kafka,omitempty"`
+	LDAP           []LDAP           `json:"ldap,omitempty" yaml:"ldap,omitempty"`
+	Docker         []Docker         `json:"docker,omitempty" yaml:"docker,omitempty"`
 	Azure          []Azure          `json:"azure,omitempty" yaml:"azure,omitempty"`
 	SQL            []SQL            `json:"sql,omitempty" yaml:"sql,omitempty"`
+	Database       []Database       `json:"database,omitempty" yaml:"database,omitempty"`
 	Trivy          []Trivy          `json:"trivy,omitempty" yaml:"trivy,omitempty"`
 	Retention      RetentionSpec    `json:"retention,omitempty"`
 
@@ -69,6 +95,17 @@ func (c ScraperSpec) IsEmpty() bool {
 	return len(c.AWS) == 0 && len(c.File) == 0
 }
 
+// IsIncremental returns true when the scraper only returns the configs changed since its last run,
+// so the configs it doesn't return are not stale.
+func (c ScraperSpec) IsIncremental() bool {
+	for _, sql := range c.SQL {
+		if sql.Cursor != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (c ScraperSpec) IsTrace() bool {
 	return c.LogLevel == "trace"
 }
@@ -0,0 +1,34 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/flanksource/commons/duration"
+	"github.com/flanksource/duty/types"
+)
+
+// Vault scrapes the metadata of a Vault server: secrets engines, auth methods, policies, roles
+// and the metadata of KV v2 secrets. Secret values are never read.
+type Vault struct {
+	BaseScraper `json:",inline"`
+	URL         string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Token requires read & list access to sys/mounts, sys/auth, sys/policies/acl,
+	// the roles of the auth methods and the metadata of the KV v2 secrets engines
+	Token types.EnvVar `yaml:"token,omitempty" json:"token,omitempty"`
+	// ConnectionName, if provided, will be used to populate the url and the token (password)
+	ConnectionName string `yaml:"connection,omitempty" json:"connection,omitempty"`
+	// Namespace of Vault Enterprise
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	// Secrets are the KV v2 paths (mount and optional prefix, e.g. secret/apps/) whose metadata is scraped,
+	// defaults to all the KV v2 secrets engines
+	Secrets []string `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	// StaleAfter is the age after which secrets that haven't been updated are reported, defaults to 90d
+	StaleAfter string `yaml:"staleAfter,omitempty" json:"staleAfter,omitempty"`
+}
+
+func (v Vault) GetStaleAfter() time.Duration {
+	if d, err := duration.ParseDuration(v.StaleAfter); err == nil && d > 0 {
+		return time.Duration(d)
+	}
+	return 90 * 24 * time.Hour
+}
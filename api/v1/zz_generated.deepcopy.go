@@ -36,6 +36,7 @@ func (in *AWS) DeepCopyInto(out *AWS) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.CloudTrail.DeepCopyInto(&out.CloudTrail)
+	in.ConfigHistory.DeepCopyInto(&out.ConfigHistory)
 	if in.Include != nil {
 		in, out := &in.Include, &out.Include
 		*out = make([]string, len(*in))
@@ -47,6 +48,11 @@ func (in *AWS) DeepCopyInto(out *AWS) {
 		copy(*out, *in)
 	}
 	out.CostReporting = in.CostReporting
+	if in.Organization != nil {
+		in, out := &in.Organization, &out.Organization
+		*out = new(AWSOrganizationAccounts)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWS.
@@ -81,6 +87,52 @@ func (in *AWSConnection) DeepCopy() *AWSConnection {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSOrganizationAccounts) DeepCopyInto(out *AWSOrganizationAccounts) {
+	*out = *in
+	if in.Accounts != nil {
+		in, out := &in.Accounts, &out.Accounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSOrganizationAccounts.
+func (in *AWSOrganizationAccounts) DeepCopy() *AWSOrganizationAccounts {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSOrganizationAccounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Ansible) DeepCopyInto(out *Ansible) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	if in.Inventory != nil {
+		in, out := &in.Inventory, &out.Inventory
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Ansible.
+func (in *Ansible) DeepCopy() *Ansible {
+	if in == nil {
+		return nil
+	}
+	out := new(Ansible)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Authentication) DeepCopyInto(out *Authentication) {
 	*out = *in
@@ -109,6 +161,16 @@ func (in *Azure) DeepCopyInto(out *Azure) {
 		*out = new(AzureExclusions)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ResourceGraph != nil {
+		in, out := &in.ResourceGraph, &out.ResourceGraph
+		*out = new(AzureResourceGraph)
+		**out = **in
+	}
+	if in.Discovery != nil {
+		in, out := &in.Discovery, &out.Discovery
+		*out = new(AzureDiscovery)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Azure.
@@ -136,6 +198,21 @@ func (in *AzureDevops) DeepCopyInto(out *AzureDevops) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Releases != nil {
+		in, out := &in.Releases, &out.Releases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureDevops.
@@ -148,6 +225,26 @@ func (in *AzureDevops) DeepCopy() *AzureDevops {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureDiscovery) DeepCopyInto(out *AzureDiscovery) {
+	*out = *in
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureDiscovery.
+func (in *AzureDiscovery) DeepCopy() *AzureDiscovery {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureDiscovery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AzureExclusions) DeepCopyInto(out *AzureExclusions) {
 	*out = *in
@@ -168,6 +265,21 @@ func (in *AzureExclusions) DeepCopy() *AzureExclusions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureResourceGraph) DeepCopyInto(out *AzureResourceGraph) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureResourceGraph.
+func (in *AzureResourceGraph) DeepCopy() *AzureResourceGraph {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureResourceGraph)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BaseScraper) DeepCopyInto(out *BaseScraper) {
 	*out = *in
@@ -246,6 +358,21 @@ func (in *CloudTrail) DeepCopyInto(out *CloudTrail) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(CloudTrailS3)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Athena != nil {
+		in, out := &in.Athena, &out.Athena
+		*out = new(CloudTrailAthena)
+		**out = **in
+	}
+	if in.SQS != nil {
+		in, out := &in.SQS, &out.SQS
+		*out = new(CloudTrailSQS)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudTrail.
@@ -258,6 +385,61 @@ func (in *CloudTrail) DeepCopy() *CloudTrail {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudTrailAthena) DeepCopyInto(out *CloudTrailAthena) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudTrailAthena.
+func (in *CloudTrailAthena) DeepCopy() *CloudTrailAthena {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudTrailAthena)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudTrailS3) DeepCopyInto(out *CloudTrailS3) {
+	*out = *in
+	if in.Accounts != nil {
+		in, out := &in.Accounts, &out.Accounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudTrailS3.
+func (in *CloudTrailS3) DeepCopy() *CloudTrailS3 {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudTrailS3)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudTrailSQS) DeepCopyInto(out *CloudTrailSQS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudTrailSQS.
+func (in *CloudTrailSQS) DeepCopy() *CloudTrailSQS {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudTrailSQS)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigFieldExclusion) DeepCopyInto(out *ConfigFieldExclusion) {
 	*out = *in
@@ -278,6 +460,26 @@ func (in *ConfigFieldExclusion) DeepCopy() *ConfigFieldExclusion {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigHistory) DeepCopyInto(out *ConfigHistory) {
+	*out = *in
+	if in.ResourceTypes != nil {
+		in, out := &in.ResourceTypes, &out.ResourceTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigHistory.
+func (in *ConfigHistory) DeepCopy() *ConfigHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigProperties) DeepCopyInto(out *ConfigProperties) {
 	*out = *in
@@ -310,6 +512,28 @@ func (in *Connection) DeepCopy() *Connection {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Consul) DeepCopyInto(out *Consul) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.Token.DeepCopyInto(&out.Token)
+	if in.KV != nil {
+		in, out := &in.KV, &out.KV
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Consul.
+func (in *Consul) DeepCopy() *Consul {
+	if in == nil {
+		return nil
+	}
+	out := new(Consul)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CostReporting) DeepCopyInto(out *CostReporting) {
 	*out = *in
@@ -325,6 +549,44 @@ func (in *CostReporting) DeepCopy() *CostReporting {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Database) DeepCopyInto(out *Database) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Database.
+func (in *Database) DeepCopy() *Database {
+	if in == nil {
+		return nil
+	}
+	out := new(Database)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Docker) DeepCopyInto(out *Docker) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Docker.
+func (in *Docker) DeepCopy() *Docker {
+	if in == nil {
+		return nil
+	}
+	out := new(Docker)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExternalID) DeepCopyInto(out *ExternalID) {
 	*out = *in
@@ -406,6 +668,33 @@ func (in *GCPConnection) DeepCopy() *GCPConnection {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHub) DeepCopyInto(out *GitHub) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.PersonalAccessToken.DeepCopyInto(&out.PersonalAccessToken)
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Alerts != nil {
+		in, out := &in.Alerts, &out.Alerts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHub.
+func (in *GitHub) DeepCopy() *GitHub {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHub)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubActions) DeepCopyInto(out *GitHubActions) {
 	*out = *in
@@ -428,6 +717,28 @@ func (in *GitHubActions) DeepCopy() *GitHubActions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLab) DeepCopyInto(out *GitLab) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.PersonalAccessToken.DeepCopyInto(&out.PersonalAccessToken)
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLab.
+func (in *GitLab) DeepCopy() *GitLab {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLab)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitLocation) DeepCopyInto(out *GitLocation) {
 	*out = *in
@@ -443,6 +754,131 @@ func (in *GitLocation) DeepCopy() *GitLocation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTP) DeepCopyInto(out *HTTP) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(Authentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]HTTPHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Bearer.DeepCopyInto(&out.Bearer)
+	if in.OAuth != nil {
+		in, out := &in.OAuth, &out.OAuth
+		*out = new(OAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Pagination != nil {
+		in, out := &in.Pagination, &out.Pagination
+		*out = new(HTTPPagination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(HTTPRetry)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTP.
+func (in *HTTP) DeepCopy() *HTTP {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPHeader) DeepCopyInto(out *HTTPHeader) {
+	*out = *in
+	in.EnvVar.DeepCopyInto(&out.EnvVar)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPHeader.
+func (in *HTTPHeader) DeepCopy() *HTTPHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPPagination) DeepCopyInto(out *HTTPPagination) {
+	*out = *in
+	if in.StartPage != nil {
+		in, out := &in.StartPage, &out.StartPage
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPPagination.
+func (in *HTTPPagination) DeepCopy() *HTTPPagination {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPPagination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPRetry) DeepCopyInto(out *HTTPRetry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPRetry.
+func (in *HTTPRetry) DeepCopy() *HTTPRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostScraper) DeepCopyInto(out *HostScraper) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Username.DeepCopyInto(&out.Username)
+	in.Password.DeepCopyInto(&out.Password)
+	in.PrivateKey.DeepCopyInto(&out.PrivateKey)
+	in.KnownHosts.DeepCopyInto(&out.KnownHosts)
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostScraper.
+func (in *HostScraper) DeepCopy() *HostScraper {
+	if in == nil {
+		return nil
+	}
+	out := new(HostScraper)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InvolvedObject) DeepCopyInto(out *InvolvedObject) {
 	*out = *in
@@ -479,6 +915,62 @@ func (in JSONStringMap) DeepCopy() JSONStringMap {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Jenkins) DeepCopyInto(out *Jenkins) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.Username.DeepCopyInto(&out.Username)
+	in.Token.DeepCopyInto(&out.Token)
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Jenkins.
+func (in *Jenkins) DeepCopy() *Jenkins {
+	if in == nil {
+		return nil
+	}
+	out := new(Jenkins)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Kafka) DeepCopyInto(out *Kafka) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Username.DeepCopyInto(&out.Username)
+	in.Password.DeepCopyInto(&out.Password)
+	if in.Topics != nil {
+		in, out := &in.Topics, &out.Topics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConsumerGroups != nil {
+		in, out := &in.ConsumerGroups, &out.ConsumerGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Kafka.
+func (in *Kafka) DeepCopy() *Kafka {
+	if in == nil {
+		return nil
+	}
+	out := new(Kafka)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Kubernetes) DeepCopyInto(out *Kubernetes) {
 	*out = *in
@@ -680,6 +1172,29 @@ func (in *KubernetesRelationshipSelectorTemplate) DeepCopy() *KubernetesRelation
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LDAP) DeepCopyInto(out *LDAP) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.BindDN.DeepCopyInto(&out.BindDN)
+	in.Password.DeepCopyInto(&out.Password)
+	if in.PrivilegedGroups != nil {
+		in, out := &in.PrivilegedGroups, &out.PrivilegedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LDAP.
+func (in *LDAP) DeepCopy() *LDAP {
+	if in == nil {
+		return nil
+	}
+	out := new(LDAP)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Mask) DeepCopyInto(out *Mask) {
 	*out = *in
@@ -714,6 +1229,35 @@ func (in MaskList) DeepCopy() MaskList {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OAuth) DeepCopyInto(out *OAuth) {
+	*out = *in
+	in.ClientID.DeepCopyInto(&out.ClientID)
+	in.ClientSecret.DeepCopyInto(&out.ClientSecret)
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OAuth.
+func (in *OAuth) DeepCopy() *OAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(OAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenAPIFieldRef) DeepCopyInto(out *OpenAPIFieldRef) {
 	*out = *in
@@ -749,6 +1293,25 @@ func (in *PodFile) DeepCopy() *PodFile {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Prometheus) DeepCopyInto(out *Prometheus) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.Username.DeepCopyInto(&out.Username)
+	in.Password.DeepCopyInto(&out.Password)
+	in.BearerToken.DeepCopyInto(&out.BearerToken)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Prometheus.
+func (in *Prometheus) DeepCopy() *Prometheus {
+	if in == nil {
+		return nil
+	}
+	out := new(Prometheus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in Properties) DeepCopyInto(out *Properties) {
 	{
@@ -1012,6 +1575,11 @@ func (in *SQL) DeepCopyInto(out *SQL) {
 	*out = *in
 	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
 	in.Connection.DeepCopyInto(&out.Connection)
+	if in.Cursor != nil {
+		in, out := &in.Cursor, &out.Cursor
+		*out = new(SQLCursor)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SQL.
@@ -1024,6 +1592,21 @@ func (in *SQL) DeepCopy() *SQL {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SQLCursor) DeepCopyInto(out *SQLCursor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SQLCursor.
+func (in *SQLCursor) DeepCopy() *SQLCursor {
+	if in == nil {
+		return nil
+	}
+	out := new(SQLCursor)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScrapeConfig) DeepCopyInto(out *ScrapeConfig) {
 	*out = *in
@@ -1136,6 +1719,13 @@ func (in *ScraperSpec) DeepCopyInto(out *ScraperSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.GitHub != nil {
+		in, out := &in.GitHub, &out.GitHub
+		*out = make([]GitHub, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.GithubActions != nil {
 		in, out := &in.GithubActions, &out.GithubActions
 		*out = make([]GitHubActions, len(*in))
@@ -1143,6 +1733,83 @@ func (in *ScraperSpec) DeepCopyInto(out *ScraperSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.GitLab != nil {
+		in, out := &in.GitLab, &out.GitLab
+		*out = make([]GitLab, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Jenkins != nil {
+		in, out := &in.Jenkins, &out.Jenkins
+		*out = make([]Jenkins, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = make([]HTTP, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Host != nil {
+		in, out := &in.Host, &out.Host
+		*out = make([]HostScraper, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Ansible != nil {
+		in, out := &in.Ansible, &out.Ansible
+		*out = make([]Ansible, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = make([]Prometheus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Consul != nil {
+		in, out := &in.Consul, &out.Consul
+		*out = make([]Consul, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Vault != nil {
+		in, out := &in.Vault, &out.Vault
+		*out = make([]Vault, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Kafka != nil {
+		in, out := &in.Kafka, &out.Kafka
+		*out = make([]Kafka, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LDAP != nil {
+		in, out := &in.LDAP, &out.LDAP
+		*out = make([]LDAP, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Docker != nil {
+		in, out := &in.Docker, &out.Docker
+		*out = make([]Docker, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Azure != nil {
 		in, out := &in.Azure, &out.Azure
 		*out = make([]Azure, len(*in))
@@ -1157,6 +1824,13 @@ func (in *ScraperSpec) DeepCopyInto(out *ScraperSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = make([]Database, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Trivy != nil {
 		in, out := &in.Trivy, &out.Trivy
 		*out = make([]Trivy, len(*in))
@@ -1373,3 +2047,25 @@ func (in *TypeRetentionSpec) DeepCopy() *TypeRetentionSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Vault) DeepCopyInto(out *Vault) {
+	*out = *in
+	in.BaseScraper.DeepCopyInto(&out.BaseScraper)
+	in.Token.DeepCopyInto(&out.Token)
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Vault.
+func (in *Vault) DeepCopy() *Vault {
+	if in == nil {
+		return nil
+	}
+	out := new(Vault)
+	in.DeepCopyInto(out)
+	return out
+}
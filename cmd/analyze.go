@@ -8,6 +8,7 @@ import (
 	"github.com/flanksource/config-db/analyzers"
 	v1 "github.com/flanksource/config-db/api/v1"
 	"github.com/flanksource/config-db/scrapers/aws"
+	"github.com/flanksource/config-db/scrapers/host"
 	"github.com/spf13/cobra"
 )
 
@@ -43,6 +44,13 @@ var Analyze = &cobra.Command{
 					logger.Fatalf("Failed to unmarshal object into ec2 instance %s", obj.ID)
 				}
 				obj.Config = instance
+			} else if obj.Type == host.ServerType {
+				nested, _ := json.Marshal(obj.Config)
+				server := host.Server{}
+				if err := json.Unmarshal(nested, &server); err != nil {
+					logger.Fatalf("Failed to unmarshal object into host %s", obj.ID)
+				}
+				obj.Config = server
 			}
 			objects = append(objects, obj)
 		}
@@ -64,6 +64,8 @@ func serve(ctx context.Context, configFiles []string) {
 
 	e.GET("/query", query.Handler)
 	e.POST("/run/:id", scrapers.RunNowHandler)
+	e.GET("/cursors", scrapers.ListCursorsHandler)
+	e.DELETE("/cursors/:id", scrapers.ResetCursorsHandler)
 
 	go startScraperCron(configFiles)
 
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+
+	"github.com/flanksource/config-db/db/models"
+)
+
+// GetScrapeCursors returns the cursors of a scraper.
+// All the cursors are returned when the scraper id is empty.
+func GetScrapeCursors(ctx context.Context, scraperID string) ([]models.ScrapeCursor, error) {
+	var cursors []models.ScrapeCursor
+	query := db.WithContext(ctx).Order("scraper_id, scraper_type, scope")
+	if scraperID != "" {
+		query = query.Where("scraper_id = ?", scraperID)
+	}
+	err := query.Find(&cursors).Error
+	return cursors, err
+}
+
+// DeleteScrapeCursors resets the cursors of a scraper, optionally
+// limited to a scraper type and scope, so the next run starts over.
+func DeleteScrapeCursors(ctx context.Context, scraperID, scraperType, scope string) (int64, error) {
+	query := db.WithContext(ctx).Where("scraper_id = ?", scraperID)
+	if scraperType != "" {
+		query = query.Where("scraper_type = ?", scraperType)
+	}
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+
+	tx := query.Delete(&models.ScrapeCursor{})
+	return tx.RowsAffected, tx.Error
+}
+
+func migrate() error {
+	return db.AutoMigrate(&models.ScrapeCursor{})
+}
@@ -109,6 +109,10 @@ func Init(ctx context.Context, connection string) error {
 		if err = duty.Migrate(connection, nil); err != nil {
 			return err
 		}
+
+		if err = migrate(); err != nil {
+			return fmt.Errorf("failed to migrate config-db tables: %w", err)
+		}
 	}
 
 	// initialize cache
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ScrapeCursor is the position an incremental scraper has reached
+// within a scope (e.g. an account and region) of a scrape config.
+type ScrapeCursor struct {
+	ScraperID   string    `gorm:"column:scraper_id;primaryKey" json:"scraper_id"`
+	ScraperType string    `gorm:"column:scraper_type;primaryKey" json:"scraper_type"`
+	Scope       string    `gorm:"column:scope;primaryKey" json:"scope"`
+	Value       string    `gorm:"column:value;not null" json:"value"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (ScrapeCursor) TableName() string {
+	return "scrape_cursors"
+}
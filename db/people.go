@@ -6,6 +6,7 @@ import (
 
 	"github.com/flanksource/duty/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func FindPersonByEmail(ctx context.Context, email string) (*models.Person, error) {
@@ -21,3 +22,12 @@ func FindPersonByEmail(ctx context.Context, email string) (*models.Person, error
 
 	return &person, err
 }
+
+// InsertPerson creates the person unless a person with the same email exists,
+// the people managed elsewhere (e.g. by the identity provider) are not overwritten.
+func InsertPerson(ctx context.Context, person *models.Person) error {
+	return db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoNothing: true,
+	}).Create(person).Error
+}
@@ -309,6 +309,12 @@ func SaveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) error {
 			}
 		}
 
+		if result.Person != nil {
+			if err := InsertPerson(ctx, result.Person); err != nil {
+				return fmt.Errorf("failed to save person %s: %w", result.Person.Email, err)
+			}
+		}
+
 		if err := saveChanges(ctx, &result); err != nil {
 			return err
 		}
@@ -330,13 +336,19 @@ func SaveResults(ctx api.ScrapeContext, results []v1.ScrapeResult) error {
 		return fmt.Errorf("failed to form relationships: %w", err)
 	}
 
-	if !startTime.IsZero() && ctx.ScrapeConfig().GetPersistedID() != nil {
-		// Any analysis that weren't observed again will be marked as resolved
+	// Any analysis that weren't observed again will be marked as resolved,
+	// unless the scraper couldn't fetch them (e.g. the alerts) and they may not have been looked at.
+	if !startTime.IsZero() && ctx.ScrapeConfig().GetPersistedID() != nil && v1.ScrapeResults(results).ResolvesAnalysis() {
 		if err := UpdateAnalysisStatusBefore(ctx, startTime, string(ctx.ScrapeConfig().GetUID()), dutyModels.AnalysisStatusResolved); err != nil {
 			logger.Errorf("failed to mark analysis before %v as healthy: %v", startTime, err)
 		}
 	}
 
+	// Cursors are only advanced (and queues acknowledged) once the results are persisted
+	if err := v1.ScrapeResults(results).Saved(); err != nil {
+		logger.Errorf("failed to run the hooks of the saved results: %v", err)
+	}
+
 	logger.Debugf("saved %d results.", len(results))
 	return nil
 }
@@ -384,7 +396,19 @@ func generateDiff(newConf, prevConfig string) (string, error) {
 // generateConfigChange calculates the diff (git style) and patches between the
 // given 2 config items and returns a ConfigChange object if there are any changes.
 func generateConfigChange(newConf, prev models.ConfigItem) (*v1.ChangeResult, error) {
-	diff, err := generateDiff(*newConf.Config, *prev.Config)
+	change, err := NewDiffChange(*newConf.Config, *prev.Config)
+	if err != nil || change == nil {
+		return nil, err
+	}
+
+	change.ConfigType = lo.FromPtr(newConf.Type)
+	return change, nil
+}
+
+// NewDiffChange calculates the diff (git style) and patches between the
+// given 2 configs and returns a "diff" change if there are any changes.
+func NewDiffChange(newConf, prevConf string) (*v1.ChangeResult, error) {
+	diff, err := generateDiff(newConf, prevConf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate diff: %w", err)
 	}
@@ -393,7 +417,7 @@ func generateConfigChange(newConf, prev models.ConfigItem) (*v1.ChangeResult, er
 		return nil, nil
 	}
 
-	patch, err := jsonpatch.CreateMergePatch([]byte(*newConf.Config), []byte(*prev.Config))
+	patch, err := jsonpatch.CreateMergePatch([]byte(newConf), []byte(prevConf))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create merge patch: %w", err)
 	}
@@ -404,7 +428,6 @@ func generateConfigChange(newConf, prev models.ConfigItem) (*v1.ChangeResult, er
 	}
 
 	return &v1.ChangeResult{
-		ConfigType:       lo.FromPtr(newConf.Type),
 		ChangeType:       "diff",
 		ExternalChangeID: utils.Sha256Hex(string(patch)),
 		Diff:             &diff,
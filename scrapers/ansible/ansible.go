@@ -0,0 +1,213 @@
+package ansible
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/samber/lo"
+)
+
+const (
+	HostType  = "Ansible::Host"
+	GroupType = "Ansible::Group"
+
+	azureVirtualMachineType = "Azure::Microsoft.Compute/virtualMachines"
+)
+
+// volatileFacts change on every run and are not stored
+var volatileFacts = []string{
+	"ansible_date_time",
+	"ansible_uptime_seconds",
+	"ansible_memfree_mb",
+	"ansible_memory_mb",
+}
+
+type Scraper struct {
+}
+
+func (s Scraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Ansible) > 0
+}
+
+func (s Scraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Ansible {
+		inventory := NewInventory()
+		for _, path := range config.Inventory {
+			if err := loadInventory(inventory, path); err != nil {
+				results.Errorf(err, "failed to read inventory %s", path)
+			}
+		}
+
+		if config.FactCache != "" {
+			if err := loadFacts(inventory, config.FactCache, config.FactCachePrefix); err != nil {
+				results.Errorf(err, "failed to read fact cache %s", config.FactCache)
+			}
+		}
+
+		results = append(results, toResults(config, inventory)...)
+	}
+	return results
+}
+
+// loadInventory reads the inventory files matching a path, directories are read recursively
+func loadInventory(inventory *Inventory, path string) error {
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return err
+	} else if len(matches) == 0 {
+		return fmt.Errorf("no inventory found")
+	}
+
+	for _, match := range matches {
+		err := filepath.Walk(match, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				// group_vars & host_vars are not inventories
+				if file != match && (info.Name() == "group_vars" || info.Name() == "host_vars" || strings.HasPrefix(info.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			// ansible ignores these extensions when reading inventory directories
+			switch filepath.Ext(file) {
+			case ".orig", ".ini~", ".cfg", ".retry", ".pyc", ".pyo", ".md":
+				return nil
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if err := inventory.Parse(file, content); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFacts reads the facts of the jsonfile cache, where each host is stored in a file named <prefix><hostname>
+func loadFacts(inventory *Inventory, dir, prefix string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var facts map[string]any
+		if err := json.Unmarshal(content, &facts); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		for _, fact := range volatileFacts {
+			delete(facts, fact)
+		}
+		inventory.host(strings.TrimPrefix(entry.Name(), prefix)).Facts = facts
+	}
+	return nil
+}
+
+func toResults(config v1.Ansible, inventory *Inventory) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	for _, name := range sortedKeys(inventory.Groups) {
+		group := inventory.Groups[name]
+		result := v1.ScrapeResult{
+			BaseScraper: config.BaseScraper,
+			ID:          name,
+			Name:        name,
+			Type:        GroupType,
+			ConfigClass: "Group",
+			Config:      group,
+		}
+
+		for _, host := range group.Hosts {
+			result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{name}, ConfigType: GroupType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{host}, ConfigType: HostType},
+				Relationship:      "GroupHost",
+			})
+		}
+		for _, child := range group.Children {
+			result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{name}, ConfigType: GroupType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{child}, ConfigType: GroupType},
+				Relationship:      "GroupGroup",
+			})
+		}
+		results = append(results, result)
+	}
+
+	for _, name := range sortedKeys(inventory.Hosts) {
+		host := inventory.Hosts[name]
+		result := v1.ScrapeResult{
+			BaseScraper:           config.BaseScraper,
+			ID:                    name,
+			Name:                  name,
+			Type:                  HostType,
+			ConfigClass:           "Host",
+			Config:                host,
+			RelationshipSelectors: virtualMachineSelectors(host),
+		}
+		if address, ok := host.Vars["ansible_host"].(string); ok && address != name {
+			result.Aliases = []string{address}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// virtualMachineSelectors links a host to the EC2 instances & Azure VMs with the same hostname, or EC2 instances with the same private IP
+func virtualMachineSelectors(host *Host) []v1.RelationshipSelector {
+	var names, ips []string
+	add := func(value any) {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return
+		}
+		if net.ParseIP(s) != nil {
+			ips = append(ips, s)
+		} else {
+			names = append(names, s)
+		}
+	}
+
+	add(host.Name)
+	add(host.Vars["ansible_host"])
+	add(host.Facts["ansible_hostname"])
+	add(host.Facts["ansible_fqdn"])
+	if ipv4, ok := host.Facts["ansible_default_ipv4"].(map[string]any); ok {
+		add(ipv4["address"])
+	}
+
+	var selectors []v1.RelationshipSelector
+	for _, name := range lo.Uniq(names) {
+		selectors = append(selectors,
+			v1.RelationshipSelector{Name: name, Type: v1.AWSEC2Instance},
+			v1.RelationshipSelector{Name: name, Type: azureVirtualMachineType},
+		)
+	}
+	// Azure virtual machines can't be matched by IP: their addresses are on the network interfaces,
+	// which aren't scraped, and the config of a virtual machine only references them by id.
+	for _, ip := range lo.Uniq(ips) {
+		selectors = append(selectors, v1.RelationshipSelector{Type: v1.AWSEC2Instance, Labels: map[string]string{"private-ip": ip}})
+	}
+	return selectors
+}
@@ -0,0 +1,115 @@
+package ansible
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestExpandHostPattern(t *testing.T) {
+	tests := map[string][]string{
+		"web.example.com":         {"web.example.com"},
+		"web-[01:03].example.com": {"web-01.example.com", "web-02.example.com", "web-03.example.com"},
+		"db-[a:c]":                {"db-a", "db-b", "db-c"},
+		"node[1:5:2]":             {"node1", "node3", "node5"},
+		"r[1:2]-[a:b]":            {"r1-a", "r1-b", "r2-a", "r2-b"},
+	}
+	for pattern, expected := range tests {
+		if hosts := expandHostPattern(pattern); !reflect.DeepEqual(hosts, expected) {
+			t.Errorf("%s: expected %v, got %v", pattern, expected, hosts)
+		}
+	}
+}
+
+func TestParseINI(t *testing.T) {
+	inventory := NewInventory()
+	err := inventory.Parse("hosts", []byte(`
+jump ansible_host=203.0.113.10 # bastion
+[web]
+web-[1:2] motd="hello world"
+[web:vars]
+http_port = 8080
+[prod:children]
+web
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := inventory.Groups["ungrouped"].Hosts; !reflect.DeepEqual(got, []string{"jump"}) {
+		t.Errorf("unexpected ungrouped hosts %v", got)
+	}
+	if got := inventory.Hosts["jump"].Vars; !reflect.DeepEqual(got, map[string]any{"ansible_host": "203.0.113.10"}) {
+		t.Errorf("unexpected vars %v", got)
+	}
+	if got := inventory.Hosts["web-2"].Vars["motd"]; got != "hello world" {
+		t.Errorf("unexpected motd %v", got)
+	}
+	if got := inventory.Groups["web"].Vars["http_port"]; got != "8080" {
+		t.Errorf("unexpected http_port %v", got)
+	}
+	if got := inventory.Groups["prod"].Children; !reflect.DeepEqual(got, []string{"web"}) {
+		t.Errorf("unexpected children %v", got)
+	}
+}
+
+func TestScrape(t *testing.T) {
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{
+		Spec: v1.ScraperSpec{Ansible: []v1.Ansible{{
+			Inventory: []string{"../../fixtures/data/ansible/inventory.*"},
+			FactCache: "../../fixtures/data/ansible/facts",
+		}}},
+	})
+
+	results := Scraper{}.Scrape(ctx)
+	items := map[string]v1.ScrapeResult{}
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		items[r.Type+"/"+r.ID] = r
+	}
+
+	for _, id := range []string{
+		"Ansible::Group/all", "Ansible::Group/ungrouped", "Ansible::Group/web", "Ansible::Group/db", "Ansible::Group/prod", "Ansible::Group/monitoring",
+		"Ansible::Host/bastion.example.com", "Ansible::Host/web-01.example.com", "Ansible::Host/web-02.example.com",
+		"Ansible::Host/10.0.1.20", "Ansible::Host/db-1.example.com", "Ansible::Host/prometheus-1.example.com",
+	} {
+		if _, ok := items[id]; !ok {
+			t.Errorf("expected %s to be scraped", id)
+		}
+	}
+	if len(items) != 12 {
+		t.Errorf("expected 12 items, got %d", len(items))
+	}
+
+	if web := items["Ansible::Group/web"]; len(web.RelationshipResults) != 3 {
+		t.Errorf("expected web to have 3 hosts, got %v", web.RelationshipResults)
+	}
+
+	host := items["Ansible::Host/web-01.example.com"]
+	facts := host.Config.(*Host).Facts
+	if facts["ansible_distribution"] != "Ubuntu" || facts["ansible_date_time"] != nil {
+		t.Errorf("unexpected facts %v", facts)
+	}
+
+	expected := []v1.RelationshipSelector{
+		{Name: "web-01.example.com", Type: v1.AWSEC2Instance},
+		{Name: "web-01.example.com", Type: azureVirtualMachineType},
+		{Name: "ip-10-0-1-11", Type: v1.AWSEC2Instance},
+		{Name: "ip-10-0-1-11", Type: azureVirtualMachineType},
+		{Name: "ip-10-0-1-11.ec2.internal", Type: v1.AWSEC2Instance},
+		{Name: "ip-10-0-1-11.ec2.internal", Type: azureVirtualMachineType},
+		{Type: v1.AWSEC2Instance, Labels: map[string]string{"private-ip": "10.0.1.11"}},
+	}
+	if !reflect.DeepEqual(host.RelationshipSelectors, expected) {
+		t.Errorf("unexpected selectors %+v", host.RelationshipSelectors)
+	}
+
+	if db := items["Ansible::Host/db-1.example.com"]; !reflect.DeepEqual(db.Aliases, []string{"10.0.2.10"}) {
+		t.Errorf("unexpected aliases %v", db.Aliases)
+	}
+}
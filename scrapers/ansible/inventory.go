@@ -0,0 +1,269 @@
+package ansible
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory is the merged content of one or more inventory sources
+type Inventory struct {
+	Hosts  map[string]*Host
+	Groups map[string]*Group
+}
+
+type Host struct {
+	Name   string         `json:"name"`
+	Vars   map[string]any `json:"vars,omitempty"`
+	Groups []string       `json:"groups,omitempty"`
+	Facts  map[string]any `json:"facts,omitempty"`
+}
+
+type Group struct {
+	Name     string         `json:"name"`
+	Vars     map[string]any `json:"vars,omitempty"`
+	Hosts    []string       `json:"hosts,omitempty"`
+	Children []string       `json:"children,omitempty"`
+}
+
+func NewInventory() *Inventory {
+	return &Inventory{Hosts: map[string]*Host{}, Groups: map[string]*Group{}}
+}
+
+func (inv *Inventory) host(name string) *Host {
+	if h, ok := inv.Hosts[name]; ok {
+		return h
+	}
+	h := &Host{Name: name, Vars: map[string]any{}}
+	inv.Hosts[name] = h
+	return h
+}
+
+func (inv *Inventory) group(name string) *Group {
+	if g, ok := inv.Groups[name]; ok {
+		return g
+	}
+	g := &Group{Name: name, Vars: map[string]any{}}
+	inv.Groups[name] = g
+	return g
+}
+
+func (inv *Inventory) addHost(group, name string, vars map[string]any) {
+	h := inv.host(name)
+	for k, v := range vars {
+		h.Vars[k] = v
+	}
+	if group == "" {
+		return
+	}
+	g := inv.group(group)
+	if !lo.Contains(g.Hosts, name) {
+		g.Hosts = append(g.Hosts, name)
+	}
+	if !lo.Contains(h.Groups, group) {
+		h.Groups = append(h.Groups, group)
+	}
+}
+
+func (inv *Inventory) addChild(group, child string) {
+	inv.group(child)
+	g := inv.group(group)
+	if !lo.Contains(g.Children, child) {
+		g.Children = append(g.Children, child)
+	}
+}
+
+// Parse adds the hosts and groups of an inventory file, the format is detected from the extension
+func (inv *Inventory) Parse(filename string, content []byte) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml", ".json":
+		return inv.parseYAML(content)
+	default:
+		return inv.parseINI(string(content))
+	}
+}
+
+type yamlGroup struct {
+	Hosts    map[string]map[string]any `yaml:"hosts"`
+	Vars     map[string]any            `yaml:"vars"`
+	Children map[string]*yamlGroup     `yaml:"children"`
+}
+
+func (inv *Inventory) parseYAML(content []byte) error {
+	var groups map[string]*yamlGroup
+	if err := yaml.Unmarshal(content, &groups); err != nil {
+		return err
+	}
+	for name, group := range groups {
+		inv.addYAMLGroup(name, group)
+	}
+	return nil
+}
+
+func (inv *Inventory) addYAMLGroup(name string, group *yamlGroup) {
+	g := inv.group(name)
+	if group == nil {
+		return
+	}
+	for k, v := range group.Vars {
+		g.Vars[k] = v
+	}
+	for pattern, vars := range group.Hosts {
+		for _, host := range expandHostPattern(pattern) {
+			inv.addHost(name, host, vars)
+		}
+	}
+	for child, childGroup := range group.Children {
+		inv.addChild(name, child)
+		inv.addYAMLGroup(child, childGroup)
+	}
+}
+
+// parseINI parses inventories in the INI format, hosts before the first section are ungrouped
+func (inv *Inventory) parseINI(content string) error {
+	section, kind := "ungrouped", "hosts"
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("line %d: invalid section %s", n, line)
+			}
+			section, kind, _ = strings.Cut(strings.Trim(line, "[]"), ":")
+			if kind == "" {
+				kind = "hosts"
+			}
+			inv.group(section)
+			continue
+		}
+
+		fields, err := splitFields(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+
+		switch kind {
+		case "hosts":
+			vars := map[string]any{}
+			for _, field := range fields[1:] {
+				if k, v, ok := strings.Cut(field, "="); ok {
+					vars[k] = v
+				}
+			}
+			for _, host := range expandHostPattern(fields[0]) {
+				inv.addHost(section, host, vars)
+			}
+		case "vars":
+			if k, v, ok := strings.Cut(line, "="); ok {
+				inv.group(section).Vars[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+			}
+		case "children":
+			inv.addChild(section, fields[0])
+		default:
+			return fmt.Errorf("line %d: unknown section type %s", n, kind)
+		}
+	}
+	return scanner.Err()
+}
+
+// splitFields splits a line on whitespace, honouring single and double quotes
+func splitFields(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	inField := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inField = r, true
+		case r == '#' && !inField:
+			// comment at the end of the line
+			return fields, nil
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %s", line)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// expandHostPattern expands numeric & alphabetic ranges, e.g. web[01:03].example.com or db-[a:c]
+func expandHostPattern(pattern string) []string {
+	start := strings.Index(pattern, "[")
+	end := strings.Index(pattern, "]")
+	if start < 0 || end < start {
+		return []string{pattern}
+	}
+
+	parts := strings.Split(pattern[start+1:end], ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return []string{pattern}
+	}
+	step := 1
+	if len(parts) == 3 {
+		if s, err := strconv.Atoi(parts[2]); err == nil && s > 0 {
+			step = s
+		}
+	}
+
+	var values []string
+	if from, err := strconv.Atoi(parts[0]); err == nil {
+		to, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return []string{pattern}
+		}
+		// leading zeros are preserved, e.g. [01:10]
+		format := "%d"
+		if len(parts[0]) > 1 && strings.HasPrefix(parts[0], "0") {
+			format = fmt.Sprintf("%%0%dd", len(parts[0]))
+		}
+		for i := from; i <= to; i += step {
+			values = append(values, fmt.Sprintf(format, i))
+		}
+	} else if len(parts[0]) == 1 && len(parts[1]) == 1 {
+		for c := parts[0][0]; c <= parts[1][0]; c += byte(step) {
+			values = append(values, string(c))
+		}
+	} else {
+		return []string{pattern}
+	}
+
+	var hosts []string
+	for _, value := range values {
+		// the suffix may contain more ranges
+		hosts = append(hosts, expandHostPattern(pattern[:start]+value+pattern[end+1:])...)
+	}
+	return hosts
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := lo.Keys(m)
+	sort.Strings(keys)
+	return keys
+}
@@ -2,10 +2,12 @@ package aws
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/configservice"
 	ec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -46,6 +48,8 @@ type AWSContext struct {
 	SSM     *ssm.Client
 	Config  *configservice.Client
 	Subnets map[string]Zone
+	// Account is set when the account is scraped as a member of an organization
+	Account *organizationAccount
 }
 
 func getTags(tags []ec2Types.Tag) v1.JSONStringMap {
@@ -60,12 +64,17 @@ func (ctx AWSContext) String() string {
 	return fmt.Sprintf("account=%s user=%s region=%s", *ctx.Caller.Account, *ctx.Caller.UserId, ctx.Session.Region)
 }
 
-func (aws Scraper) getContext(ctx api.ScrapeContext, awsConfig v1.AWS, region string) (*AWSContext, error) {
+func (aws Scraper) getContext(ctx api.ScrapeContext, awsConfig v1.AWS, region string, account *organizationAccount) (*AWSContext, error) {
 	session, err := NewSession(ctx, *awsConfig.AWSConnection, region)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AWS session for region=%q: %w", region, err)
 	}
 
+	if account != nil && account.RoleARN != "" {
+		// Chain the role of the account onto the credentials of the scraper
+		assumeRole(session, account.RoleARN)
+	}
+
 	STS := sts.NewFromConfig(*session)
 	caller, err := STS.GetCallerIdentity(ctx, nil)
 	if err != nil {
@@ -86,6 +95,7 @@ func (aws Scraper) getContext(ctx api.ScrapeContext, awsConfig v1.AWS, region st
 		IAM:           iam.NewFromConfig(*session),
 		Subnets:       make(map[string]Zone),
 		Config:        configservice.NewFromConfig(*session),
+		Account:       account,
 	}, nil
 }
 
@@ -301,17 +311,25 @@ func (aws Scraper) account(ctx *AWSContext, config v1.AWS, results *v1.ScrapeRes
 	name := *ctx.Caller.Account
 	if len(aliases.AccountAliases) > 0 {
 		name = (*aliases).AccountAliases[0]
+	} else if ctx.Account != nil && ctx.Account.Name != "" {
+		name = ctx.Account.Name
 	}
 
 	tags := make(map[string]string)
 	tags["account"] = *ctx.Caller.Account
+
+	accountTags := lo.Assign(tags)
+	if ctx.Account != nil && ctx.Account.OUPath != "" {
+		accountTags["ou"] = ctx.Account.OUPath
+	}
+
 	*results = append(*results, v1.ScrapeResult{
 		Type:        v1.AWSAccount,
 		BaseScraper: config.BaseScraper,
 		Config:      summary.SummaryMap,
 		ConfigClass: "Account",
 		Name:        name,
-		Tags:        tags,
+		Tags:        accountTags,
 		Aliases:     aliases.AccountAliases,
 		ID:          *ctx.Caller.Account,
 	})
@@ -620,6 +638,10 @@ func (aws Scraper) instances(ctx *AWSContext, config v1.AWS, results *v1.ScrapeR
 			tags["account"] = *ctx.Caller.Account
 			tags["network"] = instance.VpcID
 			tags["subnet"] = instance.SubnetID
+			// other scrapers (e.g. the ansible hosts) relate their configs to instances by this tag
+			if instance.PrivateIPAddress != "" {
+				tags["private-ip"] = instance.PrivateIPAddress
+			}
 
 			*results = append(*results, v1.ScrapeResult{
 				Type:                v1.AWSEC2Instance,
@@ -1103,49 +1125,129 @@ func (aws Scraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 	results := &v1.ScrapeResults{}
 
 	for _, awsConfig := range ctx.ScrapeConfig().Spec.AWS {
-		for _, region := range awsConfig.Region {
-			awsCtx, err := aws.getContext(ctx, awsConfig, region)
+		if awsConfig.Organization != nil {
+			aws.scrapeOrganization(ctx, awsConfig, results)
+		} else {
+			aws.scrapeAccount(ctx, awsConfig, nil, results)
+		}
+
+		// The trails are read with the credentials of the scraper (i.e. the management account)
+		// as they already cover every account and region.
+		if !awsConfig.Excludes("cloudtrail") && awsConfig.CloudTrail.UsesAlternateSource() {
+			awsCtx, err := aws.getContext(ctx, awsConfig, "us-east-1", nil)
 			if err != nil {
 				results.Errorf(err, "failed to create AWS context")
 				continue
 			}
+			aws.cloudtrailSources(awsCtx, awsConfig, results)
+		}
+	}
+
+	return *results
+}
+
+// scrapeOrganization scrapes every account of the organization with its own context.
+func (aws Scraper) scrapeOrganization(ctx api.ScrapeContext, awsConfig v1.AWS, results *v1.ScrapeResults) {
+	awsCtx, err := aws.getContext(ctx, awsConfig, "us-east-1", nil)
+	if err != nil {
+		results.Errorf(err, "failed to create AWS context")
+		return
+	}
+
+	org := awsConfig.Organization
+	accounts, err := aws.organization(awsCtx, awsConfig, results)
+	if err != nil {
+		if len(org.Accounts) == 0 {
+			results.Errorf(err, "failed to list the accounts of the organization")
+			return
+		}
+		// The accounts are explicitly listed, the scraper's credentials
+		// aren't required to have access to the organization.
+		logger.Warnf("failed to list the organization of %s: %v", awsCtx, err)
+	}
+
+	// the roles are in the partition of the scraper's credentials e.g. aws-cn or aws-us-gov
+	partition := "aws"
+	if caller, err := arn.Parse(lo.FromPtr(awsCtx.Caller.Arn)); err == nil {
+		partition = caller.Partition
+	}
+
+	for _, account := range organizationAccounts(*org, accounts, partition, lo.FromPtr(awsCtx.Caller.Account)) {
+		account := account
+		aws.scrapeAccount(ctx, awsConfig, &account, results)
+	}
+}
 
-			logger.Infof("Scraping %s", awsCtx)
-			aws.subnets(awsCtx, awsConfig, results)
-			aws.instances(awsCtx, awsConfig, results)
-			aws.vpcs(awsCtx, awsConfig, results)
-			aws.securityGroups(awsCtx, awsConfig, results)
-			aws.routes(awsCtx, awsConfig, results)
-			aws.dhcp(awsCtx, awsConfig, results)
-			aws.eksClusters(awsCtx, awsConfig, results)
-			aws.ebs(awsCtx, awsConfig, results)
-			aws.efs(awsCtx, awsConfig, results)
-			aws.rds(awsCtx, awsConfig, results)
-			aws.config(awsCtx, awsConfig, results)
-			aws.loadBalancers(awsCtx, awsConfig, results)
-			aws.containerImages(awsCtx, awsConfig, results)
-			aws.cloudtrail(awsCtx, awsConfig, results)
-			aws.availabilityZones(awsCtx, awsConfig, results)
-			// We are querying half a million amis, need to optimize for this
-			// aws.ami(awsCtx, awsConfig, results)
+// organizationAccounts returns the accounts to scrape, with the role to assume in each of them
+// except for the account of the scraper's credentials.
+func organizationAccounts(org v1.AWSOrganizationAccounts, accounts map[string]organizationAccount, partition, caller string) []organizationAccount {
+	accountIDs := org.Accounts
+	if len(accountIDs) == 0 {
+		accountIDs = lo.Keys(accounts)
+		sort.Strings(accountIDs)
+	}
+
+	var selected []organizationAccount
+	for _, id := range accountIDs {
+		if lo.Contains(org.Exclude, id) {
+			continue
 		}
 
-		awsCtx, err := aws.getContext(ctx, awsConfig, "us-east-1")
+		account := accounts[id]
+		account.ID = id
+		if id != caller {
+			account.RoleARN = org.GetRoleARN(partition, id)
+		}
+		selected = append(selected, account)
+	}
+	return selected
+}
+
+// scrapeAccount scrapes a single account in all the configured regions.
+// The account is nil when scraping the account of the scraper's credentials.
+func (aws Scraper) scrapeAccount(ctx api.ScrapeContext, awsConfig v1.AWS, account *organizationAccount, results *v1.ScrapeResults) {
+	for _, region := range awsConfig.Region {
+		awsCtx, err := aws.getContext(ctx, awsConfig, region, account)
 		if err != nil {
 			results.Errorf(err, "failed to create AWS context")
 			continue
 		}
 
-		aws.account(awsCtx, awsConfig, results)
-		aws.users(awsCtx, awsConfig, results)
-		aws.iamRoles(awsCtx, awsConfig, results)
-		aws.iamProfiles(awsCtx, awsConfig, results)
-		aws.dnsZones(awsCtx, awsConfig, results)
-		aws.trustedAdvisor(awsCtx, awsConfig, results)
-		aws.s3Buckets(awsCtx, awsConfig, results)
+		logger.Infof("Scraping %s", awsCtx)
+		aws.subnets(awsCtx, awsConfig, results)
+		aws.instances(awsCtx, awsConfig, results)
+		aws.vpcs(awsCtx, awsConfig, results)
+		aws.securityGroups(awsCtx, awsConfig, results)
+		aws.routes(awsCtx, awsConfig, results)
+		aws.dhcp(awsCtx, awsConfig, results)
+		aws.eksClusters(awsCtx, awsConfig, results)
+		aws.ebs(awsCtx, awsConfig, results)
+		aws.efs(awsCtx, awsConfig, results)
+		aws.rds(awsCtx, awsConfig, results)
+		aws.config(awsCtx, awsConfig, results)
+		aws.configHistory(awsCtx, awsConfig, results)
+		aws.loadBalancers(awsCtx, awsConfig, results)
+		aws.containerImages(awsCtx, awsConfig, results)
+		aws.cloudtrail(awsCtx, awsConfig, results)
+		aws.availabilityZones(awsCtx, awsConfig, results)
+		aws.cloudformationStacks(awsCtx, awsConfig, results)
+		// We are querying half a million amis, need to optimize for this
+		// aws.ami(awsCtx, awsConfig, results)
+	}
+
+	awsCtx, err := aws.getContext(ctx, awsConfig, "us-east-1", account)
+	if err != nil {
+		results.Errorf(err, "failed to create AWS context")
+		return
 	}
 
-	return *results
+	aws.account(awsCtx, awsConfig, results)
+	aws.users(awsCtx, awsConfig, results)
+	aws.iamRoles(awsCtx, awsConfig, results)
+	aws.iamProfiles(awsCtx, awsConfig, results)
+	aws.dnsZones(awsCtx, awsConfig, results)
+	aws.trustedAdvisor(awsCtx, awsConfig, results)
+	aws.s3Buckets(awsCtx, awsConfig, results)
 }
 
 func getConfigTypeById(id string) string {
@@ -1168,6 +1270,19 @@ func getConfigTypeById(id string) string {
 	return ""
 }
 
+// resourceTypeAliases maps the resource types used by CloudFormation
+// and AWS Config that are scraped under a different config type.
+var resourceTypeAliases = map[string]string{
+	"AWS::EC2::Volume": v1.AWSEBSVolume,
+}
+
+func getConfigTypeByResourceType(resourceType string) string {
+	if configType, ok := resourceTypeAliases[resourceType]; ok {
+		return configType
+	}
+	return resourceType
+}
+
 func getRegionFromArn(arn, resourceType string) string {
 	return strings.Split(strings.ReplaceAll(arn, fmt.Sprintf("arn:aws:%s:", resourceType), ""), ":")[0]
 }
@@ -24,11 +24,17 @@ func NewSession(ctx api.ScrapeContext, conn v1.AWSConnection, region string) (*a
 		return nil, err
 	}
 	if conn.AssumeRole != "" {
-		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(*cfg), conn.AssumeRole))
+		assumeRole(cfg, conn.AssumeRole)
 	}
 	return cfg, nil
 }
 
+// assumeRole replaces the credentials of the config with the ones of the role,
+// assumed with the current credentials.
+func assumeRole(cfg *aws.Config, roleARN string) {
+	cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(*cfg), roleARN))
+}
+
 // EndpointResolver ...
 type EndpointResolver struct {
 	Endpoint string
@@ -0,0 +1,208 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cloudformationTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/samber/lo"
+)
+
+// cloudformationEventsMaxAge is how far back stack events are paged through.
+const cloudformationEventsMaxAge = 7 * 24 * time.Hour
+
+// cloudformationAPI is the part of the cloudformation client used to scrape the stacks.
+type cloudformationAPI interface {
+	cloudformation.DescribeStacksAPIClient
+	cloudformation.ListStackResourcesAPIClient
+	cloudformation.DescribeStackEventsAPIClient
+	cloudformation.DescribeStackResourceDriftsAPIClient
+}
+
+// stackResourceARNs are the formats of the arn of the resources that CloudFormation identifies by name,
+// when the resource is scraped with the arn as its id or alias.
+var stackResourceARNs = map[string]string{
+	"AWS::ECR::Repository": "arn:%s:ecr:%s:%s:repository/%s",
+}
+
+func (aws Scraper) cloudformationStacks(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.Includes("CloudFormation") {
+		return
+	}
+
+	aws.scrapeCloudformationStacks(ctx, cloudformation.NewFromConfig(*ctx.Session), config, results)
+}
+
+func (aws Scraper) scrapeCloudformationStacks(ctx *AWSContext, CloudFormation cloudformationAPI, config v1.AWS, results *v1.ScrapeResults) {
+	var stacks []cloudformationTypes.Stack
+	paginator := cloudformation.NewDescribeStacksPaginator(CloudFormation, &cloudformation.DescribeStacksInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			results.Errorf(err, "failed to describe cloudformation stacks")
+			return
+		}
+		stacks = append(stacks, page.Stacks...)
+	}
+
+	for _, stack := range stacks {
+		stackID := lo.FromPtr(stack.StackId)
+		selfExternalID := v1.ExternalID{ExternalID: []string{stackID}, ConfigType: v1.AWSCloudFormationStack}
+
+		var resources []cloudformationTypes.StackResourceSummary
+		resourcePages := cloudformation.NewListStackResourcesPaginator(CloudFormation, &cloudformation.ListStackResourcesInput{StackName: stack.StackId})
+		for resourcePages.HasMorePages() {
+			page, err := resourcePages.NextPage(ctx)
+			if err != nil {
+				results.Errorf(err, "failed to list resources of stack %s", lo.FromPtr(stack.StackName))
+				break
+			}
+			resources = append(resources, page.StackResourceSummaries...)
+		}
+
+		var relationships v1.RelationshipResults
+		for _, resource := range resources {
+			resourceID := stackResourceID(stackID, resource)
+			if resourceID == "" {
+				continue
+			}
+
+			relationships = append(relationships, v1.RelationshipResult{
+				ConfigExternalID:  selfExternalID,
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{resourceID}, ConfigType: getConfigTypeByResourceType(lo.FromPtr(resource.ResourceType))},
+				Relationship:      "CloudFormationStackResource",
+			})
+		}
+
+		tags := map[string]string{
+			"account": *ctx.Caller.Account,
+			"region":  ctx.Session.Region,
+		}
+		for _, tag := range stack.Tags {
+			tags[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+		}
+
+		*results = append(*results, v1.ScrapeResult{
+			Type:                v1.AWSCloudFormationStack,
+			CreatedAt:           stack.CreationTime,
+			BaseScraper:         config.BaseScraper,
+			Config:              map[string]any{"stack": stack, "resources": resources},
+			ConfigClass:         "Stack",
+			Name:                lo.FromPtr(stack.StackName),
+			Tags:                tags,
+			ID:                  stackID,
+			Aliases:             []string{"AWSCloudFormation/" + stackID},
+			Status:              string(stack.StackStatus),
+			ParentExternalID:    *ctx.Caller.Account,
+			ParentType:          v1.AWSAccount,
+			RelationshipResults: relationships,
+			Ignore:              []string{"stack.LastUpdatedTime", "stack.DriftInformation.LastCheckTimestamp"},
+		})
+
+		aws.cloudformationStackEvents(ctx, CloudFormation, stack, results)
+		aws.cloudformationStackDrifts(ctx, CloudFormation, stack, results)
+	}
+}
+
+// stackResourceID returns the id a resource of the stack is scraped with, the arn of the resources
+// that CloudFormation identifies by name and that are scraped with their arn, or else the physical id
+// which is the arn of some resources e.g. load balancers.
+func stackResourceID(stackID string, resource cloudformationTypes.StackResourceSummary) string {
+	physicalID := lo.FromPtr(resource.PhysicalResourceId)
+	if physicalID == "" || strings.HasPrefix(physicalID, "arn:") {
+		return physicalID
+	}
+
+	format, ok := stackResourceARNs[lo.FromPtr(resource.ResourceType)]
+	if !ok {
+		return physicalID
+	}
+	// the resources are in the partition, region and account of the stack
+	stack, err := arn.Parse(stackID)
+	if err != nil {
+		return physicalID
+	}
+	return fmt.Sprintf(format, stack.Partition, stack.Region, stack.AccountID, physicalID)
+}
+
+func (aws Scraper) cloudformationStackEvents(ctx *AWSContext, client cloudformationAPI, stack cloudformationTypes.Stack, results *v1.ScrapeResults) {
+	since := time.Now().Add(-cloudformationEventsMaxAge)
+	paginator := cloudformation.NewDescribeStackEventsPaginator(client, &cloudformation.DescribeStackEventsInput{StackName: stack.StackId})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			results.Errorf(err, "failed to describe events of stack %s", lo.FromPtr(stack.StackName))
+			return
+		}
+
+		for _, event := range page.StackEvents {
+			results.AddChange(v1.ChangeResult{
+				ExternalID:       lo.FromPtr(stack.StackId),
+				ConfigType:       v1.AWSCloudFormationStack,
+				ExternalChangeID: lo.FromPtr(event.EventId),
+				ChangeType:       string(event.ResourceStatus),
+				Severity:         cloudformationEventSeverity(string(event.ResourceStatus)),
+				Summary:          strings.TrimSpace(fmt.Sprintf("%s %s", lo.FromPtr(event.LogicalResourceId), lo.FromPtr(event.ResourceStatusReason))),
+				Source:           fmt.Sprintf("AWS::CloudFormation::%s:%s", ctx.Session.Region, *ctx.Caller.Account),
+				CreatedAt:        event.Timestamp,
+				Details:          v1.NewJSON(event),
+			})
+		}
+
+		// Events are returned newest first, older events would have been
+		// saved on previous runs.
+		if len(page.StackEvents) == 0 || !lo.FromPtr(page.StackEvents[len(page.StackEvents)-1].Timestamp).After(since) {
+			return
+		}
+	}
+}
+
+func (aws Scraper) cloudformationStackDrifts(ctx *AWSContext, client cloudformationAPI, stack cloudformationTypes.Stack, results *v1.ScrapeResults) {
+	if stack.DriftInformation == nil || stack.DriftInformation.StackDriftStatus != cloudformationTypes.StackDriftStatusDrifted {
+		return
+	}
+
+	input := &cloudformation.DescribeStackResourceDriftsInput{
+		StackName: stack.StackId,
+		StackResourceDriftStatusFilters: []cloudformationTypes.StackResourceDriftStatus{
+			cloudformationTypes.StackResourceDriftStatusModified,
+			cloudformationTypes.StackResourceDriftStatusDeleted,
+		},
+	}
+	paginator := cloudformation.NewDescribeStackResourceDriftsPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			results.Errorf(err, "failed to describe drifts of stack %s", lo.FromPtr(stack.StackName))
+			return
+		}
+
+		for _, drift := range page.StackResourceDrifts {
+			analysis := results.Analysis(fmt.Sprintf("CloudFormation drift: %s", lo.FromPtr(drift.LogicalResourceId)), v1.AWSCloudFormationStack, lo.FromPtr(stack.StackId))
+			analysis.AnalysisType = models.AnalysisTypeCompliance
+			analysis.Severity = models.SeverityMedium
+			analysis.Source = "AWS CloudFormation"
+			analysis.Status = models.AnalysisStatusOpen
+			analysis.Summary = fmt.Sprintf("%s (%s) is %s", lo.FromPtr(drift.LogicalResourceId), lo.FromPtr(drift.ResourceType), strings.ToLower(string(drift.StackResourceDriftStatus)))
+			analysis.Analysis = v1.NewJSON(drift)
+			for _, difference := range drift.PropertyDifferences {
+				analysis.Message(fmt.Sprintf("%s: expected %s, actual %s", lo.FromPtr(difference.PropertyPath), lo.FromPtr(difference.ExpectedValue), lo.FromPtr(difference.ActualValue)))
+			}
+		}
+	}
+}
+
+func cloudformationEventSeverity(status string) string {
+	switch {
+	case strings.HasSuffix(status, "_FAILED"):
+		return string(models.SeverityHigh)
+	case strings.Contains(status, "ROLLBACK"):
+		return string(models.SeverityMedium)
+	}
+	return string(models.SeverityInfo)
+}
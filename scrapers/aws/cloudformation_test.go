@@ -0,0 +1,120 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cloudformationTypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+type fakeCloudFormation struct {
+	stacks    []cloudformationTypes.Stack
+	resources []cloudformationTypes.StackResourceSummary
+	events    []cloudformationTypes.StackEvent
+	drifts    []cloudformationTypes.StackResourceDrift
+}
+
+func (f fakeCloudFormation) DescribeStacks(context.Context, *cloudformation.DescribeStacksInput, ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	return &cloudformation.DescribeStacksOutput{Stacks: f.stacks}, nil
+}
+
+func (f fakeCloudFormation) ListStackResources(context.Context, *cloudformation.ListStackResourcesInput, ...func(*cloudformation.Options)) (*cloudformation.ListStackResourcesOutput, error) {
+	return &cloudformation.ListStackResourcesOutput{StackResourceSummaries: f.resources}, nil
+}
+
+func (f fakeCloudFormation) DescribeStackEvents(context.Context, *cloudformation.DescribeStackEventsInput, ...func(*cloudformation.Options)) (*cloudformation.DescribeStackEventsOutput, error) {
+	return &cloudformation.DescribeStackEventsOutput{StackEvents: f.events}, nil
+}
+
+func (f fakeCloudFormation) DescribeStackResourceDrifts(context.Context, *cloudformation.DescribeStackResourceDriftsInput, ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourceDriftsOutput, error) {
+	return &cloudformation.DescribeStackResourceDriftsOutput{StackResourceDrifts: f.drifts}, nil
+}
+
+func TestCloudformationStacks(t *testing.T) {
+	stackID := "arn:aws:cloudformation:eu-west-1:123456789012:stack/web/1"
+	loadBalancerARN := "arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/web/1"
+	client := fakeCloudFormation{
+		stacks: []cloudformationTypes.Stack{{
+			StackId:          aws.String(stackID),
+			StackName:        aws.String("web"),
+			StackStatus:      cloudformationTypes.StackStatusUpdateComplete,
+			DriftInformation: &cloudformationTypes.StackDriftInformation{StackDriftStatus: cloudformationTypes.StackDriftStatusDrifted},
+			Tags:             []cloudformationTypes.Tag{{Key: aws.String("team"), Value: aws.String("web")}},
+		}},
+		resources: []cloudformationTypes.StackResourceSummary{
+			{LogicalResourceId: aws.String("Bucket"), PhysicalResourceId: aws.String("web-assets"), ResourceType: aws.String("AWS::S3::Bucket")},
+			{LogicalResourceId: aws.String("Pending"), ResourceType: aws.String("AWS::S3::Bucket")},
+			{LogicalResourceId: aws.String("Repository"), PhysicalResourceId: aws.String("web"), ResourceType: aws.String("AWS::ECR::Repository")},
+			{LogicalResourceId: aws.String("LoadBalancer"), PhysicalResourceId: aws.String(loadBalancerARN), ResourceType: aws.String("AWS::ElasticLoadBalancingV2::LoadBalancer")},
+		},
+		events: []cloudformationTypes.StackEvent{{
+			EventId:              aws.String("event-1"),
+			LogicalResourceId:    aws.String("Bucket"),
+			ResourceStatus:       cloudformationTypes.ResourceStatusUpdateFailed,
+			ResourceStatusReason: aws.String("access denied"),
+			Timestamp:            aws.Time(time.Now()),
+		}},
+		drifts: []cloudformationTypes.StackResourceDrift{{
+			LogicalResourceId:        aws.String("Bucket"),
+			ResourceType:             aws.String("AWS::S3::Bucket"),
+			StackResourceDriftStatus: cloudformationTypes.StackResourceDriftStatusModified,
+			PropertyDifferences: []cloudformationTypes.PropertyDifference{{
+				PropertyPath: aws.String("/VersioningConfiguration/Status"), ExpectedValue: aws.String("Enabled"), ActualValue: aws.String("Suspended"),
+			}},
+		}},
+	}
+
+	ctx := &AWSContext{
+		ScrapeContext: api.NewScrapeContext(context.TODO(), nil, nil),
+		Session:       &aws.Config{Region: "eu-west-1"},
+		Caller:        &sts.GetCallerIdentityOutput{Account: aws.String("123456789012")},
+	}
+	var results v1.ScrapeResults
+	Scraper{}.scrapeCloudformationStacks(ctx, client, v1.AWS{}, &results)
+
+	var stack *v1.ScrapeResult
+	var changes []v1.ChangeResult
+	var analysis []*v1.AnalysisResult
+	for i, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		if r.Type == v1.AWSCloudFormationStack && r.Config != nil {
+			stack = &results[i]
+		}
+		changes = append(changes, r.Changes...)
+		if r.AnalysisResult != nil {
+			analysis = append(analysis, r.AnalysisResult)
+		}
+	}
+
+	if stack == nil {
+		t.Fatalf("expected the stack, got %v", results)
+	}
+	if stack.Status != "UPDATE_COMPLETE" || stack.Tags["team"] != "web" || stack.ParentExternalID != "123456789012" {
+		t.Errorf("unexpected stack %s %v %s", stack.Status, stack.Tags, stack.ParentExternalID)
+	}
+	var related []string
+	for _, relationship := range stack.RelationshipResults {
+		related = append(related, relationship.RelatedExternalID.ExternalID...)
+	}
+	// the repository is scraped with its arn, the load balancer's physical id is its arn
+	if strings.Join(related, " ") != "web-assets arn:aws:ecr:eu-west-1:123456789012:repository/web "+loadBalancerARN {
+		t.Errorf("expected only the created resources to be related by physical id or arn, got %v", related)
+	}
+
+	if len(changes) != 1 || changes[0].ChangeType != "UPDATE_FAILED" || changes[0].Severity != "high" || changes[0].Summary != "Bucket access denied" {
+		t.Errorf("unexpected changes %v", changes)
+	}
+
+	if len(analysis) != 1 || analysis[0].Analyzer != "CloudFormation drift: Bucket" || len(analysis[0].Messages) != 1 {
+		t.Errorf("unexpected drift analysis %v", analysis)
+	}
+}
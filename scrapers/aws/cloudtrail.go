@@ -43,7 +43,12 @@ func lookupEvents(ctx *AWSContext, input *cloudtrail.LookupEventsInput, c chan<-
 	return nil
 }
 
-var LastEventTime = sync.Map{}
+// Cursor types of the cloudtrail sources
+const (
+	cloudtrailCursorType       = "aws/cloudtrail"
+	cloudtrailS3CursorType     = "aws/cloudtrail/s3"
+	cloudtrailAthenaCursorType = "aws/cloudtrail/athena"
+)
 
 type CloudTrailEvent struct {
 	UserIdentity struct {
@@ -61,15 +66,23 @@ func (t *CloudTrailEvent) FromJSON(j string) error {
 	return json.Unmarshal([]byte(j), t)
 }
 
+// GetCreatedBy returns the name of the identity that made the request.
+func (t CloudTrailEvent) GetCreatedBy() string {
+	if t.UserIdentity.Username != "" {
+		return t.UserIdentity.Username
+	}
+	return t.UserIdentity.SessionContext.SessionIssuer.Username
+}
+
 func (aws Scraper) cloudtrail(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
-	if config.Excludes("cloudtrail") {
+	if config.Excludes("cloudtrail") || config.CloudTrail.UsesAlternateSource() {
 		return
 	}
 	if len(config.CloudTrail.Exclude) == 0 {
 		config.CloudTrail.Exclude = []string{"AssumeRole"}
 	}
 
-	var lastEventKey = ctx.Session.Region + *ctx.Caller.Account
+	var lastEventKey = fmt.Sprintf("%s/%s", *ctx.Caller.Account, ctx.Session.Region)
 	c := make(chan types.Event)
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -103,10 +116,8 @@ func (aws Scraper) cloudtrail(ctx *AWSContext, config v1.AWS, results *v1.Scrape
 					continue
 				}
 
-				if cloudtrailEvent.UserIdentity.Username != "" {
-					change.CreatedBy = &cloudtrailEvent.UserIdentity.Username
-				} else if cloudtrailEvent.UserIdentity.SessionContext.SessionIssuer.Username != "" {
-					change.CreatedBy = &cloudtrailEvent.UserIdentity.SessionContext.SessionIssuer.Username
+				if createdBy := cloudtrailEvent.GetCreatedBy(); createdBy != "" {
+					change.CreatedBy = &createdBy
 				} else if event.Username != nil {
 					change.CreatedBy = event.Username
 				}
@@ -122,14 +133,23 @@ func (aws Scraper) cloudtrail(ctx *AWSContext, config v1.AWS, results *v1.Scrape
 				results.AddChange(change)
 			}
 		}
-		LastEventTime.Store(lastEventKey, maxTime)
+		if !maxTime.IsZero() {
+			results.OnSave(func() error {
+				if err := ctx.SaveCursor(cloudtrailCursorType, lastEventKey, maxTime.Format(time.RFC3339Nano)); err != nil {
+					return fmt.Errorf("failed to save cloudtrail cursor for %s: %w", lastEventKey, err)
+				}
+				return nil
+			})
+		}
 		logger.Infof("Processed %d events, changes=%d ignored=%d", count, len(*results), ignored)
 		wg.Done()
 	}()
 
 	start := time.Now().Add(-1 * config.CloudTrail.GetMaxAge()).UTC()
-	if lastEventTime, ok := LastEventTime.Load(lastEventKey); ok {
-		start = lastEventTime.(time.Time)
+	if cursor, err := ctx.GetCursor(cloudtrailCursorType, lastEventKey); err != nil {
+		logger.Warnf("failed to get cloudtrail cursor for %s: %v", lastEventKey, err)
+	} else if lastEventTime, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
+		start = lastEventTime
 	}
 	err := lookupEvents(ctx, &cloudtrail.LookupEventsInput{
 		StartTime:  &start,
@@ -0,0 +1,524 @@
+package aws
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqsTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/flanksource/commons/logger"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/samber/lo"
+	athena "github.com/uber/athenadriver/go"
+)
+
+// CloudTrailRecord is an event as delivered by a trail to S3 or to EventBridge.
+type CloudTrailRecord struct {
+	CloudTrailEvent
+	EventID            string                     `json:"eventID"`
+	EventName          string                     `json:"eventName"`
+	EventSource        string                     `json:"eventSource"`
+	EventTime          time.Time                  `json:"eventTime"`
+	AWSRegion          string                     `json:"awsRegion"`
+	RecipientAccountID string                     `json:"recipientAccountId"`
+	ReadOnly           bool                       `json:"readOnly"`
+	Resources          []CloudTrailRecordResource `json:"resources"`
+	RequestParameters  map[string]any             `json:"requestParameters"`
+
+	raw string
+}
+
+type CloudTrailRecordResource struct {
+	ARN       string `json:"ARN"`
+	AccountID string `json:"accountId"`
+	Type      string `json:"type"`
+}
+
+// requestParameterTypes maps the request parameters that identify
+// the resource of an event to its config type.
+var requestParameterTypes = map[string]string{
+	"instanceId":           v1.AWSEC2Instance,
+	"volumeId":             v1.AWSEBSVolume,
+	"vpcId":                v1.AWSEC2VPC,
+	"subnetId":             v1.AWSEC2Subnet,
+	"groupId":              v1.AWSEC2SecurityGroup,
+	"bucketName":           v1.AWSS3Bucket,
+	"roleName":             v1.AWSIAMRole,
+	"userName":             v1.AWSIAMUser,
+	"instanceProfileName":  v1.AWSIAMInstanceProfile,
+	"dBInstanceIdentifier": v1.AWSRDSInstance,
+	"hostedZoneId":         v1.AWSZone,
+	"stackName":            v1.AWSCloudFormationStack,
+}
+
+// GetResources returns the config items an event applies to.
+// LookupEvents resolves these server side, whereas raw records only list
+// the resources of some events so the request parameters are used as well.
+func (r CloudTrailRecord) GetResources() []v1.ExternalID {
+	var ids []v1.ExternalID
+	for _, resource := range r.Resources {
+		if resource.ARN == "" || resource.Type == "" {
+			continue
+		}
+		ids = append(ids, v1.ExternalID{ConfigType: getConfigTypeByResourceType(resource.Type), ExternalID: []string{getNameFromArn(resource.ARN)}})
+	}
+	if len(ids) > 0 {
+		return ids
+	}
+
+	for param, configType := range requestParameterTypes {
+		if id, ok := r.RequestParameters[param].(string); ok && id != "" {
+			ids = append(ids, v1.ExternalID{ConfigType: configType, ExternalID: []string{id}})
+		}
+	}
+
+	// e.g. RunInstances, StartInstances and TerminateInstances
+	if set, ok := r.RequestParameters["instancesSet"].(map[string]any); ok {
+		items, _ := set["items"].([]any)
+		for _, item := range items {
+			if instance, ok := item.(map[string]any); ok {
+				if id, ok := instance["instanceId"].(string); ok && id != "" {
+					ids = append(ids, v1.ExternalID{ConfigType: v1.AWSEC2Instance, ExternalID: []string{id}})
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+// getNameFromArn returns the resource name of an arn
+// e.g. arn:aws:iam::123456789012:role/path/name => name
+func getNameFromArn(arn string) string {
+	if i := strings.LastIndexAny(arn, ":/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
+
+func (aws Scraper) cloudtrailRecordChanges(config v1.AWS, record CloudTrailRecord, results *v1.ScrapeResults) bool {
+	if record.ReadOnly || containsAny(config.CloudTrail.Exclude, record.EventName) {
+		return false
+	}
+
+	for _, resource := range record.GetResources() {
+		change := v1.ChangeResult{
+			CreatedAt:        lo.ToPtr(record.EventTime),
+			ExternalChangeID: record.EventID,
+			ChangeType:       record.EventName,
+			Details:          v1.NewJSON(record.raw),
+			Source:           fmt.Sprintf("AWS::CloudTrail::%s:%s", record.AWSRegion, record.RecipientAccountID),
+			ExternalID:       resource.ExternalID[0],
+			ConfigType:       resource.ConfigType,
+		}
+		change.Details["Event"] = record.raw
+
+		if createdBy := record.GetCreatedBy(); createdBy != "" {
+			change.CreatedBy = &createdBy
+		}
+
+		results.AddChange(change)
+	}
+
+	return true
+}
+
+// cloudtrailSources reads the events of a trail from S3, Athena or SQS.
+// Unlike LookupEvents, these sources cover every region (and account for organization trails)
+// so they are read once per scrape config.
+func (aws Scraper) cloudtrailSources(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if len(config.CloudTrail.Exclude) == 0 {
+		config.CloudTrail.Exclude = []string{"AssumeRole"}
+	}
+
+	if config.CloudTrail.S3 != nil {
+		aws.cloudtrailS3(ctx, config, results)
+	}
+	if config.CloudTrail.Athena != nil {
+		aws.cloudtrailAthena(ctx, config, results)
+	}
+	if config.CloudTrail.SQS != nil {
+		aws.cloudtrailSQS(ctx, config, results)
+	}
+}
+
+func sessionInRegion(ctx *AWSContext, config v1.AWS, region string) awsv2.Config {
+	session := ctx.Session.Copy()
+	if region != "" {
+		session.Region = region
+	} else if len(config.Region) > 0 {
+		session.Region = config.Region[0]
+	}
+	return session
+}
+
+func (aws Scraper) cloudtrailS3(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	source := config.CloudTrail.S3
+	S3 := s3.NewFromConfig(sessionInRegion(ctx, config, source.Region))
+
+	base := path.Join(source.Prefix, "AWSLogs", source.OrganizationID) + "/"
+
+	accounts := source.Accounts
+	if len(accounts) == 0 && source.OrganizationID != "" {
+		paginator := s3.NewListObjectsV2Paginator(S3, &s3.ListObjectsV2Input{Bucket: &source.Bucket, Prefix: &base, Delimiter: lo.ToPtr("/")})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				results.Errorf(err, "failed to list accounts of organization trail in s3://%s/%s", source.Bucket, base)
+				return
+			}
+			for _, prefix := range page.CommonPrefixes {
+				accounts = append(accounts, path.Base(lo.FromPtr(prefix.Prefix)))
+			}
+		}
+	} else if len(accounts) == 0 {
+		accounts = []string{*ctx.Caller.Account}
+	}
+
+	regions := source.Regions
+	if len(regions) == 0 {
+		regions = config.Region
+	}
+
+	for _, account := range accounts {
+		for _, region := range regions {
+			prefix := path.Join(base, account, "CloudTrail", region) + "/"
+			if err := aws.cloudtrailS3Prefix(ctx, S3, config, prefix, fmt.Sprintf("%s/%s", account, region), results); err != nil {
+				results.Errorf(err, "failed to read cloudtrail logs from s3://%s/%s", source.Bucket, prefix)
+			}
+		}
+	}
+}
+
+// cloudtrailLookback is how far back the events are read again on the next scrape,
+// as CloudTrail delivers log files 5 to 15 minutes late (i.e. after log files with later keys).
+// The events that were already read are deduplicated by their event id.
+const cloudtrailLookback = time.Hour
+
+// cloudtrailS3Prefix reads the log files under the prefix of an account & region.
+// Log file keys are ordered by time, so the cursor is the key to list the log files after.
+func (aws Scraper) cloudtrailS3Prefix(ctx *AWSContext, S3 *s3.Client, config v1.AWS, prefix, scope string, results *v1.ScrapeResults) error {
+	bucket := config.CloudTrail.S3.Bucket
+	startAfter, err := ctx.GetCursor(cloudtrailS3CursorType, scope)
+	if err != nil {
+		logger.Warnf("failed to get cloudtrail cursor for %s: %v", scope, err)
+	}
+	if startAfter == "" {
+		startAfter = prefix + time.Now().Add(-config.CloudTrail.GetMaxAge()).UTC().Format("2006/01/02/")
+	}
+
+	var lastKey string
+	defer func() {
+		if lastKey == "" {
+			return
+		}
+		cursor := cloudtrailS3Lookback(prefix, lastKey)
+		if cursor < startAfter {
+			cursor = startAfter
+		}
+		results.OnSave(func() error {
+			if err := ctx.SaveCursor(cloudtrailS3CursorType, scope, cursor); err != nil {
+				return fmt.Errorf("failed to save cloudtrail cursor for %s: %w", scope, err)
+			}
+			return nil
+		})
+	}()
+
+	count, ignored := 0, 0
+	paginator := s3.NewListObjectsV2Paginator(S3, &s3.ListObjectsV2Input{Bucket: &bucket, Prefix: &prefix, StartAfter: &startAfter})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range page.Contents {
+			key := lo.FromPtr(object.Key)
+			if !strings.HasSuffix(key, ".json.gz") {
+				continue
+			}
+
+			records, err := getCloudTrailLogFile(ctx, S3, bucket, key)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", key, err)
+			}
+
+			for _, record := range records {
+				count++
+				if !aws.cloudtrailRecordChanges(config, record, results) {
+					ignored++
+				}
+			}
+			lastKey = key
+		}
+	}
+
+	logger.Infof("Processed %d cloudtrail events from s3 for %s, ignored=%d", count, scope, ignored)
+	return nil
+}
+
+// cloudtrailS3Lookback returns the key of the log files delivered the lookback before a log file
+// e.g. <prefix>/2024/01/02/<account>_CloudTrail_<region>_20240102T1205Z_<id>.json.gz
+func cloudtrailS3Lookback(prefix, key string) string {
+	parts := strings.Split(path.Base(key), "_")
+	if len(parts) < 5 {
+		return key
+	}
+	delivered, err := time.Parse("20060102T1504Z", parts[3])
+	if err != nil {
+		return key
+	}
+
+	since := delivered.Add(-cloudtrailLookback)
+	return prefix + since.Format("2006/01/02/") + fmt.Sprintf("%s_%s_%s_%s", parts[0], parts[1], parts[2], since.Format("20060102T1504Z"))
+}
+
+func getCloudTrailLogFile(ctx *AWSContext, S3 *s3.Client, bucket, key string) ([]CloudTrailRecord, error) {
+	object, err := S3.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Body.Close()
+
+	reader, err := gzip.NewReader(object.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var logFile struct {
+		Records []json.RawMessage `json:"Records"`
+	}
+	if err := json.NewDecoder(reader).Decode(&logFile); err != nil {
+		return nil, err
+	}
+
+	return parseCloudTrailRecords(logFile.Records), nil
+}
+
+func parseCloudTrailRecords(raw []json.RawMessage) []CloudTrailRecord {
+	var records []CloudTrailRecord
+	for _, r := range raw {
+		var record CloudTrailRecord
+		if err := json.Unmarshal(r, &record); err != nil {
+			logger.Warnf("error parsing cloudtrail record: %v", err)
+			continue
+		}
+		record.raw = string(r)
+		records = append(records, record)
+	}
+	return records
+}
+
+const cloudtrailAthenaQuery = `
+    SELECT
+        eventid, eventname, eventsource, eventtime, awsregion, recipientaccountid,
+        json_format(CAST(useridentity AS JSON)), json_format(CAST(resources AS JSON)), requestparameters
+    FROM %s
+    WHERE readonly = 'false' AND "%s" >= ? AND eventtime >= ?
+    ORDER BY eventtime
+    LIMIT %d
+`
+
+func (aws Scraper) cloudtrailAthena(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	source := config.CloudTrail.Athena
+	region := source.Region
+	if region == "" && len(config.Region) > 0 {
+		region = config.Region[0]
+	}
+
+	athenaConf, err := getAWSAthenaConfig(ctx, *config.AWSConnection, region, source.S3BucketPath)
+	if err != nil {
+		results.Errorf(err, "failed to create athena config")
+		return
+	}
+
+	athenaDB, err := sql.Open(athena.DriverName, athenaConf.Stringify())
+	if err != nil {
+		results.Errorf(err, "failed to connect to athena")
+		return
+	}
+	defer athenaDB.Close()
+
+	// The cursor is the time the next query starts from
+	table := fmt.Sprintf("%s.%s", source.Database, source.Table)
+	cursor, err := ctx.GetCursor(cloudtrailAthenaCursorType, table)
+	if err != nil {
+		logger.Warnf("failed to get cloudtrail cursor for %s: %v", table, err)
+	}
+	since, err := time.Parse(time.RFC3339, cursor)
+	if err != nil {
+		since = time.Now().Add(-config.CloudTrail.GetMaxAge()).UTC().Truncate(time.Second)
+	}
+
+	query := fmt.Sprintf(cloudtrailAthenaQuery, table, source.GetDatePartition(), source.GetMaxEvents())
+	rows, err := athenaDB.QueryContext(ctx, query, since.Format("2006/01/02"), since.Format(time.RFC3339))
+	if err != nil {
+		results.Errorf(err, "failed to query cloudtrail events from athena")
+		return
+	}
+	defer rows.Close()
+
+	read, count, ignored := 0, 0, 0
+	var lastEventTime string
+	for rows.Next() {
+		read++
+		var eventID, eventName, eventSource, eventTime, region, account string
+		var userIdentity, resources, requestParameters sql.NullString
+		if err := rows.Scan(&eventID, &eventName, &eventSource, &eventTime, &region, &account, &userIdentity, &resources, &requestParameters); err != nil {
+			logger.Errorf("error scanning athena cloudtrail rows: %v", err)
+			continue
+		}
+		count++
+		lastEventTime = eventTime
+
+		record := CloudTrailRecord{
+			EventID:            eventID,
+			EventName:          eventName,
+			EventSource:        eventSource,
+			AWSRegion:          region,
+			RecipientAccountID: account,
+		}
+		record.EventTime, _ = time.Parse(time.RFC3339, eventTime)
+		// Field names are lower cased by athena, which json.Unmarshal matches case insensitively
+		_ = json.Unmarshal([]byte(userIdentity.String), &record.UserIdentity)
+		_ = json.Unmarshal([]byte(resources.String), &record.Resources)
+		_ = json.Unmarshal([]byte(requestParameters.String), &record.RequestParameters)
+
+		raw, _ := json.Marshal(record)
+		record.raw = string(raw)
+
+		if !aws.cloudtrailRecordChanges(config, record, results) {
+			ignored++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		results.Errorf(err, "failed to read cloudtrail events from athena")
+	}
+
+	if next, err := nextAthenaCursor(since, lastEventTime, read >= source.GetMaxEvents()); err != nil {
+		results.Errorf(err, "failed to read cloudtrail events from athena")
+	} else if next.After(since) {
+		results.OnSave(func() error {
+			if err := ctx.SaveCursor(cloudtrailAthenaCursorType, table, next.Format(time.RFC3339)); err != nil {
+				return fmt.Errorf("failed to save cloudtrail cursor for %s: %w", table, err)
+			}
+			return nil
+		})
+	}
+	logger.Infof("Processed %d cloudtrail events from athena, ignored=%d", count, ignored)
+}
+
+// nextAthenaCursor returns the time the next query starts from.
+// The lookback before the last event is read again, unless the query was
+// truncated by the limit and the next query continues from the last event.
+func nextAthenaCursor(since time.Time, lastEventTime string, truncated bool) (time.Time, error) {
+	last, err := time.Parse(time.RFC3339, lastEventTime)
+	if err != nil {
+		return since, nil
+	}
+	if !truncated {
+		if next := last.Add(-cloudtrailLookback); next.After(since) {
+			return next, nil
+		}
+		return since, nil
+	}
+	if !last.After(since) {
+		return since, fmt.Errorf("all the events read occurred at %s, the max_events must be raised to read past them", lastEventTime)
+	}
+	return last, nil
+}
+
+const cloudtrailDetailType = "AWS API Call via CloudTrail"
+
+// eventBridgeEvent is the envelope of the events EventBridge delivers to SQS.
+type eventBridgeEvent struct {
+	DetailType string          `json:"detail-type"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// sqsVisibilityTimeout hides the received messages from the other consumers
+// (and the next receive calls) until the results have been saved.
+const sqsVisibilityTimeout = 10 * 60
+
+// cloudtrailSQS consumes the events of an SQS queue.
+// Messages are deleted once the results are saved so the queue itself acts as the cursor.
+func (aws Scraper) cloudtrailSQS(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	source := config.CloudTrail.SQS
+	aws.consumeCloudtrailSQS(ctx, sqs.NewFromConfig(sessionInRegion(ctx, config, source.Region)), config, results)
+}
+
+// sqsAPI is the part of the sqs client used to consume a queue.
+type sqsAPI interface {
+	ReceiveMessage(context.Context, *sqs.ReceiveMessageInput, ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatch(context.Context, *sqs.DeleteMessageBatchInput, ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+}
+
+func (aws Scraper) consumeCloudtrailSQS(ctx *AWSContext, SQS sqsAPI, config v1.AWS, results *v1.ScrapeResults) {
+	source := config.CloudTrail.SQS
+	maxMessages := source.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = 1000
+	}
+
+	count, ignored := 0, 0
+	var batches [][]sqsTypes.DeleteMessageBatchRequestEntry
+	defer func() {
+		if len(batches) == 0 {
+			return
+		}
+		results.OnSave(func() error {
+			for _, entries := range batches {
+				if _, err := SQS.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{QueueUrl: &source.QueueURL, Entries: entries}); err != nil {
+					return fmt.Errorf("failed to delete messages from %s: %w", source.QueueURL, err)
+				}
+			}
+			return nil
+		})
+	}()
+
+	for count < maxMessages {
+		output, err := SQS.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            &source.QueueURL,
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     1,
+			VisibilityTimeout:   sqsVisibilityTimeout,
+		})
+		if err != nil {
+			results.Errorf(err, "failed to receive messages from %s", source.QueueURL)
+			return
+		}
+		if len(output.Messages) == 0 {
+			break
+		}
+
+		var processed []sqsTypes.DeleteMessageBatchRequestEntry
+		for _, message := range output.Messages {
+			count++
+
+			var event eventBridgeEvent
+			if err := json.Unmarshal([]byte(lo.FromPtr(message.Body)), &event); err != nil || event.DetailType != cloudtrailDetailType {
+				logger.Warnf("ignoring sqs message %s: not an eventbridge cloudtrail event", lo.FromPtr(message.MessageId))
+				ignored++
+			} else if records := parseCloudTrailRecords([]json.RawMessage{event.Detail}); len(records) == 0 || !aws.cloudtrailRecordChanges(config, records[0], results) {
+				ignored++
+			}
+
+			processed = append(processed, sqsTypes.DeleteMessageBatchRequestEntry{
+				Id:            message.MessageId,
+				ReceiptHandle: message.ReceiptHandle,
+			})
+		}
+		batches = append(batches, processed)
+	}
+
+	logger.Infof("Processed %d cloudtrail events from sqs, ignored=%d", count, ignored)
+}
@@ -0,0 +1,177 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqsTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestGetNameFromArn(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{"arn:aws:iam::123456789012:role/path/deployer", "deployer"},
+		{"arn:aws:s3:::flanksource-backups", "flanksource-backups"},
+		{"arn:aws:ec2:eu-west-1:123456789012:instance/i-0123456789", "i-0123456789"},
+		{"i-0123456789", "i-0123456789"},
+	}
+
+	for _, test := range tests {
+		result := getNameFromArn(test.input)
+		if result != test.expectedOutput {
+			t.Errorf("Input: %s, Expected: %s, Got: %s", test.input, test.expectedOutput, result)
+		}
+	}
+}
+
+func TestCloudTrailRecordResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   string
+		expected []v1.ExternalID
+	}{
+		{
+			name:     "resources",
+			record:   `{"eventID": "1", "resources": [{"ARN": "arn:aws:iam::123456789012:role/deployer", "accountId": "123456789012", "type": "AWS::IAM::Role"}]}`,
+			expected: []v1.ExternalID{{ConfigType: v1.AWSIAMRole, ExternalID: []string{"deployer"}}},
+		},
+		{
+			name:     "request parameters",
+			record:   `{"eventID": "2", "requestParameters": {"volumeId": "vol-123", "force": false}}`,
+			expected: []v1.ExternalID{{ConfigType: v1.AWSEBSVolume, ExternalID: []string{"vol-123"}}},
+		},
+		{
+			name:   "instances set",
+			record: `{"eventID": "3", "requestParameters": {"instancesSet": {"items": [{"instanceId": "i-1"}, {"instanceId": "i-2"}]}}}`,
+			expected: []v1.ExternalID{
+				{ConfigType: v1.AWSEC2Instance, ExternalID: []string{"i-1"}},
+				{ConfigType: v1.AWSEC2Instance, ExternalID: []string{"i-2"}},
+			},
+		},
+		{
+			name:   "no resources",
+			record: `{"eventID": "4", "requestParameters": {"filterSet": {}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records := parseCloudTrailRecords([]json.RawMessage{json.RawMessage(test.record)})
+			if len(records) != 1 {
+				t.Fatalf("expected 1 record, got %d", len(records))
+			}
+
+			resources := records[0].GetResources()
+			if len(resources) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, resources)
+			}
+			for i := range resources {
+				if resources[i].String() != test.expected[i].String() {
+					t.Errorf("expected %v, got %v", test.expected[i], resources[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCloudtrailS3Lookback(t *testing.T) {
+	prefix := "AWSLogs/123456789012/CloudTrail/eu-west-1/"
+	key := prefix + "2024/01/02/123456789012_CloudTrail_eu-west-1_20240102T0005Z_abc.json.gz"
+
+	// the log files delivered late, after the last key, are listed again
+	if lookback := cloudtrailS3Lookback(prefix, key); lookback != prefix+"2024/01/01/123456789012_CloudTrail_eu-west-1_20240101T2305Z" {
+		t.Errorf("expected the key an hour before, across days, got %s", lookback)
+	}
+	if lookback := cloudtrailS3Lookback(prefix, prefix+"2024/01/02/digest.json.gz"); lookback != prefix+"2024/01/02/digest.json.gz" {
+		t.Errorf("expected unknown keys to be kept, got %s", lookback)
+	}
+}
+
+func TestNextAthenaCursor(t *testing.T) {
+	since := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		last      string
+		truncated bool
+		expected  time.Time
+		err       bool
+	}{
+		{name: "no events", expected: since},
+		{name: "late events are read again", last: "2024-01-02T05:00:00Z", expected: since.Add(4 * time.Hour)},
+		{name: "the lookback doesn't go back before the query", last: "2024-01-02T00:30:00Z", expected: since},
+		{name: "truncated queries continue from the last event", last: "2024-01-02T05:00:00Z", truncated: true, expected: since.Add(5 * time.Hour)},
+		{name: "truncated queries that can't progress", last: "2024-01-02T00:00:00Z", truncated: true, expected: since, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next, err := nextAthenaCursor(since, test.last, test.truncated)
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !next.Equal(test.expected) {
+				t.Errorf("expected %s, got %s", test.expected, next)
+			}
+		})
+	}
+}
+
+type fakeSQS struct {
+	messages []sqsTypes.Message
+	deleted  []string
+}
+
+func (f *fakeSQS) ReceiveMessage(_ context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	messages := f.messages
+	f.messages = nil
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (f *fakeSQS) DeleteMessageBatch(_ context.Context, input *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	for _, entry := range input.Entries {
+		f.deleted = append(f.deleted, *entry.Id)
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func TestCloudtrailSQSDeletesOnSave(t *testing.T) {
+	event := `{"detail-type": "AWS API Call via CloudTrail", "detail": {"eventID": "1", "eventName": "DeleteVolume", "requestParameters": {"volumeId": "vol-123"}}}`
+	client := &fakeSQS{messages: []sqsTypes.Message{
+		{MessageId: aws.String("1"), ReceiptHandle: aws.String("r1"), Body: aws.String(event)},
+		{MessageId: aws.String("2"), ReceiptHandle: aws.String("r2"), Body: aws.String(`{"detail-type": "EC2 Instance State-change Notification"}`)},
+	}}
+
+	ctx := &AWSContext{
+		ScrapeContext: api.NewScrapeContext(context.TODO(), nil, nil),
+		Session:       &aws.Config{Region: "eu-west-1"},
+	}
+	var results v1.ScrapeResults
+	Scraper{}.consumeCloudtrailSQS(ctx, client, v1.AWS{CloudTrail: v1.CloudTrail{SQS: &v1.CloudTrailSQS{QueueURL: "queue"}}}, &results)
+
+	var changes []v1.ChangeResult
+	for _, r := range results {
+		changes = append(changes, r.Changes...)
+	}
+	if len(changes) != 1 || changes[0].ExternalID != "vol-123" {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+
+	if len(client.deleted) != 0 {
+		t.Fatalf("expected the messages to be kept until the results are saved, got %v deleted", client.deleted)
+	}
+	if err := results.Saved(); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.deleted) != 2 {
+		t.Errorf("expected the messages to be deleted once saved, got %v", client.deleted)
+	}
+}
@@ -1,9 +1,17 @@
 package aws
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/configservice"
 	"github.com/aws/aws-sdk-go-v2/service/configservice/types"
+	"github.com/flanksource/commons/logger"
 	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db"
+	"github.com/samber/lo"
 )
 
 func (aws Scraper) config(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
@@ -41,3 +49,204 @@ func (aws Scraper) config(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResu
 	}
 
 }
+
+// configHistoryCursorType keeps track of the last configuration item captured per resource type
+const configHistoryCursorType = "aws/config"
+
+// configHistoryAPI is the subset of the AWS Config client used to read the configuration timeline
+type configHistoryAPI interface {
+	configservice.GetDiscoveredResourceCountsAPIClient
+	configservice.GetResourceConfigHistoryAPIClient
+	SelectResourceConfig(context.Context, *configservice.SelectResourceConfigInput, ...func(*configservice.Options)) (*configservice.SelectResourceConfigOutput, error)
+}
+
+func (aws Scraper) configHistory(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) {
+	if !config.ConfigHistory.Enabled {
+		return
+	}
+
+	aws.scrapeConfigHistory(ctx, ctx.Config, config, results)
+}
+
+// scrapeConfigHistory only reads the history of the resources captured since the last run,
+// which are found with an advanced query on the capture time of their latest configuration item.
+func (aws Scraper) scrapeConfigHistory(ctx *AWSContext, client configHistoryAPI, config v1.AWS, results *v1.ScrapeResults) {
+	resourceTypes := config.ConfigHistory.ResourceTypes
+	if len(resourceTypes) == 0 {
+		paginator := configservice.NewGetDiscoveredResourceCountsPaginator(client, &configservice.GetDiscoveredResourceCountsInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				results.Errorf(err, "failed to get discovered resource counts")
+				return
+			}
+			for _, count := range page.ResourceCounts {
+				resourceTypes = append(resourceTypes, string(count.ResourceType))
+			}
+		}
+	}
+
+	for _, resourceType := range resourceTypes {
+		scope := fmt.Sprintf("%s/%s/%s", *ctx.Caller.Account, ctx.Session.Region, resourceType)
+		since := time.Now().Add(-config.ConfigHistory.GetMaxAge()).UTC()
+		if cursor, err := ctx.GetCursor(configHistoryCursorType, scope); err != nil {
+			logger.Warnf("failed to get config history cursor for %s: %v", scope, err)
+		} else if lastCaptureTime, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
+			since = lastCaptureTime
+		}
+
+		resources, err := changedResources(ctx, client, resourceType, since)
+		if err != nil {
+			results.Errorf(err, "failed to list the resources of type %s captured since %s", resourceType, since)
+			continue
+		}
+
+		var lastCaptureTime time.Time
+		var failed bool
+		for _, resource := range resources {
+			captured, err := aws.resourceConfigHistory(ctx, client, resource, since, results)
+			if err != nil {
+				results.Errorf(err, "failed to get config history of %s/%s", resourceType, deref(resource.ResourceId))
+				failed = true
+			} else if captured.After(lastCaptureTime) {
+				lastCaptureTime = captured
+			}
+		}
+
+		// The resources that failed are retried on the next run
+		if failed || lastCaptureTime.IsZero() {
+			continue
+		}
+		results.OnSave(func() error {
+			if err := ctx.SaveCursor(configHistoryCursorType, scope, lastCaptureTime.Format(time.RFC3339Nano)); err != nil {
+				return fmt.Errorf("failed to save config history cursor for %s: %w", scope, err)
+			}
+			return nil
+		})
+	}
+}
+
+// changedResources returns the resources of a type whose configuration was captured after the given time
+func changedResources(ctx *AWSContext, client configHistoryAPI, resourceType string, since time.Time) ([]types.ResourceIdentifier, error) {
+	input := &configservice.SelectResourceConfigInput{
+		Expression: lo.ToPtr(fmt.Sprintf("SELECT resourceId, resourceType WHERE resourceType = '%s' AND configurationItemCaptureTime > '%s'",
+			resourceType, since.UTC().Format(time.RFC3339))),
+	}
+
+	var resources []types.ResourceIdentifier
+	for {
+		output, err := client.SelectResourceConfig(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range output.Results {
+			var resource struct {
+				ResourceID   string `json:"resourceId"`
+				ResourceType string `json:"resourceType"`
+			}
+			if err := json.Unmarshal([]byte(result), &resource); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", result, err)
+			}
+			resources = append(resources, types.ResourceIdentifier{
+				ResourceId:   lo.ToPtr(resource.ResourceID),
+				ResourceType: types.ResourceType(resource.ResourceType),
+			})
+		}
+
+		if output.NextToken == nil {
+			return resources, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// resourceConfigHistory adds the changes of the configuration items captured after the given time
+// and returns the capture time of the latest one.
+// The history is read backwards, down to the last item captured before that time which is only used as the base of the first diff.
+func (aws Scraper) resourceConfigHistory(ctx *AWSContext, client configHistoryAPI, resource types.ResourceIdentifier, since time.Time, results *v1.ScrapeResults) (time.Time, error) {
+	input := &configservice.GetResourceConfigHistoryInput{
+		ResourceId:         resource.ResourceId,
+		ResourceType:       resource.ResourceType,
+		ChronologicalOrder: types.ChronologicalOrderReverse,
+	}
+
+	var items []types.ConfigurationItem
+	var base *types.ConfigurationItem
+	for base == nil {
+		history, err := client.GetResourceConfigHistory(ctx, input)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		for i := range history.ConfigurationItems {
+			item := history.ConfigurationItems[i]
+			if item.ConfigurationItemCaptureTime != nil && !item.ConfigurationItemCaptureTime.After(since) {
+				base = &item
+				break
+			}
+			items = append(items, item)
+		}
+
+		if history.NextToken == nil {
+			break
+		}
+		input.NextToken = history.NextToken
+	}
+
+	var lastCaptureTime time.Time
+	previous := base
+	if previous != nil && previous.Configuration == nil {
+		previous = nil
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if change := newConfigHistoryChange(ctx, item, previous); change != nil {
+			results.AddChange(*change)
+		}
+		if item.Configuration != nil {
+			previous = &item
+		}
+		if item.ConfigurationItemCaptureTime != nil && item.ConfigurationItemCaptureTime.After(lastCaptureTime) {
+			lastCaptureTime = *item.ConfigurationItemCaptureTime
+		}
+	}
+
+	return lastCaptureTime, nil
+}
+
+// newConfigHistoryChange returns the change recorded by the configuration item.
+// When the previous configuration is known, the change is a diff of the two.
+func newConfigHistoryChange(ctx *AWSContext, item types.ConfigurationItem, previous *types.ConfigurationItem) *v1.ChangeResult {
+	change := &v1.ChangeResult{
+		ChangeType: string(item.ConfigurationItemStatus),
+		Summary:    string(item.ConfigurationItemStatus),
+	}
+
+	if previous != nil && item.Configuration != nil {
+		diff, err := db.NewDiffChange(*item.Configuration, *previous.Configuration)
+		if err != nil {
+			logger.Warnf("failed to generate diff for %s/%s: %v", item.ResourceType, deref(item.ResourceId), err)
+		} else if diff == nil {
+			return nil
+		} else {
+			change = diff
+		}
+	}
+
+	if item.ConfigurationItemStatus == types.ConfigurationItemStatusResourceDeleted {
+		change.Action = v1.Delete
+	}
+
+	change.ExternalID = deref(item.ResourceId)
+	change.ConfigType = getConfigTypeByResourceType(string(item.ResourceType))
+	change.ExternalChangeID = deref(item.ConfigurationStateId)
+	change.CreatedAt = item.ConfigurationItemCaptureTime
+	change.Source = fmt.Sprintf("AWS::Config::%s:%s", ctx.Session.Region, *ctx.Caller.Account)
+	change.Details = map[string]any{
+		"configurationItemStatus": item.ConfigurationItemStatus,
+		"relatedEvents":           item.RelatedEvents,
+	}
+
+	return change
+}
@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	"github.com/aws/aws-sdk-go-v2/service/configservice/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// fakeConfigService serves the history of a single instance,
+// with the newest configuration item first.
+type fakeConfigService struct {
+	history []types.ConfigurationItem
+
+	queries      []string
+	historyCalls int
+}
+
+func (f *fakeConfigService) GetDiscoveredResourceCounts(context.Context, *configservice.GetDiscoveredResourceCountsInput, ...func(*configservice.Options)) (*configservice.GetDiscoveredResourceCountsOutput, error) {
+	return &configservice.GetDiscoveredResourceCountsOutput{ResourceCounts: []types.ResourceCount{{ResourceType: types.ResourceTypeInstance}}}, nil
+}
+
+func (f *fakeConfigService) SelectResourceConfig(_ context.Context, input *configservice.SelectResourceConfigInput, _ ...func(*configservice.Options)) (*configservice.SelectResourceConfigOutput, error) {
+	f.queries = append(f.queries, *input.Expression)
+
+	var results []string
+	for _, item := range f.history {
+		since := strings.Split(*input.Expression, "configurationItemCaptureTime > '")[1]
+		if item.ConfigurationItemCaptureTime.Format(time.RFC3339) > strings.TrimSuffix(since, "'") {
+			results = append(results, `{"resourceId": "i-1", "resourceType": "AWS::EC2::Instance"}`)
+			break
+		}
+	}
+	return &configservice.SelectResourceConfigOutput{Results: results}, nil
+}
+
+func (f *fakeConfigService) GetResourceConfigHistory(_ context.Context, input *configservice.GetResourceConfigHistoryInput, _ ...func(*configservice.Options)) (*configservice.GetResourceConfigHistoryOutput, error) {
+	f.historyCalls++
+
+	// one item per page so that the pages after the base item are never requested
+	page := 0
+	if input.NextToken != nil {
+		page = len(*input.NextToken)
+	}
+	output := &configservice.GetResourceConfigHistoryOutput{ConfigurationItems: f.history[page : page+1]}
+	if page+1 < len(f.history) {
+		output.NextToken = aws.String(strings.Repeat("n", page+1))
+	}
+	return output, nil
+}
+
+func configurationItem(id string, captured time.Time, configuration string) types.ConfigurationItem {
+	return types.ConfigurationItem{
+		ResourceId:                   aws.String("i-1"),
+		ResourceType:                 types.ResourceTypeInstance,
+		ConfigurationStateId:         aws.String(id),
+		ConfigurationItemStatus:      types.ConfigurationItemStatusOk,
+		ConfigurationItemCaptureTime: aws.Time(captured),
+		Configuration:                aws.String(configuration),
+	}
+}
+
+func TestConfigHistoryCursor(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	client := &fakeConfigService{history: []types.ConfigurationItem{
+		configurationItem("3", now.Add(-1*time.Hour), `{"size": "large"}`),
+		configurationItem("2", now.Add(-2*time.Hour), `{"size": "medium"}`),
+		configurationItem("1", now.Add(-30*24*time.Hour), `{"size": "small"}`),
+	}}
+
+	ctx := &AWSContext{
+		ScrapeContext: api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{}),
+		Session:       &aws.Config{Region: "eu-west-1"},
+		Caller:        &sts.GetCallerIdentityOutput{Account: aws.String("123456789012")},
+	}
+	config := v1.AWS{ConfigHistory: v1.ConfigHistory{Enabled: true}}
+
+	var results v1.ScrapeResults
+	Scraper{}.scrapeConfigHistory(ctx, client, config, &results)
+
+	var changes []v1.ChangeResult
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		changes = append(changes, r.Changes...)
+	}
+
+	// the item older than the max age is only the base of the first diff
+	if len(changes) != 2 || changes[0].ExternalChangeID != "2" || changes[1].ExternalChangeID != "3" {
+		t.Fatalf("expected the changes of the last 7 days in order, got %v", changes)
+	}
+	if changes[0].ChangeType != "diff" || changes[0].Diff == nil || !strings.Contains(*changes[0].Diff, "medium") {
+		t.Errorf("expected a diff from the base item, got %v", changes[0])
+	}
+	if client.historyCalls != 3 {
+		t.Errorf("expected the history to be read down to the base item, got %d calls", client.historyCalls)
+	}
+
+	// the cursor is only advanced once the results are saved
+	if cursor, _ := ctx.GetCursor(configHistoryCursorType, "123456789012/eu-west-1/AWS::EC2::Instance"); cursor != "" {
+		t.Fatalf("expected no cursor before the results are saved, got %s", cursor)
+	}
+	if err := results.Saved(); err != nil {
+		t.Fatal(err)
+	}
+	if cursor, _ := ctx.GetCursor(configHistoryCursorType, "123456789012/eu-west-1/AWS::EC2::Instance"); cursor != now.Add(-1*time.Hour).Format(time.RFC3339Nano) {
+		t.Fatalf("expected the cursor at the last capture time, got %s", cursor)
+	}
+
+	// nothing was captured since, so the history isn't read again
+	client.historyCalls = 0
+	results = nil
+	Scraper{}.scrapeConfigHistory(ctx, client, config, &results)
+	if len(results) != 0 || client.historyCalls != 0 {
+		t.Errorf("expected no history calls, got %d calls and %v", client.historyCalls, results)
+	}
+	if !strings.Contains(client.queries[1], now.Add(-1*time.Hour).Format(time.RFC3339)) {
+		t.Errorf("expected the query to start from the cursor, got %s", client.queries[1])
+	}
+}
@@ -48,17 +48,17 @@ const costQueryTemplate = `
     ON cost_30d.line_item_product_code = items.line_item_product_code AND items.line_item_resource_id = cost_30d.line_item_resource_id
 `
 
-func getAWSAthenaConfig(ctx api.ScrapeContext, awsConfig v1.AWS) (*athena.Config, error) {
+func getAWSAthenaConfig(ctx api.ScrapeContext, conn v1.AWSConnection, region, outputBucket string) (*athena.Config, error) {
 	conf := athena.NewNoOpsConfig()
 
-	if err := conf.SetRegion(awsConfig.CostReporting.Region); err != nil {
+	if err := conf.SetRegion(region); err != nil {
 		return nil, err
 	}
-	if err := conf.SetOutputBucket(awsConfig.CostReporting.S3BucketPath); err != nil {
+	if err := conf.SetOutputBucket(outputBucket); err != nil {
 		return nil, err
 	}
 
-	accessKey, secretKey, err := getAccessAndSecretKey(ctx, *awsConfig.AWSConnection)
+	accessKey, secretKey, err := getAccessAndSecretKey(ctx, conn)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +87,7 @@ type LineItemRow struct {
 func fetchCosts(ctx api.ScrapeContext, config v1.AWS) ([]LineItemRow, error) {
 	var lineItemRows []LineItemRow
 
-	athenaConf, err := getAWSAthenaConfig(ctx, config)
+	athenaConf, err := getAWSAthenaConfig(ctx, *config.AWSConnection, config.CostReporting.Region, config.CostReporting.S3BucketPath)
 	if err != nil {
 		return lineItemRows, err
 	}
@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	organizationTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/samber/lo"
+)
+
+// organizationAccount is an account of an organization scraped with an assumed role.
+type organizationAccount struct {
+	ID   string
+	Name string
+	// OUPath is the path of the organizational unit of the account e.g. Root/Workloads/Prod
+	OUPath string
+	// RoleARN is the role assumed to scrape the account.
+	// Empty for the account of the scraper's credentials.
+	RoleARN string
+}
+
+// organizationsAPI is the part of the organizations client used to walk an organization.
+type organizationsAPI interface {
+	DescribeOrganization(context.Context, *organizations.DescribeOrganizationInput, ...func(*organizations.Options)) (*organizations.DescribeOrganizationOutput, error)
+	organizations.ListRootsAPIClient
+	organizations.ListAccountsForParentAPIClient
+	organizations.ListOrganizationalUnitsForParentAPIClient
+}
+
+// organization emits the organization and its organizational units as config items,
+// and returns the active accounts of the organization.
+func (aws Scraper) organization(ctx *AWSContext, config v1.AWS, results *v1.ScrapeResults) (map[string]organizationAccount, error) {
+	return aws.listOrganization(ctx, organizations.NewFromConfig(*ctx.Session), config, results)
+}
+
+func (aws Scraper) listOrganization(ctx *AWSContext, client organizationsAPI, config v1.AWS, results *v1.ScrapeResults) (map[string]organizationAccount, error) {
+	org, err := client.DescribeOrganization(ctx, &organizations.DescribeOrganizationInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe organization: %w", err)
+	}
+
+	orgID := lo.FromPtr(org.Organization.Id)
+	walker := organizationWalker{
+		client:   client,
+		ctx:      ctx,
+		config:   config,
+		orgID:    orgID,
+		results:  results,
+		accounts: make(map[string]organizationAccount),
+	}
+
+	roots := organizations.NewListRootsPaginator(client, &organizations.ListRootsInput{})
+	for roots.HasMorePages() {
+		page, err := roots.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization roots: %w", err)
+		}
+		for _, root := range page.Roots {
+			if err := walker.walk(lo.FromPtr(root.Id), "", lo.FromPtr(root.Name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	*results = append(*results, v1.ScrapeResult{
+		Type:                v1.AWSOrganization,
+		BaseScraper:         config.BaseScraper,
+		Config:              org.Organization,
+		ConfigClass:         "Organization",
+		Name:                orgID,
+		ID:                  orgID,
+		Aliases:             []string{lo.FromPtr(org.Organization.Arn)},
+		Tags:                map[string]string{"account": lo.FromPtr(org.Organization.MasterAccountId)},
+		RelationshipResults: walker.relationships,
+	})
+
+	return walker.accounts, nil
+}
+
+// organizationWalker walks the tree of organizational units of an organization.
+type organizationWalker struct {
+	client        organizationsAPI
+	ctx           *AWSContext
+	config        v1.AWS
+	orgID         string
+	results       *v1.ScrapeResults
+	accounts      map[string]organizationAccount
+	relationships v1.RelationshipResults
+}
+
+// walk records the accounts and organizational units under the given parent.
+// The parent OU id is empty for the root of the organization.
+func (w *organizationWalker) walk(parentID, parentOU, path string) error {
+	accounts := organizations.NewListAccountsForParentPaginator(w.client, &organizations.ListAccountsForParentInput{ParentId: &parentID})
+	for accounts.HasMorePages() {
+		page, err := accounts.NextPage(w.ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list accounts of %s: %w", parentID, err)
+		}
+
+		for _, account := range page.Accounts {
+			if account.Status != organizationTypes.AccountStatusActive {
+				continue
+			}
+
+			accountID := lo.FromPtr(account.Id)
+			w.accounts[accountID] = organizationAccount{ID: accountID, Name: lo.FromPtr(account.Name), OUPath: path}
+
+			accountExternalID := v1.ExternalID{ConfigType: v1.AWSAccount, ExternalID: []string{accountID}}
+			w.relationships = append(w.relationships, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ConfigType: v1.AWSOrganization, ExternalID: []string{w.orgID}},
+				RelatedExternalID: accountExternalID,
+				Relationship:      "OrganizationAccount",
+			})
+
+			if parentOU != "" {
+				w.relationships = append(w.relationships, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ConfigType: v1.AWSOrganizationalUnit, ExternalID: []string{parentOU}},
+					RelatedExternalID: accountExternalID,
+					Relationship:      "OrganizationalUnitAccount",
+				})
+			}
+		}
+	}
+
+	var units []organizationTypes.OrganizationalUnit
+	pages := organizations.NewListOrganizationalUnitsForParentPaginator(w.client, &organizations.ListOrganizationalUnitsForParentInput{ParentId: &parentID})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(w.ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list organizational units of %s: %w", parentID, err)
+		}
+		units = append(units, page.OrganizationalUnits...)
+	}
+
+	for _, unit := range units {
+		unitID := lo.FromPtr(unit.Id)
+		unitPath := fmt.Sprintf("%s/%s", path, lo.FromPtr(unit.Name))
+
+		result := v1.ScrapeResult{
+			Type:             v1.AWSOrganizationalUnit,
+			BaseScraper:      w.config.BaseScraper,
+			Config:           unit,
+			ConfigClass:      "OrganizationalUnit",
+			Name:             lo.FromPtr(unit.Name),
+			ID:               unitID,
+			Aliases:          []string{lo.FromPtr(unit.Arn)},
+			Tags:             map[string]string{"path": unitPath},
+			ParentExternalID: w.orgID,
+			ParentType:       v1.AWSOrganization,
+		}
+		if parentOU != "" {
+			result.ParentExternalID = parentOU
+			result.ParentType = v1.AWSOrganizationalUnit
+		}
+		*w.results = append(*w.results, result)
+
+		if err := w.walk(unitID, unitID, unitPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	organizationTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/types"
+)
+
+// fakeOrganizations is an organization with an account in the root and one in the Prod OU.
+type fakeOrganizations struct{}
+
+func (fakeOrganizations) DescribeOrganization(context.Context, *organizations.DescribeOrganizationInput, ...func(*organizations.Options)) (*organizations.DescribeOrganizationOutput, error) {
+	return &organizations.DescribeOrganizationOutput{Organization: &organizationTypes.Organization{
+		Id:              aws.String("o-1"),
+		Arn:             aws.String("arn:aws:organizations::111111111111:organization/o-1"),
+		MasterAccountId: aws.String("111111111111"),
+	}}, nil
+}
+
+func (fakeOrganizations) ListRoots(context.Context, *organizations.ListRootsInput, ...func(*organizations.Options)) (*organizations.ListRootsOutput, error) {
+	return &organizations.ListRootsOutput{Roots: []organizationTypes.Root{{Id: aws.String("r-1"), Name: aws.String("Root")}}}, nil
+}
+
+func (fakeOrganizations) ListAccountsForParent(_ context.Context, input *organizations.ListAccountsForParentInput, _ ...func(*organizations.Options)) (*organizations.ListAccountsForParentOutput, error) {
+	var accounts []organizationTypes.Account
+	switch *input.ParentId {
+	case "r-1":
+		accounts = []organizationTypes.Account{
+			{Id: aws.String("111111111111"), Name: aws.String("management"), Status: organizationTypes.AccountStatusActive},
+			{Id: aws.String("333333333333"), Name: aws.String("closed"), Status: organizationTypes.AccountStatusSuspended},
+		}
+	case "ou-prod":
+		accounts = []organizationTypes.Account{{Id: aws.String("222222222222"), Name: aws.String("prod"), Status: organizationTypes.AccountStatusActive}}
+	}
+	return &organizations.ListAccountsForParentOutput{Accounts: accounts}, nil
+}
+
+func (fakeOrganizations) ListOrganizationalUnitsForParent(_ context.Context, input *organizations.ListOrganizationalUnitsForParentInput, _ ...func(*organizations.Options)) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+	var units []organizationTypes.OrganizationalUnit
+	if *input.ParentId == "r-1" {
+		units = []organizationTypes.OrganizationalUnit{{Id: aws.String("ou-prod"), Name: aws.String("Prod"), Arn: aws.String("arn:aws:organizations::111111111111:ou/o-1/ou-prod")}}
+	}
+	return &organizations.ListOrganizationalUnitsForParentOutput{OrganizationalUnits: units}, nil
+}
+
+func TestOrganizationDiscovery(t *testing.T) {
+	ctx := &AWSContext{ScrapeContext: api.NewScrapeContext(context.TODO(), nil, nil)}
+
+	var results v1.ScrapeResults
+	accounts, err := Scraper{}.listOrganization(ctx, fakeOrganizations{}, v1.AWS{}, &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(accounts) != 2 || accounts["222222222222"].OUPath != "Root/Prod" || accounts["111111111111"].OUPath != "Root" {
+		t.Errorf("expected the active accounts with their OU path, got %v", accounts)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected the OU and the organization, got %v", results)
+	}
+	if unit := results[0]; unit.Type != v1.AWSOrganizationalUnit || unit.ParentExternalID != "o-1" || unit.Tags["path"] != "Root/Prod" {
+		t.Errorf("unexpected organizational unit %v", unit)
+	}
+	if org := results[1]; org.Type != v1.AWSOrganization || len(org.RelationshipResults) != 3 {
+		t.Errorf("expected the organization related to both accounts and the OU to its account, got %v", org.RelationshipResults)
+	}
+}
+
+func TestOrganizationAccounts(t *testing.T) {
+	accounts := map[string]organizationAccount{
+		"111111111111": {ID: "111111111111", Name: "management"},
+		"222222222222": {ID: "222222222222", Name: "prod"},
+		"333333333333": {ID: "333333333333", Name: "sandbox"},
+	}
+
+	selected := organizationAccounts(v1.AWSOrganizationAccounts{Exclude: []string{"333333333333"}, Role: "Scraper"}, accounts, "aws", "111111111111")
+	if len(selected) != 2 {
+		t.Fatalf("expected the excluded account to be skipped, got %v", selected)
+	}
+	if selected[0].ID != "111111111111" || selected[0].RoleARN != "" {
+		t.Errorf("expected the account of the credentials to be scraped without a role, got %v", selected[0])
+	}
+	if selected[1].RoleARN != "arn:aws:iam::222222222222:role/Scraper" || selected[1].Name != "prod" {
+		t.Errorf("expected the role of the member account, got %v", selected[1])
+	}
+
+	// accounts listed explicitly don't require access to the organization
+	selected = organizationAccounts(v1.AWSOrganizationAccounts{Accounts: []string{"444444444444"}}, nil, "aws-us-gov", "111111111111")
+	if len(selected) != 1 || selected[0].RoleARN != "arn:aws-us-gov:iam::444444444444:role/OrganizationAccountAccessRole" {
+		t.Errorf("unexpected accounts %v", selected)
+	}
+}
+
+// newFakeSTS returns the account of the role when the request is signed
+// with the credentials returned by AssumeRole.
+func newFakeSTS(t *testing.T, assumed *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		switch r.Form.Get("Action") {
+		case "AssumeRole":
+			*assumed = append(*assumed, r.Form.Get("RoleArn"))
+			_, _ = w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>
+				<Credentials><AccessKeyId>ASSUMED</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration></Credentials>
+				<AssumedRoleUser><Arn>arn:aws:sts::222222222222:assumed-role/OrganizationAccountAccessRole/scraper</Arn><AssumedRoleId>AROA:scraper</AssumedRoleId></AssumedRoleUser>
+			</AssumeRoleResult></AssumeRoleResponse>`))
+		case "GetCallerIdentity":
+			account := "111111111111"
+			if strings.Contains(r.Header.Get("Authorization"), "Credential=ASSUMED/") {
+				account = "222222222222"
+			}
+			_, _ = w.Write([]byte(`<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><GetCallerIdentityResult>
+				<Arn>arn:aws:iam::` + account + `:user/scraper</Arn><UserId>scraper</UserId><Account>` + account + `</Account>
+			</GetCallerIdentityResult></GetCallerIdentityResponse>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestAccountRoleAssumption(t *testing.T) {
+	// the http client of the session can't be combined with a CA bundle from the environment
+	t.Setenv("AWS_CA_BUNDLE", "")
+
+	var assumed []string
+	server := newFakeSTS(t, &assumed)
+	defer server.Close()
+
+	config := v1.AWS{AWSConnection: &v1.AWSConnection{
+		Endpoint:  server.URL,
+		AccessKey: types.EnvVar{ValueStatic: "KEY"},
+		SecretKey: types.EnvVar{ValueStatic: "SECRET"},
+	}}
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{})
+
+	management, err := Scraper{}.getContext(ctx, config, "eu-west-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.ToString(management.Caller.Account) != "111111111111" || len(assumed) != 0 {
+		t.Errorf("expected the account of the credentials without assuming a role, got %s %v", aws.ToString(management.Caller.Account), assumed)
+	}
+
+	account := organizationAccount{ID: "222222222222", RoleARN: "arn:aws:iam::222222222222:role/OrganizationAccountAccessRole"}
+	member, err := Scraper{}.getContext(ctx, config, "eu-west-1", &account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.ToString(member.Caller.Account) != "222222222222" || member.Account.ID != "222222222222" {
+		t.Errorf("expected the clients of the member account to use the assumed role, got %s", aws.ToString(member.Caller.Account))
+	}
+	if len(assumed) != 1 || assumed[0] != account.RoleARN {
+		t.Errorf("expected the role of the account to be assumed, got %v", assumed)
+	}
+}
@@ -0,0 +1,77 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// armResource is a resource returned by the ARM api in its json form.
+type armResource map[string]any
+
+func (r armResource) get(key string) string {
+	if v, ok := r[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+func (r armResource) ID() string {
+	return r.get("id")
+}
+
+func (r armResource) Name() string {
+	return r.get("name")
+}
+
+func (r armResource) Type() string {
+	return r.get("type")
+}
+
+// listARMResources lists a collection of the ARM api
+// for the services the SDK has no (compatible) client for.
+func (azure Scraper) listARMResources(path, apiVersion string) ([]armResource, error) {
+	client, err := arm.NewClient("azure.Scraper", "v1.0.0", azure.cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s?api-version=%s", runtime.JoinPaths(client.Endpoint(), path), apiVersion)
+	return listPages[armResource](azure.ctx, client.Pipeline(), link)
+}
+
+// listPages returns the values of all the pages of a list api,
+// following the nextLink of every page.
+func listPages[T any](ctx context.Context, pipeline runtime.Pipeline, link string) ([]T, error) {
+	var values []T
+	for link != "" {
+		req, err := runtime.NewRequest(ctx, http.MethodGet, escapeNextLink(link))
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := pipeline.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if !runtime.HasStatusCode(resp, http.StatusOK) {
+			return nil, runtime.NewResponseError(resp)
+		}
+
+		var page struct {
+			Value    []T    `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := runtime.UnmarshalAsJSON(resp, &page); err != nil {
+			return nil, err
+		}
+
+		values = append(values, page.Value...)
+		link = page.NextLink
+	}
+
+	return values, nil
+}
@@ -3,10 +3,16 @@ package azure
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appservice/armappservice"
@@ -35,8 +41,9 @@ const (
 	defaultActivityLogMaxage = time.Hour * 24 * 7
 )
 
-// activityLogLastRecordTime keeps track of the time of the last activity log per subscription.
-var activityLogLastRecordTime = sync.Map{}
+// activityLogCursorType is the cursor type that keeps track
+// of the time of the last activity log per subscription.
+const activityLogCursorType = "azure/activitylog"
 
 var activityLogFilter = strings.Join([]string{
 	"authorization",
@@ -66,8 +73,8 @@ var defaultExcludes = []v1.ConfigFieldExclusion{
 }
 
 type Scraper struct {
-	ctx    context.Context
-	cred   *azidentity.ClientSecretCredential
+	ctx    api.ScrapeContext
+	cred   azcore.TokenCredential
 	config *v1.Azure
 
 	// Populate these first with list of all resource groups
@@ -110,6 +117,31 @@ func (azure Scraper) hydrateConnection(ctx api.ScrapeContext, t v1.Azure) (v1.Az
 	return t, nil
 }
 
+// getCredential returns the credential of the identity the config authenticates with.
+func getCredential(config v1.Azure) (azcore.TokenCredential, error) {
+	switch config.Identity {
+	case v1.AzureIdentityManaged:
+		var options azidentity.ManagedIdentityCredentialOptions
+		if config.ClientID.ValueStatic != "" {
+			options.ID = azidentity.ClientID(config.ClientID.ValueStatic)
+		}
+		return azidentity.NewManagedIdentityCredential(&options)
+
+	case v1.AzureIdentityWorkload:
+		// Unset fields are read from the AZURE_* environment variables injected by the workload identity webhook
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientID: config.ClientID.ValueStatic,
+			TenantID: config.TenantID,
+		})
+
+	case "", v1.AzureIdentityClientSecret:
+		return azidentity.NewClientSecretCredential(config.TenantID, config.ClientID.ValueStatic, config.ClientSecret.ValueStatic, nil)
+
+	default:
+		return nil, fmt.Errorf("unknown azure identity %q", config.Identity)
+	}
+}
+
 func (azure Scraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 	var results v1.ScrapeResults
 	for _, _config := range ctx.ScrapeConfig().Spec.Azure {
@@ -121,45 +153,100 @@ func (azure Scraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 
 		config.Transform.Exclude = append(config.Transform.Exclude, defaultExcludes...)
 
-		cred, err := azidentity.NewClientSecretCredential(config.TenantID, config.ClientID.ValueStatic, config.ClientSecret.ValueStatic, nil)
+		cred, err := getCredential(config)
 		if err != nil {
 			results.Errorf(err, "failed to get credentials for azure")
 			continue
 		}
 
-		azure.ctx = context.Background()
-		azure.config = &config
-		azure.cred = cred
+		if config.Discovery == nil {
+			scraper := Scraper{ctx: ctx, cred: cred, config: &config}
+			results = append(results, scraper.scrapeSubscription(true)...)
+			continue
+		}
 
-		// We fetch resource groups first as they are used to fetch further resources
-		results = append(results, azure.fetchResourceGroups()...)
-		results = append(results, azure.fetchVirtualMachines()...)
-		results = append(results, azure.fetchLoadBalancers()...)
-		results = append(results, azure.fetchVirtualNetworks()...)
-		results = append(results, azure.fetchContainerRegistries()...)
-		results = append(results, azure.fetchFirewalls()...)
-		results = append(results, azure.fetchDatabases()...)
-		results = append(results, azure.fetchK8s()...)
-		results = append(results, azure.fetchSubscriptions()...)
-		results = append(results, azure.fetchStorageAccounts()...)
-		results = append(results, azure.fetchAppServices()...)
-		results = append(results, azure.fetchDNS()...)
-		results = append(results, azure.fetchPrivateDNSZones()...)
-		results = append(results, azure.fetchTrafficManagerProfiles()...)
-		results = append(results, azure.fetchNetworkSecurityGroups()...)
-		results = append(results, azure.fetchPublicIPAddresses()...)
-		results = append(results, azure.fetchAdvisorAnalysis()...)
-		results = append(results, azure.fetchActivityLogs()...)
-
-		// Post processing of all results
-		for i := range results {
-			// Add subscription-id tag to all the resources
-			results[i].Tags = collections.MergeMap(results[i].Tags, map[string]string{
-				"azure-subscription-id": azure.config.SubscriptionID,
-			})
+		subscriptions, discovered, err := discoverSubscriptions(ctx, cred, config)
+		if err != nil {
+			results.Errorf(err, "failed to discover azure subscriptions")
+			continue
 		}
+		results = append(results, discovered...)
+
+		// Every subscription is scraped with its own copy of the config & clients
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, config.Discovery.GetConcurrency())
+		for _, subscriptionID := range subscriptions {
+			subscriptionConfig := config
+			subscriptionConfig.SubscriptionID = subscriptionID
+			scraper := Scraper{ctx: ctx, cred: cred, config: &subscriptionConfig}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				subscriptionResults := scraper.scrapeSubscription(false)
+				mu.Lock()
+				results = append(results, subscriptionResults...)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
 	}
 
+	return results
+}
+
+// scrapeSubscription scrapes the resources of the subscription of the config.
+// The subscriptions are fetched too when they weren't already discovered.
+func (azure *Scraper) scrapeSubscription(fetchSubscriptions bool) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	// We fetch resource groups first as they are used to fetch further resources
+	results = append(results, azure.fetchResourceGroups()...)
+	results = append(results, azure.fetchVirtualMachines()...)
+	results = append(results, azure.fetchLoadBalancers()...)
+	results = append(results, azure.fetchVirtualNetworks()...)
+	results = append(results, azure.fetchContainerRegistries()...)
+	results = append(results, azure.fetchFirewalls()...)
+	results = append(results, azure.fetchDatabases()...)
+	results = append(results, azure.fetchK8s()...)
+	if fetchSubscriptions {
+		results = append(results, azure.fetchSubscriptions()...)
+	}
+	results = append(results, azure.fetchStorageAccounts()...)
+	results = append(results, azure.fetchAppServices()...)
+	results = append(results, azure.fetchDNS()...)
+	results = append(results, azure.fetchPrivateDNSZones()...)
+	results = append(results, azure.fetchTrafficManagerProfiles()...)
+	results = append(results, azure.fetchNetworkSecurityGroups()...)
+	results = append(results, azure.fetchPublicIPAddresses()...)
+	results = append(results, azure.fetchKeyVaults()...)
+	results = append(results, azure.fetchCosmosDBAccounts()...)
+	results = append(results, azure.fetchServiceBuses()...)
+	results = append(results, azure.fetchEventHubs()...)
+	results = append(results, azure.fetchApplicationGateways()...)
+	results = append(results, azure.fetchManagedIdentities()...)
+	if azure.config.ResourceGraph != nil {
+		results = append(results, azure.fetchResourceGraph(results)...)
+	}
+	results = append(results, azure.fetchAdvisorAnalysis()...)
+	results = append(results, azure.fetchActivityLogs()...)
+
+	// Post processing of all results
+	for i := range results {
+		// Add subscription-id tag to all the resources
+		results[i].Tags = collections.MergeMap(results[i].Tags, map[string]string{
+			"azure-subscription-id": azure.config.SubscriptionID,
+		})
+	}
+
+	relateManagedIdentities(results)
+
 	// Establish relationship of all resources to the corresponding subscription & resource group
 	for i, r := range results {
 		if r.ID == "" {
@@ -214,22 +301,23 @@ func (azure Scraper) fetchActivityLogs() v1.ScrapeResults {
 
 	var results v1.ScrapeResults
 
-	clientFactory, err := armmonitor.NewClientFactory(azure.config.SubscriptionID, azure.cred, nil)
-	if err != nil {
-		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate arm monitor client: %w", err)})
-	}
-
 	// corelatedActivities keeps together events that belong to the same "uber operation"
 	// https://learn.microsoft.com/en-us/rest/api/monitor/activity-logs/list?view=rest-monitor-2015-04-01&tabs=HTTP#eventdata
 	var corelatedActivities = map[string][]activityChangeRecord{}
 
 	var recordSince = time.Now().Add(-defaultActivityLogMaxage)
-	if v, ok := activityLogLastRecordTime.Load(azure.config.SubscriptionID); ok {
-		recordSince = v.(time.Time)
+	if cursor, err := azure.ctx.GetCursor(activityLogCursorType, azure.config.SubscriptionID); err != nil {
+		logger.Warnf("failed to get activity log cursor for subscription %s: %v", azure.config.SubscriptionID, err)
+	} else if lastRecordTime, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
+		recordSince = lastRecordTime
 	}
 
 	filter := fmt.Sprintf("eventTimestamp ge '%s'", recordSince.Format(time.RFC3339))
-	pager := clientFactory.NewActivityLogsClient().NewListPager(filter, &armmonitor.ActivityLogsClientListOptions{Select: &activityLogFilter})
+	pager, err := azure.newActivityLogsPager(filter)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate activity logs client: %w", err)})
+	}
+
 	for pager.More() {
 		page, err := pager.NextPage(azure.ctx)
 		if err != nil {
@@ -262,14 +350,16 @@ func (azure Scraper) fetchActivityLogs() v1.ScrapeResults {
 				EventData: v,
 			})
 		}
-
-		// Stop the pager because there's a bug in the SDK itself
-		// error: failed to read activity logs next page: invalid semicolon separator in query
-		// TODO:
-		break
 	}
 
-	activityLogLastRecordTime.Store(azure.config.SubscriptionID, recordSince)
+	// The cursor is only advanced once the changes have been saved
+	subscriptionID := azure.config.SubscriptionID
+	results.OnSave(func() error {
+		if err := azure.ctx.SaveCursor(activityLogCursorType, subscriptionID, recordSince.Format(time.RFC3339Nano)); err != nil {
+			return fmt.Errorf("failed to save activity log cursor for subscription %s: %w", subscriptionID, err)
+		}
+		return nil
+	})
 
 	// For the correlated activities, we merge some fields and aggregate the timestamps into a single change record
 	for _, changeRecords := range corelatedActivities {
@@ -298,6 +388,67 @@ func (azure Scraper) fetchActivityLogs() v1.ScrapeResults {
 	return results
 }
 
+// newActivityLogsPager returns a pager over the activity logs of the subscription.
+//
+// It's a copy of armmonitor.ActivityLogsClient.NewListPager that escapes the semicolons
+// of the skip token in the next link, which the SDK fails to parse with
+// "invalid semicolon separator in query".
+func (azure Scraper) newActivityLogsPager(filter string) (*runtime.Pager[armmonitor.ActivityLogsClientListResponse], error) {
+	client, err := arm.NewClient("armmonitor.ActivityLogsClient", "v0.11.0", azure.cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	firstRequest := func(ctx context.Context) (*policy.Request, error) {
+		urlPath := fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Insights/eventtypes/management/values", url.PathEscape(azure.config.SubscriptionID))
+		req, err := runtime.NewRequest(ctx, http.MethodGet, runtime.JoinPaths(client.Endpoint(), urlPath))
+		if err != nil {
+			return nil, err
+		}
+
+		query := req.Raw().URL.Query()
+		query.Set("api-version", "2015-04-01")
+		query.Set("$filter", filter)
+		query.Set("$select", activityLogFilter)
+		req.Raw().URL.RawQuery = query.Encode()
+		req.Raw().Header["Accept"] = []string{"application/json"}
+		return req, nil
+	}
+
+	return runtime.NewPager(runtime.PagingHandler[armmonitor.ActivityLogsClientListResponse]{
+		More: func(page armmonitor.ActivityLogsClientListResponse) bool {
+			return page.NextLink != nil && len(*page.NextLink) > 0
+		},
+		Fetcher: func(ctx context.Context, page *armmonitor.ActivityLogsClientListResponse) (armmonitor.ActivityLogsClientListResponse, error) {
+			var nextLink string
+			if page != nil {
+				nextLink = escapeNextLink(*page.NextLink)
+			}
+
+			resp, err := runtime.FetcherForNextLink(ctx, client.Pipeline(), nextLink, firstRequest, nil)
+			if err != nil {
+				return armmonitor.ActivityLogsClientListResponse{}, err
+			}
+
+			var result armmonitor.ActivityLogsClientListResponse
+			if err := runtime.UnmarshalAsJSON(resp, &result.EventDataCollection); err != nil {
+				return armmonitor.ActivityLogsClientListResponse{}, err
+			}
+			return result, nil
+		},
+	}), nil
+}
+
+// escapeNextLink escapes the semicolons in the query of a next link
+// as go doesn't accept them as query separators.
+func escapeNextLink(nextLink string) string {
+	before, query, found := strings.Cut(nextLink, "?")
+	if !found {
+		return nextLink
+	}
+	return before + "?" + strings.ReplaceAll(query, ";", "%3B")
+}
+
 func getSeverityFromReason(v *armmonitor.EventData) models.Severity {
 	switch utils.Deref(v.Level) {
 	case "Critical":
@@ -689,7 +840,7 @@ func (azure Scraper) fetchStorageAccounts() v1.ScrapeResults {
 	return results
 }
 
-// fetchAppServices gets Azure app services in a subscription.
+// fetchAppServices gets Azure app services and function apps in a subscription.
 func (azure Scraper) fetchAppServices() v1.ScrapeResults {
 	logger.Debugf("fetching web services for subscription %s", azure.config.SubscriptionID)
 
@@ -707,12 +858,17 @@ func (azure Scraper) fetchAppServices() v1.ScrapeResults {
 		}
 
 		for _, v := range respPage.Value {
+			configClass := "AppService"
+			if isFunctionApp(v) {
+				configClass = "FunctionApp"
+			}
+
 			results = append(results, v1.ScrapeResult{
 				BaseScraper: azure.config.BaseScraper,
 				ID:          getARMID(v.ID),
 				Name:        deref(v.Name),
 				Config:      v,
-				ConfigClass: "AppService",
+				ConfigClass: configClass,
 				Type:        getARMType(v.Type),
 			})
 		}
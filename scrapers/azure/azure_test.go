@@ -1,6 +1,15 @@
 package azure
 
-import "testing"
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/managementgroups/armmanagementgroups"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
 
 func TestExtractResourceGroup(t *testing.T) {
 	tests := []struct {
@@ -27,3 +36,79 @@ func TestExtractResourceGroup(t *testing.T) {
 		}
 	}
 }
+
+func TestEscapeNextLink(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedToken string
+	}{
+		{"https://management.azure.com/subscriptions/123/providers/microsoft.insights/eventtypes/management/values?api-version=2015-04-01&$skiptoken=abc;def;ghi", "abc;def;ghi"},
+		{"https://management.azure.com/subscriptions/123/providers/microsoft.insights/eventtypes/management/values?api-version=2015-04-01&$skiptoken=abc", "abc"},
+	}
+
+	for _, test := range tests {
+		escaped, err := runtime.EncodeQueryParams(escapeNextLink(test.input))
+		if err != nil {
+			t.Errorf("Input: %s, Unexpected error: %v", test.input, err)
+			continue
+		}
+
+		parsed, err := url.Parse(escaped)
+		if err != nil {
+			t.Errorf("Input: %s, Unexpected error: %v", test.input, err)
+			continue
+		}
+
+		if token := parsed.Query().Get("$skiptoken"); token != test.expectedToken {
+			t.Errorf("Input: %s, Expected: %s, Got: %s", test.input, test.expectedToken, token)
+		}
+	}
+}
+
+func TestWalkManagementGroup(t *testing.T) {
+	children := []*armmanagementgroups.ManagementGroupChildInfo{
+		{
+			ID:   lo.ToPtr("/subscriptions/sub-a"),
+			Name: lo.ToPtr("sub-a"),
+			Type: lo.ToPtr(armmanagementgroups.ManagementGroupChildTypeSubscriptions),
+		},
+		{
+			ID:          lo.ToPtr("/providers/Microsoft.Management/managementGroups/prod"),
+			Name:        lo.ToPtr("prod"),
+			DisplayName: lo.ToPtr("Production"),
+			Type:        lo.ToPtr(armmanagementgroups.ManagementGroupChildTypeMicrosoftManagementManagementGroups),
+			Children: []*armmanagementgroups.ManagementGroupChildInfo{
+				{
+					ID:   lo.ToPtr("/subscriptions/SUB-B"),
+					Name: lo.ToPtr("SUB-B"),
+					Type: lo.ToPtr(armmanagementgroups.ManagementGroupChildTypeSubscriptions),
+				},
+			},
+		},
+	}
+
+	var results v1.ScrapeResults
+	subscriptions := map[string]struct{}{}
+	walkManagementGroup(v1.Azure{}, "/providers/Microsoft.Management/managementGroups/root", children, &results, subscriptions)
+
+	for _, id := range []string{"sub-a", "sub-b"} {
+		if _, ok := subscriptions[id]; !ok {
+			t.Errorf("Expected subscription %s to be discovered, Got: %v", id, subscriptions)
+		}
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 management group, Got: %d", len(results))
+	}
+
+	group := results[0]
+	if group.ID != "/providers/microsoft.management/managementgroups/prod" || group.Name != "Production" {
+		t.Errorf("Unexpected management group %s (%s)", group.ID, group.Name)
+	}
+	if group.ParentExternalID != "/providers/microsoft.management/managementgroups/root" {
+		t.Errorf("Unexpected parent %s", group.ParentExternalID)
+	}
+	if len(group.RelationshipResults) != 1 || group.RelationshipResults[0].RelatedExternalID.ExternalID[0] != "/subscriptions/sub-b" {
+		t.Errorf("Expected a relationship to sub-b, Got: %v", group.RelationshipResults)
+	}
+}
@@ -1,12 +1,14 @@
 package devops
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/samber/lo"
 
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
@@ -41,10 +43,15 @@ type Pipeline struct {
 	Variables          map[string]Variable `json:"variables,omitempty"`
 	TemplateParameters map[string]string   `json:"templateParameters,omitempty"`
 	Runs               []v1.ChangeResult   `json:"-"`
+	// Repositories are the ids of the repositories the runs were built from
+	Repositories []string `json:"-"`
 }
 
 func (p Pipeline) GetTags() map[string]string {
-	var tags = p.TemplateParameters
+	var tags = make(map[string]string, len(p.TemplateParameters)+len(p.Variables))
+	for k, v := range p.TemplateParameters {
+		tags[k] = v
+	}
 	for k, v := range p.Variables {
 		tags[k] = v.Value
 	}
@@ -90,6 +97,10 @@ type Run struct {
 	URL      string `json:"url"`
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
+	// Repository, SourceBranch & SourceVersion are the source the run was built from
+	Repository    *BuildRepository `json:"repository,omitempty"`
+	SourceBranch  string           `json:"sourceBranch,omitempty"`
+	SourceVersion string           `json:"sourceVersion,omitempty"`
 }
 
 func (r Run) GetTags() map[string]string {
@@ -105,9 +116,240 @@ type Runs struct {
 	Value []Run `json:"value"`
 }
 
+type IdentityRef struct {
+	ID          string `json:"id,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	UniqueName  string `json:"uniqueName,omitempty"`
+}
+
+type Repository struct {
+	ID            string                `json:"id"`
+	Name          string                `json:"name"`
+	URL           string                `json:"url"`
+	Project       Project               `json:"project"`
+	DefaultBranch string                `json:"defaultBranch,omitempty"`
+	Size          int64                 `json:"size"`
+	RemoteURL     string                `json:"remoteUrl"`
+	SSHURL        string                `json:"sshUrl,omitempty"`
+	WebURL        string                `json:"webUrl"`
+	IsDisabled    bool                  `json:"isDisabled,omitempty"`
+	Policies      []PolicyConfiguration `json:"policies,omitempty"`
+}
+
+type Repositories struct {
+	Count int          `json:"count"`
+	Value []Repository `json:"value"`
+}
+
+type PolicyType struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+// PolicyConfiguration is a branch policy of a repository
+type PolicyConfiguration struct {
+	ID          int            `json:"id"`
+	Type        PolicyType     `json:"type"`
+	IsEnabled   bool           `json:"isEnabled"`
+	IsBlocking  bool           `json:"isBlocking"`
+	IsDeleted   bool           `json:"isDeleted,omitempty"`
+	Settings    map[string]any `json:"settings,omitempty"`
+	CreatedBy   IdentityRef    `json:"createdBy"`
+	CreatedDate time.Time      `json:"createdDate"`
+}
+
+type PolicyConfigurations struct {
+	Count int                   `json:"count"`
+	Value []PolicyConfiguration `json:"value"`
+}
+
+type CommitRef struct {
+	CommitID string `json:"commitId"`
+}
+
+type Reviewer struct {
+	IdentityRef
+	Vote       int  `json:"vote"`
+	IsRequired bool `json:"isRequired,omitempty"`
+}
+
+type PullRequest struct {
+	PullRequestID   int         `json:"pullRequestId"`
+	Status          string      `json:"status"`
+	Title           string      `json:"title"`
+	Description     string      `json:"description,omitempty"`
+	SourceRefName   string      `json:"sourceRefName"`
+	TargetRefName   string      `json:"targetRefName"`
+	MergeStatus     string      `json:"mergeStatus,omitempty"`
+	CreatedBy       IdentityRef `json:"createdBy"`
+	CreationDate    time.Time   `json:"creationDate"`
+	ClosedDate      time.Time   `json:"closedDate"`
+	LastMergeCommit *CommitRef  `json:"lastMergeCommit,omitempty"`
+	Reviewers       []Reviewer  `json:"reviewers,omitempty"`
+	URL             string      `json:"url"`
+}
+
+type PullRequests struct {
+	Count int           `json:"count"`
+	Value []PullRequest `json:"value"`
+}
+
+type ReleaseDefinition struct {
+	Links        map[string]Link  `json:"_links,omitempty"`
+	ID           int              `json:"id"`
+	Name         string           `json:"name"`
+	Path         string           `json:"path"`
+	Revision     int              `json:"revision"`
+	URL          string           `json:"url"`
+	CreatedBy    IdentityRef      `json:"createdBy"`
+	CreatedOn    time.Time        `json:"createdOn"`
+	ModifiedBy   IdentityRef      `json:"modifiedBy"`
+	ModifiedOn   time.Time        `json:"modifiedOn"`
+	Environments []map[string]any `json:"environments,omitempty"`
+}
+
+type ReleaseDefinitions struct {
+	Count int                 `json:"count"`
+	Value []ReleaseDefinition `json:"value"`
+}
+
+type ReleaseEnvironment struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type Release struct {
+	Links        map[string]Link      `json:"_links,omitempty"`
+	ID           int                  `json:"id"`
+	Name         string               `json:"name"`
+	Status       string               `json:"status"`
+	Reason       string               `json:"reason,omitempty"`
+	Description  string               `json:"description,omitempty"`
+	CreatedBy    IdentityRef          `json:"createdBy"`
+	CreatedOn    time.Time            `json:"createdOn"`
+	ModifiedOn   time.Time            `json:"modifiedOn"`
+	Environments []ReleaseEnvironment `json:"environments,omitempty"`
+}
+
+type Releases struct {
+	Count int       `json:"count"`
+	Value []Release `json:"value"`
+}
+
+type CheckType struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CheckConfiguration is an approval or a check of an environment
+type CheckConfiguration struct {
+	ID        int            `json:"id"`
+	Version   int            `json:"version"`
+	Type      CheckType      `json:"type"`
+	Settings  map[string]any `json:"settings,omitempty"`
+	Timeout   int            `json:"timeout,omitempty"`
+	CreatedBy IdentityRef    `json:"createdBy"`
+	CreatedOn time.Time      `json:"createdOn"`
+}
+
+type CheckConfigurations struct {
+	Count int                  `json:"count"`
+	Value []CheckConfiguration `json:"value"`
+}
+
+type Environment struct {
+	ID             int                  `json:"id"`
+	Name           string               `json:"name"`
+	Description    string               `json:"description,omitempty"`
+	CreatedBy      IdentityRef          `json:"createdBy"`
+	CreatedOn      time.Time            `json:"createdOn"`
+	LastModifiedOn time.Time            `json:"lastModifiedOn"`
+	Checks         []CheckConfiguration `json:"checks,omitempty"`
+}
+
+type Environments struct {
+	Count int           `json:"count"`
+	Value []Environment `json:"value"`
+}
+
+type BuildRepository struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// runsPageSize is the number of runs fetched per page
+const runsPageSize = 100
+
+// Build is the build of a pipeline run
+type Build struct {
+	Links              map[string]Link   `json:"_links,omitempty"`
+	ID                 int               `json:"id"`
+	BuildNumber        string            `json:"buildNumber"`
+	Status             string            `json:"status"`
+	Result             string            `json:"result"`
+	URL                string            `json:"url"`
+	Repository         BuildRepository   `json:"repository"`
+	SourceBranch       string            `json:"sourceBranch"`
+	SourceVersion      string            `json:"sourceVersion"`
+	RequestedFor       IdentityRef       `json:"requestedFor"`
+	QueueTime          time.Time         `json:"queueTime"`
+	StartTime          time.Time         `json:"startTime"`
+	FinishTime         time.Time         `json:"finishTime"`
+	TemplateParameters map[string]string `json:"templateParameters,omitempty"`
+	// Parameters are the variables the build was queued with, as a json object
+	Parameters string `json:"parameters,omitempty"`
+}
+
+// Run converts the build to the run of its pipeline
+func (b Build) Run() Run {
+	run := Run{
+		Links:              b.Links,
+		TemplateParameters: b.TemplateParameters,
+		State:              b.Status,
+		Result:             b.Result,
+		CreatedDate:        b.QueueTime,
+		FinishedDate:       b.FinishTime,
+		URL:                b.URL,
+		ID:                 b.ID,
+		Name:               b.BuildNumber,
+		Repository:         &b.Repository,
+		SourceBranch:       b.SourceBranch,
+		SourceVersion:      b.SourceVersion,
+	}
+
+	if b.Parameters != "" {
+		var parameters map[string]string
+		if err := json.Unmarshal([]byte(b.Parameters), &parameters); err == nil {
+			run.Variables = make(map[string]Variable, len(parameters))
+			for k, v := range parameters {
+				run.Variables[k] = Variable{Value: v}
+			}
+		}
+	}
+
+	if !run.FinishedDate.IsZero() {
+		run.Duration = int(run.FinishedDate.Sub(run.CreatedDate).Milliseconds())
+	}
+	return run
+}
+
+// IsCompleted returns true once the run has finished
+func (r Run) IsCompleted() bool {
+	return r.State == "completed"
+}
+
+type Builds struct {
+	Count int     `json:"count"`
+	Value []Build `json:"value"`
+}
+
 type AzureDevopsClient struct {
 	*resty.Client
 	api.ScrapeContext
+	// releaseURL is the url of the organization on the release management host
+	releaseURL string
 }
 
 func NewAzureDevopsClient(ctx api.ScrapeContext, ado v1.AzureDevops) (*AzureDevopsClient, error) {
@@ -131,6 +373,7 @@ func NewAzureDevopsClient(ctx api.ScrapeContext, ado v1.AzureDevops) (*AzureDevo
 	return &AzureDevopsClient{
 		ScrapeContext: ctx,
 		Client:        client,
+		releaseURL:    fmt.Sprintf("https://vsrm.dev.azure.com/%s", ado.Organization),
 	}, nil
 }
 
@@ -144,24 +387,60 @@ func (ado *AzureDevopsClient) GetPipelines(project string) ([]Pipeline, error) {
 	return response.Value, nil
 }
 
-func (ado *AzureDevopsClient) GetPipelineRuns(project string, pipeline Pipeline) ([]Run, error) {
-	var runs Runs
-	_, err := ado.R().SetResult(&runs).Get(fmt.Sprintf("/%s/_apis/pipelines/%d/runs", project, pipeline.ID))
-
-	if err != nil {
-		return nil, err
+// GetPipelineRuns returns the runs of a pipeline queued at or after the given time, oldest first.
+// All the runs are returned when the time is zero.
+//
+// The runs are read from the builds api which, unlike the runs api,
+// supports filtering by time and paging with continuation tokens.
+func (ado *AzureDevopsClient) GetPipelineRuns(project string, pipeline Pipeline, since time.Time) ([]Run, error) {
+	params := map[string]string{
+		"definitions": fmt.Sprint(pipeline.ID),
+		"queryOrder":  "queueTimeAscending",
+		"$top":        fmt.Sprint(runsPageSize),
+		"api-version": apiVersion,
+	}
+	if !since.IsZero() {
+		params["minTime"] = since.UTC().Format(time.RFC3339Nano)
 	}
+
 	var results []Run
-	for _, run := range runs.Value {
-		if !run.FinishedDate.IsZero() {
-			run.Duration = int(run.FinishedDate.Sub(run.CreatedDate).Milliseconds())
+	for {
+		var builds Builds
+		resp, err := ado.R().SetResult(&builds).SetQueryParams(params).Get(fmt.Sprintf("/%s/_apis/build/builds", project))
+		if err != nil {
+			return nil, err
 		}
-		results = append(results, run)
+		if resp.IsError() {
+			return nil, fmt.Errorf("%s: %s", resp.Status(), resp.String())
+		}
+
+		for _, build := range builds.Value {
+			results = append(results, build.Run())
+		}
+
+		continuationToken := resp.Header().Get("x-ms-continuationtoken")
+		if continuationToken == "" {
+			break
+		}
+		params["continuationToken"] = continuationToken
 	}
 
 	return results, nil
 }
 
+// GetLatestPipelineRuns returns the latest runs of a pipeline, latest first.
+func (ado *AzureDevopsClient) GetLatestPipelineRuns(project string, pipeline Pipeline, top int) ([]Run, error) {
+	var builds Builds
+	err := ado.get(fmt.Sprintf("/%s/_apis/build/builds", project), map[string]string{
+		"definitions": fmt.Sprint(pipeline.ID),
+		"queryOrder":  "queueTimeDescending",
+		"$top":        fmt.Sprint(top),
+		"api-version": apiVersion,
+	}, &builds)
+
+	return lo.Map(builds.Value, func(build Build, _ int) Run { return build.Run() }), err
+}
+
 func (ado *AzureDevopsClient) GetProjects() ([]Project, error) {
 	var projects Projects
 	_, err := ado.R().SetResult(&projects).Get("/_apis/projects")
@@ -172,3 +451,91 @@ func (ado *AzureDevopsClient) GetProjects() ([]Project, error) {
 
 	return projects.Value, nil
 }
+
+// apiVersion is the version of the apis that require one
+const apiVersion = "7.1"
+
+// get fetches the given url into the result, returning an error for non 2xx responses
+func (ado *AzureDevopsClient) get(url string, params map[string]string, result any) error {
+	resp, err := ado.R().SetResult(result).SetQueryParams(params).Get(url)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("%s: %s", resp.Status(), resp.String())
+	}
+	return nil
+}
+
+func (ado *AzureDevopsClient) GetRepositories(project string) ([]Repository, error) {
+	var response Repositories
+	err := ado.get(fmt.Sprintf("/%s/_apis/git/repositories", project), map[string]string{"api-version": apiVersion}, &response)
+	return response.Value, err
+}
+
+// GetBranchPolicies returns the policies that apply to the branches of the repository
+func (ado *AzureDevopsClient) GetBranchPolicies(project, repositoryID string) ([]PolicyConfiguration, error) {
+	var response PolicyConfigurations
+	err := ado.get(fmt.Sprintf("/%s/_apis/policy/configurations", project), map[string]string{
+		"repositoryId": repositoryID,
+		"api-version":  apiVersion,
+	}, &response)
+	return response.Value, err
+}
+
+// GetCompletedPullRequests returns the most recently completed pull requests of the repository
+func (ado *AzureDevopsClient) GetCompletedPullRequests(project, repositoryID string, top int) ([]PullRequest, error) {
+	var response PullRequests
+	err := ado.get(fmt.Sprintf("/%s/_apis/git/repositories/%s/pullrequests", project, repositoryID), map[string]string{
+		"searchCriteria.status": "completed",
+		"$top":                  fmt.Sprint(top),
+		"api-version":           apiVersion,
+	}, &response)
+	return response.Value, err
+}
+
+// vsrm returns the url of the release management apis, which are served from a different host
+func (ado *AzureDevopsClient) vsrm(project, path string) string {
+	return fmt.Sprintf("%s/%s/_apis/release/%s", ado.releaseURL, project, path)
+}
+
+func (ado *AzureDevopsClient) GetReleaseDefinitions(project string) ([]ReleaseDefinition, error) {
+	var response ReleaseDefinitions
+	err := ado.get(ado.vsrm(project, "definitions"), map[string]string{
+		"$expand":     "environments",
+		"api-version": apiVersion,
+	}, &response)
+	return response.Value, err
+}
+
+// GetReleases returns the latest releases of a release definition
+func (ado *AzureDevopsClient) GetReleases(project string, definitionID, top int) ([]Release, error) {
+	var response Releases
+	err := ado.get(ado.vsrm(project, "releases"), map[string]string{
+		"definitionId": fmt.Sprint(definitionID),
+		"$expand":      "environments",
+		"$top":         fmt.Sprint(top),
+		"api-version":  apiVersion,
+	}, &response)
+	return response.Value, err
+}
+
+func (ado *AzureDevopsClient) GetEnvironments(project string) ([]Environment, error) {
+	var response Environments
+	err := ado.get(fmt.Sprintf("/%s/_apis/distributedtask/environments", project), map[string]string{
+		"api-version": apiVersion + "-preview.1",
+	}, &response)
+	return response.Value, err
+}
+
+// GetEnvironmentChecks returns the approvals & checks configured on an environment
+func (ado *AzureDevopsClient) GetEnvironmentChecks(project string, environmentID int) ([]CheckConfiguration, error) {
+	var response CheckConfigurations
+	err := ado.get(fmt.Sprintf("/%s/_apis/pipelines/checks/configurations", project), map[string]string{
+		"resourceType": "environment",
+		"resourceId":   fmt.Sprint(environmentID),
+		"$expand":      "settings",
+		"api-version":  apiVersion + "-preview.1",
+	}, &response)
+	return response.Value, err
+}
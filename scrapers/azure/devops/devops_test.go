@@ -0,0 +1,243 @@
+package devops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// newTestClient returns a client of a fake organization that serves the given responses by path.
+// A response can be a func(*http.Request) any to respond depending on the request.
+func newTestClient(t *testing.T, responses map[string]any) *AzureDevopsClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if fn, ok := response.(func(*http.Request) any); ok {
+			response = fn(r)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return &AzureDevopsClient{
+		Client:        resty.New().SetBaseURL(server.URL),
+		ScrapeContext: api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{}),
+		releaseURL:    server.URL + "/vsrm",
+	}
+}
+
+var project = Project{ID: "p-1", Name: "Demo1"}
+
+func TestScrapeRepositories(t *testing.T) {
+	closed := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	client := newTestClient(t, map[string]any{
+		"/Demo1/_apis/git/repositories": Repositories{Value: []Repository{
+			{ID: "r-1", Name: "api", RemoteURL: "https://dev.azure.com/org/Demo1/_git/api", WebURL: "https://dev.azure.com/org/Demo1/_git/api"},
+			{ID: "r-2", Name: "archived", IsDisabled: true},
+			{ID: "r-3", Name: "docs"},
+		}},
+		"/Demo1/_apis/policy/configurations": PolicyConfigurations{Value: []PolicyConfiguration{
+			{ID: 1, Type: PolicyType{DisplayName: "Minimum number of reviewers"}, IsEnabled: true, IsBlocking: true},
+			{ID: 2, Type: PolicyType{DisplayName: "Build"}, IsDeleted: true},
+		}},
+		"/Demo1/_apis/git/repositories/r-1/pullrequests": PullRequests{Value: []PullRequest{
+			{PullRequestID: 7, Title: "Add health checks", Status: "completed", ClosedDate: closed, CreatedBy: IdentityRef{UniqueName: "jane@example.com"}},
+		}},
+	})
+
+	results := scrapeRepositories(client, v1.AzureDevops{Repositories: []string{"api", "archived"}}, project)
+	if len(results) != 2 {
+		t.Fatalf("expected the matching repositories, got %v", results)
+	}
+
+	repository := results[0]
+	if repository.Type != RepositoryType || repository.ID != "r-1" || repository.Aliases[0] != "Demo1/api" {
+		t.Errorf("unexpected repository %v", repository)
+	}
+	if policies := repository.Config.(Repository).Policies; len(policies) != 1 || policies[0].ID != 1 {
+		t.Errorf("expected the deleted policies to be dropped, got %v", policies)
+	}
+
+	if len(repository.Changes) != 1 {
+		t.Fatalf("expected the completed pull request, got %v", repository.Changes)
+	}
+	change := repository.Changes[0]
+	if change.ChangeType != "PullRequestCompleted" || change.Summary != "!7 Add health checks" || change.ExternalChangeID != "Demo1/r-1/7" {
+		t.Errorf("unexpected pull request change %v", change)
+	}
+	if !change.CreatedAt.Equal(closed) || change.CreatedBy == nil || *change.CreatedBy != "jane@example.com" {
+		t.Errorf("expected the pull request to be attributed to its author when it closed, got %v", change)
+	}
+
+	// the pull requests of disabled repositories can't be fetched
+	if len(results[1].Changes) != 0 {
+		t.Errorf("expected no pull requests for a disabled repository, got %v", results[1].Changes)
+	}
+}
+
+func TestScrapeReleases(t *testing.T) {
+	client := newTestClient(t, map[string]any{
+		"/vsrm/Demo1/_apis/release/definitions": ReleaseDefinitions{Value: []ReleaseDefinition{
+			{ID: 3, Name: "web", Links: map[string]Link{"self": {Href: "https://vsrm"}, "web": {Href: "https://dev.azure.com"}}},
+		}},
+		"/vsrm/Demo1/_apis/release/releases": Releases{Value: []Release{
+			{ID: 10, Name: "Release-10", Links: map[string]Link{"web": {Href: "https://dev.azure.com/release/10"}}, Environments: []ReleaseEnvironment{
+				{Name: "staging", Status: "succeeded"},
+				{Name: "production", Status: "rejected"},
+			}},
+			{ID: 11, Name: "Release-11", Environments: []ReleaseEnvironment{{Name: "staging", Status: "inProgress"}}},
+		}},
+	})
+
+	results := scrapeReleases(client, v1.AzureDevops{Releases: []string{"*"}}, project)
+	if len(results) != 1 {
+		t.Fatalf("expected the release definition, got %v", results)
+	}
+
+	release := results[0]
+	if release.Type != ReleaseType || release.ID != "Demo1/release/3" {
+		t.Errorf("unexpected release definition %v", release)
+	}
+	if _, ok := release.Config.(ReleaseDefinition).Links["self"]; ok {
+		t.Errorf("expected the self link to be dropped")
+	}
+
+	if len(release.Changes) != 2 {
+		t.Fatalf("expected a change per release, got %v", release.Changes)
+	}
+	if change := release.Changes[0]; change.Summary != "Release-10 staging: succeeded, production: rejected" || change.Severity != "failed" || change.Source != "https://dev.azure.com/release/10" {
+		t.Errorf("unexpected release change %v", change)
+	}
+	if change := release.Changes[1]; change.Severity != "info" || !change.UpdateExisting || change.ExternalChangeID != "Demo1/3/11" {
+		t.Errorf("expected releases in progress to be updated on the next run, got %v", change)
+	}
+}
+
+func TestScrapeEnvironments(t *testing.T) {
+	client := newTestClient(t, map[string]any{
+		"/Demo1/_apis/distributedtask/environments": Environments{Value: []Environment{
+			{ID: 1, Name: "production"},
+			{ID: 2, Name: "dev"},
+		}},
+		"/Demo1/_apis/pipelines/checks/configurations": CheckConfigurations{Value: []CheckConfiguration{
+			{ID: 5, Type: CheckType{Name: "Approval"}, Settings: map[string]any{"approvers": []any{"jane@example.com"}}},
+		}},
+	})
+
+	results := scrapeEnvironments(client, v1.AzureDevops{Environments: []string{"production", "staging"}}, project)
+	if len(results) != 1 {
+		t.Fatalf("expected the matching environment, got %v", results)
+	}
+	environment := results[0]
+	if environment.Type != EnvironmentType || environment.ID != "Demo1/environment/1" || environment.Name != "production" {
+		t.Errorf("unexpected environment %v", environment)
+	}
+	if checks := environment.Config.(Environment).Checks; len(checks) != 1 || checks[0].Type.Name != "Approval" {
+		t.Errorf("expected the approvals of the environment, got %v", checks)
+	}
+}
+
+func TestScrapePipelineRuns(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	build := func(id int, env, status string, queued time.Time) Build {
+		return Build{
+			ID:                 id,
+			Status:             status,
+			Result:             map[string]string{"completed": "succeeded"}[status],
+			QueueTime:          queued,
+			Repository:         BuildRepository{ID: "r-1", Type: "TfsGit"},
+			TemplateParameters: map[string]string{"env": env},
+			Parameters:         `{"token": "secret"}`,
+		}
+	}
+	pipeline := Pipeline{ID: 1, Name: "deploy"}
+
+	// the second run is still in progress on the first scrape
+	builds := []Build{build(1, "dev", "completed", start), build(2, "prod", "inProgress", start.Add(time.Hour)), build(3, "dev", "completed", start.Add(2*time.Hour))}
+
+	var minTimes []string
+	client := newTestClient(t, map[string]any{
+		"/Demo1/_apis/build/builds": func(r *http.Request) any {
+			if r.URL.Query().Get("queryOrder") == "queueTimeDescending" {
+				return Builds{Value: []Build{builds[2], builds[1], builds[0]}}
+			}
+
+			minTimes = append(minTimes, r.URL.Query().Get("minTime"))
+			var runs []Build
+			for _, b := range builds {
+				if minTime, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("minTime")); !b.QueueTime.Before(minTime) {
+					runs = append(runs, b)
+				}
+			}
+			return Builds{Value: runs}
+		},
+	})
+
+	variants := func(results v1.ScrapeResults) map[string]int {
+		changes := make(map[string]int)
+		for _, r := range results {
+			if r.Error != nil {
+				t.Fatalf("unexpected error: %v", r.Error)
+			}
+			if r.Config != nil {
+				changes[r.ID] = len(r.Changes)
+				if len(r.RelationshipResults) != 1 {
+					t.Errorf("expected %s to be related to its repository, got %v", r.ID, r.RelationshipResults)
+				}
+			}
+		}
+		return changes
+	}
+
+	// the first scrape fetches the runs within the max age
+	config := v1.AzureDevops{MaxAge: time.Since(start.Add(-time.Hour)).Round(time.Hour).String()}
+	results := scrapePipeline(client, config, project, pipeline)
+	if minTimes[0] == "" {
+		t.Errorf("expected the runs of the first scrape to be limited by the max age")
+	}
+	if changes := variants(results); len(changes) != 2 || changes["deploy//env=dev"] != 2 || changes["deploy//env=prod"] != 1 {
+		t.Fatalf("expected the runs of both variants, got %v", changes)
+	}
+
+	if cursor, _ := client.GetCursor(pipelineCursorType, "Demo1/1"); cursor != "" {
+		t.Fatalf("expected no cursor before the results are saved, got %s", cursor)
+	}
+	if err := results.Saved(); err != nil {
+		t.Fatal(err)
+	}
+	// only the time is kept, not the variables of the runs
+	if cursor, _ := client.GetCursor(pipelineCursorType, "Demo1/1"); cursor != start.Add(time.Hour).Format(time.RFC3339Nano) {
+		t.Fatalf("expected the cursor at the run in progress, got %s", cursor)
+	}
+
+	// the run in progress has completed since
+	builds[1].Status, builds[1].Result = "completed", "failed"
+	results = scrapePipeline(client, config, project, pipeline)
+	if minTimes[1] != start.Add(time.Hour).Format(time.RFC3339Nano) {
+		t.Errorf("expected the runs to be fetched from the cursor, got %s", minTimes[1])
+	}
+	// the dev variant has a run before the cursor, which is not recorded again
+	if changes := variants(results); len(changes) != 2 || changes["deploy//env=dev"] != 1 || changes["deploy//env=prod"] != 1 {
+		t.Fatalf("expected the variants to be rebuilt from the latest runs, got %v", changes)
+	}
+	for _, r := range results {
+		for _, change := range r.Changes {
+			if change.ExternalChangeID == "Demo1/1/2" && (change.Severity != "failed" || !change.UpdateExisting) {
+				t.Errorf("expected the final status of the run, got %v", change)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package devops
+
+import (
+	"fmt"
+
+	"github.com/flanksource/commons/collections"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func scrapeEnvironments(client *AzureDevopsClient, config v1.AzureDevops, project Project) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	environments, err := client.GetEnvironments(project.Name)
+	if err != nil {
+		results.Errorf(err, "failed to get environments for %s", project.Name)
+		return results
+	}
+
+	for _, environment := range environments {
+		if !collections.MatchItems(environment.Name, config.Environments...) {
+			continue
+		}
+
+		checks, err := client.GetEnvironmentChecks(project.Name, environment.ID)
+		if err != nil {
+			results.Errorf(err, "failed to get checks for environment %s/%s", project.Name, environment.Name)
+		}
+		environment.Checks = checks
+
+		results = append(results, v1.ScrapeResult{
+			BaseScraper: config.BaseScraper,
+			ConfigClass: "Environment",
+			Config:      environment,
+			Type:        EnvironmentType,
+			ID:          fmt.Sprintf("%s/environment/%d", project.Name, environment.ID),
+			Name:        environment.Name,
+			Tags:        map[string]string{"project": project.Name},
+			CreatedAt:   &environment.CreatedOn,
+		})
+	}
+
+	return results
+}
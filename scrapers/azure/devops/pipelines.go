@@ -2,13 +2,25 @@ package devops
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/logger"
+	"github.com/samber/lo"
+
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
 )
 
-const PipelineRun = "AzureDevops::PipelineRun"
+// pipelineCursorType is the cursor type that keeps track of the runs fetched per pipeline
+const pipelineCursorType = "azuredevops/pipeline"
+
+const (
+	PipelineRun     = "AzureDevops::PipelineRun"
+	RepositoryType  = "AzureDevops::Repository"
+	ReleaseType     = "AzureDevops::Release"
+	EnvironmentType = "AzureDevops::Environment"
+)
 
 type AzureDevopsScraper struct {
 }
@@ -39,80 +51,169 @@ func (ado AzureDevopsScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 				continue
 			}
 
-			fmt.Printf("%v\n", project.Name)
+			logger.Debugf("scraping azure devops project %s", project.Name)
 			pipelines, err := client.GetPipelines(project.Name)
 			if err != nil {
 				results.Errorf(err, "failed to get pipelines for %s", project.Name)
 				continue
 			}
-			for _, _pipeline := range pipelines {
-				var pipeline = _pipeline
-
+			for _, pipeline := range pipelines {
 				if !collections.MatchItems(pipeline.Name, config.Pipelines...) {
 					continue
 				}
 
-				var uniquePipelines = make(map[string]Pipeline)
-				fmt.Printf("\t->%v\n", pipeline.Name)
-
-				runs, err := client.GetPipelineRuns(project.Name, pipeline)
-				if err != nil {
-					results.Errorf(err, "failed to get pipeline runs for %s/%s", project.Name, pipeline.Name)
-					continue
-				}
-				for _, _run := range runs {
-					var run = _run
-					var pipeline = _pipeline
-					pipeline.TemplateParameters = run.TemplateParameters
-					pipeline.Variables = run.Variables
-					delete(pipeline.Links, "self")
-					var id = pipeline.GetID()
-
-					if _, ok := uniquePipelines[id]; !ok {
-						uniquePipelines[id] = pipeline
-					} else {
-						pipeline = uniquePipelines[id]
-					}
-					run.TemplateParameters = nil
-					run.Variables = nil
-					delete(run.Links, "self")
-					delete(run.Links, "pipeline")
-					delete(run.Links, "pipeline.web")
-					severity := "info"
-					if run.Result != "succeeded" {
-						severity = "failed"
-					}
-					pipeline.Runs = append(pipeline.Runs, v1.ChangeResult{
-						ChangeType:       "Deployment",
-						CreatedAt:        &run.CreatedDate,
-						Severity:         severity,
-						ExternalID:       id,
-						ConfigType:       PipelineRun,
-						Source:           run.Links["web"].Href,
-						Details:          v1.NewJSON(run),
-						ExternalChangeID: fmt.Sprintf("%s/%d/%d", project.Name, pipeline.ID, run.ID),
-					})
-					uniquePipelines[id] = pipeline
-				}
+				logger.Debugf("scraping azure devops pipeline %s/%s", project.Name, pipeline.Name)
+				results = append(results, scrapePipeline(client, config, project, pipeline)...)
+			}
 
-				for id, pipeline := range uniquePipelines {
-					var changes = pipeline.Runs
-					pipeline.Runs = nil
-					results = append(results, v1.ScrapeResult{
-						ConfigClass: "Deployment",
-						Config:      pipeline,
-						Type:        PipelineRun,
-						ID:          id,
-						Tags:        pipeline.GetTags(),
-						Name:        pipeline.Name,
-						Changes:     changes,
-						Aliases:     []string{fmt.Sprintf("%s/%d", project.Name, pipeline.ID)},
-					})
-				}
+			if len(config.Repositories) > 0 {
+				results = append(results, scrapeRepositories(client, config, project)...)
+			}
+			if len(config.Releases) > 0 {
+				results = append(results, scrapeReleases(client, config, project)...)
+			}
+			if len(config.Environments) > 0 {
+				results = append(results, scrapeEnvironments(client, config, project)...)
 			}
 		}
 	}
 
 	return results
+}
+
+// scrapePipeline returns the variants of a pipeline with the runs queued since the previous scrape as changes.
+//
+// The cursor is the queue time of the oldest run that was still in progress, or else of the latest run,
+// the first scrape fetches the runs queued within the max age.
+// The variants are rebuilt from the latest runs, so that the ones without new runs are still scraped.
+func scrapePipeline(client *AzureDevopsClient, config v1.AzureDevops, project Project, _pipeline Pipeline) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	cursorScope := fmt.Sprintf("%s/%d", project.Name, _pipeline.ID)
+	since := time.Now().Add(-config.GetMaxAge())
+	if value, err := client.GetCursor(pipelineCursorType, cursorScope); err != nil {
+		logger.Warnf("failed to get cursor of pipeline %s: %v", cursorScope, err)
+	} else if value != "" {
+		if cursor, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			logger.Warnf("invalid cursor of pipeline %s: %v", cursorScope, err)
+		} else {
+			since = cursor
+		}
+	}
+
+	runs, err := client.GetPipelineRuns(project.Name, _pipeline, since)
+	if err != nil {
+		results.Errorf(err, "failed to get pipeline runs for %s/%s", project.Name, _pipeline.Name)
+		return results
+	}
+
+	var uniquePipelines = make(map[string]Pipeline)
+	// variant returns the variant of the pipeline the run belongs to
+	variant := func(run Run) (string, Pipeline) {
+		var pipeline = _pipeline
+		pipeline.TemplateParameters = run.TemplateParameters
+		pipeline.Variables = run.Variables
+		pipeline.Links = lo.OmitByKeys(pipeline.Links, []string{"self"})
+		var id = pipeline.GetID()
+
+		if existing, ok := uniquePipelines[id]; ok {
+			pipeline = existing
+		}
+		if run.Repository != nil && run.Repository.Type == "TfsGit" && !lo.Contains(pipeline.Repositories, run.Repository.ID) {
+			pipeline.Repositories = append(pipeline.Repositories, run.Repository.ID)
+		}
+		uniquePipelines[id] = pipeline
+		return id, pipeline
+	}
+
+	latestRuns, err := client.GetLatestPipelineRuns(project.Name, _pipeline, runsPageSize)
+	if err != nil {
+		results.Errorf(err, "failed to get the latest runs of %s/%s", project.Name, _pipeline.Name)
+		return results
+	}
+	for _, run := range latestRuns {
+		// the runs queued since the cursor are recorded below
+		if run.CreatedDate.Before(since) {
+			variant(run)
+		}
+	}
+
+	var latest, oldestInProgress time.Time
+	for _, _run := range runs {
+		var run = _run
+		id, pipeline := variant(run)
 
+		if run.CreatedDate.After(latest) {
+			latest = run.CreatedDate
+		}
+		if !run.IsCompleted() && (oldestInProgress.IsZero() || run.CreatedDate.Before(oldestInProgress)) {
+			oldestInProgress = run.CreatedDate
+		}
+
+		run.TemplateParameters = nil
+		run.Variables = nil
+		delete(run.Links, "self")
+		delete(run.Links, "pipeline")
+		delete(run.Links, "pipeline.web")
+		severity := "info"
+		if run.IsCompleted() && run.Result != "succeeded" {
+			severity = "failed"
+		}
+		pipeline.Runs = append(pipeline.Runs, v1.ChangeResult{
+			ChangeType:       "Deployment",
+			CreatedAt:        &run.CreatedDate,
+			Severity:         severity,
+			ExternalID:       id,
+			ConfigType:       PipelineRun,
+			Source:           run.Links["web"].Href,
+			Details:          v1.NewJSON(run),
+			ExternalChangeID: fmt.Sprintf("%s/%d/%d", project.Name, pipeline.ID, run.ID),
+			// Runs that were in progress on the previous scrape are fetched again,
+			// so that their final status is recorded.
+			UpdateExisting: true,
+		})
+		uniquePipelines[id] = pipeline
+	}
+
+	for id, pipeline := range uniquePipelines {
+		var changes = pipeline.Runs
+		pipeline.Runs = nil
+
+		var relationships v1.RelationshipResults
+		for _, repositoryID := range pipeline.Repositories {
+			relationships = append(relationships, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{id}, ConfigType: PipelineRun},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{repositoryID}, ConfigType: RepositoryType},
+				Relationship:      "PipelineRepository",
+			})
+		}
+
+		results = append(results, v1.ScrapeResult{
+			ConfigClass: "Deployment",
+			Config:      pipeline,
+			Type:        PipelineRun,
+			ID:          id,
+			Tags:        pipeline.GetTags(),
+			Name:        pipeline.Name,
+			Changes:     changes,
+			Aliases:     []string{fmt.Sprintf("%s/%d", project.Name, pipeline.ID)},
+
+			RelationshipResults: relationships,
+		})
+	}
+
+	next := latest
+	if !oldestInProgress.IsZero() {
+		next = oldestInProgress
+	}
+	if !next.IsZero() {
+		results.OnSave(func() error {
+			if err := client.SaveCursor(pipelineCursorType, cursorScope, next.Format(time.RFC3339Nano)); err != nil {
+				return fmt.Errorf("failed to save cursor of pipeline %s: %w", cursorScope, err)
+			}
+			return nil
+		})
+	}
+
+	return results
 }
@@ -0,0 +1,90 @@
+package devops
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flanksource/commons/collections"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// releasesLimit is the number of latest releases fetched per release definition.
+const releasesLimit = 50
+
+func scrapeReleases(client *AzureDevopsClient, config v1.AzureDevops, project Project) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	definitions, err := client.GetReleaseDefinitions(project.Name)
+	if err != nil {
+		results.Errorf(err, "failed to get release definitions for %s", project.Name)
+		return results
+	}
+
+	for _, definition := range definitions {
+		if !collections.MatchItems(definition.Name, config.Releases...) {
+			continue
+		}
+
+		id := fmt.Sprintf("%s/release/%d", project.Name, definition.ID)
+		releases, err := client.GetReleases(project.Name, definition.ID, releasesLimit)
+		if err != nil {
+			results.Errorf(err, "failed to get releases for %s/%s", project.Name, definition.Name)
+		}
+
+		var changes []v1.ChangeResult
+		for _, release := range releases {
+			changes = append(changes, newReleaseChange(project, definition, id, release))
+		}
+
+		delete(definition.Links, "self")
+		results = append(results, v1.ScrapeResult{
+			BaseScraper: config.BaseScraper,
+			ConfigClass: "Release",
+			Config:      definition,
+			Type:        ReleaseType,
+			ID:          id,
+			Name:        definition.Name,
+			Tags:        map[string]string{"project": project.Name},
+			Changes:     changes,
+			CreatedAt:   &definition.CreatedOn,
+		})
+	}
+
+	return results
+}
+
+func newReleaseChange(project Project, definition ReleaseDefinition, id string, release Release) v1.ChangeResult {
+	var environments []string
+	for _, environment := range release.Environments {
+		environments = append(environments, fmt.Sprintf("%s: %s", environment.Name, environment.Status))
+	}
+
+	change := v1.ChangeResult{
+		ChangeType:       "Release",
+		CreatedAt:        &release.CreatedOn,
+		Severity:         releaseSeverity(release),
+		ExternalID:       id,
+		ConfigType:       ReleaseType,
+		Source:           release.Links["web"].Href,
+		Summary:          strings.TrimSpace(fmt.Sprintf("%s %s", release.Name, strings.Join(environments, ", "))),
+		Details:          v1.NewJSON(release),
+		ExternalChangeID: fmt.Sprintf("%s/%d/%d", project.Name, definition.ID, release.ID),
+		// The status of the environments changes as the release progresses
+		UpdateExisting: true,
+	}
+
+	if release.CreatedBy.UniqueName != "" {
+		change.CreatedBy = &release.CreatedBy.UniqueName
+	}
+	return change
+}
+
+func releaseSeverity(release Release) string {
+	for _, environment := range release.Environments {
+		switch environment.Status {
+		case "rejected", "canceled", "partiallySucceeded":
+			return "failed"
+		}
+	}
+	return "info"
+}
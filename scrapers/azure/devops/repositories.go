@@ -0,0 +1,79 @@
+package devops
+
+import (
+	"fmt"
+
+	"github.com/flanksource/commons/collections"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// completedPullRequestsLimit is the number of most recently completed pull requests fetched per repository.
+// Older pull requests would have been saved on previous runs.
+const completedPullRequestsLimit = 100
+
+func scrapeRepositories(client *AzureDevopsClient, config v1.AzureDevops, project Project) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	repositories, err := client.GetRepositories(project.Name)
+	if err != nil {
+		results.Errorf(err, "failed to get repositories for %s", project.Name)
+		return results
+	}
+
+	for _, repository := range repositories {
+		if !collections.MatchItems(repository.Name, config.Repositories...) {
+			continue
+		}
+
+		policies, err := client.GetBranchPolicies(project.Name, repository.ID)
+		if err != nil {
+			results.Errorf(err, "failed to get branch policies for %s/%s", project.Name, repository.Name)
+		}
+		repository.Policies = lo.Filter(policies, func(p PolicyConfiguration, _ int) bool { return !p.IsDeleted })
+
+		var changes []v1.ChangeResult
+		if !repository.IsDisabled {
+			pullRequests, err := client.GetCompletedPullRequests(project.Name, repository.ID, completedPullRequestsLimit)
+			if err != nil {
+				results.Errorf(err, "failed to get pull requests for %s/%s", project.Name, repository.Name)
+			}
+			for _, pr := range pullRequests {
+				changes = append(changes, newPullRequestChange(project, repository, pr))
+			}
+		}
+
+		results = append(results, v1.ScrapeResult{
+			BaseScraper: config.BaseScraper,
+			ConfigClass: "Repository",
+			Config:      repository,
+			Type:        RepositoryType,
+			ID:          repository.ID,
+			Name:        repository.Name,
+			Tags:        map[string]string{"project": project.Name},
+			Changes:     changes,
+			Aliases:     []string{fmt.Sprintf("%s/%s", project.Name, repository.Name), repository.RemoteURL},
+		})
+	}
+
+	return results
+}
+
+func newPullRequestChange(project Project, repository Repository, pr PullRequest) v1.ChangeResult {
+	change := v1.ChangeResult{
+		ChangeType:       "PullRequestCompleted",
+		CreatedAt:        &pr.ClosedDate,
+		Severity:         "info",
+		ExternalID:       repository.ID,
+		ConfigType:       RepositoryType,
+		Source:           fmt.Sprintf("%s/pullrequest/%d", repository.WebURL, pr.PullRequestID),
+		Summary:          fmt.Sprintf("!%d %s", pr.PullRequestID, pr.Title),
+		Details:          v1.NewJSON(pr),
+		ExternalChangeID: fmt.Sprintf("%s/%s/%d", project.Name, repository.ID, pr.PullRequestID),
+	}
+
+	if pr.CreatedBy.UniqueName != "" {
+		change.CreatedBy = &pr.CreatedBy.UniqueName
+	}
+	return change
+}
@@ -0,0 +1,183 @@
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/keyvault/armkeyvault"
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/duty/models"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const (
+	keyVaultType            = ConfigTypePrefix + "Microsoft.KeyVault/vaults"
+	keyVaultSecretType      = ConfigTypePrefix + "Microsoft.KeyVault/vaults/secrets"
+	keyVaultCertificateType = ConfigTypePrefix + "Microsoft.KeyVault/vaults/certificates"
+
+	keyVaultAPIVersion = "7.4"
+
+	// certificateExpiryWarning is how long before expiry a certificate is reported
+	certificateExpiryWarning = 30 * 24 * time.Hour
+)
+
+// keyVaultAttributes are the attributes of a secret or a certificate.
+// The timestamps are in unix seconds.
+type keyVaultAttributes struct {
+	Enabled       *bool  `json:"enabled,omitempty"`
+	NotBefore     *int64 `json:"nbf,omitempty"`
+	Expires       *int64 `json:"exp,omitempty"`
+	Created       *int64 `json:"created,omitempty"`
+	Updated       *int64 `json:"updated,omitempty"`
+	RecoveryLevel string `json:"recoveryLevel,omitempty"`
+}
+
+func (a keyVaultAttributes) ExpiresAt() *time.Time {
+	return unixTime(a.Expires)
+}
+
+// keyVaultItem is the metadata of a secret or a certificate as returned by the list apis.
+// The list apis never return the value of a secret.
+type keyVaultItem struct {
+	ID          string             `json:"id"`
+	Attributes  keyVaultAttributes `json:"attributes"`
+	Tags        map[string]string  `json:"tags,omitempty"`
+	ContentType string             `json:"contentType,omitempty"`
+	Managed     bool               `json:"managed,omitempty"`
+	Subject     string             `json:"subject,omitempty"`
+	Thumbprint  string             `json:"x5t,omitempty"`
+}
+
+// Name returns the name of the item from its id e.g. https://vault.vault.azure.net/secrets/<name>
+func (item keyVaultItem) Name() string {
+	segments := strings.Split(strings.TrimSuffix(item.ID, "/"), "/")
+	return segments[len(segments)-1]
+}
+
+// fetchKeyVaults gets the key vaults in a subscription along with the metadata of their secrets & certificates.
+func (azure Scraper) fetchKeyVaults() v1.ScrapeResults {
+	logger.Debugf("fetching key vaults for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armkeyvault.NewVaultsClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate key vault client: %w", err)})
+	}
+
+	// The secrets & certificates are listed with the data plane api
+	pipeline := runtime.NewPipeline("azure.Scraper", "v1.0.0", runtime.PipelineOptions{
+		PerRetry: []policy.Policy{runtime.NewBearerTokenPolicy(azure.cred, []string{"https://vault.azure.net/.default"}, nil)},
+	}, nil)
+
+	pager := client.NewListBySubscriptionPager(nil)
+	for pager.More() {
+		nextPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read key vaults page: %w", err)})
+		}
+
+		for _, vault := range nextPage.Value {
+			vaultID := getARMID(vault.ID)
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          vaultID,
+				Name:        deref(vault.Name),
+				Config:      vault,
+				ConfigClass: "KeyVault",
+				Type:        keyVaultType,
+			})
+
+			if vault.Properties == nil || vault.Properties.VaultURI == nil {
+				continue
+			}
+			vaultURI := strings.TrimSuffix(*vault.Properties.VaultURI, "/")
+
+			secrets, err := listPages[keyVaultItem](azure.ctx, pipeline, fmt.Sprintf("%s/secrets?api-version=%s", vaultURI, keyVaultAPIVersion))
+			if err != nil {
+				results.Errorf(err, "failed to list secrets of key vault %s", deref(vault.Name))
+			}
+			for _, secret := range secrets {
+				// Managed secrets back the certificates
+				if secret.Managed {
+					continue
+				}
+				results = append(results, azure.keyVaultItemResult(secret, vaultID, keyVaultSecretType, "Secret"))
+			}
+
+			certificates, err := listPages[keyVaultItem](azure.ctx, pipeline, fmt.Sprintf("%s/certificates?api-version=%s", vaultURI, keyVaultAPIVersion))
+			if err != nil {
+				results.Errorf(err, "failed to list certificates of key vault %s", deref(vault.Name))
+			}
+			for _, certificate := range certificates {
+				results = append(results, azure.keyVaultItemResult(certificate, vaultID, keyVaultCertificateType, "Certificate"))
+				certificateExpiryAnalysis(&results, certificate, time.Now())
+			}
+		}
+	}
+
+	return results
+}
+
+func (azure Scraper) keyVaultItemResult(item keyVaultItem, vaultID, configType, configClass string) v1.ScrapeResult {
+	tags := map[string]string{}
+	if expiresAt := item.Attributes.ExpiresAt(); expiresAt != nil {
+		tags["expires"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	return v1.ScrapeResult{
+		BaseScraper:      azure.config.BaseScraper,
+		ID:               strings.ToLower(item.ID),
+		Name:             item.Name(),
+		Config:           item,
+		ConfigClass:      configClass,
+		Type:             configType,
+		CreatedAt:        unixTime(item.Attributes.Created),
+		Tags:             tags,
+		ParentExternalID: vaultID,
+		ParentType:       keyVaultType,
+	}
+}
+
+// certificateExpiryAnalysis reports the certificates that are expired or about to expire.
+func certificateExpiryAnalysis(results *v1.ScrapeResults, certificate keyVaultItem, now time.Time) {
+	expiresAt := certificate.Attributes.ExpiresAt()
+	if expiresAt == nil || (certificate.Attributes.Enabled != nil && !*certificate.Attributes.Enabled) {
+		return
+	}
+
+	remaining := expiresAt.Sub(now)
+	if remaining > certificateExpiryWarning {
+		return
+	}
+
+	analysis := results.Analysis("Certificate expiry", keyVaultCertificateType, strings.ToLower(certificate.ID))
+	analysis.AnalysisType = models.AnalysisTypeSecurity
+	analysis.Source = "Azure Key Vault"
+	analysis.Status = models.AnalysisStatusOpen
+	analysis.Analysis = map[string]any{"expires": expiresAt, "subject": certificate.Subject, "thumbprint": certificate.Thumbprint}
+
+	switch {
+	case remaining <= 0:
+		analysis.Severity = models.SeverityCritical
+		analysis.Summary = fmt.Sprintf("Certificate %s expired on %s", certificate.Name(), expiresAt.Format(time.DateOnly))
+	case remaining <= 7*24*time.Hour:
+		analysis.Severity = models.SeverityHigh
+		analysis.Summary = fmt.Sprintf("Certificate %s expires on %s", certificate.Name(), expiresAt.Format(time.DateOnly))
+	default:
+		analysis.Severity = models.SeverityMedium
+		analysis.Summary = fmt.Sprintf("Certificate %s expires on %s", certificate.Name(), expiresAt.Format(time.DateOnly))
+	}
+	analysis.Message(analysis.Summary)
+}
+
+func unixTime(seconds *int64) *time.Time {
+	if seconds == nil {
+		return nil
+	}
+	t := time.Unix(*seconds, 0)
+	return &t
+}
@@ -0,0 +1,74 @@
+package azure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flanksource/duty/models"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestCertificateExpiryAnalysis(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		expires          *int64
+		enabled          *bool
+		expectedSeverity models.Severity
+	}{
+		{"expired", lo.ToPtr(now.Add(-time.Hour).Unix()), nil, models.SeverityCritical},
+		{"expires this week", lo.ToPtr(now.Add(3 * 24 * time.Hour).Unix()), nil, models.SeverityHigh},
+		{"expires this month", lo.ToPtr(now.Add(20 * 24 * time.Hour).Unix()), lo.ToPtr(true), models.SeverityMedium},
+		{"valid", lo.ToPtr(now.Add(90 * 24 * time.Hour).Unix()), nil, ""},
+		{"disabled", lo.ToPtr(now.Add(-time.Hour).Unix()), lo.ToPtr(false), ""},
+		{"no expiry", nil, nil, ""},
+	}
+
+	for _, test := range tests {
+		var results v1.ScrapeResults
+		certificate := keyVaultItem{
+			ID:         "https://vault.vault.azure.net/certificates/" + test.name,
+			Attributes: keyVaultAttributes{Expires: test.expires, Enabled: test.enabled},
+		}
+		certificateExpiryAnalysis(&results, certificate, now)
+
+		if test.expectedSeverity == "" {
+			if len(results) != 0 {
+				t.Errorf("%s: Expected no analysis, Got: %v", test.name, results[0].AnalysisResult)
+			}
+			continue
+		}
+
+		if len(results) != 1 || results[0].AnalysisResult == nil {
+			t.Errorf("%s: Expected an analysis, Got: %d results", test.name, len(results))
+			continue
+		}
+		if results[0].AnalysisResult.Severity != test.expectedSeverity {
+			t.Errorf("%s: Expected severity %s, Got: %s", test.name, test.expectedSeverity, results[0].AnalysisResult.Severity)
+		}
+	}
+}
+
+func TestGetUserAssignedIdentities(t *testing.T) {
+	config := map[string]any{
+		"identity": map[string]any{
+			"type": "SystemAssigned, UserAssigned",
+			"userAssignedIdentities": map[string]any{
+				"/subscriptions/123/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/b": map[string]any{},
+				"/subscriptions/123/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/a": map[string]any{},
+			},
+		},
+	}
+
+	ids := getUserAssignedIdentities(config)
+	if len(ids) != 2 || ids[0] != "/subscriptions/123/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/a" {
+		t.Errorf("Unexpected identities: %v", ids)
+	}
+
+	if ids := getUserAssignedIdentities(map[string]any{"name": "no identity"}); len(ids) != 0 {
+		t.Errorf("Expected no identities, Got: %v", ids)
+	}
+}
@@ -0,0 +1,95 @@
+package azure
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resourcegraph/armresourcegraph"
+	"github.com/flanksource/commons/logger"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// resourceGraphPageSize is the maximum number of rows Resource Graph returns per page.
+const resourceGraphPageSize = 1000
+
+// fetchResourceGraph gets all the resources of the subscription that the Resource Graph query returns.
+// Resources that were already scraped by the typed fetchers are skipped, as those carry more details.
+func (azure Scraper) fetchResourceGraph(scraped v1.ScrapeResults) v1.ScrapeResults {
+	logger.Debugf("fetching resource graph for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armresourcegraph.NewClient(azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate resource graph client: %w", err)})
+	}
+
+	existing := make(map[string]struct{}, len(scraped))
+	for _, r := range scraped {
+		existing[strings.ToLower(r.ID)] = struct{}{}
+	}
+
+	request := armresourcegraph.QueryRequest{
+		Query:         lo.ToPtr(azure.config.ResourceGraph.GetQuery()),
+		Subscriptions: []*string{&azure.config.SubscriptionID},
+		Options: &armresourcegraph.QueryRequestOptions{
+			ResultFormat: lo.ToPtr(armresourcegraph.ResultFormatObjectArray),
+			Top:          lo.ToPtr(int32(resourceGraphPageSize)),
+		},
+	}
+
+	for {
+		response, err := client.Resources(azure.ctx, request, nil)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to query resource graph: %w", err)})
+		}
+
+		rows, ok := response.Data.([]any)
+		if !ok {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("unexpected resource graph response of type %T", response.Data)})
+		}
+
+		for _, row := range rows {
+			resource, ok := row.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			id := getARMID(lo.ToPtr(fmt.Sprint(resource["id"])))
+			if _, ok := existing[id]; ok || resource["id"] == nil {
+				continue
+			}
+
+			resourceType := fmt.Sprint(resource["type"])
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          id,
+				Name:        fmt.Sprint(resource["name"]),
+				Config:      resource,
+				ConfigClass: path.Base(resourceType),
+				Type:        getARMType(&resourceType),
+				Tags:        getResourceGraphTags(resource),
+			})
+		}
+
+		if response.SkipToken == nil || *response.SkipToken == "" {
+			break
+		}
+		request.Options.SkipToken = response.SkipToken
+	}
+
+	return results
+}
+
+// getResourceGraphTags returns the location & resource group of a resource graph row as tags.
+func getResourceGraphTags(resource map[string]any) map[string]string {
+	tags := make(map[string]string)
+	for tag, key := range map[string]string{"region": "location", "resourceGroup": "resourceGroup"} {
+		if value, ok := resource[key].(string); ok && value != "" {
+			tags[tag] = value
+		}
+	}
+	return tags
+}
@@ -0,0 +1,166 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appservice/armappservice"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/flanksource/commons/logger"
+	"github.com/samber/lo"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const managedIdentityType = ConfigTypePrefix + "Microsoft.ManagedIdentity/userAssignedIdentities"
+
+// fetchARMResources gets the resources of a provider collection of the subscription
+// e.g. Microsoft.DocumentDB/databaseAccounts
+func (azure Scraper) fetchARMResources(collection, apiVersion, configClass string) (v1.ScrapeResults, []armResource) {
+	logger.Debugf("fetching %s for subscription %s", collection, azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	resources, err := azure.listARMResources(fmt.Sprintf("/subscriptions/%s/providers/%s", azure.config.SubscriptionID, collection), apiVersion)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to list %s: %w", collection, err)}), nil
+	}
+
+	for _, resource := range resources {
+		results = append(results, azure.armResourceResult(resource, configClass))
+	}
+	return results, resources
+}
+
+// fetchARMChildResources gets the child resources of the given resources e.g. the queues of Service Bus namespaces
+func (azure Scraper) fetchARMChildResources(parents []armResource, collection, apiVersion, configClass string) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	for _, parent := range parents {
+		children, err := azure.listARMResources(parent.ID()+"/"+collection, apiVersion)
+		if err != nil {
+			results.Errorf(err, "failed to list %s of %s", collection, parent.Name())
+			continue
+		}
+
+		for _, child := range children {
+			result := azure.armResourceResult(child, configClass)
+			result.ParentExternalID = getARMID(to.Ptr(parent.ID()))
+			result.ParentType = getARMType(to.Ptr(parent.Type()))
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func (azure Scraper) armResourceResult(resource armResource, configClass string) v1.ScrapeResult {
+	return v1.ScrapeResult{
+		BaseScraper: azure.config.BaseScraper,
+		ID:          getARMID(to.Ptr(resource.ID())),
+		Name:        resource.Name(),
+		Config:      map[string]any(resource),
+		ConfigClass: configClass,
+		Type:        getARMType(to.Ptr(resource.Type())),
+	}
+}
+
+// fetchCosmosDBAccounts gets Cosmos DB accounts in a subscription.
+func (azure Scraper) fetchCosmosDBAccounts() v1.ScrapeResults {
+	results, _ := azure.fetchARMResources("Microsoft.DocumentDB/databaseAccounts", "2023-04-15", "CosmosDB")
+	return results
+}
+
+// fetchServiceBuses gets Service Bus namespaces along with their queues in a subscription.
+func (azure Scraper) fetchServiceBuses() v1.ScrapeResults {
+	results, namespaces := azure.fetchARMResources("Microsoft.ServiceBus/namespaces", "2021-11-01", "ServiceBusNamespace")
+	return append(results, azure.fetchARMChildResources(namespaces, "queues", "2021-11-01", "ServiceBusQueue")...)
+}
+
+// fetchEventHubs gets Event Hubs namespaces along with their event hubs in a subscription.
+func (azure Scraper) fetchEventHubs() v1.ScrapeResults {
+	results, namespaces := azure.fetchARMResources("Microsoft.EventHub/namespaces", "2021-11-01", "EventHubNamespace")
+	return append(results, azure.fetchARMChildResources(namespaces, "eventhubs", "2021-11-01", "EventHub")...)
+}
+
+// fetchManagedIdentities gets user assigned managed identities in a subscription.
+func (azure Scraper) fetchManagedIdentities() v1.ScrapeResults {
+	results, _ := azure.fetchARMResources("Microsoft.ManagedIdentity/userAssignedIdentities", "2023-01-31", "ManagedIdentity")
+	return results
+}
+
+// isFunctionApp returns true for the sites of kind functionapp e.g. "functionapp,linux"
+func isFunctionApp(site *armappservice.Site) bool {
+	return strings.Contains(strings.ToLower(deref(site.Kind)), "functionapp")
+}
+
+// fetchApplicationGateways gets application gateways in a subscription.
+func (azure Scraper) fetchApplicationGateways() v1.ScrapeResults {
+	logger.Debugf("fetching application gateways for subscription %s", azure.config.SubscriptionID)
+
+	var results v1.ScrapeResults
+	client, err := armnetwork.NewApplicationGatewaysClient(azure.config.SubscriptionID, azure.cred, nil)
+	if err != nil {
+		return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to initiate application gateways client: %w", err)})
+	}
+
+	pager := client.NewListAllPager(nil)
+	for pager.More() {
+		nextPage, err := pager.NextPage(azure.ctx)
+		if err != nil {
+			return append(results, v1.ScrapeResult{Error: fmt.Errorf("failed to read application gateways page: %w", err)})
+		}
+
+		for _, v := range nextPage.Value {
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: azure.config.BaseScraper,
+				ID:          getARMID(v.ID),
+				Name:        deref(v.Name),
+				Config:      v,
+				ConfigClass: "ApplicationGateway",
+				Type:        getARMType(v.Type),
+			})
+		}
+	}
+
+	return results
+}
+
+// relateManagedIdentities links the user assigned managed identities to the resources that use them.
+func relateManagedIdentities(results v1.ScrapeResults) {
+	for i, r := range results {
+		if r.ID == "" || r.Config == nil || r.Type == managedIdentityType {
+			continue
+		}
+
+		for _, identityID := range getUserAssignedIdentities(r.Config) {
+			results[i].RelationshipResults = append(results[i].RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{getARMID(&identityID)}, ConfigType: managedIdentityType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{r.ID}, ConfigType: r.Type},
+				Relationship:      "ManagedIdentity" + strings.TrimPrefix(r.Type, ConfigTypePrefix),
+			})
+		}
+	}
+}
+
+// getUserAssignedIdentities returns the ids of the user assigned identities of a resource
+// from its identity.userAssignedIdentities field.
+func getUserAssignedIdentities(config any) []string {
+	var resource struct {
+		Identity *struct {
+			UserAssignedIdentities map[string]any `json:"userAssignedIdentities"`
+		} `json:"identity"`
+	}
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &resource); err != nil || resource.Identity == nil {
+		return nil
+	}
+
+	ids := lo.Keys(resource.Identity.UserAssignedIdentities)
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,193 @@
+package azure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/managementgroups/armmanagementgroups"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/subscription/armsubscription"
+	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/commons/utils"
+	"github.com/samber/lo"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const managementGroupType = ConfigTypePrefix + "Microsoft.Management/managementGroups"
+
+// discoverSubscriptions returns the ids of the subscriptions to scrape
+// along with the subscriptions & management groups as config items.
+func discoverSubscriptions(ctx api.ScrapeContext, cred azcore.TokenCredential, config v1.Azure) ([]string, v1.ScrapeResults, error) {
+	client, err := armsubscription.NewSubscriptionsClient(cred, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initiate subscriptions client: %w", err)
+	}
+
+	var subscriptions []*armsubscription.Subscription
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read subscription next page: %w", err)
+		}
+		subscriptions = append(subscriptions, page.Value...)
+	}
+
+	results, groupSubscriptions, err := discoverManagementGroups(ctx, cred, config)
+	if err != nil {
+		if config.Discovery.ManagementGroup != "" {
+			return nil, nil, err
+		}
+		// The management groups are only needed for the relationships
+		// when scraping all the subscriptions.
+		logger.Warnf("failed to list azure management groups: %v", err)
+	}
+
+	var subscriptionIDs []string
+	for _, subscription := range subscriptions {
+		id := deref(subscription.SubscriptionID)
+		if utils.Deref(subscription.State) != armsubscription.SubscriptionStateEnabled {
+			continue
+		}
+		if collections.Contains(config.Discovery.Exclude, id) {
+			continue
+		}
+		if _, ok := groupSubscriptions[strings.ToLower(id)]; config.Discovery.ManagementGroup != "" && !ok {
+			continue
+		}
+
+		subscriptionIDs = append(subscriptionIDs, id)
+		results = append(results, v1.ScrapeResult{
+			BaseScraper: config.BaseScraper,
+			ID:          getARMID(subscription.ID),
+			Name:        deref(subscription.DisplayName),
+			Config:      subscription,
+			ConfigClass: "Subscription",
+			Type:        getARMType(utils.Ptr("Subscription")),
+			Tags:        map[string]string{"azure-subscription-id": id},
+		})
+	}
+
+	return subscriptionIDs, results, nil
+}
+
+// discoverManagementGroups returns the management groups as config items.
+// When the config is limited to a management group, only that group and its descendants
+// are returned along with the (lowercased) ids of the subscriptions under them.
+func discoverManagementGroups(ctx api.ScrapeContext, cred azcore.TokenCredential, config v1.Azure) (v1.ScrapeResults, map[string]struct{}, error) {
+	client, err := armmanagementgroups.NewClient(cred, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initiate management groups client: %w", err)
+	}
+
+	var results v1.ScrapeResults
+	if group := config.Discovery.ManagementGroup; group != "" {
+		response, err := client.Get(ctx, group, &armmanagementgroups.ClientGetOptions{
+			Expand:  lo.ToPtr(armmanagementgroups.ManagementGroupExpandTypeChildren),
+			Recurse: lo.ToPtr(true),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get management group %s: %w", group, err)
+		}
+
+		subscriptions := make(map[string]struct{})
+		results = append(results, newManagementGroupResult(config, response.ManagementGroup))
+		walkManagementGroup(config, deref(response.ID), response.Properties.Children, &results, subscriptions)
+		return results, subscriptions, nil
+	}
+
+	pager := client.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read management groups next page: %w", err)
+		}
+
+		for _, info := range page.Value {
+			response, err := client.Get(ctx, deref(info.Name), &armmanagementgroups.ClientGetOptions{
+				Expand: lo.ToPtr(armmanagementgroups.ManagementGroupExpandTypeChildren),
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get management group %s: %w", deref(info.Name), err)
+			}
+			results = append(results, newManagementGroupResult(config, response.ManagementGroup))
+		}
+	}
+
+	return results, nil, nil
+}
+
+func newManagementGroupResult(config v1.Azure, group armmanagementgroups.ManagementGroup) v1.ScrapeResult {
+	result := v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ID:          getARMID(group.ID),
+		Name:        deref(group.Name),
+		Config:      group,
+		ConfigClass: "ManagementGroup",
+		Type:        managementGroupType,
+	}
+
+	if group.Properties == nil {
+		return result
+	}
+
+	if group.Properties.DisplayName != nil {
+		result.Name = deref(group.Properties.DisplayName)
+	}
+	if group.Properties.Details != nil && group.Properties.Details.Parent != nil && group.Properties.Details.Parent.ID != nil {
+		result.ParentExternalID = getARMID(group.Properties.Details.Parent.ID)
+		result.ParentType = managementGroupType
+	}
+	result.RelationshipResults = managementGroupSubscriptions(deref(group.ID), group.Properties.Children)
+	return result
+}
+
+// walkManagementGroup adds the nested management groups as config items
+// and collects the subscriptions under them.
+func walkManagementGroup(config v1.Azure, groupID string, children []*armmanagementgroups.ManagementGroupChildInfo, results *v1.ScrapeResults, subscriptions map[string]struct{}) {
+	for _, child := range children {
+		switch utils.Deref(child.Type) {
+		case armmanagementgroups.ManagementGroupChildTypeSubscriptions:
+			subscriptions[strings.ToLower(deref(child.Name))] = struct{}{}
+
+		case armmanagementgroups.ManagementGroupChildTypeMicrosoftManagementManagementGroups:
+			// The descendants are part of their own config items
+			childConfig := *child
+			childConfig.Children = nil
+
+			*results = append(*results, v1.ScrapeResult{
+				BaseScraper:         config.BaseScraper,
+				ID:                  getARMID(child.ID),
+				Name:                deref(child.DisplayName),
+				Config:              childConfig,
+				ConfigClass:         "ManagementGroup",
+				Type:                managementGroupType,
+				ParentExternalID:    getARMID(&groupID),
+				ParentType:          managementGroupType,
+				RelationshipResults: managementGroupSubscriptions(deref(child.ID), child.Children),
+			})
+
+			walkManagementGroup(config, deref(child.ID), child.Children, results, subscriptions)
+		}
+	}
+}
+
+// managementGroupSubscriptions relates the management group to its direct subscriptions.
+func managementGroupSubscriptions(groupID string, children []*armmanagementgroups.ManagementGroupChildInfo) v1.RelationshipResults {
+	var relationships v1.RelationshipResults
+	for _, child := range children {
+		if utils.Deref(child.Type) != armmanagementgroups.ManagementGroupChildTypeSubscriptions {
+			continue
+		}
+
+		relationships = append(relationships, v1.RelationshipResult{
+			ConfigExternalID:  v1.ExternalID{ExternalID: []string{getARMID(&groupID)}, ConfigType: managementGroupType},
+			RelatedExternalID: v1.ExternalID{ExternalID: []string{getARMID(child.ID)}, ConfigType: ConfigTypePrefix + "SUBSCRIPTION"},
+			Relationship:      "ManagementGroupSubscription",
+		})
+	}
+	return relationships
+}
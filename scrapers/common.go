@@ -7,12 +7,23 @@ import (
 	"github.com/flanksource/duty/types"
 
 	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/scrapers/ansible"
 	"github.com/flanksource/config-db/scrapers/aws"
 	"github.com/flanksource/config-db/scrapers/azure/devops"
+	"github.com/flanksource/config-db/scrapers/consul"
+	"github.com/flanksource/config-db/scrapers/docker"
 	"github.com/flanksource/config-db/scrapers/file"
 	"github.com/flanksource/config-db/scrapers/github"
+	"github.com/flanksource/config-db/scrapers/gitlab"
+	"github.com/flanksource/config-db/scrapers/host"
+	"github.com/flanksource/config-db/scrapers/http"
+	"github.com/flanksource/config-db/scrapers/jenkins"
+	"github.com/flanksource/config-db/scrapers/kafka"
 	"github.com/flanksource/config-db/scrapers/kubernetes"
+	"github.com/flanksource/config-db/scrapers/ldap"
+	"github.com/flanksource/config-db/scrapers/prometheus"
 	"github.com/flanksource/config-db/scrapers/sql"
+	"github.com/flanksource/config-db/scrapers/vault"
 )
 
 // All is the scrappers registry
@@ -24,8 +35,21 @@ var All = []api.Scraper{
 	kubernetes.KubernetesScraper{},
 	kubernetes.KubernetesFileScraper{},
 	devops.AzureDevopsScraper{},
+	github.GithubScraper{},
 	github.GithubActionsScraper{},
+	gitlab.GitLabScraper{},
+	jenkins.JenkinsScraper{},
+	http.HTTPScraper{},
+	host.Scraper{},
+	ansible.Scraper{},
+	prometheus.PrometheusScraper{},
+	consul.ConsulScraper{},
+	vault.VaultScraper{},
+	kafka.KafkaScraper{},
+	ldap.LDAPScraper{},
+	docker.DockerScraper{},
 	sql.SqlScraper{},
+	sql.DatabaseScraper{},
 	trivy.Scanner{},
 }
 
@@ -0,0 +1,142 @@
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// Node is a node of the catalog
+type Node struct {
+	ID              string            `json:"ID"`
+	Node            string            `json:"Node"`
+	Address         string            `json:"Address"`
+	Datacenter      string            `json:"Datacenter"`
+	TaggedAddresses map[string]string `json:"TaggedAddresses,omitempty"`
+	Meta            map[string]string `json:"Meta,omitempty"`
+}
+
+// ServiceInstance is an instance of a service registered on a node
+type ServiceInstance struct {
+	Node           string            `json:"Node"`
+	Address        string            `json:"Address"`
+	Datacenter     string            `json:"Datacenter"`
+	ServiceID      string            `json:"ServiceID"`
+	ServiceName    string            `json:"ServiceName"`
+	ServiceAddress string            `json:"ServiceAddress,omitempty"`
+	ServicePort    int               `json:"ServicePort,omitempty"`
+	ServiceTags    []string          `json:"ServiceTags,omitempty"`
+	ServiceMeta    map[string]string `json:"ServiceMeta,omitempty"`
+	ServiceKind    string            `json:"ServiceKind,omitempty"`
+}
+
+// Check is a health check of a node or of a service instance.
+// The output of the check is not decoded as it changes on every run.
+type Check struct {
+	Node        string   `json:"Node"`
+	CheckID     string   `json:"CheckID"`
+	Name        string   `json:"Name"`
+	Status      string   `json:"Status"`
+	Notes       string   `json:"Notes,omitempty"`
+	ServiceID   string   `json:"ServiceID,omitempty"`
+	ServiceName string   `json:"ServiceName,omitempty"`
+	ServiceTags []string `json:"ServiceTags,omitempty"`
+	Type        string   `json:"Type,omitempty"`
+}
+
+// KVPair is a key of the KV store, the value is base64 encoded
+type KVPair struct {
+	Key         string `json:"Key"`
+	Value       []byte `json:"Value"`
+	Flags       uint64 `json:"Flags"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+type ConsulClient struct {
+	*resty.Client
+	api.ScrapeContext
+	URL string
+}
+
+func NewConsulClient(ctx api.ScrapeContext, config v1.Consul) (*ConsulClient, error) {
+	var token string
+	url := config.URL
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		token = connection.Password
+		if connection.URL != "" {
+			url = connection.URL
+		}
+	} else if token, err = ctx.GetEnvValueFromCache(config.Token); err != nil {
+		return nil, err
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("consul url is required")
+	}
+
+	client := resty.New()
+	if token != "" {
+		client.SetHeader("X-Consul-Token", token)
+	}
+	if config.Datacenter != "" {
+		client.SetQueryParam("dc", config.Datacenter)
+	}
+
+	return &ConsulClient{
+		ScrapeContext: ctx,
+		Client:        client,
+		URL:           strings.TrimSuffix(url, "/"),
+	}, nil
+}
+
+func (c *ConsulClient) get(path string, query map[string]string, result any) error {
+	resp, err := c.R().SetQueryParams(query).Get(c.URL + path)
+	if err != nil {
+		return err
+	}
+	// missing KV prefixes are returned as 404
+	if resp.StatusCode() == http.StatusNotFound && strings.HasPrefix(path, "/v1/kv/") {
+		return nil
+	}
+	if resp.IsError() {
+		return fmt.Errorf("received %s from consul: %s", resp.Status(), strings.TrimSpace(string(resp.Body())))
+	}
+	return json.Unmarshal(resp.Body(), result)
+}
+
+// GetNodes returns the nodes of the catalog
+func (c *ConsulClient) GetNodes() ([]Node, error) {
+	var nodes []Node
+	return nodes, c.get("/v1/catalog/nodes", nil, &nodes)
+}
+
+// GetServices returns the names & tags of the services of the catalog
+func (c *ConsulClient) GetServices() (map[string][]string, error) {
+	var services map[string][]string
+	return services, c.get("/v1/catalog/services", nil, &services)
+}
+
+// GetServiceInstances returns the instances of a service
+func (c *ConsulClient) GetServiceInstances(name string) ([]ServiceInstance, error) {
+	var instances []ServiceInstance
+	return instances, c.get("/v1/catalog/service/"+name, nil, &instances)
+}
+
+// GetChecks returns the health checks of all the nodes & services
+func (c *ConsulClient) GetChecks() ([]Check, error) {
+	var checks []Check
+	return checks, c.get("/v1/health/state/any", nil, &checks)
+}
+
+// GetKV returns the keys under a prefix
+func (c *ConsulClient) GetKV(prefix string) ([]KVPair, error) {
+	var pairs []KVPair
+	return pairs, c.get("/v1/kv/"+strings.TrimPrefix(prefix, "/"), map[string]string{"recurse": "true"}, &pairs)
+}
@@ -0,0 +1,178 @@
+package consul
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const (
+	NodeType    = "Consul::Node"
+	ServiceType = "Consul::Service"
+	CheckType   = "Consul::Check"
+	KVType      = "Consul::KV"
+)
+
+// checkStatuses in order of severity
+var checkStatuses = map[string]int{"passing": 0, "warning": 1, "critical": 2}
+
+type ConsulScraper struct {
+}
+
+func (c ConsulScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Consul) > 0
+}
+
+// Scrape scrapes the nodes, services, health checks and KV prefixes of consul datacenters
+func (c ConsulScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Consul {
+		client, err := NewConsulClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create consul client for %s", config.URL)
+			continue
+		}
+
+		nodes, err := client.GetNodes()
+		if err != nil {
+			results.Errorf(err, "failed to get nodes of %s", client.URL)
+			continue
+		}
+		datacenter := config.Datacenter
+		if datacenter == "" && len(nodes) > 0 {
+			datacenter = nodes[0].Datacenter
+		}
+		id := func(parts ...string) string {
+			return strings.Join(append([]string{datacenter}, parts...), "/")
+		}
+
+		checks, err := client.GetChecks()
+		if err != nil {
+			results.Errorf(err, "failed to get health checks of %s", client.URL)
+		}
+		nodeChecks := map[string][]Check{}
+		serviceChecks := map[string][]Check{}
+		for _, check := range checks {
+			if check.ServiceName == "" {
+				nodeChecks[check.Node] = append(nodeChecks[check.Node], check)
+			} else {
+				serviceChecks[check.ServiceName] = append(serviceChecks[check.ServiceName], check)
+			}
+
+			results = append(results, v1.ScrapeResult{
+				BaseScraper:      config.BaseScraper,
+				ID:               id("check", check.Node, check.CheckID),
+				Name:             check.Name,
+				Type:             CheckType,
+				ConfigClass:      "HealthCheck",
+				Config:           check,
+				Status:           check.Status,
+				ParentExternalID: id("node", check.Node),
+				ParentType:       NodeType,
+				Tags:             map[string]string{"datacenter": datacenter},
+			})
+		}
+
+		for _, node := range nodes {
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: config.BaseScraper,
+				ID:          id("node", node.Node),
+				Name:        node.Node,
+				Type:        NodeType,
+				ConfigClass: "Node",
+				Config:      node,
+				Status:      worstStatus(nodeChecks[node.Node]),
+				Aliases:     []string{node.ID},
+				Tags:        map[string]string{"datacenter": datacenter},
+			})
+		}
+
+		services, err := client.GetServices()
+		if err != nil {
+			results.Errorf(err, "failed to get services of %s", client.URL)
+		}
+		names := make([]string, 0, len(services))
+		for name := range services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			instances, err := client.GetServiceInstances(name)
+			if err != nil {
+				results.Errorf(err, "failed to get instances of service %s", name)
+				continue
+			}
+
+			result := v1.ScrapeResult{
+				BaseScraper: config.BaseScraper,
+				ID:          id("service", name),
+				Name:        name,
+				Type:        ServiceType,
+				ConfigClass: "Service",
+				Config: map[string]any{
+					"name":      name,
+					"tags":      services[name],
+					"instances": instances,
+				},
+				Status: worstStatus(serviceChecks[name]),
+				Tags:   map[string]string{"datacenter": datacenter},
+			}
+			for _, instance := range instances {
+				result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ExternalID: []string{id("node", instance.Node)}, ConfigType: NodeType},
+					RelatedExternalID: v1.ExternalID{ExternalID: []string{result.ID}, ConfigType: ServiceType},
+					Relationship:      "NodeService",
+				})
+			}
+			for _, check := range serviceChecks[name] {
+				result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ExternalID: []string{result.ID}, ConfigType: ServiceType},
+					RelatedExternalID: v1.ExternalID{ExternalID: []string{id("check", check.Node, check.CheckID)}, ConfigType: CheckType},
+					Relationship:      "ServiceCheck",
+				})
+			}
+			results = append(results, result)
+		}
+
+		for _, prefix := range config.KV {
+			pairs, err := client.GetKV(prefix)
+			if err != nil {
+				results.Errorf(err, "failed to get keys of %s", prefix)
+				continue
+			} else if len(pairs) == 0 {
+				continue
+			}
+			kv := map[string]string{}
+			for _, pair := range pairs {
+				// folders are keys ending with / without a value
+				if strings.HasSuffix(pair.Key, "/") && len(pair.Value) == 0 {
+					continue
+				}
+				kv[pair.Key] = string(pair.Value)
+			}
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: config.BaseScraper,
+				ID:          id("kv", prefix),
+				Name:        prefix,
+				Type:        KVType,
+				ConfigClass: "KV",
+				Config:      kv,
+				Tags:        map[string]string{"datacenter": datacenter},
+			})
+		}
+	}
+	return results
+}
+
+// worstStatus returns the most severe status of the checks, nodes & services without checks are passing
+func worstStatus(checks []Check) string {
+	status := "passing"
+	for _, check := range checks {
+		if checkStatuses[check.Status] > checkStatuses[status] {
+			status = check.Status
+		}
+	}
+	return status
+}
@@ -0,0 +1,91 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/types"
+)
+
+func TestScrape(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/catalog/nodes":
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"ID": "6f0b", "Node": "node-1", "Address": "10.0.0.1", "Datacenter": "dc1"},
+				{"ID": "9a1c", "Node": "node-2", "Address": "10.0.0.2", "Datacenter": "dc1"},
+			})
+		case "/v1/health/state/any":
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"Node": "node-1", "CheckID": "serfHealth", "Name": "Serf Health Status", "Status": "passing", "Output": "Agent alive and reachable"},
+				{"Node": "node-2", "CheckID": "serfHealth", "Name": "Serf Health Status", "Status": "critical"},
+				{"Node": "node-1", "CheckID": "service:web-1", "Name": "web health", "Status": "warning", "ServiceID": "web-1", "ServiceName": "web"},
+			})
+		case "/v1/catalog/services":
+			_ = json.NewEncoder(w).Encode(map[string][]string{"web": {"http"}, "consul": {}})
+		case "/v1/catalog/service/web":
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"Node": "node-1", "ServiceID": "web-1", "ServiceName": "web", "ServicePort": 8080}})
+		case "/v1/catalog/service/consul":
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"Node": "node-1", "ServiceID": "consul", "ServiceName": "consul", "ServicePort": 8300}})
+		case "/v1/kv/config/web":
+			if r.URL.Query().Get("recurse") != "true" {
+				t.Errorf("expected the prefix to be read recursively")
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"Key": "config/web/", "Value": nil},
+				{"Key": "config/web/replicas", "Value": "Mw=="},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{
+		Spec: v1.ScraperSpec{Consul: []v1.Consul{{
+			URL:   server.URL,
+			Token: types.EnvVar{ValueStatic: "secret"},
+			KV:    []string{"config/web", "missing"},
+		}}},
+	})
+
+	items := map[string]v1.ScrapeResult{}
+	for _, r := range (ConsulScraper{}).Scrape(ctx) {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		items[r.Type+"/"+r.ID] = r
+	}
+	if len(items) != 8 {
+		t.Errorf("expected 2 nodes, 3 checks, 2 services & 1 kv prefix, got %d", len(items))
+	}
+
+	if node := items["Consul::Node/dc1/node/node-2"]; node.Status != "critical" || node.Aliases[0] != "9a1c" {
+		t.Errorf("unexpected node %+v", node)
+	}
+	check := items["Consul::Check/dc1/check/node-1/service:web-1"]
+	if check.Status != "warning" || check.ParentExternalID != "dc1/node/node-1" {
+		t.Errorf("unexpected check %+v", check)
+	}
+	web := items["Consul::Service/dc1/service/web"]
+	if web.Status != "warning" || len(web.RelationshipResults) != 2 {
+		t.Errorf("unexpected service %+v", web)
+	}
+	if consul := items["Consul::Service/dc1/service/consul"]; consul.Status != "passing" {
+		t.Errorf("unexpected service %+v", consul)
+	}
+	kv := items["Consul::KV/dc1/kv/config/web"].Config.(map[string]string)
+	if len(kv) != 1 || kv["config/web/replicas"] != "3" {
+		t.Errorf("unexpected kv %v", kv)
+	}
+}
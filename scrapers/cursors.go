@@ -0,0 +1,30 @@
+package scrapers
+
+import (
+	"net/http"
+
+	"github.com/flanksource/config-db/db"
+	"github.com/labstack/echo/v4"
+)
+
+// ListCursorsHandler returns the persisted incremental scrape cursors,
+// optionally filtered by the scraper_id query param.
+func ListCursorsHandler(c echo.Context) error {
+	cursors, err := db.GetScrapeCursors(c.Request().Context(), c.QueryParam("scraper_id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get scrape cursors", err)
+	}
+
+	return c.JSON(http.StatusOK, cursors)
+}
+
+// ResetCursorsHandler deletes the cursors of a scraper so that the next run
+// scrapes from the beginning. The type & scope query params limit the reset.
+func ResetCursorsHandler(c echo.Context) error {
+	deleted, err := db.DeleteScrapeCursors(c.Request().Context(), c.Param("id"), c.QueryParam("type"), c.QueryParam("scope"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to reset scrape cursors", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]int64{"deleted": deleted})
+}
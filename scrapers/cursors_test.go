@@ -0,0 +1,96 @@
+package scrapers
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/db"
+	"github.com/flanksource/config-db/db/models"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Scrape cursors", Ordered, func() {
+	config := v1.ScrapeConfig{ObjectMeta: metav1.ObjectMeta{Name: "cursors", Namespace: "default"}}
+	scraperID := "default/cursors"
+
+	BeforeAll(func() {
+		Expect(db.DefaultDB().AutoMigrate(&models.ScrapeCursor{})).To(Succeed())
+	})
+
+	It("should persist cursors per scraper type and scope", func() {
+		ctx := api.NewScrapeContext(gocontext.Background(), db.DefaultDB(), db.Pool).WithScrapeConfig(&config)
+
+		value, err := ctx.GetCursor("test", "eu-west-1")
+		Expect(err).To(BeNil())
+		Expect(value).To(BeEmpty())
+
+		Expect(ctx.SaveCursor("test", "eu-west-1", "1")).To(Succeed())
+		Expect(ctx.SaveCursor("test", "eu-west-1", "2")).To(Succeed())
+		Expect(ctx.SaveCursor("test", "us-east-1", "3")).To(Succeed())
+		Expect(ctx.SaveCursor("other", "eu-west-1", "4")).To(Succeed())
+
+		// a new context reads the cursors back from the database
+		ctx = api.NewScrapeContext(gocontext.Background(), db.DefaultDB(), db.Pool).WithScrapeConfig(&config)
+		value, err = ctx.GetCursor("test", "eu-west-1")
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("2"))
+
+		value, err = ctx.GetCursor("test", "us-east-1")
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("3"))
+	})
+
+	It("should list the cursors of a scraper", func() {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/cursors?scraper_id="+scraperID, nil), rec)
+
+		Expect(ListCursorsHandler(c)).To(Succeed())
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var cursors []models.ScrapeCursor
+		Expect(json.Unmarshal(rec.Body.Bytes(), &cursors)).To(Succeed())
+		Expect(cursors).To(HaveLen(3))
+		Expect(cursors[0].ScraperType).To(Equal("other"))
+		Expect(cursors[1].Scope).To(Equal("eu-west-1"))
+		Expect(cursors[1].Value).To(Equal("2"))
+	})
+
+	It("should reset the cursors of a scraper type", func() {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/cursors/"+scraperID+"?type=test", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(scraperID)
+
+		Expect(ResetCursorsHandler(c)).To(Succeed())
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"deleted": 2}`))
+
+		cursors, err := db.GetScrapeCursors(gocontext.Background(), scraperID)
+		Expect(err).To(BeNil())
+		Expect(cursors).To(HaveLen(1))
+		Expect(cursors[0].ScraperType).To(Equal("other"))
+	})
+
+	It("should keep the cursors in the context without a database", func() {
+		ctx := api.NewScrapeContext(gocontext.Background(), nil, nil).WithScrapeConfig(&config)
+		Expect(ctx.SaveCursor("test", "eu-west-1", "1")).To(Succeed())
+
+		value, err := ctx.WithValue("key", "value").GetCursor("test", "eu-west-1")
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("1"))
+
+		other := api.NewScrapeContext(gocontext.Background(), nil, nil).WithScrapeConfig(&config)
+		value, err = other.GetCursor("test", "eu-west-1")
+		Expect(err).To(BeNil())
+		Expect(value).To(BeEmpty())
+	})
+})
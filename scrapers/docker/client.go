@@ -0,0 +1,251 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// Info is the engine, Podman returns the same fields from its Docker compatible API
+type Info struct {
+	ID              string `json:"ID"`
+	Name            string `json:"Name"`
+	ServerVersion   string `json:"ServerVersion"`
+	OperatingSystem string `json:"OperatingSystem"`
+	OSType          string `json:"OSType"`
+	Architecture    string `json:"Architecture"`
+	KernelVersion   string `json:"KernelVersion"`
+	Driver          string `json:"Driver"`
+	CgroupDriver    string `json:"CgroupDriver"`
+	CgroupVersion   string `json:"CgroupVersion,omitempty"`
+	DockerRootDir   string `json:"DockerRootDir"`
+	NCPU            int    `json:"NCPU"`
+	MemTotal        int64  `json:"MemTotal"`
+}
+
+// ContainerSummary is a container of /containers/json
+type ContainerSummary struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// ContainerState is the state of a container, it's not part of the config as it changes on every restart
+type ContainerState struct {
+	Status     string `json:"Status"`
+	Running    bool   `json:"Running"`
+	Paused     bool   `json:"Paused"`
+	Restarting bool   `json:"Restarting"`
+	OOMKilled  bool   `json:"OOMKilled"`
+	Dead       bool   `json:"Dead"`
+	ExitCode   int    `json:"ExitCode"`
+	Error      string `json:"Error"`
+	StartedAt  string `json:"StartedAt"`
+	Health     *struct {
+		Status        string `json:"Status"`
+		FailingStreak int    `json:"FailingStreak"`
+	} `json:"Health,omitempty"`
+}
+
+// Container is the part of /containers/{id}/json used for the status & the relationships
+type Container struct {
+	ID      string         `json:"Id"`
+	Name    string         `json:"Name"`
+	Created time.Time      `json:"Created"`
+	Image   string         `json:"Image"`
+	State   ContainerState `json:"State"`
+	Config  struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	Mounts []struct {
+		Type string `json:"Type"`
+		Name string `json:"Name"`
+	} `json:"Mounts"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			NetworkID string `json:"NetworkID"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+type Image struct {
+	ID          string            `json:"Id"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Created     int64             `json:"Created"`
+	Size        int64             `json:"Size"`
+	Labels      map[string]string `json:"Labels"`
+}
+
+type Volume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	Scope      string            `json:"Scope"`
+	CreatedAt  string            `json:"CreatedAt,omitempty"`
+	Labels     map[string]string `json:"Labels"`
+	Options    map[string]string `json:"Options"`
+}
+
+// Network excludes the attached containers & their addresses, they're scraped as relationships
+type Network struct {
+	ID         string            `json:"Id"`
+	Name       string            `json:"Name"`
+	Created    string            `json:"Created"`
+	Driver     string            `json:"Driver"`
+	Scope      string            `json:"Scope"`
+	Internal   bool              `json:"Internal"`
+	Attachable bool              `json:"Attachable"`
+	EnableIPv6 bool              `json:"EnableIPv6"`
+	IPAM       any               `json:"IPAM"`
+	Labels     map[string]string `json:"Labels"`
+	Options    map[string]string `json:"Options"`
+}
+
+// Event is an event of /events
+type Event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	TimeNano int64 `json:"timeNano"`
+}
+
+type DockerClient struct {
+	*resty.Client
+	api.ScrapeContext
+	URL string
+}
+
+// NewDockerClient connects to unix sockets or to tcp addresses over http
+func NewDockerClient(ctx api.ScrapeContext, config v1.Docker) (*DockerClient, error) {
+	host, err := url.Parse(config.GetHost())
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %s: %w", config.GetHost(), err)
+	}
+
+	client := resty.New()
+	var baseURL string
+	switch host.Scheme {
+	case "unix":
+		socket := host.Path
+		client.SetTransport(&http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		})
+		// the host is ignored when dialing the socket
+		baseURL = "http://docker"
+	case "tcp", "http":
+		baseURL = "http://" + host.Host
+	case "https":
+		baseURL = "https://" + host.Host
+	default:
+		return nil, fmt.Errorf("unsupported host %s, expected a unix:// or tcp:// address", config.GetHost())
+	}
+
+	return &DockerClient{
+		ScrapeContext: ctx,
+		Client:        client,
+		URL:           baseURL,
+	}, nil
+}
+
+func (c *DockerClient) get(path string, query map[string]string, result any) error {
+	resp, err := c.R().SetQueryParams(query).Get(c.URL + path)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("received %s from %s: %s", resp.Status(), path, strings.TrimSpace(string(resp.Body())))
+	}
+	return json.Unmarshal(resp.Body(), result)
+}
+
+func (c *DockerClient) GetInfo() (*Info, error) {
+	var info Info
+	return &info, c.get("/info", nil, &info)
+}
+
+// GetContainers returns the running & stopped containers
+func (c *DockerClient) GetContainers() ([]ContainerSummary, error) {
+	var containers []ContainerSummary
+	return containers, c.get("/containers/json", map[string]string{"all": "true"}, &containers)
+}
+
+// InspectContainer returns the container decoded & as returned by the engine
+func (c *DockerClient) InspectContainer(id string) (*Container, map[string]any, error) {
+	var raw json.RawMessage
+	if err := c.get("/containers/"+id+"/json", nil, &raw); err != nil {
+		return nil, nil, err
+	}
+	var container Container
+	if err := json.Unmarshal(raw, &container); err != nil {
+		return nil, nil, err
+	}
+	var config map[string]any
+	return &container, config, json.Unmarshal(raw, &config)
+}
+
+func (c *DockerClient) GetImages() ([]Image, error) {
+	var images []Image
+	return images, c.get("/images/json", nil, &images)
+}
+
+func (c *DockerClient) GetVolumes() ([]Volume, error) {
+	var volumes struct {
+		Volumes []Volume `json:"Volumes"`
+	}
+	return volumes.Volumes, c.get("/volumes", nil, &volumes)
+}
+
+func (c *DockerClient) GetNetworks() ([]Network, error) {
+	var networks []Network
+	return networks, c.get("/networks", nil, &networks)
+}
+
+// GetEvents returns the container events between since & until.
+// The stream is closed by the engine once until is reached.
+func (c *DockerClient) GetEvents(since, until time.Time) ([]Event, error) {
+	resp, err := c.R().SetDoNotParseResponse(true).SetQueryParams(map[string]string{
+		"since":   strconv.FormatInt(since.Unix(), 10),
+		"until":   strconv.FormatInt(until.Unix(), 10),
+		"filters": `{"type":["container"]}`,
+	}).Get(c.URL + "/events")
+	if err != nil {
+		return nil, err
+	}
+	body := resp.RawBody()
+	defer body.Close()
+	if resp.IsError() {
+		message, _ := io.ReadAll(body)
+		return nil, fmt.Errorf("received %s from /events: %s", resp.Status(), strings.TrimSpace(string(message)))
+	}
+
+	var events []Event
+	decoder := json.NewDecoder(body)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
@@ -0,0 +1,402 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/scrapers/host"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/is-healthy/pkg/health"
+	"github.com/samber/lo"
+)
+
+const (
+	EngineType         = "Docker::Engine"
+	ContainerType      = "Docker::Container"
+	ImageType          = "Docker::Image"
+	VolumeType         = "Docker::Volume"
+	NetworkType        = "Docker::Network"
+	ComposeProjectType = "Docker::ComposeProject"
+
+	eventCursorType = "docker/events"
+
+	composeProjectLabel     = "com.docker.compose.project"
+	composeServiceLabel     = "com.docker.compose.service"
+	composeWorkingDirLabel  = "com.docker.compose.project.working_dir"
+	composeConfigFilesLabel = "com.docker.compose.project.config_files"
+)
+
+// volatileContainerFields change on every restart, the state is reported as the status & the events as changes
+var volatileContainerFields = []string{
+	"State", "NetworkSettings", "RestartCount", "ResolvConfPath", "HostnamePath", "HostsPath", "LogPath",
+	"GraphDriver", "SizeRw", "SizeRootFs",
+}
+
+// containerEvents are the container actions recorded as changes, the other actions (e.g. exec_create, attach) are ignored
+var containerEvents = map[string]struct{ changeType, verb string }{
+	"create":  {"ContainerCreated", "created"},
+	"start":   {"ContainerStarted", "started"},
+	"restart": {"ContainerRestarted", "restarted"},
+	"stop":    {"ContainerStopped", "stopped"},
+	"kill":    {"ContainerKilled", "killed"},
+	"die":     {"ContainerDied", "exited"},
+	"oom":     {"ContainerOOMKilled", "was OOM killed"},
+	"pause":   {"ContainerPaused", "paused"},
+	"unpause": {"ContainerUnpaused", "unpaused"},
+	"destroy": {"ContainerDestroyed", "destroyed"},
+	// podman
+	"remove": {"ContainerDestroyed", "destroyed"},
+}
+
+type DockerScraper struct {
+}
+
+func (d DockerScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Docker) > 0
+}
+
+// Scrape scrapes the containers, images, volumes, networks & compose projects of Docker or Podman engines.
+// The events of the containers since the previous run are scraped as changes.
+func (d DockerScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Docker {
+		client, err := NewDockerClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create docker client")
+			continue
+		}
+		results = append(results, scrapeEngine(client, config)...)
+	}
+	return results
+}
+
+type engine struct {
+	config v1.Docker
+	id     string
+	name   string
+}
+
+func (e engine) externalID(kind, name string) string {
+	return e.id + "/" + kind + "/" + name
+}
+
+func (e engine) result(configType, id, name string, config any) v1.ScrapeResult {
+	return v1.ScrapeResult{
+		BaseScraper:      e.config.BaseScraper,
+		ID:               id,
+		Name:             name,
+		Type:             configType,
+		ConfigClass:      strings.TrimPrefix(configType, "Docker::"),
+		Config:           config,
+		ParentExternalID: e.id,
+		ParentType:       EngineType,
+		Tags:             map[string]string{"engine": e.name},
+	}
+}
+
+func relationship(configType, id, relatedType, relatedID, name string) v1.RelationshipResult {
+	return v1.RelationshipResult{
+		ConfigExternalID:  v1.ExternalID{ExternalID: []string{id}, ConfigType: configType},
+		RelatedExternalID: v1.ExternalID{ExternalID: []string{relatedID}, ConfigType: relatedType},
+		Relationship:      name,
+	}
+}
+
+func scrapeEngine(client *DockerClient, config v1.Docker) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	info, err := client.GetInfo()
+	if err != nil {
+		results.Errorf(err, "failed to get info of %s", config.GetHost())
+		return results
+	}
+	// podman doesn't return an id
+	e := engine{config: config, id: lo.Ternary(info.ID != "", info.ID, info.Name), name: info.Name}
+
+	engineResult := v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ID:          e.id,
+		Name:        info.Name,
+		Type:        EngineType,
+		ConfigClass: "Engine",
+		Config:      info,
+		Tags:        map[string]string{"engine": e.name},
+	}
+	// the engine runs on the server scraped by the host scraper
+	engineResult.RelationshipResults = append(engineResult.RelationshipResults,
+		relationship(host.ServerType, info.Name, EngineType, e.id, "ServerEngine"))
+	results = append(results, engineResult)
+
+	images, err := client.GetImages()
+	if err != nil {
+		results.Errorf(err, "failed to list images")
+	}
+	for _, image := range images {
+		name := image.ID
+		if len(image.RepoTags) > 0 && image.RepoTags[0] != "<none>:<none>" {
+			name = image.RepoTags[0]
+		}
+		result := e.result(ImageType, e.externalID("image", image.ID), name, image)
+		result.CreatedAt = lo.ToPtr(time.Unix(image.Created, 0))
+		results = append(results, result)
+	}
+
+	projects := map[string]*ComposeProject{}
+	project := func(labels map[string]string) *ComposeProject {
+		name := labels[composeProjectLabel]
+		if name == "" {
+			return nil
+		}
+		if _, ok := projects[name]; !ok {
+			projects[name] = &ComposeProject{Name: name, status: health.HealthStatusHealthy}
+		}
+		p := projects[name]
+		if dir := labels[composeWorkingDirLabel]; dir != "" {
+			p.WorkingDir = dir
+		}
+		if files := labels[composeConfigFilesLabel]; files != "" {
+			p.ConfigFiles = strings.Split(files, ",")
+		}
+		return p
+	}
+
+	volumes, err := client.GetVolumes()
+	if err != nil {
+		results.Errorf(err, "failed to list volumes")
+	}
+	for _, volume := range volumes {
+		result := e.result(VolumeType, e.externalID("volume", volume.Name), volume.Name, volume)
+		result.CreatedAt = parseTime(volume.CreatedAt)
+		if p := project(volume.Labels); p != nil {
+			result.RelationshipResults = append(result.RelationshipResults,
+				relationship(ComposeProjectType, e.externalID("compose", p.Name), VolumeType, result.ID, "ComposeProjectVolume"))
+		}
+		results = append(results, result)
+	}
+
+	networks, err := client.GetNetworks()
+	if err != nil {
+		results.Errorf(err, "failed to list networks")
+	}
+	for _, network := range networks {
+		result := e.result(NetworkType, e.externalID("network", network.Name), network.Name, network)
+		result.CreatedAt = parseTime(network.Created)
+		if p := project(network.Labels); p != nil {
+			result.RelationshipResults = append(result.RelationshipResults,
+				relationship(ComposeProjectType, e.externalID("compose", p.Name), NetworkType, result.ID, "ComposeProjectNetwork"))
+		}
+		results = append(results, result)
+	}
+
+	containers, err := client.GetContainers()
+	if err != nil {
+		results.Errorf(err, "failed to list containers")
+	}
+	for _, summary := range containers {
+		container, config, err := client.InspectContainer(summary.ID)
+		if err != nil {
+			results.Errorf(err, "failed to inspect container %s", summary.ID)
+			continue
+		}
+		for _, field := range volatileContainerFields {
+			delete(config, field)
+		}
+
+		name := strings.TrimPrefix(container.Name, "/")
+		status := containerHealth(container.State)
+		result := e.result(ContainerType, e.externalID("container", container.ID), name, config)
+		result.Status = string(status)
+		result.Description = containerDescription(container.State)
+		result.CreatedAt = &container.Created
+		result.Aliases = []string{container.ID}
+
+		result.RelationshipResults = append(result.RelationshipResults,
+			relationship(ImageType, e.externalID("image", container.Image), ContainerType, result.ID, "ImageContainer"))
+		for _, mount := range container.Mounts {
+			if mount.Type == "volume" && mount.Name != "" {
+				result.RelationshipResults = append(result.RelationshipResults,
+					relationship(VolumeType, e.externalID("volume", mount.Name), ContainerType, result.ID, "VolumeContainer"))
+			}
+		}
+		for network := range container.NetworkSettings.Networks {
+			result.RelationshipResults = append(result.RelationshipResults,
+				relationship(NetworkType, e.externalID("network", network), ContainerType, result.ID, "NetworkContainer"))
+		}
+
+		if p := project(container.Config.Labels); p != nil {
+			result.ParentExternalID = e.externalID("compose", p.Name)
+			result.ParentType = ComposeProjectType
+			result.Tags["compose-project"] = p.Name
+			if service := container.Config.Labels[composeServiceLabel]; service != "" {
+				result.Tags["compose-service"] = service
+				p.Services = lo.Uniq(append(p.Services, service))
+			}
+			// the project is as healthy as its least healthy container
+			if status != health.HealthStatusHealthy && (p.status == health.HealthStatusHealthy || health.IsWorse(p.status, status)) {
+				p.status = status
+			}
+		}
+		results = append(results, result)
+	}
+
+	names := lo.Keys(projects)
+	sort.Strings(names)
+	for _, name := range names {
+		p := projects[name]
+		sort.Strings(p.Services)
+		result := e.result(ComposeProjectType, e.externalID("compose", name), name, p)
+		result.Status = string(p.status)
+		results = append(results, result)
+	}
+
+	results = append(results, scrapeEvents(client, e)...)
+	return results
+}
+
+// ComposeProject is built from the labels compose sets on the containers, volumes & networks
+type ComposeProject struct {
+	Name        string   `json:"name"`
+	WorkingDir  string   `json:"workingDir,omitempty"`
+	ConfigFiles []string `json:"configFiles,omitempty"`
+	Services    []string `json:"services,omitempty"`
+
+	status health.HealthStatusCode
+}
+
+// containerHealth maps the state of a container to a health status, similar to
+// the health of pods returned by health.GetResourceHealth
+func containerHealth(state ContainerState) health.HealthStatusCode {
+	switch {
+	case state.Restarting, state.Dead, state.OOMKilled:
+		return health.HealthStatusDegraded
+	case state.Paused:
+		return health.HealthStatusSuspended
+	case state.Running:
+		if state.Health != nil {
+			switch state.Health.Status {
+			case "unhealthy":
+				return health.HealthStatusUnhealthy
+			case "starting":
+				return health.HealthStatusProgressing
+			}
+		}
+		return health.HealthStatusHealthy
+	}
+
+	switch state.Status {
+	case "created":
+		return health.HealthStatusPending
+	case "removing":
+		return health.HealthStatusDeleting
+	case "exited", "stopped":
+		if state.ExitCode != 0 {
+			return health.HealthStatusDegraded
+		}
+		return health.HealthStatusStopped
+	}
+	return health.HealthStatusUnknown
+}
+
+func containerDescription(state ContainerState) string {
+	switch {
+	case state.OOMKilled:
+		return "OOMKilled"
+	case state.Error != "":
+		return state.Error
+	case state.Status == "exited":
+		return fmt.Sprintf("exited with code %d", state.ExitCode)
+	case state.Health != nil && state.Health.Status != "":
+		return fmt.Sprintf("%s (%s)", state.Status, state.Health.Status)
+	}
+	return state.Status
+}
+
+// scrapeEvents returns the container events since the previous run as changes.
+// The events of the last hour are scraped on the first run.
+func scrapeEvents(client *DockerClient, e engine) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	until := time.Now()
+	since := until.Add(-time.Hour)
+	if cursor, err := client.GetCursor(eventCursorType, e.id); err != nil {
+		results.Errorf(err, "failed to get the events cursor of %s", e.name)
+		return results
+	} else if seconds, err := strconv.ParseInt(cursor, 10, 64); err == nil {
+		since = time.Unix(seconds, 0)
+	}
+
+	events, err := client.GetEvents(since, until)
+	if err != nil {
+		results.Errorf(err, "failed to get the events of %s", e.name)
+		return results
+	}
+
+	var changes []v1.ChangeResult
+	for _, event := range events {
+		action, status, _ := strings.Cut(event.Action, ": ")
+		name := event.Actor.Attributes["name"]
+		var changeType, summary string
+		if action == "health_status" {
+			// podman sets the status as an attribute
+			status = lo.Ternary(status != "", status, event.Actor.Attributes["health_status"])
+			changeType = lo.Ternary(status == "unhealthy", "ContainerUnhealthy", "ContainerHealthy")
+			summary = fmt.Sprintf("%s is %s", name, status)
+		} else if known, ok := containerEvents[action]; ok {
+			changeType, summary = known.changeType, fmt.Sprintf("%s %s", name, known.verb)
+		} else {
+			continue
+		}
+
+		createdAt := time.Unix(0, event.TimeNano)
+		change := v1.ChangeResult{
+			ExternalID:       e.externalID("container", event.Actor.ID),
+			ConfigType:       ContainerType,
+			ExternalChangeID: fmt.Sprintf("%s/%s/%d", event.Actor.ID, event.Action, event.TimeNano),
+			ChangeType:       changeType,
+			Summary:          summary,
+			Source:           "Docker",
+			CreatedAt:        &createdAt,
+			Details:          lo.MapValues(event.Actor.Attributes, func(v string, _ string) any { return v }),
+		}
+		switch action {
+		case "die":
+			if code := event.Actor.Attributes["exitCode"]; code != "" && code != "0" {
+				change.Summary = fmt.Sprintf("%s exited with code %s", name, code)
+				change.Severity = string(models.SeverityHigh)
+			}
+		case "oom":
+			change.Severity = string(models.SeverityHigh)
+		case "health_status":
+			if status == "unhealthy" {
+				change.Severity = string(models.SeverityMedium)
+			}
+		case "destroy", "remove":
+			change.Action = v1.Delete
+		}
+		changes = append(changes, change)
+	}
+
+	if len(changes) > 0 {
+		results = append(results, v1.ScrapeResult{BaseScraper: e.config.BaseScraper, Changes: changes})
+	}
+	// the cursor is only advanced once the changes have been saved
+	results.OnSave(func() error {
+		if err := client.SaveCursor(eventCursorType, e.id, strconv.FormatInt(until.Unix(), 10)); err != nil {
+			return fmt.Errorf("failed to save the events cursor of %s: %w", e.name, err)
+		}
+		return nil
+	})
+	return results
+}
+
+// parseTime parses the RFC3339 times of volumes & networks
+func parseTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,152 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/is-healthy/pkg/health"
+)
+
+func TestScrape(t *testing.T) {
+	composeLabels := map[string]string{
+		composeProjectLabel:    "shop",
+		composeServiceLabel:    "web",
+		composeWorkingDirLabel: "/srv/shop",
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/info":
+			_ = json.NewEncoder(w).Encode(map[string]any{"ID": "engine-1", "Name": "docker-01", "ServerVersion": "24.0.7"})
+		case "/images/json":
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"Id": "sha256:abc", "RepoTags": []string{"nginx:1.25"}, "Created": 1700000000}})
+		case "/volumes":
+			_ = json.NewEncoder(w).Encode(map[string]any{"Volumes": []map[string]any{{"Name": "shop_data", "Driver": "local", "Labels": composeLabels}}})
+		case "/networks":
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"Id": "n1", "Name": "shop_default", "Driver": "bridge", "Labels": composeLabels}})
+		case "/containers/json":
+			if r.URL.Query().Get("all") != "true" {
+				t.Errorf("expected stopped containers to be listed")
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"Id": "c1"}, {"Id": "c2"}})
+		case "/containers/c1/json":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"Id": "c1", "Name": "/shop-web-1", "Image": "sha256:abc", "Created": "2024-01-02T03:04:05Z",
+				"State":           map[string]any{"Status": "running", "Running": true, "Pid": 1234, "Health": map[string]any{"Status": "unhealthy"}},
+				"Config":          map[string]any{"Image": "nginx:1.25", "Labels": composeLabels},
+				"Mounts":          []map[string]any{{"Type": "volume", "Name": "shop_data"}, {"Type": "bind", "Source": "/etc/nginx"}},
+				"NetworkSettings": map[string]any{"Networks": map[string]any{"shop_default": map[string]any{"NetworkID": "n1", "IPAddress": "172.18.0.2"}}},
+			})
+		case "/containers/c2/json":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"Id": "c2", "Name": "/backup", "Image": "sha256:abc", "Created": "2024-01-02T03:04:05Z",
+				"State":  map[string]any{"Status": "exited", "ExitCode": 0},
+				"Config": map[string]any{"Image": "nginx:1.25"},
+			})
+		case "/events":
+			if r.URL.Query().Get("until") == "" {
+				t.Errorf("expected the events to be requested until now")
+			}
+			for _, event := range []map[string]any{
+				{"Type": "container", "Action": "die", "Actor": map[string]any{"ID": "c1", "Attributes": map[string]string{"name": "shop-web-1", "exitCode": "137"}}, "timeNano": 1700000000000000000},
+				{"Type": "container", "Action": "exec_create: sh", "Actor": map[string]any{"ID": "c1"}, "timeNano": 1700000001000000000},
+				{"Type": "container", "Action": "health_status: unhealthy", "Actor": map[string]any{"ID": "c1", "Attributes": map[string]string{"name": "shop-web-1"}}, "timeNano": 1700000002000000000},
+			} {
+				_ = json.NewEncoder(w).Encode(event)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{
+		Spec: v1.ScraperSpec{Docker: []v1.Docker{{Host: "unix://" + socket}}},
+	})
+	results := DockerScraper{}.Scrape(ctx)
+
+	byID := map[string]v1.ScrapeResult{}
+	var changes []v1.ChangeResult
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		changes = append(changes, r.Changes...)
+		if r.ID != "" {
+			byID[r.ID] = r
+		}
+	}
+
+	web, ok := byID["engine-1/container/c1"]
+	if !ok {
+		t.Fatalf("expected the web container, got %v", results)
+	}
+	if web.Status != string(health.HealthStatusUnhealthy) {
+		t.Errorf("expected the web container to be unhealthy, got %s", web.Status)
+	}
+	if _, ok := web.Config.(map[string]any)["State"]; ok {
+		t.Errorf("expected the state to be removed from the config")
+	}
+	if web.ParentExternalID != "engine-1/compose/shop" || web.Tags["compose-service"] != "web" {
+		t.Errorf("expected the web container to be in the shop project, got %s %v", web.ParentExternalID, web.Tags)
+	}
+	related := map[string]bool{}
+	for _, rel := range web.RelationshipResults {
+		related[rel.ConfigExternalID.ExternalID[0]] = true
+	}
+	for _, id := range []string{"engine-1/image/sha256:abc", "engine-1/volume/shop_data", "engine-1/network/shop_default"} {
+		if !related[id] {
+			t.Errorf("expected the web container to be related to %s, got %v", id, related)
+		}
+	}
+
+	if backup := byID["engine-1/container/c2"]; backup.Status != string(health.HealthStatusStopped) || backup.ParentExternalID != "engine-1" {
+		t.Errorf("expected the backup container to be stopped on the engine, got %s %s", backup.Status, backup.ParentExternalID)
+	}
+
+	project, ok := byID["engine-1/compose/shop"]
+	if !ok {
+		t.Fatalf("expected the shop compose project")
+	}
+	if p := project.Config.(*ComposeProject); project.Status != string(health.HealthStatusUnhealthy) || p.WorkingDir != "/srv/shop" || len(p.Services) != 1 {
+		t.Errorf("unexpected compose project %s %v", project.Status, p)
+	}
+	if image := byID["engine-1/image/sha256:abc"]; image.Name != "nginx:1.25" {
+		t.Errorf("expected the image to be named by its tag, got %s", image.Name)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+	if changes[0].ChangeType != "ContainerDied" || changes[0].Summary != "shop-web-1 exited with code 137" || changes[0].ExternalID != "engine-1/container/c1" {
+		t.Errorf("unexpected change %v", changes[0])
+	}
+	if changes[1].ChangeType != "ContainerUnhealthy" {
+		t.Errorf("unexpected change %v", changes[1])
+	}
+
+	// the events are read again unless the changes were saved
+	if cursor, _ := ctx.GetCursor(eventCursorType, "engine-1"); cursor != "" {
+		t.Fatalf("expected no cursor before the results are saved, got %s", cursor)
+	}
+	if err := results.Saved(); err != nil {
+		t.Fatal(err)
+	}
+	if cursor, _ := ctx.GetCursor(eventCursorType, "engine-1"); cursor == "" {
+		t.Errorf("expected the events cursor once the results are saved")
+	}
+}
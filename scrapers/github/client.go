@@ -66,6 +66,90 @@ type Run struct {
 	HeadRepository      any       `json:"head_repository"`
 }
 
+// Job is a job of a workflow run with its steps.
+// see https://docs.github.com/en/rest/actions/workflow-jobs?apiVersion=2022-11-28#list-jobs-for-a-workflow-run
+type Job struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion,omitempty"`
+	HtmlURL     string     `json:"html_url"`
+	RunAttempt  int        `json:"run_attempt"`
+	Labels      []string   `json:"labels,omitempty"`
+	RunnerName  string     `json:"runner_name,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Duration in milliseconds
+	Duration int    `json:"duration,omitempty"`
+	Steps    []Step `json:"steps,omitempty"`
+}
+
+// Step is a step of a workflow job
+type Step struct {
+	Number      int        `json:"number"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Duration in milliseconds
+	Duration int `json:"duration,omitempty"`
+}
+
+type Jobs struct {
+	Count int   `json:"total_count"`
+	Value []Job `json:"jobs"`
+}
+
+// Environment is a deployment environment of a repository.
+// see https://docs.github.com/en/rest/deployments/environments?apiVersion=2022-11-28#list-environments
+type Environment struct {
+	ID                     int64     `json:"id"`
+	NodeID                 string    `json:"node_id"`
+	Name                   string    `json:"name"`
+	HtmlURL                string    `json:"html_url"`
+	ProtectionRules        any       `json:"protection_rules,omitempty"`
+	DeploymentBranchPolicy any       `json:"deployment_branch_policy,omitempty"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+type Environments struct {
+	Count int           `json:"total_count"`
+	Value []Environment `json:"environments"`
+}
+
+// Deployment is a deployment of a repository to an environment.
+// see https://docs.github.com/en/rest/deployments/deployments?apiVersion=2022-11-28#list-deployments
+type Deployment struct {
+	ID                    int64     `json:"id"`
+	NodeID                string    `json:"node_id"`
+	SHA                   string    `json:"sha"`
+	Ref                   string    `json:"ref"`
+	Task                  string    `json:"task"`
+	Environment           string    `json:"environment"`
+	Description           string    `json:"description,omitempty"`
+	Creator               any       `json:"creator,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+	TransientEnvironment  bool      `json:"transient_environment"`
+	ProductionEnvironment bool      `json:"production_environment"`
+
+	Statuses []DeploymentStatus `json:"statuses,omitempty"`
+}
+
+// DeploymentStatus see https://docs.github.com/en/rest/deployments/statuses?apiVersion=2022-11-28#list-deployment-statuses
+type DeploymentStatus struct {
+	ID             int64     `json:"id"`
+	State          string    `json:"state"`
+	Description    string    `json:"description,omitempty"`
+	Environment    string    `json:"environment"`
+	TargetURL      string    `json:"target_url,omitempty"`
+	LogURL         string    `json:"log_url,omitempty"`
+	EnvironmentURL string    `json:"environment_url,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type Runs struct {
 	Count int64 `json:"total_count"`
 	Value []Run `json:"workflow_runs"`
@@ -112,12 +196,25 @@ func (gh *GitHubActionsClient) GetWorkflows() ([]Workflow, error) {
 	return response.Value, nil
 }
 
+// runsPerPage is the maximum number of workflow runs github returns per page
+const runsPerPage = 100
+
 func (gh *GitHubActionsClient) GetWorkflowRuns(id, page int) (Runs, error) {
+	return gh.GetWorkflowRunsCreatedSince(id, page, time.Time{})
+}
+
+// GetWorkflowRunsCreatedSince returns the runs of a workflow created at or after the given time.
+// All the runs are returned when the time is zero.
+func (gh *GitHubActionsClient) GetWorkflowRunsCreatedSince(id, page int, since time.Time) (Runs, error) {
 	var response Runs
-	resp, err := gh.R().SetResult(&response).
+	req := gh.R().SetResult(&response).
 		SetQueryParam("page", fmt.Sprint(page)).
-		SetQueryParam("per_page", "100").
-		Get(fmt.Sprintf("/actions/workflows/%d/runs", id))
+		SetQueryParam("per_page", fmt.Sprint(runsPerPage))
+	if !since.IsZero() {
+		req.SetQueryParam("created", ">="+since.UTC().Format(time.RFC3339))
+	}
+
+	resp, err := req.Get(fmt.Sprintf("/actions/workflows/%d/runs", id))
 	if err != nil {
 		return response, err
 	}
@@ -128,6 +225,58 @@ func (gh *GitHubActionsClient) GetWorkflowRuns(id, page int) (Runs, error) {
 	return response, nil
 }
 
+// GetWorkflowRunJobs returns the jobs of the latest attempt of a workflow run
+func (gh *GitHubActionsClient) GetWorkflowRunJobs(runID int) ([]Job, error) {
+	var jobs []Job
+	for page := 1; ; page++ {
+		var response Jobs
+		_, err := get(gh.Client, fmt.Sprintf("/actions/runs/%d/jobs", runID), map[string]string{
+			"page":     fmt.Sprint(page),
+			"per_page": fmt.Sprint(runsPerPage),
+		}, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, response.Value...)
+		if len(response.Value) < runsPerPage {
+			break
+		}
+	}
+
+	for i := range jobs {
+		if jobs[i].CompletedAt != nil {
+			jobs[i].Duration = int(jobs[i].CompletedAt.Sub(jobs[i].StartedAt).Milliseconds())
+		}
+		for j, step := range jobs[i].Steps {
+			if step.StartedAt != nil && step.CompletedAt != nil {
+				jobs[i].Steps[j].Duration = int(step.CompletedAt.Sub(*step.StartedAt).Milliseconds())
+			}
+		}
+	}
+	return jobs, nil
+}
+
+func (gh *GitHubActionsClient) GetEnvironments() ([]Environment, error) {
+	var response Environments
+	_, err := get(gh.Client, "/environments", map[string]string{"per_page": fmt.Sprint(runsPerPage)}, &response)
+	return response.Value, err
+}
+
+// GetDeployments returns the latest deployments of the repository
+func (gh *GitHubActionsClient) GetDeployments() ([]Deployment, error) {
+	var deployments []Deployment
+	_, err := get(gh.Client, "/deployments", map[string]string{"per_page": fmt.Sprint(runsPerPage)}, &deployments)
+	return deployments, err
+}
+
+// GetDeploymentStatuses returns the statuses of a deployment, newest first
+func (gh *GitHubActionsClient) GetDeploymentStatuses(id int64) ([]DeploymentStatus, error) {
+	var statuses []DeploymentStatus
+	_, err := get(gh.Client, fmt.Sprintf("/deployments/%d/statuses", id), nil, &statuses)
+	return statuses, err
+}
+
 func (gh *GitHubActionsClient) GetAllWorkflowRuns() ([]Run, error) {
 	var response Runs
 	_, err := gh.R().SetResult(&response).Get("/actions/runs")
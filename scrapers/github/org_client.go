@@ -0,0 +1,395 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// User is the subset of a github user returned by the API
+type User struct {
+	Login   string `json:"login"`
+	ID      int64  `json:"id"`
+	Type    string `json:"type,omitempty"`
+	HtmlURL string `json:"html_url,omitempty"`
+}
+
+// Organization is a github organization.
+// see https://docs.github.com/en/rest/orgs/orgs?apiVersion=2022-11-28#get-an-organization
+type Organization struct {
+	Login                        string    `json:"login"`
+	ID                           int64     `json:"id"`
+	NodeID                       string    `json:"node_id"`
+	Name                         string    `json:"name,omitempty"`
+	Description                  string    `json:"description,omitempty"`
+	HtmlURL                      string    `json:"html_url"`
+	PublicRepos                  int       `json:"public_repos"`
+	TotalPrivateRepos            int       `json:"total_private_repos,omitempty"`
+	Plan                         any       `json:"plan,omitempty"`
+	DefaultRepositoryPermission  string    `json:"default_repository_permission,omitempty"`
+	MembersCanCreateRepositories bool      `json:"members_can_create_repositories,omitempty"`
+	TwoFactorRequirementEnabled  bool      `json:"two_factor_requirement_enabled,omitempty"`
+	CreatedAt                    time.Time `json:"created_at"`
+	UpdatedAt                    time.Time `json:"updated_at"`
+}
+
+// Repository is a github repository with its settings.
+// see https://docs.github.com/en/rest/repos/repos?apiVersion=2022-11-28#list-organization-repositories
+type Repository struct {
+	ID                  int64          `json:"id"`
+	NodeID              string         `json:"node_id"`
+	Name                string         `json:"name"`
+	FullName            string         `json:"full_name"`
+	Description         string         `json:"description,omitempty"`
+	Private             bool           `json:"private"`
+	Visibility          string         `json:"visibility"`
+	Fork                bool           `json:"fork"`
+	Archived            bool           `json:"archived"`
+	Disabled            bool           `json:"disabled"`
+	IsTemplate          bool           `json:"is_template"`
+	DefaultBranch       string         `json:"default_branch"`
+	HtmlURL             string         `json:"html_url"`
+	CloneURL            string         `json:"clone_url"`
+	Homepage            string         `json:"homepage,omitempty"`
+	Language            string         `json:"language,omitempty"`
+	Topics              []string       `json:"topics,omitempty"`
+	License             any            `json:"license,omitempty"`
+	HasIssues           bool           `json:"has_issues"`
+	HasProjects         bool           `json:"has_projects"`
+	HasWiki             bool           `json:"has_wiki"`
+	HasDiscussions      bool           `json:"has_discussions"`
+	AllowForking        bool           `json:"allow_forking"`
+	AllowMergeCommit    *bool          `json:"allow_merge_commit,omitempty"`
+	AllowSquashMerge    *bool          `json:"allow_squash_merge,omitempty"`
+	AllowRebaseMerge    *bool          `json:"allow_rebase_merge,omitempty"`
+	AllowAutoMerge      *bool          `json:"allow_auto_merge,omitempty"`
+	DeleteBranchOnMerge *bool          `json:"delete_branch_on_merge,omitempty"`
+	SecurityAndAnalysis map[string]any `json:"security_and_analysis,omitempty"`
+	CreatedAt           time.Time      `json:"created_at"`
+	PushedAt            *time.Time     `json:"pushed_at,omitempty"`
+
+	DeployKeys []DeployKey `json:"deploy_keys,omitempty"`
+	Webhooks   []Webhook   `json:"webhooks,omitempty"`
+}
+
+// Branch is a branch of a repository
+type Branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+}
+
+// BranchProtection are the protection rules of a branch.
+// see https://docs.github.com/en/rest/branches/branch-protection?apiVersion=2022-11-28#get-branch-protection
+type BranchProtection struct {
+	URL                            string `json:"url,omitempty"`
+	RequiredStatusChecks           any    `json:"required_status_checks,omitempty"`
+	EnforceAdmins                  any    `json:"enforce_admins,omitempty"`
+	RequiredPullRequestReviews     any    `json:"required_pull_request_reviews,omitempty"`
+	Restrictions                   any    `json:"restrictions,omitempty"`
+	RequiredLinearHistory          any    `json:"required_linear_history,omitempty"`
+	AllowForcePushes               any    `json:"allow_force_pushes,omitempty"`
+	AllowDeletions                 any    `json:"allow_deletions,omitempty"`
+	BlockCreations                 any    `json:"block_creations,omitempty"`
+	RequiredConversationResolution any    `json:"required_conversation_resolution,omitempty"`
+	RequiredSignatures             any    `json:"required_signatures,omitempty"`
+	LockBranch                     any    `json:"lock_branch,omitempty"`
+}
+
+// Team is a team of an organization
+// see https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#list-teams
+type Team struct {
+	ID          int64  `json:"id"`
+	NodeID      string `json:"node_id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description,omitempty"`
+	Privacy     string `json:"privacy"`
+	Permission  string `json:"permission"`
+	HtmlURL     string `json:"html_url"`
+	Parent      *Team  `json:"parent,omitempty"`
+
+	// Repositories maps the full name of a repository to the role of the team on it
+	Repositories map[string]string `json:"repositories,omitempty"`
+}
+
+// TeamRepository is a repository a team has access to
+type TeamRepository struct {
+	FullName string `json:"full_name"`
+	RoleName string `json:"role_name"`
+}
+
+// DeployKey is a deploy key of a repository, the key itself is not kept.
+type DeployKey struct {
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Verified  bool       `json:"verified"`
+	ReadOnly  bool       `json:"read_only"`
+	AddedBy   string     `json:"added_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// Webhook is a webhook of a repository, only the non-sensitive part of its config is kept.
+type Webhook struct {
+	ID           int64          `json:"id"`
+	Name         string         `json:"name"`
+	Active       bool           `json:"active"`
+	Events       []string       `json:"events"`
+	Config       WebhookConfig  `json:"config"`
+	LastResponse map[string]any `json:"last_response,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type WebhookConfig struct {
+	URL         string `json:"url,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	InsecureSSL string `json:"insecure_ssl,omitempty"`
+}
+
+// Release is a published release of a repository
+// see https://docs.github.com/en/rest/releases/releases?apiVersion=2022-11-28#list-releases
+type Release struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TagName     string     `json:"tag_name"`
+	Target      string     `json:"target_commitish"`
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	Author      User       `json:"author"`
+	HtmlURL     string     `json:"html_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// AlertRepository is the repository an organization alert was raised on
+type AlertRepository struct {
+	ID       int64  `json:"id"`
+	FullName string `json:"full_name"`
+}
+
+// DependabotAlert see https://docs.github.com/en/rest/dependabot/alerts?apiVersion=2022-11-28#list-dependabot-alerts-for-an-organization
+type DependabotAlert struct {
+	Number     int    `json:"number"`
+	State      string `json:"state"`
+	HtmlURL    string `json:"html_url"`
+	Dependency struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		ManifestPath string `json:"manifest_path"`
+		Scope        string `json:"scope"`
+	} `json:"dependency"`
+	SecurityAdvisory struct {
+		GhsaID      string `json:"ghsa_id"`
+		CveID       string `json:"cve_id"`
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Severity    string `json:"severity"`
+	} `json:"security_advisory"`
+	SecurityVulnerability struct {
+		VulnerableVersionRange string `json:"vulnerable_version_range"`
+		FirstPatchedVersion    *struct {
+			Identifier string `json:"identifier"`
+		} `json:"first_patched_version"`
+	} `json:"security_vulnerability"`
+	Repository AlertRepository `json:"repository"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// CodeScanningAlert see https://docs.github.com/en/rest/code-scanning/code-scanning?apiVersion=2022-11-28#list-code-scanning-alerts-for-an-organization
+type CodeScanningAlert struct {
+	Number  int    `json:"number"`
+	State   string `json:"state"`
+	HtmlURL string `json:"html_url"`
+	Rule    struct {
+		ID                    string `json:"id"`
+		Name                  string `json:"name"`
+		Description           string `json:"description"`
+		Severity              string `json:"severity"`
+		SecuritySeverityLevel string `json:"security_severity_level"`
+	} `json:"rule"`
+	Tool struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"tool"`
+	MostRecentInstance struct {
+		Ref      string `json:"ref"`
+		Location struct {
+			Path      string `json:"path"`
+			StartLine int    `json:"start_line"`
+		} `json:"location"`
+		Message struct {
+			Text string `json:"text"`
+		} `json:"message"`
+	} `json:"most_recent_instance"`
+	Repository AlertRepository `json:"repository"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// SecretScanningAlert see https://docs.github.com/en/rest/secret-scanning/secret-scanning?apiVersion=2022-11-28#list-secret-scanning-alerts-for-an-organization
+// The leaked secret is deliberately not decoded.
+type SecretScanningAlert struct {
+	Number                 int             `json:"number"`
+	State                  string          `json:"state"`
+	HtmlURL                string          `json:"html_url"`
+	SecretType             string          `json:"secret_type"`
+	SecretTypeDisplayName  string          `json:"secret_type_display_name"`
+	Validity               string          `json:"validity,omitempty"`
+	PushProtectionBypassed bool            `json:"push_protection_bypassed"`
+	Repository             AlertRepository `json:"repository"`
+	CreatedAt              time.Time       `json:"created_at"`
+	UpdatedAt              *time.Time      `json:"updated_at,omitempty"`
+}
+
+// GitHubClient is a client of the github REST API for an organization
+type GitHubClient struct {
+	*resty.Client
+	api.ScrapeContext
+	organization string
+}
+
+func NewGitHubClient(ctx api.ScrapeContext, gh v1.GitHub) (*GitHubClient, error) {
+	var token string
+	if connection, err := ctx.HydrateConnection(gh.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		token = connection.Password
+	} else {
+		token, err = ctx.GetEnvValueFromCache(gh.PersonalAccessToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client := resty.New().
+		SetHeader("Accept", "application/vnd.github+json").
+		SetHeader("X-GitHub-Api-Version", "2022-11-28").
+		SetBaseURL(gh.GetURL())
+	if token != "" {
+		client.SetAuthToken(token)
+	}
+
+	return &GitHubClient{
+		ScrapeContext: ctx,
+		Client:        client,
+		organization:  gh.Organization,
+	}, nil
+}
+
+// errNotAvailable is returned when a feature isn't enabled for the organization or repository,
+// or the token isn't allowed to access it.
+var errNotAvailable = fmt.Errorf("not available")
+
+// get fetches a path of the github API into result
+func get(client *resty.Client, path string, params map[string]string, result any) (*resty.Response, error) {
+	resp, err := client.R().SetQueryParams(params).SetResult(result).Get(path)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode() {
+	case http.StatusNotFound, http.StatusForbidden:
+		return resp, fmt.Errorf("%w: %s", errNotAvailable, string(resp.Body()))
+	}
+	if resp.IsError() {
+		return resp, fmt.Errorf("received non 2xx status code from github: %s", string(resp.Body()))
+	}
+	return resp, nil
+}
+
+// pageSize is the maximum number of items github returns per page
+const pageSize = 100
+
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// getAll follows the "next" links of the responses, see
+// https://docs.github.com/en/rest/using-the-rest-api/using-pagination-in-the-rest-api
+func getAll[T any](gh *GitHubClient, path string, params map[string]string) ([]T, error) {
+	var all []T
+	query := map[string]string{"per_page": fmt.Sprint(pageSize)}
+	for k, v := range params {
+		query[k] = v
+	}
+
+	for path != "" {
+		var page []T
+		resp, err := get(gh.Client, path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+
+		path, query = "", nil
+		if matches := nextLinkRegex.FindStringSubmatch(resp.Header().Get("Link")); len(matches) == 2 {
+			path = matches[1]
+		}
+	}
+	return all, nil
+}
+
+func (gh *GitHubClient) GetOrganization() (*Organization, error) {
+	var org Organization
+	if _, err := get(gh.Client, fmt.Sprintf("/orgs/%s", gh.organization), nil, &org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (gh *GitHubClient) GetRepositories() ([]Repository, error) {
+	return getAll[Repository](gh, fmt.Sprintf("/orgs/%s/repos", gh.organization), map[string]string{"type": "all"})
+}
+
+func (gh *GitHubClient) GetProtectedBranches(repo string) ([]Branch, error) {
+	return getAll[Branch](gh, fmt.Sprintf("/repos/%s/%s/branches", gh.organization, repo), map[string]string{"protected": "true"})
+}
+
+func (gh *GitHubClient) GetBranchProtection(repo, branch string) (*BranchProtection, error) {
+	var protection BranchProtection
+	if _, err := get(gh.Client, fmt.Sprintf("/repos/%s/%s/branches/%s/protection", gh.organization, repo, branch), nil, &protection); err != nil {
+		return nil, err
+	}
+	return &protection, nil
+}
+
+func (gh *GitHubClient) GetDeployKeys(repo string) ([]DeployKey, error) {
+	return getAll[DeployKey](gh, fmt.Sprintf("/repos/%s/%s/keys", gh.organization, repo), nil)
+}
+
+func (gh *GitHubClient) GetWebhooks(repo string) ([]Webhook, error) {
+	return getAll[Webhook](gh, fmt.Sprintf("/repos/%s/%s/hooks", gh.organization, repo), nil)
+}
+
+// GetReleases returns the latest page of releases of a repository
+func (gh *GitHubClient) GetReleases(repo string) ([]Release, error) {
+	var releases []Release
+	_, err := get(gh.Client, fmt.Sprintf("/repos/%s/%s/releases", gh.organization, repo), map[string]string{"per_page": fmt.Sprint(pageSize)}, &releases)
+	return releases, err
+}
+
+func (gh *GitHubClient) GetTeams() ([]Team, error) {
+	return getAll[Team](gh, fmt.Sprintf("/orgs/%s/teams", gh.organization), nil)
+}
+
+func (gh *GitHubClient) GetTeamRepositories(team string) ([]TeamRepository, error) {
+	return getAll[TeamRepository](gh, fmt.Sprintf("/orgs/%s/teams/%s/repos", gh.organization, team), nil)
+}
+
+func (gh *GitHubClient) GetDependabotAlerts() ([]DependabotAlert, error) {
+	return getAll[DependabotAlert](gh, fmt.Sprintf("/orgs/%s/dependabot/alerts", gh.organization), map[string]string{"state": "open"})
+}
+
+func (gh *GitHubClient) GetCodeScanningAlerts() ([]CodeScanningAlert, error) {
+	return getAll[CodeScanningAlert](gh, fmt.Sprintf("/orgs/%s/code-scanning/alerts", gh.organization), map[string]string{"state": "open"})
+}
+
+func (gh *GitHubClient) GetSecretScanningAlerts() ([]SecretScanningAlert, error) {
+	return getAll[SecretScanningAlert](gh, fmt.Sprintf("/orgs/%s/secret-scanning/alerts", gh.organization), map[string]string{"state": "open"})
+}
@@ -0,0 +1,363 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+)
+
+const (
+	OrganizationType     = "GitHub::Organization"
+	RepositoryType       = "GitHub::Repository"
+	BranchProtectionType = "GitHub::BranchProtection"
+	TeamType             = "GitHub::Team"
+)
+
+type GithubScraper struct {
+}
+
+func (gh GithubScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.GitHub) > 0
+}
+
+// Scrape fetches the repositories, teams and security alerts of github organizations.
+func (gh GithubScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.GitHub {
+		client, err := NewGitHubClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create github client for %s", config.Organization)
+			continue
+		}
+
+		results = append(results, scrapeOrganization(client, config)...)
+	}
+	return results
+}
+
+func scrapeOrganization(client *GitHubClient, config v1.GitHub) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	org, err := client.GetOrganization()
+	if err != nil {
+		results.Errorf(err, "failed to get github organization %s", config.Organization)
+		return results
+	}
+	results = append(results, v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ConfigClass: "Organization",
+		Config:      org,
+		Type:        OrganizationType,
+		ID:          org.Login,
+		Name:        org.Login,
+		CreatedAt:   &org.CreatedAt,
+		Aliases:     []string{org.NodeID},
+	})
+
+	repositories, err := client.GetRepositories()
+	if err != nil {
+		results.Errorf(err, "failed to get repositories of %s", org.Login)
+		return results
+	}
+
+	var scraped = make(map[string]bool)
+	for _, repo := range repositories {
+		if repo.Archived && !config.IncludeArchived {
+			continue
+		}
+		if !collections.MatchItems(repo.Name, config.Repositories...) {
+			continue
+		}
+
+		logger.Debugf("scraping github repository %s", repo.FullName)
+		scraped[repo.FullName] = true
+		results = append(results, scrapeRepository(client, config, repo)...)
+	}
+
+	results = append(results, scrapeTeams(client, config, scraped)...)
+	results = append(results, scrapeAlerts(client, config, scraped)...)
+	return results
+}
+
+func scrapeRepository(client *GitHubClient, config v1.GitHub, repo Repository) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	if keys, err := client.GetDeployKeys(repo.Name); err == nil {
+		repo.DeployKeys = keys
+	} else if !errors.Is(err, errNotAvailable) {
+		results.Errorf(err, "failed to get deploy keys of %s", repo.FullName)
+	}
+
+	if hooks, err := client.GetWebhooks(repo.Name); err == nil {
+		repo.Webhooks = hooks
+	} else if !errors.Is(err, errNotAvailable) {
+		results.Errorf(err, "failed to get webhooks of %s", repo.FullName)
+	}
+
+	var changes []v1.ChangeResult
+	if releases, err := client.GetReleases(repo.Name); err != nil {
+		results.Errorf(err, "failed to get releases of %s", repo.FullName)
+	} else {
+		for _, _release := range releases {
+			var release = _release
+			if release.Draft || release.PublishedAt == nil {
+				continue
+			}
+
+			changeType := "Release"
+			if release.Prerelease {
+				changeType = "PreRelease"
+			}
+			changes = append(changes, v1.ChangeResult{
+				ExternalID:       repo.FullName,
+				ConfigType:       RepositoryType,
+				ExternalChangeID: fmt.Sprintf("%s/release/%d", repo.FullName, release.ID),
+				ChangeType:       changeType,
+				Summary:          strings.TrimSpace(fmt.Sprintf("%s %s", release.TagName, release.Name)),
+				Severity:         string(models.SeverityInfo),
+				Source:           release.HtmlURL,
+				CreatedBy:        &release.Author.Login,
+				CreatedAt:        release.PublishedAt,
+				Details:          v1.NewJSON(release),
+			})
+		}
+	}
+
+	// the time of the last push changes on every push, it's kept out of the config so that it isn't diffed
+	var properties types.Properties
+	if repo.PushedAt != nil {
+		properties = append(properties, &types.Property{Name: "Last Push", Text: repo.PushedAt.Format(time.RFC3339)})
+		repo.PushedAt = nil
+	}
+
+	results = append(results, v1.ScrapeResult{
+		BaseScraper:      config.BaseScraper,
+		ConfigClass:      "Repository",
+		Config:           repo,
+		Properties:       properties,
+		Type:             RepositoryType,
+		ID:               repo.FullName,
+		Name:             repo.Name,
+		CreatedAt:        &repo.CreatedAt,
+		Aliases:          []string{repo.NodeID, repo.HtmlURL},
+		Tags:             map[string]string{"organization": config.Organization, "visibility": repo.Visibility},
+		Changes:          changes,
+		ParentExternalID: config.Organization,
+		ParentType:       OrganizationType,
+	})
+
+	branches, err := client.GetProtectedBranches(repo.Name)
+	if err != nil {
+		if !errors.Is(err, errNotAvailable) {
+			results.Errorf(err, "failed to get protected branches of %s", repo.FullName)
+		}
+		return results
+	}
+
+	for _, branch := range branches {
+		protection, err := client.GetBranchProtection(repo.Name, branch.Name)
+		if err != nil {
+			if !errors.Is(err, errNotAvailable) {
+				results.Errorf(err, "failed to get protection of %s:%s", repo.FullName, branch.Name)
+			}
+			continue
+		}
+
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ConfigClass:      "BranchProtection",
+			Config:           protection,
+			Type:             BranchProtectionType,
+			ID:               fmt.Sprintf("%s/%s", repo.FullName, branch.Name),
+			Name:             branch.Name,
+			Tags:             map[string]string{"organization": config.Organization, "repository": repo.Name},
+			ParentExternalID: repo.FullName,
+			ParentType:       RepositoryType,
+		})
+	}
+
+	return results
+}
+
+// scrapeTeams returns the teams of the organization, related to the scraped repositories they have access to.
+func scrapeTeams(client *GitHubClient, config v1.GitHub, repositories map[string]bool) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	teams, err := client.GetTeams()
+	if err != nil {
+		if !errors.Is(err, errNotAvailable) {
+			results.Errorf(err, "failed to get teams of %s", config.Organization)
+		}
+		return results
+	}
+
+	for _, team := range teams {
+		teamRepositories, err := client.GetTeamRepositories(team.Slug)
+		if err != nil {
+			results.Errorf(err, "failed to get repositories of team %s", team.Slug)
+			continue
+		}
+
+		var relationships v1.RelationshipResults
+		team.Repositories = make(map[string]string)
+		for _, repo := range teamRepositories {
+			if !repositories[repo.FullName] {
+				continue
+			}
+
+			team.Repositories[repo.FullName] = repo.RoleName
+			relationships = append(relationships, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{teamID(config.Organization, team.Slug)}, ConfigType: TeamType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{repo.FullName}, ConfigType: RepositoryType},
+				Relationship:      "TeamRepository",
+			})
+		}
+
+		if team.Parent != nil {
+			relationships = append(relationships, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{teamID(config.Organization, team.Parent.Slug)}, ConfigType: TeamType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{teamID(config.Organization, team.Slug)}, ConfigType: TeamType},
+				Relationship:      "TeamTeam",
+			})
+		}
+
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:         config.BaseScraper,
+			ConfigClass:         "Team",
+			Config:              team,
+			Type:                TeamType,
+			ID:                  teamID(config.Organization, team.Slug),
+			Name:                team.Name,
+			Aliases:             []string{team.NodeID},
+			Tags:                map[string]string{"organization": config.Organization},
+			ParentExternalID:    config.Organization,
+			ParentType:          OrganizationType,
+			RelationshipResults: relationships,
+		})
+	}
+
+	return results
+}
+
+func teamID(org, slug string) string {
+	return fmt.Sprintf("%s/%s", org, slug)
+}
+
+// scrapeAlerts returns the open security alerts of the scraped repositories as analysis.
+// Alerts that aren't enabled for the organization are skipped.
+func scrapeAlerts(client *GitHubClient, config v1.GitHub, repositories map[string]bool) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	if config.IncludesAlerts("dependabot") {
+		if alerts, err := client.GetDependabotAlerts(); err != nil {
+			handleAlertsError(&results, err, "dependabot", config.Organization)
+		} else {
+			for _, alert := range alerts {
+				if repositories[alert.Repository.FullName] {
+					dependabotAnalysis(&results, alert)
+				}
+			}
+		}
+	}
+
+	if config.IncludesAlerts("code-scanning") {
+		if alerts, err := client.GetCodeScanningAlerts(); err != nil {
+			handleAlertsError(&results, err, "code scanning", config.Organization)
+		} else {
+			for _, alert := range alerts {
+				if repositories[alert.Repository.FullName] {
+					codeScanningAnalysis(&results, alert)
+				}
+			}
+		}
+	}
+
+	if config.IncludesAlerts("secret-scanning") {
+		if alerts, err := client.GetSecretScanningAlerts(); err != nil {
+			handleAlertsError(&results, err, "secret scanning", config.Organization)
+		} else {
+			for _, alert := range alerts {
+				if repositories[alert.Repository.FullName] {
+					secretScanningAnalysis(&results, alert)
+				}
+			}
+		}
+	}
+
+	return results
+}
+
+func handleAlertsError(results *v1.ScrapeResults, err error, kind, org string) {
+	if errors.Is(err, errNotAvailable) {
+		logger.Debugf("%s alerts are not available for %s: %v", kind, org, err)
+		return
+	}
+	results.Errorf(err, "failed to get %s alerts of %s", kind, org)
+}
+
+func dependabotAnalysis(results *v1.ScrapeResults, alert DependabotAlert) {
+	// an advisory can be raised on several manifests of a repository, the alert number tells them apart
+	analyzer := fmt.Sprintf("%s: %s #%d", alert.SecurityAdvisory.GhsaID, alert.Dependency.Package.Name, alert.Number)
+	analysis := results.Analysis(analyzer, RepositoryType, alert.Repository.FullName)
+	analysis.AnalysisType = models.AnalysisTypeSecurity
+	analysis.Severity = alertSeverity(alert.SecurityAdvisory.Severity)
+	analysis.Source = "GitHub Dependabot"
+	analysis.Status = models.AnalysisStatusOpen
+	analysis.Summary = alert.SecurityAdvisory.Summary
+	analysis.Analysis = v1.NewJSON(alert)
+	analysis.Message(fmt.Sprintf("%s %s (%s) in %s", alert.Dependency.Package.Ecosystem, alert.Dependency.Package.Name, alert.SecurityVulnerability.VulnerableVersionRange, alert.Dependency.ManifestPath))
+	if patched := alert.SecurityVulnerability.FirstPatchedVersion; patched != nil {
+		analysis.Message(fmt.Sprintf("fixed in %s", patched.Identifier))
+	}
+}
+
+func codeScanningAnalysis(results *v1.ScrapeResults, alert CodeScanningAlert) {
+	analysis := results.Analysis(fmt.Sprintf("%s: %s #%d", alert.Tool.Name, alert.Rule.ID, alert.Number), RepositoryType, alert.Repository.FullName)
+	analysis.AnalysisType = models.AnalysisTypeSecurity
+	if alert.Rule.SecuritySeverityLevel != "" {
+		analysis.Severity = alertSeverity(alert.Rule.SecuritySeverityLevel)
+	} else {
+		analysis.Severity = alertSeverity(alert.Rule.Severity)
+	}
+	analysis.Source = "GitHub Code Scanning"
+	analysis.Status = models.AnalysisStatusOpen
+	analysis.Summary = alert.Rule.Description
+	analysis.Analysis = v1.NewJSON(alert)
+	location := alert.MostRecentInstance.Location
+	analysis.Message(fmt.Sprintf("%s:%d %s", location.Path, location.StartLine, alert.MostRecentInstance.Message.Text))
+}
+
+func secretScanningAnalysis(results *v1.ScrapeResults, alert SecretScanningAlert) {
+	analysis := results.Analysis(fmt.Sprintf("Leaked secret: %s #%d", alert.SecretTypeDisplayName, alert.Number), RepositoryType, alert.Repository.FullName)
+	analysis.AnalysisType = models.AnalysisTypeSecurity
+	analysis.Severity = models.SeverityCritical
+	analysis.Source = "GitHub Secret Scanning"
+	analysis.Status = models.AnalysisStatusOpen
+	analysis.Summary = fmt.Sprintf("%s found in %s", alert.SecretTypeDisplayName, alert.Repository.FullName)
+	analysis.Analysis = v1.NewJSON(alert)
+	analysis.Message(alert.HtmlURL)
+}
+
+// alertSeverity maps the severity of advisories (critical, high, medium, low)
+// and of code scanning rules (error, warning, note) to an analysis severity.
+func alertSeverity(severity string) models.Severity {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return models.SeverityCritical
+	case "high", "error":
+		return models.SeverityHigh
+	case "medium", "moderate", "warning":
+		return models.SeverityMedium
+	case "low", "note":
+		return models.SeverityLow
+	}
+	return models.SeverityInfo
+}
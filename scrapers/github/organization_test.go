@@ -0,0 +1,149 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+)
+
+// newGitHubStandIn serves the parts of the github API used by the organization scraper
+func newGitHubStandIn(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	responses := map[string]string{
+		"/orgs/acme": `{"login": "acme", "id": 1, "node_id": "O_1", "created_at": "2020-01-01T00:00:00Z"}`,
+		"/orgs/acme/repos?page=2": `[
+			{"id": 12, "node_id": "R_12", "name": "legacy", "full_name": "acme/legacy", "archived": true, "created_at": "2020-01-01T00:00:00Z"}
+		]`,
+		"/repos/acme/api/keys":                     `[{"id": 1, "title": "deploy", "key": "ssh-rsa AAAA", "read_only": true, "created_at": "2021-01-01T00:00:00Z"}]`,
+		"/repos/acme/api/hooks":                    `[{"id": 2, "name": "web", "active": true, "events": ["push"], "config": {"url": "https://ci.acme.io", "content_type": "json", "secret": "********"}}]`,
+		"/repos/acme/api/branches":                 `[{"name": "main", "protected": true}]`,
+		"/repos/acme/api/branches/main/protection": `{"enforce_admins": {"enabled": true}, "required_linear_history": {"enabled": true}}`,
+		"/repos/acme/api/releases":                 `[{"id": 3, "tag_name": "v1.0.0", "name": "First", "author": {"login": "jdoe"}, "published_at": "2022-01-01T00:00:00Z"}, {"id": 4, "tag_name": "v1.1.0", "draft": true}]`,
+		"/orgs/acme/teams":                         `[{"id": 5, "node_id": "T_5", "name": "Platform", "slug": "platform"}]`,
+		"/orgs/acme/teams/platform/repos":          `[{"full_name": "acme/api", "role_name": "admin"}, {"full_name": "acme/legacy", "role_name": "read"}]`,
+		"/orgs/acme/dependabot/alerts":             `[{"number": 1, "state": "open", "dependency": {"package": {"ecosystem": "go", "name": "golang.org/x/net"}, "manifest_path": "go.mod"}, "security_advisory": {"ghsa_id": "GHSA-1", "summary": "HTTP/2 rapid reset", "severity": "high"}, "repository": {"full_name": "acme/api"}}, {"number": 2, "state": "open", "security_advisory": {"ghsa_id": "GHSA-2", "severity": "low"}, "repository": {"full_name": "acme/legacy"}}]`,
+		"/orgs/acme/secret-scanning/alerts":        `[{"number": 1, "state": "open", "secret_type_display_name": "AWS Access Key", "secret": "AKIAEXAMPLE", "repository": {"full_name": "acme/api"}}, {"number": 3, "state": "open", "secret_type_display_name": "AWS Access Key", "secret": "AKIAOTHER", "repository": {"full_name": "acme/api"}}]`,
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/orgs/acme/repos" && r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/acme/repos?page=2>; rel="next", <%s/orgs/acme/repos?page=2>; rel="last"`, server.URL, server.URL))
+			fmt.Fprint(w, `[{"id": 11, "node_id": "R_11", "name": "api", "full_name": "acme/api", "visibility": "private", "default_branch": "main", "created_at": "2020-01-01T00:00:00Z", "pushed_at": "2024-01-01T00:00:00Z"}]`)
+			return
+		}
+
+		key := r.URL.Path
+		if page := r.URL.Query().Get("page"); page != "" {
+			key += "?page=" + page
+		}
+		body, ok := responses[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestScrapeOrganization(t *testing.T) {
+	server := newGitHubStandIn(t)
+
+	config := v1.GitHub{
+		Organization:        "acme",
+		URL:                 server.URL,
+		PersonalAccessToken: types.EnvVar{ValueStatic: "token"},
+	}
+	client, err := NewGitHubClient(api.NewScrapeContext(context.TODO(), nil, nil), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := scrapeOrganization(client, config)
+
+	items := make(map[string]v1.ScrapeResult)
+	var analyses []v1.AnalysisResult
+	for _, result := range results {
+		if result.Error != nil {
+			t.Fatalf("unexpected error: %v", result.Error)
+		}
+		if result.AnalysisResult != nil {
+			analyses = append(analyses, *result.AnalysisResult)
+			continue
+		}
+		items[result.Type+"/"+result.ID] = result
+	}
+
+	for _, id := range []string{
+		OrganizationType + "/acme",
+		RepositoryType + "/acme/api",
+		BranchProtectionType + "/acme/api/main",
+		TeamType + "/acme/platform",
+	} {
+		if _, ok := items[id]; !ok {
+			t.Errorf("expected %s to be scraped", id)
+		}
+	}
+	if _, ok := items[RepositoryType+"/acme/legacy"]; ok {
+		t.Errorf("archived repository should not be scraped")
+	}
+
+	repo := items[RepositoryType+"/acme/api"]
+	if len(repo.Changes) != 1 || *repo.Changes[0].CreatedBy != "jdoe" {
+		t.Errorf("expected the published release as a change, got %+v", repo.Changes)
+	}
+	repoConfig, _ := json.Marshal(repo.Config)
+	if strings.Contains(string(repoConfig), "ssh-rsa") || strings.Contains(string(repoConfig), "********") {
+		t.Errorf("deploy keys and webhook secrets should not be kept: %s", repoConfig)
+	}
+	if !strings.Contains(string(repoConfig), "https://ci.acme.io") {
+		t.Errorf("expected webhook metadata in %s", repoConfig)
+	}
+	// the last push is a property, so that pushes don't change the config
+	if strings.Contains(string(repoConfig), "pushed_at") || len(repo.Properties) != 1 || repo.Properties[0].Text != "2024-01-01T00:00:00Z" {
+		t.Errorf("expected the last push as a property, got %s %v", repoConfig, repo.Properties)
+	}
+
+	team := items[TeamType+"/acme/platform"]
+	if len(team.RelationshipResults) != 1 || team.RelationshipResults[0].RelatedExternalID.ExternalID[0] != "acme/api" {
+		t.Errorf("expected the team to be related to acme/api only, got %+v", team.RelationshipResults)
+	}
+
+	if len(analyses) != 3 {
+		t.Fatalf("expected 3 analysis, got %d", len(analyses))
+	}
+	// every alert is analyzed separately, even for the same advisory or secret type
+	for i, analyzer := range []string{"GHSA-1: golang.org/x/net #1", "Leaked secret: AWS Access Key #1", "Leaked secret: AWS Access Key #3"} {
+		if analyses[i].Analyzer != analyzer {
+			t.Errorf("expected analyzer %s, got %s", analyzer, analyses[i].Analyzer)
+		}
+	}
+	for _, analysis := range analyses {
+		if analysis.ExternalID != "acme/api" {
+			t.Errorf("unexpected analysis on %s", analysis.ExternalID)
+		}
+		if details, _ := json.Marshal(analysis.Analysis); strings.Contains(string(details), "AKIA") {
+			t.Errorf("leaked secret should not be kept")
+		}
+	}
+	if analyses[0].Severity != models.SeverityHigh || analyses[1].Severity != models.SeverityCritical {
+		t.Errorf("unexpected severities %s, %s", analyses[0].Severity, analyses[1].Severity)
+	}
+}
@@ -2,15 +2,32 @@ package github
 
 import (
 	"fmt"
-	"math"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
-	"github.com/flanksource/config-db/db"
+	"github.com/samber/lo"
 )
 
-const WorkflowRun = "GitHubActions::WorkflowRun"
+const (
+	WorkflowRun     = "GitHubActions::WorkflowRun"
+	EnvironmentType = "GitHubActions::Environment"
+	DeploymentType  = "GitHubActions::Deployment"
+)
+
+// workflowRunCursorType is the cursor type that keeps track of the creation time
+// of the oldest run still in progress, or else of the last run, per workflow.
+// The "<workflow>/updated" scope keeps the last update time of the runs,
+// so that the runs already recorded once completed are skipped.
+const workflowRunCursorType = "github/workflowrun"
+
+// maxInProgressAge is how long a run in progress holds the cursor back,
+// runs stuck in progress for longer are not fetched again.
+const maxInProgressAge = 24 * time.Hour
 
 type GithubActionsScraper struct {
 }
@@ -29,6 +46,9 @@ func (gh GithubActionsScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 			continue
 		}
 
+		deployments, deploymentsByRun := scrapeDeployments(client, config)
+		results = append(results, deployments...)
+
 		workflows, err := client.GetWorkflows()
 		if err != nil {
 			results.Errorf(err, "failed to get projects for %s", config.Repository)
@@ -39,69 +59,223 @@ func (gh GithubActionsScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 			if !collections.MatchItems(workflow.Name, config.Workflows...) {
 				continue
 			}
-			runs, err := getNewWorkflowRuns(client, workflow)
+			runs, relationships, err := getNewWorkflowRuns(ctx, client, config, workflow, deploymentsByRun, &results)
 			if err != nil {
 				results.Errorf(err, "failed to get workflow runs for %s", workflow.GetID())
 				continue
 			}
+
 			results = append(results, v1.ScrapeResult{
-				ConfigClass: "GithubWorkflow",
-				Config:      workflow,
-				Type:        WorkflowRun,
-				ID:          workflow.GetID(),
-				Name:        workflow.Name,
-				Changes:     runs,
-				Aliases:     []string{fmt.Sprintf("%s/%d", workflow.Name, workflow.ID)},
+				ConfigClass:         "GithubWorkflow",
+				Config:              workflow,
+				Type:                WorkflowRun,
+				ID:                  workflow.GetID(),
+				Name:                workflow.Name,
+				Changes:             runs,
+				Aliases:             []string{fmt.Sprintf("%s/%d", workflow.Name, workflow.ID)},
+				RelationshipResults: relationships,
 			})
 		}
 	}
 	return results
 }
 
-func getNewWorkflowRuns(client *GitHubActionsClient, workflow Workflow) ([]v1.ChangeResult, error) {
-	runs, err := client.GetWorkflowRuns(workflow.ID, 1)
-	if err != nil {
-		return nil, err
-	}
+// workflowRunDetails are the details of a workflow run change
+type workflowRunDetails struct {
+	Run
+	Jobs []Job `json:"jobs,omitempty"`
+	// Deployments are the ids of the deployments created by the run
+	Deployments []string `json:"deployments,omitempty"`
+}
 
-	var allRuns []v1.ChangeResult
-	for _, run := range runs.Value {
-		allRuns = append(allRuns, v1.ChangeResult{
-			ChangeType:       "GithubWorkflowRun",
-			CreatedAt:        &run.CreatedAt,
-			Severity:         fmt.Sprint(run.Conclusion),
-			ExternalID:       workflow.GetID(),
-			ConfigType:       WorkflowRun,
-			Source:           run.Event,
-			Details:          v1.NewJSON(run),
-			ExternalChangeID: fmt.Sprintf("%s/%d/%d", workflow.Name, workflow.ID, run.ID),
-		})
+// getNewWorkflowRuns returns the runs of the workflow created or updated since the last scrape,
+// with the relationships of the workflow to the deployments of the runs.
+// The cursors are saved once the results are.
+func getNewWorkflowRuns(ctx api.ScrapeContext, client *GitHubActionsClient, config v1.GitHubActions, workflow Workflow, deploymentsByRun map[int][]string, results *v1.ScrapeResults) ([]v1.ChangeResult, v1.RelationshipResults, error) {
+	scope := fmt.Sprintf("%s/%s/%d", config.Owner, config.Repository, workflow.ID)
+
+	since := time.Now().Add(-config.GetMaxAge()).UTC()
+	if cursor, err := ctx.GetCursor(workflowRunCursorType, scope); err != nil {
+		logger.Warnf("failed to get workflow run cursor for %s: %v", scope, err)
+	} else if cursor != "" {
+		since, _ = time.Parse(time.RFC3339, cursor)
 	}
 
-	// Get total runs from DB for that workflow
-	totalRunsInDB, err := db.GetWorkflowRunCount(workflow.GetID())
-	if err != nil {
-		return nil, err
+	var updatedSince time.Time
+	if cursor, err := ctx.GetCursor(workflowRunCursorType, scope+"/updated"); err != nil {
+		logger.Warnf("failed to get workflow run cursor for %s/updated: %v", scope, err)
+	} else if cursor != "" {
+		updatedSince, _ = time.Parse(time.RFC3339, cursor)
 	}
-	delta := runs.Count - totalRunsInDB
-	pagesToFetch := int(math.Ceil(float64(delta) / 100))
-	for page := 2; page <= pagesToFetch; page += 1 {
-		runs, err := client.GetWorkflowRuns(workflow.ID, page)
+
+	var allRuns []v1.ChangeResult
+	var relationships v1.RelationshipResults
+	var lastCreatedAt, lastUpdatedAt, oldestInProgress time.Time
+	for page := 1; ; page++ {
+		runs, err := client.GetWorkflowRunsCreatedSince(workflow.ID, page, since)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		for _, run := range runs.Value {
+
+		for _, _run := range runs.Value {
+			var run = _run
+			if run.CreatedAt.After(lastCreatedAt) {
+				lastCreatedAt = run.CreatedAt
+			}
+			if run.UpdatedAt.After(lastUpdatedAt) {
+				lastUpdatedAt = run.UpdatedAt
+			}
+			if run.Status != "completed" && time.Since(run.CreatedAt) < maxInProgressAge && (oldestInProgress.IsZero() || run.CreatedAt.Before(oldestInProgress)) {
+				oldestInProgress = run.CreatedAt
+			}
+
+			// runs created after the oldest run in progress are fetched again,
+			// the ones that had already completed on the previous scrape are recorded.
+			if run.Status == "completed" && !updatedSince.IsZero() && !run.UpdatedAt.After(updatedSince) {
+				continue
+			}
+
+			details := workflowRunDetails{Run: run, Deployments: deploymentsByRun[run.ID]}
+			if details.Jobs, err = client.GetWorkflowRunJobs(run.ID); err != nil {
+				logger.Warnf("failed to get jobs of workflow run %d: %v", run.ID, err)
+			}
+
+			severity := "info"
+			if run.Conclusion != nil {
+				severity = fmt.Sprint(run.Conclusion)
+			}
+
 			allRuns = append(allRuns, v1.ChangeResult{
 				ChangeType:       "GithubWorkflowRun",
 				CreatedAt:        &run.CreatedAt,
-				Severity:         run.Conclusion.(string),
+				Severity:         severity,
 				ExternalID:       workflow.GetID(),
 				ConfigType:       WorkflowRun,
 				Source:           run.Event,
-				Details:          v1.NewJSON(run),
+				Details:          v1.NewJSON(details),
 				ExternalChangeID: fmt.Sprintf("%s/%d/%d", workflow.Name, workflow.ID, run.ID),
+				// Runs that were in progress on the previous scrape are fetched again,
+				// so that their conclusion and jobs are recorded.
+				UpdateExisting: true,
 			})
+
+			for _, deployment := range details.Deployments {
+				relationships = append(relationships, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ExternalID: []string{workflow.GetID()}, ConfigType: WorkflowRun},
+					RelatedExternalID: v1.ExternalID{ExternalID: []string{deployment}, ConfigType: DeploymentType},
+					Relationship:      "WorkflowRunDeployment",
+				})
+			}
+		}
+
+		if len(runs.Value) < runsPerPage {
+			break
+		}
+	}
+
+	next := lastCreatedAt
+	if !oldestInProgress.IsZero() {
+		next = oldestInProgress
+	}
+	if !next.IsZero() {
+		results.OnSave(func() error {
+			if err := ctx.SaveCursor(workflowRunCursorType, scope, next.Format(time.RFC3339)); err != nil {
+				return fmt.Errorf("failed to save workflow run cursor for %s: %w", scope, err)
+			}
+			if err := ctx.SaveCursor(workflowRunCursorType, scope+"/updated", lastUpdatedAt.Format(time.RFC3339)); err != nil {
+				return fmt.Errorf("failed to save workflow run cursor for %s/updated: %w", scope, err)
+			}
+			return nil
+		})
+	}
+
+	return allRuns, relationships, nil
+}
+
+var workflowRunURLRegex = regexp.MustCompile(`/actions/runs/(\d+)`)
+
+// scrapeDeployments returns the environments and the deployments of the repository created
+// within the max age, with the ids of the deployments created by each workflow run.
+// Older deployments are skipped so their statuses aren't fetched on every scrape.
+func scrapeDeployments(client *GitHubActionsClient, config v1.GitHubActions) (v1.ScrapeResults, map[int][]string) {
+	var results v1.ScrapeResults
+	var deploymentsByRun = make(map[int][]string)
+
+	environments, err := client.GetEnvironments()
+	if err != nil {
+		results.Errorf(err, "failed to get environments of %s/%s", config.Owner, config.Repository)
+		return results, deploymentsByRun
+	}
+
+	for _, _environment := range environments {
+		var environment = _environment
+		results = append(results, v1.ScrapeResult{
+			BaseScraper: config.BaseScraper,
+			ConfigClass: "Environment",
+			Config:      environment,
+			Type:        EnvironmentType,
+			ID:          environmentID(config, environment.Name),
+			Name:        environment.Name,
+			CreatedAt:   &environment.CreatedAt,
+			Aliases:     []string{environment.NodeID},
+			Tags:        map[string]string{"repository": fmt.Sprintf("%s/%s", config.Owner, config.Repository)},
+		})
+	}
+
+	deployments, err := client.GetDeployments()
+	if err != nil {
+		results.Errorf(err, "failed to get deployments of %s/%s", config.Owner, config.Repository)
+		return results, deploymentsByRun
+	}
+
+	since := time.Now().Add(-config.GetMaxAge())
+	for _, _deployment := range deployments {
+		var deployment = _deployment
+		if deployment.CreatedAt.Before(since) {
+			// deployments are listed newest first
+			break
+		}
+
+		id := fmt.Sprintf("%s/%s/%d", config.Owner, config.Repository, deployment.ID)
+		if deployment.Statuses, err = client.GetDeploymentStatuses(deployment.ID); err != nil {
+			logger.Warnf("failed to get statuses of deployment %s: %v", id, err)
+		}
+
+		var status string
+		if len(deployment.Statuses) > 0 {
+			status = deployment.Statuses[0].State
+		}
+
+		for _, deploymentStatus := range deployment.Statuses {
+			matches := workflowRunURLRegex.FindStringSubmatch(deploymentStatus.LogURL + " " + deploymentStatus.TargetURL)
+			if len(matches) != 2 {
+				continue
+			}
+			runID, _ := strconv.Atoi(matches[1])
+			if !lo.Contains(deploymentsByRun[runID], id) {
+				deploymentsByRun[runID] = append(deploymentsByRun[runID], id)
+			}
 		}
+
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ConfigClass:      "Deployment",
+			Config:           deployment,
+			Type:             DeploymentType,
+			ID:               id,
+			Name:             fmt.Sprintf("%s@%s", deployment.Environment, deployment.Ref),
+			Status:           status,
+			CreatedAt:        &deployment.CreatedAt,
+			Aliases:          []string{deployment.NodeID},
+			Tags:             map[string]string{"environment": deployment.Environment},
+			ParentExternalID: environmentID(config, deployment.Environment),
+			ParentType:       EnvironmentType,
+		})
 	}
-	return allRuns, nil
+
+	return results, deploymentsByRun
+}
+
+func environmentID(config v1.GitHubActions, name string) string {
+	return fmt.Sprintf("%s/%s/%s", config.Owner, config.Repository, name)
 }
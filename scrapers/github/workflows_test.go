@@ -0,0 +1,148 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+func TestScrapeDeployments(t *testing.T) {
+	responses := map[string]string{
+		"/environments":             `{"total_count": 1, "environments": [{"id": 1, "name": "production", "created_at": "2023-01-01T00:00:00Z"}]}`,
+		"/deployments":              fmt.Sprintf(`[{"id": 7, "sha": "abc", "ref": "main", "environment": "production", "created_at": %q}, {"id": 6, "sha": "def", "ref": "main", "environment": "production", "created_at": "2023-01-01T00:00:00Z"}]`, time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)),
+		"/deployments/7/statuses":   `[{"id": 2, "state": "success", "log_url": "https://github.com/acme/api/actions/runs/42/job/1"}, {"id": 1, "state": "in_progress", "log_url": "https://github.com/acme/api/actions/runs/42/job/1"}]`,
+		"/actions/runs/42/jobs":     `{"total_count": 1, "jobs": [{"id": 1, "name": "deploy", "status": "completed", "conclusion": "success", "started_at": "2023-01-02T00:00:00Z", "completed_at": "2023-01-02T00:01:30Z", "steps": [{"number": 1, "name": "checkout", "status": "completed", "conclusion": "success", "started_at": "2023-01-02T00:00:00Z", "completed_at": "2023-01-02T00:00:05Z"}]}]}`,
+		"/actions/workflows":        `{"total_count": 1, "workflows": [{"id": 3, "name": "deploy"}]}`,
+		"/actions/workflows/3/runs": `{"total_count": 1, "workflow_runs": [{"id": 42, "status": "completed", "conclusion": "success", "created_at": "2023-01-02T00:00:00Z"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/deployments/6/statuses" {
+			t.Errorf("fetched the statuses of a deployment older than the max age")
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{})
+	client := &GitHubActionsClient{ScrapeContext: ctx, Client: resty.New().SetBaseURL(server.URL)}
+	config := v1.GitHubActions{Owner: "acme", Repository: "api"}
+
+	results, deploymentsByRun := scrapeDeployments(client, config)
+	if len(results) != 2 {
+		t.Fatalf("expected an environment and the recent deployment, got %v", results)
+	}
+	if deployment := results[1]; deployment.Status != "success" || deployment.ParentExternalID != "acme/api/production" {
+		t.Errorf("unexpected deployment %+v", deployment)
+	}
+	if deployments := deploymentsByRun[42]; len(deployments) != 1 || deployments[0] != "acme/api/7" {
+		t.Errorf("expected run 42 to be linked to deployment acme/api/7, got %v", deployments)
+	}
+
+	runs, relationships, err := getNewWorkflowRuns(ctx, client, config, Workflow{ID: 3, Name: "deploy"}, deploymentsByRun, &v1.ScrapeResults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || len(relationships) != 1 {
+		t.Fatalf("expected a run linked to a deployment, got %v and %v", runs, relationships)
+	}
+	jobs, _ := runs[0].Details["jobs"].([]any)
+	if len(jobs) != 1 || jobs[0].(map[string]any)["duration"] != float64(90000) {
+		t.Errorf("expected the job with its duration in the run details, got %v", runs[0].Details["jobs"])
+	}
+}
+
+func TestGetNewWorkflowRuns(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	type run struct {
+		ID        int       `json:"id"`
+		Status    string    `json:"status"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// the second run is in progress on the first scrape, the first one is stuck in progress
+	runs := []run{
+		{1, "in_progress", now.Add(-48 * time.Hour), now.Add(-48 * time.Hour)},
+		{2, "completed", now.Add(-3 * time.Hour), now.Add(-3 * time.Hour)},
+		{3, "in_progress", now.Add(-2 * time.Hour), now.Add(-2 * time.Hour)},
+		{4, "completed", now.Add(-1 * time.Hour), now.Add(-1 * time.Hour)},
+	}
+
+	var created []string
+	jobs := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/actions/workflows/3/runs" {
+			created = append(created, r.URL.Query().Get("created"))
+			since, _ := time.Parse(time.RFC3339, strings.TrimPrefix(r.URL.Query().Get("created"), ">="))
+			var response struct {
+				Runs []run `json:"workflow_runs"`
+			}
+			for _, run := range runs {
+				if !run.CreatedAt.Before(since) {
+					response.Runs = append(response.Runs, run)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(response)
+			return
+		}
+		jobs[r.URL.Path]++
+		fmt.Fprint(w, `{"jobs": []}`)
+	}))
+	defer server.Close()
+
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{})
+	client := &GitHubActionsClient{ScrapeContext: ctx, Client: resty.New().SetBaseURL(server.URL)}
+	config := v1.GitHubActions{Owner: "acme", Repository: "api", MaxAge: "72h"}
+	workflow := Workflow{ID: 3, Name: "deploy"}
+
+	var results v1.ScrapeResults
+	changes, _, err := getNewWorkflowRuns(ctx, client, config, workflow, nil, &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 4 || len(jobs) != 4 {
+		t.Fatalf("expected all the runs with their jobs, got %d runs and %v", len(changes), jobs)
+	}
+	if created[0] != ">="+now.Add(-72*time.Hour).Format(time.RFC3339) {
+		t.Errorf("expected the first scrape to be bounded by the max age, got %s", created[0])
+	}
+
+	if err := results.Saved(); err != nil {
+		t.Fatal(err)
+	}
+	// the run stuck in progress doesn't hold the cursor back
+	if cursor, _ := ctx.GetCursor(workflowRunCursorType, "acme/api/3"); cursor != now.Add(-2*time.Hour).Format(time.RFC3339) {
+		t.Fatalf("expected the cursor at the run in progress, got %s", cursor)
+	}
+
+	// the run in progress has completed since
+	runs[2].Status, runs[2].UpdatedAt = "completed", now
+	jobs = make(map[string]int)
+	changes, _, err = getNewWorkflowRuns(ctx, client, config, workflow, nil, &v1.ScrapeResults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created[1] != ">="+now.Add(-2*time.Hour).Format(time.RFC3339) {
+		t.Errorf("expected the runs to be fetched from the cursor, got %s", created[1])
+	}
+	// the runs that had already completed are not recorded again
+	if len(changes) != 1 || len(jobs) != 1 || jobs["/actions/runs/3/jobs"] != 1 {
+		t.Errorf("expected only the runs updated since the last scrape, got %d runs and %v", len(changes), jobs)
+	}
+}
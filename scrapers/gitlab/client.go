@@ -0,0 +1,308 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// User is the subset of a gitlab user returned by the API
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name,omitempty"`
+}
+
+// Project see https://docs.gitlab.com/ee/api/projects.html
+type Project struct {
+	ID                int        `json:"id"`
+	Name              string     `json:"name"`
+	PathWithNamespace string     `json:"path_with_namespace"`
+	Description       string     `json:"description,omitempty"`
+	Visibility        string     `json:"visibility"`
+	DefaultBranch     string     `json:"default_branch"`
+	WebURL            string     `json:"web_url"`
+	HttpURLToRepo     string     `json:"http_url_to_repo"`
+	Archived          bool       `json:"archived"`
+	Topics            []string   `json:"topics,omitempty"`
+	Namespace         any        `json:"namespace,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastActivityAt    *time.Time `json:"last_activity_at,omitempty"`
+
+	MergeMethod                      string `json:"merge_method,omitempty"`
+	OnlyAllowMergeIfPipelineSucceeds bool   `json:"only_allow_merge_if_pipeline_succeeds"`
+	RemoveSourceBranchAfterMerge     bool   `json:"remove_source_branch_after_merge"`
+	ContainerRegistryEnabled         bool   `json:"container_registry_enabled"`
+	JobsEnabled                      bool   `json:"jobs_enabled"`
+	IssuesEnabled                    bool   `json:"issues_enabled"`
+	MergeRequestsEnabled             bool   `json:"merge_requests_enabled"`
+	WikiEnabled                      bool   `json:"wiki_enabled"`
+}
+
+// Pipeline see https://docs.gitlab.com/ee/api/pipelines.html
+type Pipeline struct {
+	ID         int        `json:"id"`
+	IID        int        `json:"iid"`
+	Status     string     `json:"status"`
+	Source     string     `json:"source"`
+	Ref        string     `json:"ref"`
+	SHA        string     `json:"sha"`
+	WebURL     string     `json:"web_url"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	User       *User      `json:"user,omitempty"`
+
+	Jobs []Job `json:"jobs,omitempty"`
+	// Deployments are the ids of the deployments created by the pipeline
+	Deployments []string `json:"deployments,omitempty"`
+}
+
+// Job see https://docs.gitlab.com/ee/api/jobs.html
+type Job struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	Stage         string     `json:"stage"`
+	Status        string     `json:"status"`
+	Ref           string     `json:"ref"`
+	AllowFailure  bool       `json:"allow_failure"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	WebURL        string     `json:"web_url"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	// Duration in seconds
+	Duration       float64 `json:"duration,omitempty"`
+	QueuedDuration float64 `json:"queued_duration,omitempty"`
+	Runner         any     `json:"runner,omitempty"`
+}
+
+// Environment see https://docs.gitlab.com/ee/api/environments.html
+type Environment struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	ExternalURL string     `json:"external_url,omitempty"`
+	State       string     `json:"state"`
+	Tier        string     `json:"tier,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	AutoStopAt  *time.Time `json:"auto_stop_at,omitempty"`
+}
+
+// Deployment see https://docs.gitlab.com/ee/api/deployments.html
+type Deployment struct {
+	ID          int       `json:"id"`
+	IID         int       `json:"iid"`
+	Ref         string    `json:"ref"`
+	SHA         string    `json:"sha"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	User        *User     `json:"user,omitempty"`
+	Environment struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"environment"`
+	Deployable *struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Status   string `json:"status"`
+		Pipeline struct {
+			ID int `json:"id"`
+		} `json:"pipeline"`
+	} `json:"deployable,omitempty"`
+}
+
+// MergeRequest see https://docs.gitlab.com/ee/api/merge_requests.html
+type MergeRequest struct {
+	ID             int        `json:"id"`
+	IID            int        `json:"iid"`
+	Title          string     `json:"title"`
+	State          string     `json:"state"`
+	SourceBranch   string     `json:"source_branch"`
+	TargetBranch   string     `json:"target_branch"`
+	SHA            string     `json:"sha"`
+	MergeCommitSHA string     `json:"merge_commit_sha,omitempty"`
+	WebURL         string     `json:"web_url"`
+	Author         *User      `json:"author,omitempty"`
+	MergedBy       *User      `json:"merged_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	MergedAt       *time.Time `json:"merged_at,omitempty"`
+}
+
+// ProtectedBranch see https://docs.gitlab.com/ee/api/protected_branches.html
+type ProtectedBranch struct {
+	ID                        int    `json:"id"`
+	Name                      string `json:"name"`
+	PushAccessLevels          any    `json:"push_access_levels,omitempty"`
+	MergeAccessLevels         any    `json:"merge_access_levels,omitempty"`
+	UnprotectAccessLevels     any    `json:"unprotect_access_levels,omitempty"`
+	AllowForcePush            bool   `json:"allow_force_push"`
+	CodeOwnerApprovalRequired bool   `json:"code_owner_approval_required"`
+}
+
+// Vulnerability see https://docs.gitlab.com/ee/api/vulnerabilities.html
+type Vulnerability struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	State       string     `json:"state"`
+	Severity    string     `json:"severity"`
+	ReportType  string     `json:"report_type"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DetectedAt  *time.Time `json:"detected_at,omitempty"`
+	Finding     any        `json:"finding,omitempty"`
+}
+
+type GitLabClient struct {
+	*resty.Client
+	api.ScrapeContext
+}
+
+func NewGitLabClient(ctx api.ScrapeContext, config v1.GitLab) (*GitLabClient, error) {
+	var token string
+	baseURL := config.GetURL()
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		token = connection.Password
+		if connection.URL != "" {
+			baseURL = connection.URL
+		}
+	} else {
+		token, err = ctx.GetEnvValueFromCache(config.PersonalAccessToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client := resty.New().
+		SetBaseURL(fmt.Sprintf("%s/api/v4", baseURL)).
+		SetHeader("PRIVATE-TOKEN", token)
+
+	return &GitLabClient{
+		ScrapeContext: ctx,
+		Client:        client,
+	}, nil
+}
+
+// errNotAvailable is returned when a feature isn't available on the gitlab tier
+// or isn't accessible with the token.
+var errNotAvailable = fmt.Errorf("not available")
+
+// pageSize is the maximum number of items gitlab returns per page
+const pageSize = 100
+
+// getAll follows the X-Next-Page header of the responses, see
+// https://docs.gitlab.com/ee/api/rest/index.html#pagination
+func getAll[T any](gl *GitLabClient, path string, params map[string]string) ([]T, error) {
+	var all []T
+	page := "1"
+	for page != "" {
+		var result []T
+		resp, err := gl.R().
+			SetQueryParams(params).
+			SetQueryParam("per_page", fmt.Sprint(pageSize)).
+			SetQueryParam("page", page).
+			SetResult(&result).
+			Get(path)
+		if err != nil {
+			return nil, err
+		}
+		switch resp.StatusCode() {
+		case http.StatusForbidden, http.StatusNotFound:
+			return nil, fmt.Errorf("%w: %s", errNotAvailable, string(resp.Body()))
+		}
+		if resp.IsError() {
+			return nil, fmt.Errorf("received non 2xx status code from gitlab: %s", string(resp.Body()))
+		}
+
+		all = append(all, result...)
+		page = resp.Header().Get("X-Next-Page")
+	}
+	return all, nil
+}
+
+// GetProjects returns the projects of the group including its subgroups,
+// or the projects the token is a member of if no group is given.
+func (gl *GitLabClient) GetProjects(group string, archived bool) ([]Project, error) {
+	params := map[string]string{}
+	if !archived {
+		params["archived"] = "false"
+	}
+	if group == "" {
+		params["membership"] = "true"
+		return getAll[Project](gl, "/projects", params)
+	}
+	params["include_subgroups"] = "true"
+	return getAll[Project](gl, fmt.Sprintf("/groups/%s/projects", url.PathEscape(group)), params)
+}
+
+// GetPipelines returns the pipelines of a project updated after the given time, oldest first.
+// All the pipelines are returned when the time is zero.
+func (gl *GitLabClient) GetPipelines(project int, updatedAfter time.Time) ([]Pipeline, error) {
+	params := map[string]string{"order_by": "updated_at", "sort": "asc"}
+	if !updatedAfter.IsZero() {
+		params["updated_after"] = updatedAfter.UTC().Format(time.RFC3339)
+	}
+	return getAll[Pipeline](gl, fmt.Sprintf("/projects/%d/pipelines", project), params)
+}
+
+func (gl *GitLabClient) GetPipelineJobs(project, pipeline int) ([]Job, error) {
+	return getAll[Job](gl, fmt.Sprintf("/projects/%d/pipelines/%d/jobs", project, pipeline), map[string]string{"include_retried": "true"})
+}
+
+func (gl *GitLabClient) GetEnvironments(project int) ([]Environment, error) {
+	return getAll[Environment](gl, fmt.Sprintf("/projects/%d/environments", project), nil)
+}
+
+// GetDeployments returns the latest page of deployments of a project
+func (gl *GitLabClient) GetDeployments(project int) ([]Deployment, error) {
+	var deployments []Deployment
+	resp, err := gl.R().
+		SetQueryParams(map[string]string{"order_by": "updated_at", "sort": "desc", "per_page": fmt.Sprint(pageSize)}).
+		SetResult(&deployments).
+		Get(fmt.Sprintf("/projects/%d/deployments", project))
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("received non 2xx status code from gitlab: %s", string(resp.Body()))
+	}
+	return deployments, nil
+}
+
+// GetMergedMergeRequests returns the merge requests of a project merged and updated after the given time
+func (gl *GitLabClient) GetMergedMergeRequests(project int, updatedAfter time.Time) ([]MergeRequest, error) {
+	params := map[string]string{"state": "merged", "order_by": "updated_at", "sort": "asc"}
+	if !updatedAfter.IsZero() {
+		params["updated_after"] = updatedAfter.UTC().Format(time.RFC3339)
+	}
+	return getAll[MergeRequest](gl, fmt.Sprintf("/projects/%d/merge_requests", project), params)
+}
+
+func (gl *GitLabClient) GetProtectedBranches(project int) ([]ProtectedBranch, error) {
+	return getAll[ProtectedBranch](gl, fmt.Sprintf("/projects/%d/protected_branches", project), nil)
+}
+
+// GetVulnerabilities returns the detected and confirmed vulnerabilities of a project
+func (gl *GitLabClient) GetVulnerabilities(project int) ([]Vulnerability, error) {
+	var vulnerabilities []Vulnerability
+	for _, state := range []string{"detected", "confirmed"} {
+		page, err := getAll[Vulnerability](gl, fmt.Sprintf("/projects/%d/vulnerabilities", project), map[string]string{"state": state})
+		if err != nil {
+			return nil, err
+		}
+		vulnerabilities = append(vulnerabilities, page...)
+	}
+	return vulnerabilities, nil
+}
@@ -0,0 +1,369 @@
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+)
+
+const (
+	ProjectType         = "GitLab::Project"
+	EnvironmentType     = "GitLab::Environment"
+	DeploymentType      = "GitLab::Deployment"
+	ProtectedBranchType = "GitLab::ProtectedBranch"
+)
+
+// Cursor types that keep track of the last update time of the pipelines
+// and merge requests fetched per project.
+const (
+	pipelineCursorType     = "gitlab/pipeline"
+	mergeRequestCursorType = "gitlab/mergerequest"
+)
+
+type GitLabScraper struct {
+}
+
+func (gl GitLabScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.GitLab) > 0
+}
+
+// Scrape fetches the projects of a gitlab instance, with their pipelines and merged merge requests as changes.
+func (gl GitLabScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.GitLab {
+		client, err := NewGitLabClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create gitlab client for %s", config.GetURL())
+			continue
+		}
+
+		projects, err := client.GetProjects(config.Group, config.IncludeArchived)
+		if err != nil {
+			results.Errorf(err, "failed to get gitlab projects of %s", config.Group)
+			continue
+		}
+
+		for _, project := range projects {
+			if !collections.MatchItems(project.PathWithNamespace, config.Projects...) {
+				continue
+			}
+
+			logger.Debugf("scraping gitlab project %s", project.PathWithNamespace)
+			results = append(results, scrapeProject(client, config, project)...)
+		}
+	}
+	return results
+}
+
+func scrapeProject(client *GitLabClient, config v1.GitLab, project Project) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	deployments, deploymentsByPipeline := scrapeDeployments(client, config, project)
+	results = append(results, deployments...)
+
+	var changes []v1.ChangeResult
+	if pipelines, err := getNewPipelines(client, config, project, deploymentsByPipeline, &results); err != nil {
+		results.Errorf(err, "failed to get pipelines of %s", project.PathWithNamespace)
+	} else {
+		changes = append(changes, pipelines...)
+	}
+
+	if mergeRequests, err := getNewMergeRequests(client, config, project, &results); err != nil {
+		results.Errorf(err, "failed to get merge requests of %s", project.PathWithNamespace)
+	} else {
+		changes = append(changes, mergeRequests...)
+	}
+
+	results = append(results, v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ConfigClass: "Repository",
+		Config:      project,
+		Type:        ProjectType,
+		ID:          project.PathWithNamespace,
+		Name:        project.Name,
+		CreatedAt:   &project.CreatedAt,
+		Aliases:     []string{fmt.Sprint(project.ID), project.WebURL},
+		Tags:        map[string]string{"visibility": project.Visibility},
+		Changes:     changes,
+	})
+
+	if branches, err := client.GetProtectedBranches(project.ID); err != nil {
+		if !errors.Is(err, errNotAvailable) {
+			results.Errorf(err, "failed to get protected branches of %s", project.PathWithNamespace)
+		}
+	} else {
+		for _, branch := range branches {
+			results = append(results, v1.ScrapeResult{
+				BaseScraper:      config.BaseScraper,
+				ConfigClass:      "BranchProtection",
+				Config:           branch,
+				Type:             ProtectedBranchType,
+				ID:               fmt.Sprintf("%s/%s", project.PathWithNamespace, branch.Name),
+				Name:             branch.Name,
+				Tags:             map[string]string{"project": project.PathWithNamespace},
+				ParentExternalID: project.PathWithNamespace,
+				ParentType:       ProjectType,
+			})
+		}
+	}
+
+	if vulnerabilities, err := client.GetVulnerabilities(project.ID); err != nil {
+		if errors.Is(err, errNotAvailable) {
+			logger.Debugf("vulnerabilities are not available for %s: %v", project.PathWithNamespace, err)
+		} else {
+			results.Errorf(err, "failed to get vulnerabilities of %s", project.PathWithNamespace)
+		}
+	} else {
+		for _, vulnerability := range vulnerabilities {
+			// the title of a vulnerability is not unique within a project, e.g. the same CVE in several images
+			analysis := results.Analysis(fmt.Sprintf("%s #%d", vulnerability.ReportType, vulnerability.ID), ProjectType, project.PathWithNamespace)
+			analysis.AnalysisType = models.AnalysisTypeSecurity
+			analysis.Severity = vulnerabilitySeverity(vulnerability.Severity)
+			analysis.Source = fmt.Sprintf("GitLab %s", vulnerability.ReportType)
+			analysis.Status = models.AnalysisStatusOpen
+			analysis.Summary = vulnerability.Title
+			analysis.Analysis = v1.NewJSON(vulnerability)
+			if vulnerability.Description != "" {
+				analysis.Message(vulnerability.Description)
+			}
+		}
+	}
+
+	return results
+}
+
+// getNewPipelines returns the pipelines of the project updated since the last scrape, followed by their jobs.
+func getNewPipelines(client *GitLabClient, config v1.GitLab, project Project, deploymentsByPipeline map[int][]string, results *v1.ScrapeResults) ([]v1.ChangeResult, error) {
+	since := getCursor(client, config, pipelineCursorType, project)
+	pipelines, err := client.GetPipelines(project.ID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []v1.ChangeResult
+	lastUpdatedAt := since
+	for _, _pipeline := range pipelines {
+		var pipeline = _pipeline
+		if pipeline.Jobs, err = client.GetPipelineJobs(project.ID, pipeline.ID); err != nil {
+			logger.Warnf("failed to get jobs of pipeline %s/%d: %v", project.PathWithNamespace, pipeline.ID, err)
+		}
+		pipeline.Deployments = deploymentsByPipeline[pipeline.ID]
+
+		severity := "info"
+		if pipeline.Status == "failed" {
+			severity = "failed"
+		}
+
+		var createdBy *string
+		if pipeline.User != nil {
+			createdBy = &pipeline.User.Username
+		}
+
+		changes = append(changes, v1.ChangeResult{
+			ExternalID:       project.PathWithNamespace,
+			ConfigType:       ProjectType,
+			ExternalChangeID: fmt.Sprintf("%s/pipeline/%d", project.PathWithNamespace, pipeline.ID),
+			ChangeType:       "GitLabPipeline",
+			Summary:          fmt.Sprintf("#%d %s on %s", pipeline.IID, pipeline.Status, pipeline.Ref),
+			Severity:         severity,
+			Source:           pipeline.WebURL,
+			CreatedBy:        createdBy,
+			CreatedAt:        &pipeline.CreatedAt,
+			Details:          v1.NewJSON(pipeline),
+			// Pipelines are fetched again whenever they're updated, e.g. when they finish.
+			UpdateExisting: true,
+		})
+
+		for _, job := range pipeline.Jobs {
+			changes = append(changes, jobChange(project, pipeline, job))
+		}
+
+		if pipeline.UpdatedAt.After(lastUpdatedAt) {
+			lastUpdatedAt = pipeline.UpdatedAt
+		}
+	}
+
+	saveCursor(client, results, pipelineCursorType, project, since, lastUpdatedAt)
+	return changes, nil
+}
+
+// getNewMergeRequests returns the merge requests of the project merged since the last scrape.
+func getNewMergeRequests(client *GitLabClient, config v1.GitLab, project Project, results *v1.ScrapeResults) ([]v1.ChangeResult, error) {
+	since := getCursor(client, config, mergeRequestCursorType, project)
+	mergeRequests, err := client.GetMergedMergeRequests(project.ID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []v1.ChangeResult
+	lastUpdatedAt := since
+	for _, _mergeRequest := range mergeRequests {
+		var mergeRequest = _mergeRequest
+		if mergeRequest.UpdatedAt.After(lastUpdatedAt) {
+			lastUpdatedAt = mergeRequest.UpdatedAt
+		}
+		if mergeRequest.MergedAt == nil {
+			continue
+		}
+
+		var createdBy *string
+		if mergeRequest.MergedBy != nil {
+			createdBy = &mergeRequest.MergedBy.Username
+		} else if mergeRequest.Author != nil {
+			createdBy = &mergeRequest.Author.Username
+		}
+
+		changes = append(changes, v1.ChangeResult{
+			ExternalID:       project.PathWithNamespace,
+			ConfigType:       ProjectType,
+			ExternalChangeID: fmt.Sprintf("%s/merge_request/%d", project.PathWithNamespace, mergeRequest.IID),
+			ChangeType:       "MergeRequestMerged",
+			Summary:          fmt.Sprintf("!%d %s", mergeRequest.IID, mergeRequest.Title),
+			Severity:         "info",
+			Source:           mergeRequest.WebURL,
+			CreatedBy:        createdBy,
+			CreatedAt:        mergeRequest.MergedAt,
+			Details:          v1.NewJSON(mergeRequest),
+		})
+	}
+
+	saveCursor(client, results, mergeRequestCursorType, project, since, lastUpdatedAt)
+	return changes, nil
+}
+
+// scrapeDeployments returns the environments and the latest deployments of the project,
+// with the ids of the deployments created by each pipeline.
+func scrapeDeployments(client *GitLabClient, config v1.GitLab, project Project) (v1.ScrapeResults, map[int][]string) {
+	var results v1.ScrapeResults
+	var deploymentsByPipeline = make(map[int][]string)
+
+	environments, err := client.GetEnvironments(project.ID)
+	if err != nil {
+		if !errors.Is(err, errNotAvailable) {
+			results.Errorf(err, "failed to get environments of %s", project.PathWithNamespace)
+		}
+		return results, deploymentsByPipeline
+	}
+	if len(environments) == 0 {
+		return results, deploymentsByPipeline
+	}
+
+	for _, _environment := range environments {
+		var environment = _environment
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ConfigClass:      "Environment",
+			Config:           environment,
+			Type:             EnvironmentType,
+			ID:               fmt.Sprintf("%s/%s", project.PathWithNamespace, environment.Name),
+			Name:             environment.Name,
+			Status:           environment.State,
+			CreatedAt:        &environment.CreatedAt,
+			Tags:             map[string]string{"project": project.PathWithNamespace},
+			ParentExternalID: project.PathWithNamespace,
+			ParentType:       ProjectType,
+		})
+	}
+
+	deployments, err := client.GetDeployments(project.ID)
+	if err != nil {
+		results.Errorf(err, "failed to get deployments of %s", project.PathWithNamespace)
+		return results, deploymentsByPipeline
+	}
+
+	for _, _deployment := range deployments {
+		var deployment = _deployment
+		id := fmt.Sprintf("%s/deployments/%d", project.PathWithNamespace, deployment.ID)
+		if deployment.Deployable != nil {
+			pipeline := deployment.Deployable.Pipeline.ID
+			deploymentsByPipeline[pipeline] = append(deploymentsByPipeline[pipeline], id)
+		}
+
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ConfigClass:      "Deployment",
+			Config:           deployment,
+			Type:             DeploymentType,
+			ID:               id,
+			Name:             fmt.Sprintf("%s@%s", deployment.Environment.Name, deployment.Ref),
+			Status:           deployment.Status,
+			CreatedAt:        &deployment.CreatedAt,
+			Tags:             map[string]string{"project": project.PathWithNamespace, "environment": deployment.Environment.Name},
+			ParentExternalID: fmt.Sprintf("%s/%s", project.PathWithNamespace, deployment.Environment.Name),
+			ParentType:       EnvironmentType,
+		})
+	}
+
+	return results, deploymentsByPipeline
+}
+
+// jobChange records a job of a pipeline, it's updated along with its pipeline.
+func jobChange(project Project, pipeline Pipeline, job Job) v1.ChangeResult {
+	severity := "info"
+	if job.Status == "failed" && !job.AllowFailure {
+		severity = "failed"
+	}
+
+	createdAt := job.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = pipeline.CreatedAt
+	}
+
+	return v1.ChangeResult{
+		ExternalID:       project.PathWithNamespace,
+		ConfigType:       ProjectType,
+		ExternalChangeID: fmt.Sprintf("%s/job/%d", project.PathWithNamespace, job.ID),
+		ChangeType:       "GitLabJob",
+		Summary:          fmt.Sprintf("%s %s in #%d on %s", job.Name, job.Status, pipeline.IID, pipeline.Ref),
+		Severity:         severity,
+		Source:           job.WebURL,
+		CreatedAt:        &createdAt,
+		Details:          v1.NewJSON(job),
+		UpdateExisting:   true,
+	}
+}
+
+// getCursor returns the update time to fetch from, bounded by the max age on the first run
+func getCursor(client *GitLabClient, config v1.GitLab, cursorType string, project Project) time.Time {
+	since := time.Now().Add(-config.GetMaxAge()).UTC()
+	if cursor, err := client.GetCursor(cursorType, project.PathWithNamespace); err != nil {
+		logger.Warnf("failed to get %s cursor for %s: %v", cursorType, project.PathWithNamespace, err)
+	} else if cursor != "" {
+		since, _ = time.Parse(time.RFC3339, cursor)
+	}
+	return since
+}
+
+// saveCursor saves the cursor once the results are saved
+func saveCursor(client *GitLabClient, results *v1.ScrapeResults, cursorType string, project Project, since, next time.Time) {
+	if !next.After(since) {
+		return
+	}
+	results.OnSave(func() error {
+		if err := client.SaveCursor(cursorType, project.PathWithNamespace, next.Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("failed to save %s cursor for %s: %w", cursorType, project.PathWithNamespace, err)
+		}
+		return nil
+	})
+}
+
+func vulnerabilitySeverity(severity string) models.Severity {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return models.SeverityCritical
+	case "high":
+		return models.SeverityHigh
+	case "medium":
+		return models.SeverityMedium
+	case "low":
+		return models.SeverityLow
+	}
+	return models.SeverityInfo
+}
@@ -0,0 +1,124 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+)
+
+func TestScrapeProject(t *testing.T) {
+	responses := map[string]string{
+		"/api/v4/groups/acme/projects?page=1":                       `[{"id": 1, "name": "api", "path_with_namespace": "acme/api", "created_at": "2020-01-01T00:00:00Z"}]`,
+		"/api/v4/groups/acme/projects?page=2":                       `[{"id": 2, "name": "web", "path_with_namespace": "acme/web", "created_at": "2020-01-01T00:00:00Z"}]`,
+		"/api/v4/projects/1/environments?page=1":                    `[{"id": 3, "name": "production", "state": "available", "created_at": "2020-01-01T00:00:00Z"}]`,
+		"/api/v4/projects/1/deployments?page=":                      `[{"id": 4, "ref": "main", "status": "success", "environment": {"id": 3, "name": "production"}, "deployable": {"id": 6, "pipeline": {"id": 5}}}]`,
+		"/api/v4/projects/1/pipelines?page=1":                       `[{"id": 5, "iid": 10, "status": "failed", "ref": "main", "user": {"username": "jdoe"}, "created_at": "2023-01-01T00:00:00Z", "updated_at": "2023-01-01T00:10:00Z"}]`,
+		"/api/v4/projects/1/pipelines/5/jobs?page=1":                `[{"id": 6, "name": "deploy", "stage": "deploy", "status": "failed", "duration": 12.5}]`,
+		"/api/v4/projects/1/merge_requests?page=1":                  `[{"id": 7, "iid": 2, "title": "Add health check", "state": "merged", "merged_by": {"username": "asmith"}, "merged_at": "2023-01-02T00:00:00Z", "updated_at": "2023-01-02T00:00:00Z"}]`,
+		"/api/v4/projects/1/protected_branches?page=1":              `[{"id": 8, "name": "main", "allow_force_push": false}]`,
+		"/api/v4/projects/1/vulnerabilities?page=1&state=detected":  `[{"id": 9, "title": "CVE-2023-1234 in openssl", "severity": "high", "report_type": "container_scanning", "state": "detected"}]`,
+		"/api/v4/projects/1/vulnerabilities?page=1&state=confirmed": `[]`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		key := fmt.Sprintf("%s?page=%s", r.URL.Path, r.URL.Query().Get("page"))
+		if state := r.URL.Query().Get("state"); state != "" && state != "merged" {
+			key += "&state=" + state
+		}
+		body, ok := responses[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if key == "/api/v4/groups/acme/projects?page=1" {
+			w.Header().Set("X-Next-Page", "2")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	config := v1.GitLab{
+		URL:                 server.URL,
+		Group:               "acme",
+		Projects:            []string{"acme/api"},
+		MaxAge:              "87600h",
+		PersonalAccessToken: types.EnvVar{ValueStatic: "token"},
+	}
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{
+		Spec: v1.ScraperSpec{GitLab: []v1.GitLab{config}},
+	})
+
+	results := GitLabScraper{}.Scrape(ctx)
+
+	items := make(map[string]v1.ScrapeResult)
+	var analyses []v1.AnalysisResult
+	for _, result := range results {
+		if result.Error != nil {
+			t.Fatalf("unexpected error: %v", result.Error)
+		}
+		if result.AnalysisResult != nil {
+			analyses = append(analyses, *result.AnalysisResult)
+			continue
+		}
+		items[result.Type+"/"+result.ID] = result
+	}
+
+	for _, id := range []string{
+		ProjectType + "/acme/api",
+		EnvironmentType + "/acme/api/production",
+		DeploymentType + "/acme/api/deployments/4",
+		ProtectedBranchType + "/acme/api/main",
+	} {
+		if _, ok := items[id]; !ok {
+			t.Errorf("expected %s to be scraped", id)
+		}
+	}
+	if _, ok := items[ProjectType+"/acme/web"]; ok {
+		t.Errorf("acme/web should have been filtered out")
+	}
+
+	changes := items[ProjectType+"/acme/api"].Changes
+	if len(changes) != 3 {
+		t.Fatalf("expected a pipeline, its job and a merge request, got %v", changes)
+	}
+	if pipeline := changes[0]; pipeline.Severity != "failed" || *pipeline.CreatedBy != "jdoe" || len(pipeline.Details["jobs"].([]any)) != 1 {
+		t.Errorf("unexpected pipeline change %+v", pipeline)
+	}
+	if deployments, _ := changes[0].Details["deployments"].([]any); len(deployments) != 1 || deployments[0] != "acme/api/deployments/4" {
+		t.Errorf("expected the pipeline to be linked to its deployment, got %v", changes[0].Details["deployments"])
+	}
+	if job := changes[1]; job.ChangeType != "GitLabJob" || job.Severity != "failed" || job.ExternalChangeID != "acme/api/job/6" || !job.UpdateExisting {
+		t.Errorf("unexpected job change %+v", job)
+	}
+	if mergeRequest := changes[2]; mergeRequest.ChangeType != "MergeRequestMerged" || *mergeRequest.CreatedBy != "asmith" {
+		t.Errorf("unexpected merge request change %+v", mergeRequest)
+	}
+
+	if len(analyses) != 1 || analyses[0].Severity != models.SeverityHigh || analyses[0].Analyzer != "container_scanning #9" {
+		t.Errorf("expected a high vulnerability, got %+v", analyses)
+	}
+
+	// pipelines are fetched incrementally, once the results are saved
+	if cursor, _ := ctx.GetCursor(pipelineCursorType, "acme/api"); cursor != "" {
+		t.Errorf("expected no cursor before the results are saved, got %q", cursor)
+	}
+	if err := results.Saved(); err != nil {
+		t.Fatal(err)
+	}
+	if cursor, _ := ctx.GetCursor(pipelineCursorType, "acme/api"); cursor != "2023-01-01T00:10:00Z" {
+		t.Errorf("unexpected pipeline cursor %q", cursor)
+	}
+}
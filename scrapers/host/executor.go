@@ -0,0 +1,166 @@
+package host
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// executor runs shell commands on a host
+type executor interface {
+	Run(command string) (string, error)
+	Close() error
+}
+
+type localExecutor struct {
+	ctx     context.Context
+	timeout time.Duration
+}
+
+func (l localExecutor) Run(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(l.ctx, l.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("%s: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (l localExecutor) Close() error {
+	return nil
+}
+
+type sshExecutor struct {
+	client  *ssh.Client
+	timeout time.Duration
+}
+
+func (s sshExecutor) Run(command string) (string, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout, session.Stderr = &stdout, &stderr
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+
+	select {
+	case err = <-done:
+	case <-time.After(s.timeout):
+		_ = session.Signal(ssh.SIGKILL)
+		err = fmt.Errorf("timed out after %s", s.timeout)
+	}
+	if err != nil {
+		return stdout.String(), fmt.Errorf("%s: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (s sshExecutor) Close() error {
+	return s.client.Close()
+}
+
+// sshConfig builds the client config of the hosts from the connection or the credentials of the scraper
+func sshConfig(ctx api.ScrapeContext, config v1.HostScraper) (*ssh.ClientConfig, error) {
+	var username, password, privateKey string
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		username, password, privateKey = connection.Username, connection.Password, connection.Certificate
+	} else {
+		if username, err = ctx.GetEnvValueFromCache(config.Username); err != nil {
+			return nil, err
+		}
+		if password, err = ctx.GetEnvValueFromCache(config.Password); err != nil {
+			return nil, err
+		}
+		if privateKey, err = ctx.GetEnvValueFromCache(config.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var auth []ssh.AuthMethod
+	if privateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("a password or private key is required to connect over ssh")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	knownHosts, err := ctx.GetEnvValueFromCache(config.KnownHosts)
+	if err != nil {
+		return nil, err
+	} else if knownHosts != "" {
+		if hostKeyCallback, err = knownHostsCallback(knownHosts); err != nil {
+			return nil, err
+		}
+	} else if config.InsecureSkipHostKeyVerification {
+		logger.Warnf("host keys are not verified, set knownHosts to verify them")
+		hostKeyCallback = ssh.InsecureIgnoreHostKey() //nolint:gosec
+	} else {
+		return nil, fmt.Errorf("knownHosts is required to verify the host keys, unless insecureSkipHostKeyVerification is set")
+	}
+
+	return &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         config.GetTimeout(),
+	}, nil
+}
+
+// knownHostsCallback verifies host keys against the contents of a known_hosts file
+func knownHostsCallback(knownHosts string) (ssh.HostKeyCallback, error) {
+	// knownhosts only reads files, they're read once so the file can be removed right away
+	file, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(knownHosts); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	return knownhosts.New(file.Name())
+}
+
+func dial(host string, config *ssh.ClientConfig) (*sshExecutor, error) {
+	address := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		address = net.JoinHostPort(host, "22")
+	}
+	client, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+	return &sshExecutor{client: client, timeout: config.Timeout}, nil
+}
@@ -0,0 +1,183 @@
+package host
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const (
+	ServerType  = "Host::Server"
+	ServiceType = "Host::Service"
+	FileType    = "Host::File"
+)
+
+// filePatternChars are the characters allowed in the file patterns, which are expanded by the shell.
+// Quotes, variables, command substitutions, separators and whitespace are not allowed.
+const filePatternChars = `a-z A-Z 0-9 / . _ - + @ , = : * ? [ ]`
+
+var filePatternRegex = regexp.MustCompile(`^/[a-zA-Z0-9/._\-+@,=:*?\[\]]+$`)
+
+type Scraper struct {
+}
+
+func (s Scraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Host) > 0
+}
+
+// Scrape runs commands on the hosts (or the local host) to collect their OS, packages, services, ports, users, groups & files
+func (s Scraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Host {
+		if len(config.Hosts) == 0 {
+			results = append(results, scrapeHost(config, localExecutor{ctx: ctx, timeout: config.GetTimeout()}, "")...)
+			continue
+		}
+
+		sshConfig, err := sshConfig(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create ssh config")
+			continue
+		}
+
+		for _, host := range config.Hosts {
+			executor, err := dial(host, sshConfig)
+			if err != nil {
+				results.Errorf(err, "failed to connect to %s", host)
+				continue
+			}
+			results = append(results, scrapeHost(config, executor, host)...)
+			if err := executor.Close(); err != nil {
+				logger.Warnf("failed to close connection to %s: %v", host, err)
+			}
+		}
+	}
+	return results
+}
+
+// scrapeHost collects the config of a host. Only the hostname is required,
+// the failures of the other commands (e.g. systemctl on hosts without systemd) are logged.
+func scrapeHost(config v1.HostScraper, exec executor, address string) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	hostname, err := exec.Run("hostname")
+	if err != nil {
+		results.Errorf(err, "failed to get hostname of %s", address)
+		return results
+	}
+	server := Server{Hostname: strings.TrimSpace(hostname)}
+
+	run := func(name, command string) string {
+		output, err := exec.Run(command)
+		if err != nil {
+			logger.Debugf("failed to get %s of %s: %v", name, server.Hostname, err)
+		}
+		return output
+	}
+
+	server.OS = parseOSRelease(run("os release", "cat /etc/os-release"))
+	server.Kernel = strings.TrimSpace(run("kernel", "uname -r"))
+	server.Arch = strings.TrimSpace(run("architecture", "uname -m"))
+
+	if host, _, err := net.SplitHostPort(address); err == nil && net.ParseIP(host) != nil {
+		server.IP = host
+	} else if net.ParseIP(address) != nil {
+		server.IP = address
+	} else if ips := strings.Fields(run("ip", "hostname -I")); len(ips) > 0 {
+		server.IP = ips[0]
+	}
+
+	switch {
+	case run("package manager", "command -v dpkg-query") != "":
+		server.PackageManager = "dpkg"
+		server.Packages = parseDpkg(run("packages", `dpkg-query -W -f='${Package}\t${Version}\t${Architecture}\t${db:Status-Status}\n'`))
+	case run("package manager", "command -v rpm") != "":
+		server.PackageManager = "rpm"
+		server.Packages = parseRpm(run("packages", `rpm -qa --queryformat '%{NAME}\t%{VERSION}-%{RELEASE}\t%{ARCH}\n'`))
+	case run("package manager", "command -v apk") != "":
+		server.PackageManager = "apk"
+		server.Packages = parseApk(run("packages", "apk info -v"))
+	}
+
+	// debian based distributions list the packages that require a reboot in /var/run/reboot-required.pkgs
+	if reboot := lines(run("pending reboot", "if [ -f /var/run/reboot-required ]; then echo reboot-required; cat /var/run/reboot-required.pkgs 2>/dev/null; fi; true")); len(reboot) > 0 {
+		server.RebootRequired = true
+		for i, pkg := range server.Packages {
+			for _, name := range reboot[1:] {
+				if pkg.Name == name {
+					server.Packages[i].PendingReboot = true
+				}
+			}
+		}
+	}
+
+	server.Ports = parseSS(run("listening ports", "ss -Htlnup"))
+	server.Users = parsePasswd(run("users", "getent passwd || cat /etc/passwd"))
+	server.Groups = parseGroups(run("groups", "getent group || cat /etc/group"))
+
+	result := v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ID:          server.Hostname,
+		Name:        server.Hostname,
+		Type:        ServerType,
+		ConfigClass: "Server",
+		Config:      server,
+		Tags:        map[string]string{},
+	}
+	if platform := server.GetPlatform(); platform != "" {
+		result.Tags["platform"] = platform
+	}
+	if address != "" && address != server.Hostname {
+		result.Aliases = []string{address}
+	}
+	results = append(results, result)
+
+	for _, service := range parseSystemd(run("services", "systemctl list-units --type=service --all --no-legend --no-pager --plain")) {
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ID:               server.Hostname + "/" + service.Name,
+			Name:             service.Name,
+			Type:             ServiceType,
+			ConfigClass:      "Service",
+			Config:           service,
+			Status:           service.Active,
+			Description:      service.Description,
+			ParentExternalID: server.Hostname,
+			ParentType:       ServerType,
+			// the sub state changes while the service is starting or reloading
+			Ignore: []string{"sub"},
+		})
+	}
+
+	for _, pattern := range config.Files {
+		if !filePatternRegex.MatchString(pattern) {
+			results.Errorf(fmt.Errorf("only absolute paths with the characters %s are supported", filePatternChars), "invalid file pattern %q", pattern)
+			continue
+		}
+		// the pattern is not quoted so that the shell expands the globs
+		for _, path := range lines(run("files", fmt.Sprintf(`for f in %s; do [ -f "$f" ] && echo "$f"; done; true`, pattern))) {
+			content, err := exec.Run("cat " + shellQuote(path))
+			if err != nil {
+				results.Errorf(err, "failed to read %s of %s", path, server.Hostname)
+				continue
+			}
+			results = append(results, v1.ScrapeResult{
+				BaseScraper:      config.BaseScraper,
+				ID:               server.Hostname + ":" + path,
+				Name:             path,
+				Type:             FileType,
+				ConfigClass:      "File",
+				Config:           map[string]string{"path": path, "content": content},
+				ParentExternalID: server.Hostname,
+				ParentType:       ServerType,
+			})
+		}
+	}
+
+	return results
+}
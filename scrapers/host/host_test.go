@@ -0,0 +1,133 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/types"
+)
+
+// fakeExecutor returns the output of the first command with a matching prefix
+type fakeExecutor map[string]string
+
+func (f fakeExecutor) Run(command string) (string, error) {
+	for prefix, output := range f {
+		if strings.HasPrefix(command, prefix) {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("%s: command not found", command)
+}
+
+func (f fakeExecutor) Close() error {
+	return nil
+}
+
+var ubuntu = fakeExecutor{
+	"hostname":                   "web-1\n",
+	"cat /etc/os-release":        "NAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"22.04\"\n",
+	"uname -r":                   "5.15.0-91-generic\n",
+	"uname -m":                   "x86_64\n",
+	"command -v dpkg-query":      "/usr/bin/dpkg-query\n",
+	"dpkg-query":                 "openssl\t3.0.2-0ubuntu1.12\tamd64\tinstalled\nlibc6\t2.35-0ubuntu3.5\tamd64\thalf-configured\n",
+	"if [ -f /var/run/":          "reboot-required\nlibc6\n",
+	"ss -Htlnup":                 "tcp LISTEN 0 4096 127.0.0.53%lo:53 0.0.0.0:* users:((\"systemd-resolve\",pid=618,fd=14))\ntcp LISTEN 0 128 0.0.0.0:22 0.0.0.0:* users:((\"sshd\",pid=900,fd=3))\n",
+	"getent passwd":              "root:x:0:0:root:/root:/bin/bash\nops:x:1000:1000::/home/ops:/bin/bash\n",
+	"getent group":               "root:x:0:\nsudo:x:27:ops\n",
+	"systemctl list-units":       "ssh.service loaded active running OpenBSD Secure Shell server\n● nginx.service loaded failed failed A high performance web server\n",
+	"for f in /etc/ssh/*":        "/etc/ssh/sshd_config\n",
+	"cat '/etc/ssh/sshd_config'": "PermitRootLogin no\n",
+}
+
+func TestScrapeHost(t *testing.T) {
+	config := v1.HostScraper{Files: []string{"/etc/ssh/*"}}
+	results := scrapeHost(config, ubuntu, "10.0.0.10")
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected a server, 2 services and a file, got %d results", len(results))
+	}
+
+	server, ok := results[0].Config.(Server)
+	if !ok {
+		t.Fatalf("expected a server, got %T", results[0].Config)
+	}
+	if results[0].ID != "web-1" || results[0].Aliases[0] != "10.0.0.10" {
+		t.Errorf("unexpected id %s and aliases %v", results[0].ID, results[0].Aliases)
+	}
+	if server.GetPlatform() != "ubuntu 22.04" || server.IP != "10.0.0.10" || server.PackageManager != "dpkg" {
+		t.Errorf("unexpected server %+v", server)
+	}
+	if !server.RebootRequired || !server.Packages[1].IsPendingReboot() || !server.Packages[1].IsFailed() {
+		t.Errorf("expected libc6 to be failed and pending a reboot: %+v", server.Packages[1])
+	}
+	if len(server.Ports) != 2 || server.Ports[0].Address != "127.0.0.53" || server.Ports[1].Process != "sshd" {
+		t.Errorf("unexpected ports %+v", server.Ports)
+	}
+	if len(server.Users) != 2 || len(server.Groups) != 2 || server.Groups[1].Members[0] != "ops" {
+		t.Errorf("unexpected users %+v and groups %+v", server.Users, server.Groups)
+	}
+
+	nginx := results[2]
+	if nginx.ID != "web-1/nginx.service" || nginx.Status != "failed" || nginx.ParentExternalID != "web-1" {
+		t.Errorf("unexpected service %+v", nginx)
+	}
+
+	file := results[3].Config.(map[string]string)
+	if results[3].ID != "web-1:/etc/ssh/sshd_config" || file["content"] != "PermitRootLogin no\n" {
+		t.Errorf("unexpected file %+v", results[3])
+	}
+}
+
+func TestScrapeHostFilePatterns(t *testing.T) {
+	config := v1.HostScraper{Files: []string{"/etc/ssh/*", "/tmp/*; rm -rf /", "/etc/$(id)", "etc/hosts"}}
+	var errors int
+	for _, r := range scrapeHost(config, ubuntu, "10.0.0.10") {
+		if r.Error != nil {
+			errors++
+		}
+	}
+	if errors != 3 {
+		t.Errorf("expected the patterns that aren't plain globs to be rejected, got %d errors", errors)
+	}
+}
+
+func TestSSHConfigHostKeys(t *testing.T) {
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{})
+	config := v1.HostScraper{Hosts: []string{"10.0.0.10"}, Username: types.EnvVar{ValueStatic: "ops"}, Password: types.EnvVar{ValueStatic: "secret"}}
+
+	if _, err := sshConfig(ctx, config); err == nil {
+		t.Errorf("expected known hosts to be required")
+	}
+
+	config.InsecureSkipHostKeyVerification = true
+	if _, err := sshConfig(ctx, config); err != nil {
+		t.Errorf("expected host keys to be skipped when opted in, got %v", err)
+	}
+}
+
+func TestScrapeHostWithoutHostname(t *testing.T) {
+	results := scrapeHost(v1.HostScraper{}, fakeExecutor{}, "10.0.0.10")
+	if len(results) != 1 || results[0].Error == nil {
+		t.Fatalf("expected an error, got %+v", results)
+	}
+}
+
+func TestParsePackages(t *testing.T) {
+	rpm := parseRpm("bash\t5.1.8-6.el9\tx86_64\ngpg-pubkey\tfd431d51-4ae0493b\t(none)\n")
+	if len(rpm) != 1 || rpm[0].GetTitle() != "bash 5.1.8-6.el9" {
+		t.Errorf("unexpected rpm packages %+v", rpm)
+	}
+
+	apk := parseApk("musl-utils-1.2.4-r2\nbusybox-1.36.1-r5\n")
+	if len(apk) != 2 || apk[0].Name != "musl-utils" || apk[0].Version != "1.2.4-r2" {
+		t.Errorf("unexpected apk packages %+v", apk)
+	}
+}
@@ -0,0 +1,174 @@
+package host
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func lines(output string) []string {
+	var out []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// parseOSRelease parses the KEY="value" lines of /etc/os-release
+func parseOSRelease(output string) map[string]string {
+	release := map[string]string{}
+	for _, line := range lines(output) {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			release[key] = strings.Trim(value, `"'`)
+		}
+	}
+	return release
+}
+
+// parseDpkg parses the output of dpkg-query -W -f='${Package}\t${Version}\t${Architecture}\t${db:Status-Status}\n'
+func parseDpkg(output string) []Package {
+	var packages []Package
+	for _, line := range lines(output) {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			continue
+		}
+		packages = append(packages, Package{Name: fields[0], Version: fields[1], Arch: fields[2], Status: fields[3]})
+	}
+	return packages
+}
+
+// parseRpm parses the output of rpm -qa --queryformat '%{NAME}\t%{VERSION}-%{RELEASE}\t%{ARCH}\n'
+func parseRpm(output string) []Package {
+	var packages []Package
+	for _, line := range lines(output) {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			continue
+		}
+		// gpg-pubkey entries are signing keys, not packages
+		if fields[0] == "gpg-pubkey" {
+			continue
+		}
+		packages = append(packages, Package{Name: fields[0], Version: fields[1], Arch: fields[2]})
+	}
+	return packages
+}
+
+// parseApk parses the output of apk info -v, e.g. busybox-1.36.1-r5
+func parseApk(output string) []Package {
+	var packages []Package
+	for _, line := range lines(output) {
+		release := strings.LastIndex(line, "-")
+		if release <= 0 {
+			continue
+		}
+		version := strings.LastIndex(line[:release], "-")
+		if version <= 0 {
+			continue
+		}
+		packages = append(packages, Package{Name: line[:version], Version: line[version+1:]})
+	}
+	return packages
+}
+
+// parseSystemd parses the output of systemctl list-units --type=service --all --no-legend --no-pager --plain
+func parseSystemd(output string) []Service {
+	var services []Service
+	for _, line := range lines(output) {
+		// failed units are prefixed with ●
+		fields := strings.Fields(strings.TrimPrefix(line, "●"))
+		if len(fields) < 4 {
+			continue
+		}
+		services = append(services, Service{
+			Name:        fields[0],
+			Load:        fields[1],
+			Active:      fields[2],
+			Sub:         fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+	return services
+}
+
+var ssProcess = regexp.MustCompile(`users:\(\("([^"]+)"`)
+
+// parseSS parses the output of ss -Htlnup, e.g.
+// tcp LISTEN 0 4096 127.0.0.53%lo:53 0.0.0.0:* users:(("systemd-resolve",pid=618,fd=14))
+func parseSS(output string) []Port {
+	var ports []Port
+	seen := map[string]bool{}
+	for _, line := range lines(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		address, port, err := net.SplitHostPort(fields[4])
+		if err != nil {
+			continue
+		}
+		// strip the interface, e.g. 127.0.0.53%lo
+		address, _, _ = strings.Cut(address, "%")
+		number, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+
+		p := Port{Protocol: fields[0], Address: address, Port: number}
+		if matches := ssProcess.FindStringSubmatch(line); len(matches) == 2 {
+			p.Process = matches[1]
+		}
+		// sockets listening on both IPv4 and IPv6 are reported once
+		key := p.Protocol + p.Address + port
+		if !seen[key] {
+			seen[key] = true
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// parsePasswd parses /etc/passwd entries
+func parsePasswd(output string) []User {
+	var users []User
+	for _, line := range lines(output) {
+		fields := strings.Split(line, ":")
+		if len(fields) < 7 {
+			continue
+		}
+		uid, _ := strconv.Atoi(fields[2])
+		gid, _ := strconv.Atoi(fields[3])
+		users = append(users, User{Name: fields[0], UID: uid, GID: gid, Home: fields[5], Shell: fields[6]})
+	}
+	return users
+}
+
+// parseGroups parses /etc/group entries
+func parseGroups(output string) []Group {
+	var groups []Group
+	for _, line := range lines(output) {
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 {
+			continue
+		}
+		gid, _ := strconv.Atoi(fields[2])
+		group := Group{Name: fields[0], GID: gid}
+		if fields[3] != "" {
+			group.Members = strings.Split(fields[3], ",")
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// shellQuote quotes a value for sh
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
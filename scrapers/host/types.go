@@ -0,0 +1,135 @@
+package host
+
+import (
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+// Server is the config of a host, it implements v1.Host so that the patch analyzer can compare
+// the packages of hosts with the same platform.
+type Server struct {
+	Hostname string            `json:"hostname"`
+	IP       string            `json:"ip,omitempty"`
+	OS       map[string]string `json:"os,omitempty"`
+	Kernel   string            `json:"kernel,omitempty"`
+	Arch     string            `json:"arch,omitempty"`
+	// PackageManager is one of dpkg, rpm or apk
+	PackageManager string    `json:"packageManager,omitempty"`
+	Packages       []Package `json:"packages,omitempty"`
+	Ports          []Port    `json:"ports,omitempty"`
+	Users          []User    `json:"users,omitempty"`
+	Groups         []Group   `json:"groups,omitempty"`
+	// RebootRequired is true when updated packages require a reboot
+	RebootRequired bool `json:"rebootRequired,omitempty"`
+}
+
+// GetHostname ...
+func (s Server) GetHostname() string {
+	return s.Hostname
+}
+
+// GetPlatform returns the OS and its version, e.g. ubuntu 22.04
+func (s Server) GetPlatform() string {
+	if s.OS["ID"] == "" {
+		return ""
+	}
+	return s.OS["ID"] + " " + s.OS["VERSION_ID"]
+}
+
+// GetId ...
+func (s Server) GetId() string {
+	return s.Hostname
+}
+
+// GetIP ...
+func (s Server) GetIP() string {
+	return s.IP
+}
+
+// GetPatches returns the installed packages
+func (s Server) GetPatches() []v1.Patch {
+	patches := []v1.Patch{}
+	for _, p := range s.Packages {
+		patches = append(patches, p)
+	}
+	return patches
+}
+
+// Package is an installed package, it implements v1.Patch
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
+	// Status of dpkg packages, e.g. installed, half-configured
+	Status        string `json:"status,omitempty"`
+	PendingReboot bool   `json:"pendingReboot,omitempty"`
+}
+
+// GetName ...
+func (p Package) GetName() string {
+	return p.Name
+}
+
+// GetVersion ...
+func (p Package) GetVersion() string {
+	return p.Version
+}
+
+// GetTitle includes the version, so that hosts with different versions of a package are reported
+func (p Package) GetTitle() string {
+	return p.Name + " " + p.Version
+}
+
+// IsInstalled ...
+func (p Package) IsInstalled() bool {
+	return p.Status == "" || p.Status == "installed"
+}
+
+// IsMissing ...
+func (p Package) IsMissing() bool {
+	return p.Status == "not-installed" || p.Status == "config-files"
+}
+
+// IsPendingReboot ...
+func (p Package) IsPendingReboot() bool {
+	return p.PendingReboot
+}
+
+// IsFailed returns true for packages whose installation didn't complete
+func (p Package) IsFailed() bool {
+	switch p.Status {
+	case "half-installed", "half-configured", "unpacked", "triggers-awaited", "triggers-pending":
+		return true
+	}
+	return false
+}
+
+// Service is a systemd unit
+type Service struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Load        string `json:"load"`
+	Active      string `json:"active"`
+	Sub         string `json:"sub"`
+}
+
+// Port is a listening socket
+type Port struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+	Process  string `json:"process,omitempty"`
+}
+
+type User struct {
+	Name  string `json:"name"`
+	UID   int    `json:"uid"`
+	GID   int    `json:"gid"`
+	Home  string `json:"home,omitempty"`
+	Shell string `json:"shell,omitempty"`
+}
+
+type Group struct {
+	Name    string   `json:"name"`
+	GID     int      `json:"gid"`
+	Members []string `json:"members,omitempty"`
+}
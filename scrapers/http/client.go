@@ -0,0 +1,305 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/gomplate/v3"
+	"github.com/go-resty/resty/v2"
+	"github.com/ohler55/ojg/jp"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+type HTTPClient struct {
+	*resty.Client
+	api.ScrapeContext
+	config  v1.HTTP
+	URL     string
+	headers map[string]string
+}
+
+func NewHTTPClient(ctx api.ScrapeContext, config v1.HTTP) (*HTTPClient, error) {
+	var username, password string
+	endpoint := config.URL
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		username, password = connection.Username, connection.Password
+		if endpoint == "" {
+			endpoint = connection.URL
+		}
+	} else if config.Authentication != nil {
+		if username, err = ctx.GetEnvValueFromCache(config.Authentication.Username); err != nil {
+			return nil, err
+		}
+		if password, err = ctx.GetEnvValueFromCache(config.Authentication.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	if endpoint == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	client := resty.New()
+	if config.OAuth != nil {
+		clientID, err := ctx.GetEnvValueFromCache(config.OAuth.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		clientSecret, err := ctx.GetEnvValueFromCache(config.OAuth.ClientSecret)
+		if err != nil {
+			return nil, err
+		}
+		params := url.Values{}
+		for k, v := range config.OAuth.Params {
+			params.Set(k, v)
+		}
+		oauth := clientcredentials.Config{
+			ClientID:       clientID,
+			ClientSecret:   clientSecret,
+			TokenURL:       config.OAuth.TokenURL,
+			Scopes:         config.OAuth.Scopes,
+			EndpointParams: params,
+		}
+		// the oauth client fetches and refreshes the token for every request
+		client = resty.NewWithClient(oauth.Client(ctx))
+	}
+
+	if username != "" || password != "" {
+		client.SetBasicAuth(username, password)
+	}
+	if bearer, err := ctx.GetEnvValueFromCache(config.Bearer); err != nil {
+		return nil, err
+	} else if bearer != "" {
+		client.SetAuthToken(bearer)
+	}
+
+	headers := map[string]string{}
+	for _, header := range config.Headers {
+		value, err := ctx.GetEnvValueFromCache(header.EnvVar)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get value of header %s: %w", header.Name, err)
+		}
+		headers[header.Name] = value
+	}
+
+	if config.Retry != nil {
+		client.SetRetryCount(config.Retry.GetAttempts() - 1).
+			SetRetryWaitTime(config.Retry.GetBackoff()).
+			SetRetryMaxWaitTime(config.Retry.GetMaxBackoff()).
+			AddRetryCondition(func(r *resty.Response, err error) bool {
+				return err != nil || r.StatusCode() == 429 || r.StatusCode() >= 500
+			})
+	}
+
+	return &HTTPClient{
+		Client:        client,
+		ScrapeContext: ctx,
+		config:        config,
+		URL:           endpoint,
+		headers:       headers,
+	}, nil
+}
+
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+
+// GetPages requests the url, following the pagination of the config, and returns the decoded JSON of every page
+func (c *HTTPClient) GetPages() ([]any, error) {
+	var (
+		pages      []any
+		pagination = c.config.Pagination
+		endpoint   = c.URL
+		cursor     string
+		page       int
+		// the body of the previous page, servers that ignore the page parameter return the same page again
+		previous  []byte
+		itemsPath jp.Expr
+		// cursors that are links (e.g. next: https://...?after=x) are requested as is
+		cursorLink bool
+	)
+
+	if pagination != nil {
+		page = pagination.GetStartPage()
+		if strings.HasPrefix(c.config.Items, "$") {
+			var err error
+			if itemsPath, err = jp.ParseString(c.config.Items); err != nil {
+				return nil, fmt.Errorf("failed to parse items: %s: %w", c.config.Items, err)
+			}
+		}
+	}
+
+	for {
+		req := c.R().SetHeaders(c.headers)
+		if pagination != nil {
+			if pagination.PageSize > 0 && pagination.SizeParam != "" {
+				req.SetQueryParam(pagination.SizeParam, strconv.Itoa(pagination.PageSize))
+			}
+			switch pagination.Type {
+			case v1.PaginationPage:
+				req.SetQueryParam(pagination.GetParam(), strconv.Itoa(page))
+			case v1.PaginationCursor:
+				if cursor != "" && !cursorLink {
+					req.SetQueryParam(pagination.GetParam(), cursor)
+				}
+			}
+		}
+
+		if c.config.Body != "" {
+			body, err := gomplate.RunTemplate(map[string]any{"cursor": cursor, "page": page}, gomplate.Template{Template: c.config.Body})
+			if err != nil {
+				return pages, fmt.Errorf("failed to template body: %w", err)
+			}
+			req.SetBody(body)
+		}
+
+		resp, err := req.Execute(c.config.GetMethod(), endpoint)
+		if err != nil {
+			return pages, err
+		}
+		if resp.IsError() {
+			return pages, fmt.Errorf("received non 2xx status code from %s: %s", endpoint, resp.Status())
+		}
+
+		if pagination != nil && pagination.Type == v1.PaginationPage && bytes.Equal(resp.Body(), previous) {
+			return pages, nil
+		}
+		previous = resp.Body()
+
+		var result any
+		if err := json.Unmarshal(resp.Body(), &result); err != nil {
+			return pages, fmt.Errorf("failed to decode response of %s: %w", endpoint, err)
+		}
+		pages = append(pages, result)
+
+		if pagination == nil {
+			return pages, nil
+		}
+		if len(pages) >= pagination.GetMaxPages() {
+			logger.Warnf("stopped paging %s after %d pages", c.URL, len(pages))
+			return pages, nil
+		}
+
+		switch pagination.Type {
+		case v1.PaginationLink:
+			matches := nextLinkRegex.FindStringSubmatch(resp.Header().Get("Link"))
+			if len(matches) != 2 {
+				return pages, nil
+			}
+			if endpoint, err = c.nextURL(endpoint, matches[1]); err != nil {
+				return pages, err
+			}
+
+		case v1.PaginationCursor:
+			next := getCursor(result, pagination.Cursor)
+			if next == "" || next == cursor {
+				return pages, nil
+			}
+			cursor = next
+			cursorLink = strings.HasPrefix(next, "http://") || strings.HasPrefix(next, "https://") || strings.HasPrefix(next, "/")
+			if cursorLink {
+				if endpoint, err = c.nextURL(endpoint, next); err != nil {
+					return pages, err
+				}
+			}
+
+		case v1.PaginationPage:
+			count := countItems(result, itemsPath)
+			if count == 0 || (pagination.PageSize > 0 && count < pagination.PageSize) {
+				return pages, nil
+			}
+			page++
+
+		default:
+			return pages, fmt.Errorf("unknown pagination type %s", pagination.Type)
+		}
+	}
+}
+
+// getCursor returns the value at the JSONPath of the cursor in the response
+func getCursor(result any, path string) string {
+	if path == "" {
+		return ""
+	}
+	expr, err := jp.ParseString(path)
+	if err != nil {
+		logger.Warnf("failed to parse cursor path %s: %v", path, err)
+		return ""
+	}
+	for _, value := range expr.Get(result) {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// countItems returns the number of items in a page, using the items path of the scraper if it has one.
+// A page that is an object without an items path counts as a single item, paging then stops
+// on an empty page or when the same page is returned again.
+func countItems(result any, items jp.Expr) int {
+	if items != nil {
+		var count int
+		for _, value := range items.Get(result) {
+			switch v := value.(type) {
+			case nil:
+			case []any:
+				count += len(v)
+			default:
+				count++
+			}
+		}
+		return count
+	}
+	if list, ok := result.([]any); ok {
+		return len(list)
+	}
+	return 1
+}
+
+// nextURL resolves the link to the next page, which must be on the host of the url
+// so that the credentials of the requests aren't sent to another host.
+func (c *HTTPClient) nextURL(endpoint, ref string) (string, error) {
+	next, err := resolveURL(endpoint, ref)
+	if err != nil {
+		return "", err
+	}
+	origin, err := url.Parse(c.URL)
+	if err != nil {
+		return "", err
+	}
+	nextURL, err := url.Parse(next)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(nextURL.Host, origin.Host) || nextURL.Scheme != origin.Scheme {
+		return "", fmt.Errorf("refusing to follow the next page %s, it's not on %s://%s", next, origin.Scheme, origin.Host)
+	}
+	return next, nil
+}
+
+func resolveURL(base, ref string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid next link %s: %w", ref, err)
+	}
+	return baseURL.ResolveReference(refURL).String(), nil
+}
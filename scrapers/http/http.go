@@ -0,0 +1,38 @@
+package http
+
+import (
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+type HTTPScraper struct {
+}
+
+func (h HTTPScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.HTTP) > 0
+}
+
+// Scrape requests every page of the configured urls. The configs are
+// extracted from the pages using the items, id and type of the scraper.
+func (h HTTPScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.HTTP {
+		client, err := NewHTTPClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create http client for %s", config.URL)
+			continue
+		}
+
+		pages, err := client.GetPages()
+		for _, page := range pages {
+			results = append(results, v1.ScrapeResult{
+				BaseScraper: config.BaseScraper,
+				Config:      page,
+			})
+		}
+		if err != nil {
+			results.Errorf(err, "failed to scrape %s", client.URL)
+		}
+	}
+	return results
+}
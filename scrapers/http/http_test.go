@@ -0,0 +1,229 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/types"
+)
+
+func scrapeContext(configs ...v1.HTTP) api.ScrapeContext {
+	return api.NewScrapeContext(context.TODO(), nil, nil).
+		WithScrapeConfig(&v1.ScrapeConfig{Spec: v1.ScraperSpec{HTTP: configs}})
+}
+
+func TestPagination(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/link":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `</link?page=2>; rel="next"`)
+				_ = json.NewEncoder(w).Encode([]string{"a", "b"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]string{"c"})
+
+		case "/cursor":
+			switch r.URL.Query().Get("after") {
+			case "":
+				_ = json.NewEncoder(w).Encode(map[string]any{"items": []string{"a"}, "next": "x"})
+			case "x":
+				_ = json.NewEncoder(w).Encode(map[string]any{"items": []string{"b"}, "next": "/cursor?after=y"})
+			default:
+				_ = json.NewEncoder(w).Encode(map[string]any{"items": []string{"c"}, "next": nil})
+			}
+
+		case "/page":
+			items := map[string][]string{"1": {"a", "b"}, "2": {"c"}}[r.URL.Query().Get("p")]
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+
+		case "/object":
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "a"})
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		config v1.HTTP
+		pages  int
+	}{
+		{
+			name:   "no pagination",
+			config: v1.HTTP{URL: server.URL + "/link"},
+			pages:  1,
+		},
+		{
+			name:   "link",
+			config: v1.HTTP{URL: server.URL + "/link", Pagination: &v1.HTTPPagination{Type: v1.PaginationLink}},
+			pages:  2,
+		},
+		{
+			name:   "cursor",
+			config: v1.HTTP{URL: server.URL + "/cursor", Pagination: &v1.HTTPPagination{Type: v1.PaginationCursor, Cursor: "$.next", Param: "after"}},
+			pages:  3,
+		},
+		{
+			name: "page",
+			config: v1.HTTP{
+				BaseScraper: v1.BaseScraper{Items: "$.items"},
+				URL:         server.URL + "/page",
+				Pagination:  &v1.HTTPPagination{Type: v1.PaginationPage, Param: "p"},
+			},
+			pages: 3,
+		},
+		{
+			name:   "page without items",
+			config: v1.HTTP{URL: server.URL + "/object", Pagination: &v1.HTTPPagination{Type: v1.PaginationPage}},
+			pages:  1,
+		},
+		{
+			name: "page size",
+			config: v1.HTTP{
+				BaseScraper: v1.BaseScraper{Items: "$.items[*]"},
+				URL:         server.URL + "/page",
+				Pagination:  &v1.HTTPPagination{Type: v1.PaginationPage, Param: "p", PageSize: 2},
+			},
+			pages: 2,
+		},
+		{
+			name:   "max pages",
+			config: v1.HTTP{URL: server.URL + "/cursor", Pagination: &v1.HTTPPagination{Type: v1.PaginationCursor, Cursor: "$.next", Param: "after", MaxPages: 2}},
+			pages:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := HTTPScraper{}.Scrape(scrapeContext(tt.config))
+			for _, result := range results {
+				if result.Error != nil {
+					t.Fatalf("unexpected error: %v", result.Error)
+				}
+			}
+			if len(results) != tt.pages {
+				t.Errorf("expected %d pages, got %d", tt.pages, len(results))
+			}
+		})
+	}
+}
+
+func TestPaginationOtherHost(t *testing.T) {
+	var leaked atomic.Int32
+	other := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		leaked.Add(1)
+		_ = json.NewEncoder(w).Encode([]string{"c"})
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/link?page=2>; rel="next"`, other.URL))
+		_ = json.NewEncoder(w).Encode([]string{"a", "b"})
+	}))
+	defer server.Close()
+
+	config := v1.HTTP{
+		URL:        server.URL + "/link",
+		Bearer:     types.EnvVar{ValueStatic: "token"},
+		Pagination: &v1.HTTPPagination{Type: v1.PaginationLink},
+	}
+	results := HTTPScraper{}.Scrape(scrapeContext(config))
+	// the pages read before are kept
+	if len(results) != 2 || results[0].Error != nil || results[1].Error == nil {
+		t.Errorf("expected the first page and an error for the next page on another host, got %v", results)
+	}
+	if leaked.Load() != 0 {
+		t.Errorf("expected the credentials not to be sent to another host")
+	}
+}
+
+func TestAuthAndRetries(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" {
+				w.WriteHeader(nethttp.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "oauth-token", "token_type": "bearer", "expires_in": 3600})
+
+		case "/flaky":
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				w.WriteHeader(nethttp.StatusServiceUnavailable)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"ok": "true"})
+
+		default:
+			if r.Method != "POST" || r.Header.Get("X-Tenant") != "acme" {
+				w.WriteHeader(nethttp.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"authorization": r.Header.Get("Authorization")})
+		}
+	}))
+	defer server.Close()
+
+	request := v1.HTTP{
+		URL:     server.URL + "/items",
+		Method:  "POST",
+		Headers: []v1.HTTPHeader{{Name: "X-Tenant", EnvVar: types.EnvVar{ValueStatic: "acme"}}},
+		Body:    `{"page": {{.page}}}`,
+	}
+
+	basic := request
+	basic.Authentication = &v1.Authentication{Username: types.EnvVar{ValueStatic: "user"}, Password: types.EnvVar{ValueStatic: "pass"}}
+
+	bearer := request
+	bearer.Bearer = types.EnvVar{ValueStatic: "token"}
+
+	oauth := request
+	oauth.OAuth = &v1.OAuth{
+		ClientID:     types.EnvVar{ValueStatic: "client"},
+		ClientSecret: types.EnvVar{ValueStatic: "secret"},
+		TokenURL:     server.URL + "/token",
+	}
+
+	tests := []struct {
+		name   string
+		config v1.HTTP
+		auth   string
+	}{
+		{name: "basic", config: basic, auth: "Basic dXNlcjpwYXNz"},
+		{name: "bearer", config: bearer, auth: "Bearer token"},
+		{name: "oauth", config: oauth, auth: "Bearer oauth-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := HTTPScraper{}.Scrape(scrapeContext(tt.config))
+			if len(results) != 1 || results[0].Error != nil {
+				t.Fatalf("expected a single result, got %v", results)
+			}
+			if auth := fmt.Sprint(results[0].Config.(map[string]any)["authorization"]); auth != tt.auth {
+				t.Errorf("expected authorization %q, got %q", tt.auth, auth)
+			}
+		})
+	}
+
+	t.Run("retries", func(t *testing.T) {
+		config := v1.HTTP{URL: server.URL + "/flaky", Retry: &v1.HTTPRetry{Attempts: 3, Backoff: "10ms"}}
+		results := HTTPScraper{}.Scrape(scrapeContext(config))
+		if len(results) != 1 || results[0].Error != nil {
+			t.Fatalf("expected a single result, got %v", results)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+	})
+}
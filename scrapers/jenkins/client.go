@@ -0,0 +1,164 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// Job is a job of the Jenkins JSON API.
+// Folders, organization folders and multibranch pipelines are jobs that contain other jobs.
+type Job struct {
+	Class       string `json:"_class"`
+	Name        string `json:"name"`
+	FullName    string `json:"fullName"`
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+	Buildable   bool   `json:"buildable"`
+	Color       string `json:"color,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+	// HealthReport is the build stability and test results summary
+	HealthReport []struct {
+		Description string `json:"description"`
+		Score       int    `json:"score"`
+	} `json:"healthReport,omitempty"`
+	LastBuild           *BuildRef `json:"lastBuild,omitempty"`
+	LastSuccessfulBuild *BuildRef `json:"lastSuccessfulBuild,omitempty"`
+	LastFailedBuild     *BuildRef `json:"lastFailedBuild,omitempty"`
+
+	Jobs   []Job          `json:"jobs,omitempty"`
+	Builds []Build        `json:"-"`
+	Config map[string]any `json:"config,omitempty"`
+}
+
+// IsFolder returns true for jobs that contain other jobs
+func (j Job) IsFolder() bool {
+	return j.Jobs != nil
+}
+
+type BuildRef struct {
+	Number int `json:"number"`
+}
+
+// Build is a build of a job
+type Build struct {
+	Number      int    `json:"number"`
+	DisplayName string `json:"displayName"`
+	URL         string `json:"url"`
+	Result      string `json:"result,omitempty"`
+	Building    bool   `json:"building"`
+	// Duration in milliseconds
+	Duration int `json:"duration"`
+	// Timestamp is the start time of the build in milliseconds since epoch
+	Timestamp int64         `json:"timestamp"`
+	Actions   []BuildAction `json:"actions,omitempty"`
+}
+
+// BuildAction is the union of the build actions we're interested in:
+// the causes of the build and the git revision it built.
+type BuildAction struct {
+	Class  string `json:"_class,omitempty"`
+	Causes []struct {
+		ShortDescription string `json:"shortDescription"`
+		UserID           string `json:"userId,omitempty"`
+		UserName         string `json:"userName,omitempty"`
+	} `json:"causes,omitempty"`
+	LastBuiltRevision *struct {
+		SHA1   string `json:"SHA1"`
+		Branch []struct {
+			Name string `json:"name"`
+		} `json:"branch,omitempty"`
+	} `json:"lastBuiltRevision,omitempty"`
+	RemoteURLs []string `json:"remoteUrls,omitempty"`
+}
+
+const (
+	jobTree   = "_class,name,fullName,displayName,description,url,buildable,color,disabled,healthReport[description,score],lastBuild[number],lastSuccessfulBuild[number],lastFailedBuild[number],jobs[_class,name,url]"
+	buildTree = "number,displayName,url,result,building,duration,timestamp,actions[_class,causes[shortDescription,userId,userName],lastBuiltRevision[SHA1,branch[name]],remoteUrls]"
+)
+
+type JenkinsClient struct {
+	*resty.Client
+	api.ScrapeContext
+	URL string
+}
+
+func NewJenkinsClient(ctx api.ScrapeContext, config v1.Jenkins) (*JenkinsClient, error) {
+	var username, token string
+	url := config.URL
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		username, token = connection.Username, connection.Password
+		if connection.URL != "" {
+			url = connection.URL
+		}
+	} else {
+		if username, err = ctx.GetEnvValueFromCache(config.Username); err != nil {
+			return nil, err
+		}
+		if token, err = ctx.GetEnvValueFromCache(config.Token); err != nil {
+			return nil, err
+		}
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("jenkins url is required")
+	}
+
+	client := resty.New()
+	if username != "" || token != "" {
+		client.SetBasicAuth(username, token)
+	}
+
+	return &JenkinsClient{
+		ScrapeContext: ctx,
+		Client:        client,
+		URL:           strings.TrimSuffix(url, "/") + "/",
+	}, nil
+}
+
+// GetJob returns a job or folder, by its url, with its children and latest builds
+func (j *JenkinsClient) GetJob(url string, maxBuilds int) (*Job, error) {
+	var job Job
+	var builds struct {
+		Builds []Build `json:"builds"`
+	}
+	tree := fmt.Sprintf("%s,builds[%s]{0,%d}", jobTree, buildTree, maxBuilds)
+	resp, err := j.R().
+		SetQueryParam("tree", tree).
+		Get(url + "api/json")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("received non 2xx status code from jenkins: %s", resp.Status())
+	}
+
+	if err := json.Unmarshal(resp.Body(), &job); err != nil {
+		return nil, err
+	}
+	// builds are decoded separately as they're not part of the job config
+	if err := json.Unmarshal(resp.Body(), &builds); err != nil {
+		return nil, err
+	}
+	job.Builds = builds.Builds
+	return &job, nil
+}
+
+// GetJobConfig returns the config.xml of a job
+func (j *JenkinsClient) GetJobConfig(url string) (string, error) {
+	resp, err := j.R().Get(url + "config.xml")
+	if err != nil {
+		return "", err
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("received non 2xx status code from jenkins: %s", resp.Status())
+	}
+	return string(resp.Body()), nil
+}
@@ -0,0 +1,209 @@
+package jenkins
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+const (
+	FolderType              = "Jenkins::Folder"
+	JobType                 = "Jenkins::Job"
+	MultiBranchPipelineType = "Jenkins::MultiBranchPipeline"
+)
+
+type JenkinsScraper struct {
+}
+
+func (j JenkinsScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Jenkins) > 0
+}
+
+// Scrape walks the folders of jenkins controllers, and scrapes the jobs with their latest builds as changes.
+func (j JenkinsScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Jenkins {
+		client, err := NewJenkinsClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create jenkins client for %s", config.URL)
+			continue
+		}
+
+		root, err := client.GetJob(client.URL, 0)
+		if err != nil {
+			results.Errorf(err, "failed to get jobs of %s", client.URL)
+			continue
+		}
+
+		for _, job := range root.Jobs {
+			results = append(results, walkJob(client, config, job.URL, nil)...)
+		}
+	}
+	return results
+}
+
+// walkJob scrapes a job, or a folder and the jobs it contains.
+func walkJob(client *JenkinsClient, config v1.Jenkins, url string, parent *Job) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	job, err := client.GetJob(url, config.GetMaxBuilds())
+	if err != nil {
+		results.Errorf(err, "failed to get jenkins job %s", url)
+		return results
+	}
+
+	configType := getConfigType(*job)
+	if configType == JobType && !collections.MatchItems(job.FullName, config.Jobs...) {
+		return results
+	}
+
+	logger.Debugf("scraping jenkins %s %s", configType, job.FullName)
+	if xml, err := client.GetJobConfig(job.URL); err != nil {
+		logger.Warnf("failed to get config.xml of %s: %v", job.FullName, err)
+	} else if job.Config, err = parseXML(xml); err != nil {
+		logger.Warnf("failed to parse config.xml of %s: %v", job.FullName, err)
+	}
+
+	var changes []v1.ChangeResult
+	for _, build := range job.Builds {
+		changes = append(changes, buildChange(*job, configType, build))
+	}
+
+	children := job.Jobs
+	job.Jobs = nil
+
+	result := v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ConfigClass: strings.TrimPrefix(configType, "Jenkins::"),
+		Config:      *job,
+		Type:        configType,
+		ID:          job.URL,
+		Name:        job.Name,
+		Status:      getStatus(*job),
+		Aliases:     []string{job.FullName},
+		Changes:     changes,
+		// these change with every build, which is recorded as a change already
+		Ignore: []string{"color", "healthReport", "lastBuild", "lastSuccessfulBuild", "lastFailedBuild"},
+	}
+	if parent != nil {
+		result.ParentExternalID = parent.URL
+		result.ParentType = getConfigType(*parent)
+		result.Tags = map[string]string{"folder": parent.FullName}
+	}
+	results = append(results, result)
+
+	// children are fetched after the folder is emitted so that it can be used as their parent
+	job.Jobs = children
+	for _, child := range children {
+		results = append(results, walkJob(client, config, child.URL, job)...)
+	}
+
+	return results
+}
+
+// buildDetails are the details of a build change
+type buildDetails struct {
+	Number      int      `json:"number"`
+	DisplayName string   `json:"displayName"`
+	URL         string   `json:"url"`
+	Result      string   `json:"result,omitempty"`
+	Building    bool     `json:"building"`
+	Duration    int      `json:"duration"`
+	Causes      []string `json:"causes,omitempty"`
+	Revision    string   `json:"revision,omitempty"`
+	Branches    []string `json:"branches,omitempty"`
+	RemoteURLs  []string `json:"remoteUrls,omitempty"`
+}
+
+func buildChange(job Job, configType string, build Build) v1.ChangeResult {
+	details := buildDetails{
+		Number:      build.Number,
+		DisplayName: build.DisplayName,
+		URL:         build.URL,
+		Result:      build.Result,
+		Building:    build.Building,
+		Duration:    build.Duration,
+	}
+
+	var createdBy *string
+	for _, action := range build.Actions {
+		for _, cause := range action.Causes {
+			details.Causes = append(details.Causes, cause.ShortDescription)
+			if createdBy == nil && cause.UserID != "" {
+				createdBy = &cause.UserID
+			}
+		}
+		if action.LastBuiltRevision != nil && details.Revision == "" {
+			details.Revision = action.LastBuiltRevision.SHA1
+			for _, branch := range action.LastBuiltRevision.Branch {
+				details.Branches = append(details.Branches, branch.Name)
+			}
+			details.RemoteURLs = action.RemoteURLs
+		}
+	}
+
+	result := build.Result
+	if build.Building {
+		result = "BUILDING"
+	}
+
+	severity := "info"
+	if build.Result == "FAILURE" {
+		severity = "failed"
+	}
+
+	createdAt := time.UnixMilli(build.Timestamp)
+	return v1.ChangeResult{
+		ExternalID:       job.URL,
+		ConfigType:       configType,
+		ExternalChangeID: build.URL,
+		ChangeType:       "JenkinsBuild",
+		Summary:          strings.TrimSpace(fmt.Sprintf("%s %s %s", build.DisplayName, result, strings.Join(details.Causes, ", "))),
+		Severity:         severity,
+		Source:           build.URL,
+		CreatedBy:        createdBy,
+		CreatedAt:        &createdAt,
+		Details:          v1.NewJSON(details),
+		// Builds in progress are updated once they finish
+		UpdateExisting: true,
+	}
+}
+
+func getConfigType(job Job) string {
+	switch {
+	case strings.HasSuffix(job.Class, "WorkflowMultiBranchProject"):
+		return MultiBranchPipelineType
+	case job.IsFolder():
+		return FolderType
+	}
+	return JobType
+}
+
+// getStatus maps the color of a job's ball to a status
+func getStatus(job Job) string {
+	color := strings.TrimSuffix(job.Color, "_anime")
+	if color != job.Color {
+		return "Building"
+	}
+
+	switch color {
+	case "blue", "green":
+		return "Success"
+	case "red":
+		return "Failed"
+	case "yellow":
+		return "Unstable"
+	case "aborted":
+		return "Aborted"
+	case "disabled":
+		return "Disabled"
+	case "notbuilt":
+		return "NotBuilt"
+	}
+	return ""
+}
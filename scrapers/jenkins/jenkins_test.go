@@ -0,0 +1,150 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestParseXML(t *testing.T) {
+	config := `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job@1289.vd1c337fd5354">
+  <description>Deploys the api</description>
+  <keepDependencies>false</keepDependencies>
+  <properties>
+    <hudson.model.ParametersDefinitionProperty>
+      <parameterDefinitions>
+        <hudson.model.StringParameterDefinition><name>ENV</name></hudson.model.StringParameterDefinition>
+        <hudson.model.StringParameterDefinition><name>TAG</name></hudson.model.StringParameterDefinition>
+      </parameterDefinitions>
+    </hudson.model.ParametersDefinitionProperty>
+  </properties>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition">
+    <scriptPath>Jenkinsfile</scriptPath>
+  </definition>
+  <disabled/>
+</flow-definition>`
+
+	parsed, err := parseXML(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"flow-definition": map[string]any{
+			"@plugin":          "workflow-job@1289.vd1c337fd5354",
+			"description":      "Deploys the api",
+			"keepDependencies": "false",
+			"properties": map[string]any{
+				"hudson.model.ParametersDefinitionProperty": map[string]any{
+					"parameterDefinitions": map[string]any{
+						"hudson.model.StringParameterDefinition": []any{
+							map[string]any{"name": "ENV"},
+							map[string]any{"name": "TAG"},
+						},
+					},
+				},
+			},
+			"definition": map[string]any{
+				"@class":     "org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition",
+				"scriptPath": "Jenkinsfile",
+			},
+			"disabled": "",
+		},
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("expected %v, got %v", expected, parsed)
+	}
+}
+
+func TestScrapeJobs(t *testing.T) {
+	var server *httptest.Server
+	responses := map[string]string{
+		"/api/json":                   `{"_class": "hudson.model.Hudson", "jobs": [{"name": "team", "url": "%[1]s/job/team/"}]}`,
+		"/job/team/api/json":          `{"_class": "com.cloudbees.hudson.plugins.folder.Folder", "name": "team", "fullName": "team", "url": "%[1]s/job/team/", "jobs": [{"name": "api", "url": "%[1]s/job/team/job/api/"}, {"name": "docs", "url": "%[1]s/job/team/job/docs/"}]}`,
+		"/job/team/job/api/api/json":  `{"_class": "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject", "name": "api", "fullName": "team/api", "url": "%[1]s/job/team/job/api/", "jobs": [{"name": "main", "url": "%[1]s/job/team/job/api/job/main/"}]}`,
+		"/job/team/job/docs/api/json": `{"_class": "hudson.model.FreeStyleProject", "name": "docs", "fullName": "team/docs", "url": "%[1]s/job/team/job/docs/", "color": "blue"}`,
+		"/job/team/job/api/job/main/api/json": `{"_class": "org.jenkinsci.plugins.workflow.job.WorkflowJob", "name": "main", "fullName": "team/api/main", "url": "%[1]s/job/team/job/api/job/main/", "color": "red_anime",
+			"builds": [
+				{"number": 2, "url": "%[1]s/job/team/job/api/job/main/2/", "displayName": "#2", "building": true, "timestamp": 1700000600000},
+				{"number": 1, "url": "%[1]s/job/team/job/api/job/main/1/", "displayName": "#1", "result": "FAILURE", "duration": 61000, "timestamp": 1700000000000,
+				 "actions": [{"causes": [{"shortDescription": "Started by user Jane Doe", "userId": "jdoe", "userName": "Jane Doe"}]}, {"lastBuiltRevision": {"SHA1": "abc123", "branch": [{"name": "main"}]}, "remoteUrls": ["https://git.acme.io/api.git"]}]}
+			]}`,
+		"/job/team/job/api/job/main/config.xml": `<?xml version='1.1' encoding='UTF-8'?><flow-definition><description>main branch</description></flow-definition>`,
+	}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, _ := r.BasicAuth(); user != "admin" || token != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "api/json") {
+			body = fmt.Sprintf(body, server.URL)
+			w.Header().Set("Content-Type", "application/json")
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	config := v1.Jenkins{URL: server.URL, Jobs: []string{"team/api/*"}}
+	config.Username.ValueStatic = "admin"
+	config.Token.ValueStatic = "token"
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{
+		Spec: v1.ScraperSpec{Jenkins: []v1.Jenkins{config}},
+	})
+
+	results := JenkinsScraper{}.Scrape(ctx)
+
+	items := make(map[string]v1.ScrapeResult)
+	for _, result := range results {
+		if result.Error != nil {
+			t.Fatalf("unexpected error: %v", result.Error)
+		}
+		items[result.Type+" "+result.Aliases[0]] = result
+	}
+
+	if len(items) != 3 {
+		t.Errorf("expected the folder, the multibranch pipeline and its branch, got %v", results)
+	}
+	multibranch, ok := items[MultiBranchPipelineType+" team/api"]
+	if !ok || multibranch.ParentType != FolderType || multibranch.ParentExternalID != server.URL+"/job/team/" {
+		t.Errorf("expected team/api to be a multibranch pipeline in the team folder, got %+v", multibranch)
+	}
+
+	job, ok := items[JobType+" team/api/main"]
+	if !ok {
+		t.Fatalf("expected team/api/main to be scraped")
+	}
+	if job.Status != "Building" || job.ParentType != MultiBranchPipelineType {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if description := job.Config.(Job).Config["flow-definition"].(map[string]any)["description"]; description != "main branch" {
+		t.Errorf("expected config.xml to be parsed, got %v", job.Config.(Job).Config)
+	}
+
+	if len(job.Changes) != 2 {
+		t.Fatalf("expected 2 builds, got %d", len(job.Changes))
+	}
+	failed := job.Changes[1]
+	if failed.ExternalChangeID != server.URL+"/job/team/job/api/job/main/1/" || failed.Severity != "failed" || *failed.CreatedBy != "jdoe" {
+		t.Errorf("unexpected build change %+v", failed)
+	}
+	if failed.Details["revision"] != "abc123" || failed.Details["duration"] != float64(61000) {
+		t.Errorf("expected the revision and duration in the build details, got %v", failed.Details)
+	}
+	if job.Changes[0].CreatedBy != nil {
+		t.Errorf("expected no author for a build without user cause")
+	}
+}
@@ -0,0 +1,84 @@
+package jenkins
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// xmlNode is an element being decoded by parseXML
+type xmlNode struct {
+	name     string
+	values   map[string]any
+	text     strings.Builder
+	children bool
+}
+
+var xmlDeclaration = regexp.MustCompile(`^\s*<\?xml[^>]*\?>`)
+
+// parseXML converts a XML document (e.g. the config.xml of a job) into a map that can be stored as JSON.
+//
+// Attributes are prefixed with "@", repeated elements become lists and
+// elements with only text become strings. The text of elements that also
+// have attributes or children is kept under "#text".
+func parseXML(data string) (map[string]any, error) {
+	// config.xml files are declared as XML 1.1 which encoding/xml refuses to decode
+	decoder := xml.NewDecoder(strings.NewReader(xmlDeclaration.ReplaceAllString(data, "")))
+
+	root := &xmlNode{values: map[string]any{}}
+	stack := []*xmlNode{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		current := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			current.children = true
+			node := &xmlNode{name: t.Name.Local, values: map[string]any{}}
+			for _, attr := range t.Attr {
+				node.values["@"+attr.Name.Local] = attr.Value
+			}
+			stack = append(stack, node)
+
+		case xml.CharData:
+			current.text.Write(t)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			addXMLValue(stack[len(stack)-1].values, current.name, current.value())
+		}
+	}
+
+	return root.values, nil
+}
+
+func (n *xmlNode) value() any {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.values) == 0 && !n.children {
+		return text
+	}
+	if text != "" {
+		n.values["#text"] = text
+	}
+	return n.values
+}
+
+func addXMLValue(values map[string]any, name string, value any) {
+	existing, ok := values[name]
+	if !ok {
+		values[name] = value
+		return
+	}
+	if list, ok := existing.([]any); ok {
+		values[name] = append(list, value)
+	} else {
+		values[name] = []any{existing, value}
+	}
+}
@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/samber/lo"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/aws"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// Client is an admin client of a cluster, the other brokers of the cluster
+// are discovered from the bootstrap brokers.
+type Client struct {
+	*kadm.Client
+	api.ScrapeContext
+	bootstrap []string
+}
+
+func NewClient(ctx api.ScrapeContext, config v1.Kafka) (*Client, error) {
+	var username, password string
+	brokers := config.Brokers
+	insecure := config.InsecureSkipVerify
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		username, password = connection.Username, connection.Password
+		insecure = insecure || connection.InsecureTLS
+		if connection.URL != "" {
+			brokers = strings.Split(connection.URL, ",")
+		}
+	} else {
+		if username, err = ctx.GetEnvValueFromCache(config.Username); err != nil {
+			return nil, err
+		}
+		if password, err = ctx.GetEnvValueFromCache(config.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	brokers = lo.Map(brokers, func(broker string, _ int) string { return strings.TrimSpace(broker) })
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("at least one broker is required")
+	}
+
+	timeout := config.GetTimeout()
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(brokers...),
+		kgo.ClientID("config-db"),
+		kgo.DialTimeout(timeout),
+		kgo.RequestTimeoutOverhead(timeout),
+		kgo.RetryTimeout(timeout),
+	}
+	if config.TLS {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{InsecureSkipVerify: insecure})) //nolint:gosec
+	}
+	if username != "" || config.SASLMechanism == v1.KafkaSASLAWSMSKIAM {
+		mechanism, err := saslMechanism(config.SASLMechanism, username, password)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: kadm.NewClient(client), ScrapeContext: ctx, bootstrap: brokers}, nil
+}
+
+// saslMechanism returns the mechanism to authenticate with, the username & password
+// of AWS_MSK_IAM are the access & secret key, or the default credentials of the environment are used.
+func saslMechanism(mechanism, username, password string) (sasl.Mechanism, error) {
+	switch mechanism {
+	case "", v1.KafkaSASLPlain:
+		return plain.Auth{User: username, Pass: password}.AsMechanism(), nil
+	case v1.KafkaSASLScramSHA256:
+		return scram.Auth{User: username, Pass: password}.AsSha256Mechanism(), nil
+	case v1.KafkaSASLScramSHA512:
+		return scram.Auth{User: username, Pass: password}.AsSha512Mechanism(), nil
+	case v1.KafkaSASLAWSMSKIAM:
+		if username != "" {
+			return aws.Auth{AccessKey: username, SecretKey: password}.AsManagedStreamingIAMMechanism(), nil
+		}
+		return aws.ManagedStreamingIAM(func(ctx context.Context) (aws.Auth, error) {
+			cfg, err := awsconfig.LoadDefaultConfig(ctx)
+			if err != nil {
+				return aws.Auth{}, err
+			}
+			credentials, err := cfg.Credentials.Retrieve(ctx)
+			if err != nil {
+				return aws.Auth{}, err
+			}
+			return aws.Auth{
+				AccessKey:    credentials.AccessKeyID,
+				SecretKey:    credentials.SecretAccessKey,
+				SessionToken: credentials.SessionToken,
+			}, nil
+		}), nil
+	}
+	return nil, fmt.Errorf("unsupported sasl mechanism %s", mechanism)
+}
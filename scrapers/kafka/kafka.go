@@ -0,0 +1,391 @@
+package kafka
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+	"github.com/samber/lo"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+const (
+	ClusterType       = "Kafka::Cluster"
+	BrokerType        = "Kafka::Broker"
+	TopicType         = "Kafka::Topic"
+	ACLType           = "Kafka::ACL"
+	ConsumerGroupType = "Kafka::ConsumerGroup"
+)
+
+type Topic struct {
+	Name              string `json:"name"`
+	Partitions        int    `json:"partitions"`
+	ReplicationFactor int    `json:"replicationFactor"`
+	Internal          bool   `json:"internal,omitempty"`
+	// Configs are the configs overridden for the topic
+	Configs map[string]*string `json:"configs,omitempty"`
+	// Replicas are the brokers assigned to each partition. The leaders & in-sync replicas aren't included
+	// as they change on every leader election.
+	Replicas map[string][]int32 `json:"replicas"`
+}
+
+type ACL struct {
+	Principal  string `json:"principal"`
+	Host       string `json:"host"`
+	Operation  string `json:"operation"`
+	Permission string `json:"permission"`
+}
+
+type KafkaScraper struct {
+}
+
+func (k KafkaScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Kafka) > 0
+}
+
+// Scrape scrapes the brokers, topics, ACLs and consumer groups of kafka clusters.
+// Under replicated & offline partitions are reported as analysis of their topic.
+func (k KafkaScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Kafka {
+		client, err := NewClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create kafka client")
+			continue
+		}
+		results = append(results, scrapeCluster(client, config)...)
+		client.Close()
+	}
+	return results
+}
+
+type cluster struct {
+	*Client
+	config  v1.Kafka
+	id      string
+	brokers map[int32]string
+	topics  kadm.TopicDetails
+}
+
+func (c cluster) externalID(kind string, parts ...string) string {
+	return strings.Join(append([]string{c.id, kind}, parts...), "/")
+}
+
+func (c cluster) result(configType, class, id, name string, config any) v1.ScrapeResult {
+	return v1.ScrapeResult{
+		BaseScraper:      c.config.BaseScraper,
+		ID:               id,
+		Name:             name,
+		Type:             configType,
+		ConfigClass:      class,
+		Config:           config,
+		ParentExternalID: c.id,
+		ParentType:       ClusterType,
+		Tags:             map[string]string{"cluster": c.id},
+	}
+}
+
+func scrapeCluster(client *Client, config v1.Kafka) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	metadata, err := client.Metadata(client.ScrapeContext)
+	if err != nil {
+		results.Errorf(err, "failed to get metadata of %s", strings.Join(client.bootstrap, ","))
+		return results
+	}
+
+	c := cluster{Client: client, config: config, brokers: map[int32]string{}, topics: kadm.TopicDetails{}}
+	c.id = metadata.Cluster
+	if c.id == "" {
+		c.id = client.bootstrap[0]
+	}
+	for _, broker := range metadata.Brokers {
+		c.brokers[broker.NodeID] = brokerAddress(broker.Host, broker.Port)
+	}
+	for name, topic := range metadata.Topics {
+		// internal topics (e.g. __consumer_offsets) are only scraped when explicitly listed
+		if topic.IsInternal && !lo.Contains(config.Topics, name) {
+			continue
+		}
+		if collections.MatchItems(name, config.Topics...) {
+			c.topics[name] = topic
+		}
+	}
+
+	results = append(results, v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ID:          c.id,
+		Name:        c.id,
+		Type:        ClusterType,
+		ConfigClass: "Cluster",
+		Config:      map[string]any{"clusterId": c.id},
+		Tags:        map[string]string{"cluster": c.id},
+	})
+	results = append(results, c.scrapeBrokers(metadata)...)
+	results = append(results, c.scrapeTopics()...)
+	results = append(results, c.scrapeACLs()...)
+	results = append(results, c.scrapeConsumerGroups()...)
+	return results
+}
+
+func (c cluster) scrapeBrokers(metadata kadm.Metadata) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	// the configs of a broker are described by the broker itself
+	configs, err := c.DescribeBrokerConfigs(c.ScrapeContext, metadata.Brokers.NodeIDs()...)
+	if err != nil {
+		logger.Warnf("failed to describe configs of the brokers of %s: %v", c.id, err)
+	}
+	overrides := overriddenConfigs(configs)
+
+	for _, broker := range metadata.Brokers {
+		id := strconv.Itoa(int(broker.NodeID))
+		config := map[string]any{
+			"nodeId": broker.NodeID,
+			"host":   broker.Host,
+			"port":   broker.Port,
+		}
+		if broker.Rack != nil {
+			config["rack"] = *broker.Rack
+		}
+		if configs, ok := overrides[id]; ok {
+			config["configs"] = configs
+		}
+
+		result := c.result(BrokerType, "Broker", c.externalID("broker", id), c.brokers[broker.NodeID], config)
+		if broker.NodeID == metadata.Controller {
+			result.Tags["controller"] = "true"
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// overriddenConfigs returns the overridden configs of the resources, sensitive values are omitted
+func overriddenConfigs(resources kadm.ResourceConfigs) map[string]map[string]*string {
+	configs := map[string]map[string]*string{}
+	for _, resource := range resources {
+		if resource.Err != nil {
+			logger.Warnf("failed to describe configs of %s: %v", resource.Name, resource.Err)
+			continue
+		}
+		overrides := map[string]*string{}
+		for _, config := range resource.Configs {
+			switch config.Source {
+			case kmsg.ConfigSourceDynamicTopicConfig, kmsg.ConfigSourceDynamicBrokerConfig, kmsg.ConfigSourceStaticBrokerConfig:
+			default:
+				continue
+			}
+			if config.Sensitive {
+				overrides[config.Key] = nil
+			} else {
+				overrides[config.Key] = config.Value
+			}
+		}
+		configs[resource.Name] = overrides
+	}
+	return configs
+}
+
+func (c cluster) scrapeTopics() v1.ScrapeResults {
+	var results v1.ScrapeResults
+	names := c.topics.Names()
+	if len(names) == 0 {
+		return results
+	}
+
+	described, err := c.DescribeTopicConfigs(c.ScrapeContext, names...)
+	if err != nil {
+		results.Errorf(err, "failed to describe configs of topics")
+	}
+	configs := overriddenConfigs(described)
+
+	for _, name := range names {
+		metadata := c.topics[name]
+		topic := Topic{
+			Name:       name,
+			Partitions: len(metadata.Partitions),
+			Internal:   metadata.IsInternal,
+			Configs:    configs[name],
+			Replicas:   map[string][]int32{},
+		}
+
+		var underReplicated, offline []string
+		for _, partition := range metadata.Partitions.Sorted() {
+			number := strconv.Itoa(int(partition.Partition))
+			topic.Replicas[number] = partition.Replicas
+			topic.ReplicationFactor = lo.Max([]int{topic.ReplicationFactor, len(partition.Replicas)})
+			if partition.Leader < 0 {
+				offline = append(offline, number)
+			} else if len(partition.ISR) < len(partition.Replicas) {
+				underReplicated = append(underReplicated, number)
+			}
+		}
+
+		result := c.result(TopicType, "Topic", c.externalID("topic", name), name, topic)
+		switch {
+		case len(offline) > 0:
+			result.Status = "Offline"
+		case len(underReplicated) > 0:
+			result.Status = "UnderReplicated"
+		default:
+			result.Status = "Healthy"
+		}
+		results = append(results, result)
+
+		if len(offline) > 0 {
+			analysis := results.Analysis("OfflinePartitions", TopicType, result.ID)
+			analysis.AnalysisType = models.AnalysisTypeAvailability
+			analysis.Severity = models.SeverityCritical
+			analysis.Source = "Kafka"
+			analysis.Summary = fmt.Sprintf("%d of %d partitions of %s have no leader", len(offline), topic.Partitions, name)
+			analysis.Message(fmt.Sprintf("Partitions %s can't be produced to or consumed from", strings.Join(offline, ", ")))
+		}
+		if len(underReplicated) > 0 {
+			analysis := results.Analysis("UnderReplicatedPartitions", TopicType, result.ID)
+			analysis.AnalysisType = models.AnalysisTypeAvailability
+			analysis.Severity = models.SeverityHigh
+			analysis.Source = "Kafka"
+			analysis.Summary = fmt.Sprintf("%d of %d partitions of %s are under replicated", len(underReplicated), topic.Partitions, name)
+			analysis.Message(fmt.Sprintf("Partitions %s have fewer in-sync replicas than replicas", strings.Join(underReplicated, ", ")))
+		}
+	}
+	return results
+}
+
+func (c cluster) scrapeACLs() v1.ScrapeResults {
+	var results v1.ScrapeResults
+	filter := kadm.NewACLs().AnyResource().ResourcePatternType(kadm.ACLPatternAny).Operations(kadm.OpAny).
+		Allow().AllowHosts().Deny().DenyHosts()
+	described, err := c.DescribeACLs(c.ScrapeContext, filter)
+	if err != nil {
+		results.Errorf(err, "failed to describe acls")
+		return results
+	}
+
+	type resource struct {
+		resourceType kmsg.ACLResourceType
+		patternType  kmsg.ACLResourcePatternType
+		name         string
+	}
+	resources := map[resource][]ACL{}
+	for _, d := range described {
+		if d.Err != nil {
+			// e.g. SECURITY_DISABLED when no authorizer is configured
+			logger.Debugf("failed to describe acls of %s: %v", c.id, d.Err)
+			return results
+		}
+		for _, acl := range d.Described {
+			key := resource{acl.Type, acl.Pattern, acl.Name}
+			resources[key] = append(resources[key], ACL{
+				Principal:  acl.Principal,
+				Host:       acl.Host,
+				Operation:  acl.Operation.String(),
+				Permission: acl.Permission.String(),
+			})
+		}
+	}
+
+	keys := lo.Keys(resources)
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	for _, r := range keys {
+		entries := resources[r]
+		sort.Slice(entries, func(i, j int) bool {
+			return fmt.Sprint(entries[i]) < fmt.Sprint(entries[j])
+		})
+
+		resourceType, patternType := r.resourceType.String(), r.patternType.String()
+		result := c.result(ACLType, "ACL", c.externalID("acl", resourceType, patternType, r.name),
+			fmt.Sprintf("%s:%s", resourceType, r.name),
+			map[string]any{
+				"resourceType": resourceType,
+				"resourceName": r.name,
+				"patternType":  patternType,
+				"acls":         entries,
+			})
+		if _, ok := c.topics[r.name]; ok && r.resourceType == kmsg.ACLResourceTypeTopic && r.patternType == kmsg.ACLResourcePatternTypeLiteral {
+			result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{c.externalID("topic", r.name)}, ConfigType: TopicType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{result.ID}, ConfigType: ACLType},
+				Relationship:      "TopicACL",
+			})
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// scrapeConsumerGroups scrapes the consumer groups, the lag of the groups is reported as properties
+func (c cluster) scrapeConsumerGroups() v1.ScrapeResults {
+	var results v1.ScrapeResults
+	listed, err := c.ListGroups(c.ScrapeContext)
+	if err != nil {
+		results.Errorf(err, "failed to list consumer groups")
+		return results
+	}
+
+	var names []string
+	for _, name := range listed.Groups() {
+		if collections.MatchItems(name, c.config.ConsumerGroups...) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return results
+	}
+
+	lags, err := c.Lag(c.ScrapeContext, names...)
+	if err != nil {
+		results.Errorf(err, "failed to get the lag of consumer groups")
+		return results
+	}
+
+	for _, g := range lags.Sorted() {
+		if err := g.Error(); err != nil {
+			logger.Warnf("failed to describe consumer group %s: %v", g.Group, err)
+			continue
+		}
+
+		lag := g.Lag.TotalByTopic()
+		topics := lo.Keys(lag)
+		sort.Strings(topics)
+
+		result := c.result(ConsumerGroupType, "ConsumerGroup", c.externalID("group", g.Group), g.Group, map[string]any{
+			"groupId":      g.Group,
+			"protocolType": g.ProtocolType,
+			"protocol":     g.Protocol,
+			"topics":       topics,
+		})
+		result.Status = g.State
+		result.Properties = append(result.Properties,
+			&types.Property{Name: "Lag", Value: g.Lag.Total()},
+			&types.Property{Name: "Members", Value: int64(len(g.Members))},
+		)
+		for _, topic := range topics {
+			result.Properties = append(result.Properties, &types.Property{Name: "Lag: " + topic, Value: lag[topic].Lag})
+			if _, ok := c.topics[topic]; ok {
+				result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ExternalID: []string{c.externalID("topic", topic)}, ConfigType: TopicType},
+					RelatedExternalID: v1.ExternalID{ExternalID: []string{result.ID}, ConfigType: ConsumerGroupType},
+					Relationship:      "TopicConsumerGroup",
+				})
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func brokerAddress(host string, port int32) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
@@ -0,0 +1,309 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/samber/lo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// fakeBroker decodes the requests of a connection and answers them with handle
+func fakeBroker(t *testing.T, conn net.Conn, handle func(kmsg.Request) kmsg.Response) {
+	defer conn.Close()
+	for {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return
+		}
+		body := make([]byte, binary.BigEndian.Uint32(size))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+
+		key, version := int16(binary.BigEndian.Uint16(body)), int16(binary.BigEndian.Uint16(body[2:]))
+		correlation := body[4:8]
+		clientID := int16(binary.BigEndian.Uint16(body[8:]))
+		body = body[10+lo.Max([]int{int(clientID), 0}):]
+
+		req := kmsg.RequestForKey(key)
+		req.SetVersion(version)
+		if req.IsFlexible() {
+			body = body[1:]
+		}
+		if err := req.ReadFrom(body); err != nil {
+			t.Errorf("failed to decode %s: %v", kmsg.NameForKey(key), err)
+			return
+		}
+
+		resp := handle(req)
+		resp.SetVersion(version)
+		out := append([]byte{0, 0, 0, 0}, correlation...)
+		if resp.IsFlexible() && key != kmsg.ApiVersions.Int16() {
+			out = append(out, 0)
+		}
+		out = resp.AppendTo(out)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func TestScrape(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	handle := func(req kmsg.Request) kmsg.Response {
+		switch req := req.(type) {
+		case *kmsg.ApiVersionsRequest:
+			resp := kmsg.NewPtrApiVersionsResponse()
+			for k := int16(0); k <= kmsg.MaxKey; k++ {
+				r := kmsg.RequestForKey(k)
+				if r == nil {
+					continue
+				}
+				key := kmsg.NewApiVersionsResponseApiKey()
+				key.ApiKey, key.MaxVersion = k, r.MaxVersion()
+				// the groups & coordinators are batched in later versions
+				switch k {
+				case kmsg.OffsetFetch.Int16():
+					key.MaxVersion = 7
+				case kmsg.FindCoordinator.Int16():
+					key.MaxVersion = 3
+				}
+				resp.ApiKeys = append(resp.ApiKeys, key)
+			}
+			return resp
+
+		case *kmsg.FindCoordinatorRequest:
+			resp := kmsg.NewPtrFindCoordinatorResponse()
+			resp.NodeID, resp.Host, resp.Port = 1, host, int32(portNumber)
+			return resp
+
+		case *kmsg.MetadataRequest:
+			resp := kmsg.NewPtrMetadataResponse()
+			resp.ClusterID = lo.ToPtr("test-cluster")
+			resp.ControllerID = 1
+			b := kmsg.NewMetadataResponseBroker()
+			b.NodeID, b.Host, b.Port = 1, host, int32(portNumber)
+			resp.Brokers = append(resp.Brokers, b)
+
+			orders := kmsg.NewMetadataResponseTopic()
+			orders.Topic = lo.ToPtr("orders")
+			for i, isr := range [][]int32{{1, 2}, {1}} {
+				p := kmsg.NewMetadataResponseTopicPartition()
+				p.Partition, p.Leader, p.Replicas, p.ISR = int32(i), 1, []int32{1, 2}, isr
+				orders.Partitions = append(orders.Partitions, p)
+			}
+			offsets := kmsg.NewMetadataResponseTopic()
+			offsets.Topic = lo.ToPtr("__consumer_offsets")
+			offsets.IsInternal = true
+			for _, topic := range []kmsg.MetadataResponseTopic{orders, offsets} {
+				if req.Topics == nil || lo.ContainsBy(req.Topics, func(t kmsg.MetadataRequestTopic) bool { return lo.FromPtr(t.Topic) == *topic.Topic }) {
+					resp.Topics = append(resp.Topics, topic)
+				}
+			}
+			return resp
+
+		case *kmsg.DescribeConfigsRequest:
+			resp := kmsg.NewPtrDescribeConfigsResponse()
+			for _, r := range req.Resources {
+				resource := kmsg.NewDescribeConfigsResponseResource()
+				resource.ResourceType, resource.ResourceName = r.ResourceType, r.ResourceName
+				if r.ResourceName == "orders" {
+					retention := kmsg.NewDescribeConfigsResponseResourceConfig()
+					retention.Name, retention.Value, retention.Source = "retention.ms", lo.ToPtr("86400000"), kmsg.ConfigSourceDynamicTopicConfig
+					cleanup := kmsg.NewDescribeConfigsResponseResourceConfig()
+					cleanup.Name, cleanup.Value, cleanup.Source, cleanup.IsDefault = "cleanup.policy", lo.ToPtr("delete"), kmsg.ConfigSourceDefaultConfig, true
+					resource.Configs = append(resource.Configs, retention, cleanup)
+				}
+				resp.Resources = append(resp.Resources, resource)
+			}
+			return resp
+
+		case *kmsg.DescribeACLsRequest:
+			resp := kmsg.NewPtrDescribeACLsResponse()
+			if req.PermissionType == kmsg.ACLPermissionTypeDeny {
+				return resp
+			}
+			resource := kmsg.NewDescribeACLsResponseResource()
+			resource.ResourceType, resource.ResourceName, resource.ResourcePatternType = kmsg.ACLResourceTypeTopic, "orders", kmsg.ACLResourcePatternTypeLiteral
+			acl := kmsg.NewDescribeACLsResponseResourceACL()
+			acl.Principal, acl.Host, acl.Operation, acl.PermissionType = "User:billing", "*", kmsg.ACLOperationRead, kmsg.ACLPermissionTypeAllow
+			resource.ACLs = append(resource.ACLs, acl)
+			resp.Resources = append(resp.Resources, resource)
+			return resp
+
+		case *kmsg.ListGroupsRequest:
+			resp := kmsg.NewPtrListGroupsResponse()
+			for _, name := range []string{"billing", "audit"} {
+				g := kmsg.NewListGroupsResponseGroup()
+				g.Group, g.ProtocolType = name, "consumer"
+				resp.Groups = append(resp.Groups, g)
+			}
+			return resp
+
+		case *kmsg.DescribeGroupsRequest:
+			resp := kmsg.NewPtrDescribeGroupsResponse()
+			for _, name := range req.Groups {
+				g := kmsg.NewDescribeGroupsResponseGroup()
+				g.Group, g.State, g.ProtocolType, g.Protocol = name, "Stable", "consumer", "range"
+				assignment := kmsg.NewConsumerMemberAssignment()
+				topic := kmsg.NewConsumerMemberAssignmentTopic()
+				topic.Topic, topic.Partitions = "orders", []int32{0, 1}
+				assignment.Topics = append(assignment.Topics, topic)
+				member := kmsg.NewDescribeGroupsResponseGroupMember()
+				member.MemberAssignment = assignment.AppendTo(nil)
+				g.Members = append(g.Members, member)
+				resp.Groups = append(resp.Groups, g)
+			}
+			return resp
+
+		case *kmsg.OffsetFetchRequest:
+			resp := kmsg.NewPtrOffsetFetchResponse()
+			topic := kmsg.NewOffsetFetchResponseTopic()
+			topic.Topic = "orders"
+			for i, offset := range []int64{10, 5} {
+				p := kmsg.NewOffsetFetchResponseTopicPartition()
+				p.Partition, p.Offset = int32(i), offset
+				topic.Partitions = append(topic.Partitions, p)
+			}
+			resp.Topics = append(resp.Topics, topic)
+			return resp
+
+		case *kmsg.ListOffsetsRequest:
+			resp := kmsg.NewPtrListOffsetsResponse()
+			for _, t := range req.Topics {
+				topic := kmsg.NewListOffsetsResponseTopic()
+				topic.Topic = t.Topic
+				for _, p := range t.Partitions {
+					partition := kmsg.NewListOffsetsResponseTopicPartition()
+					partition.Partition, partition.Offset = p.Partition, 15
+					// -2 is the earliest offset
+					if p.Timestamp == -2 {
+						partition.Offset = 0
+					}
+					topic.Partitions = append(topic.Partitions, partition)
+				}
+				resp.Topics = append(resp.Topics, topic)
+			}
+			return resp
+		}
+		t.Errorf("unexpected request %s", kmsg.NameForKey(req.Key()))
+		return req.ResponseKind()
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fakeBroker(t, conn, handle)
+		}
+	}()
+
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).WithScrapeConfig(&v1.ScrapeConfig{
+		Spec: v1.ScraperSpec{Kafka: []v1.Kafka{{
+			Brokers:        []string{listener.Addr().String()},
+			ConsumerGroups: []string{"!audit", "*"},
+		}}},
+	})
+	results := KafkaScraper{}.Scrape(ctx)
+
+	byID := map[string]v1.ScrapeResult{}
+	var analysis []v1.ScrapeResult
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		if r.AnalysisResult != nil {
+			analysis = append(analysis, r)
+			continue
+		}
+		byID[r.ID] = r
+	}
+
+	if _, ok := byID["test-cluster/topic/__consumer_offsets"]; ok {
+		t.Errorf("expected internal topics to be skipped")
+	}
+	if _, ok := byID["test-cluster/group/audit"]; ok {
+		t.Errorf("expected excluded consumer groups to be skipped")
+	}
+	if broker := byID["test-cluster/broker/1"]; broker.Tags["controller"] != "true" {
+		t.Errorf("expected broker 1 to be the controller, got %v", broker.Tags)
+	}
+
+	orders, ok := byID["test-cluster/topic/orders"]
+	if !ok {
+		t.Fatalf("expected the orders topic, got %v", lo.Keys(byID))
+	}
+	topic := orders.Config.(Topic)
+	if topic.Partitions != 2 || topic.ReplicationFactor != 2 {
+		t.Errorf("unexpected partitions %d or replication factor %d", topic.Partitions, topic.ReplicationFactor)
+	}
+	if len(topic.Configs) != 1 || lo.FromPtr(topic.Configs["retention.ms"]) != "86400000" {
+		t.Errorf("expected only the overridden configs, got %v", topic.Configs)
+	}
+	if orders.Status != "UnderReplicated" {
+		t.Errorf("expected the topic to be under replicated, got %s", orders.Status)
+	}
+
+	if len(analysis) != 1 || analysis[0].AnalysisResult.Analyzer != "UnderReplicatedPartitions" ||
+		analysis[0].AnalysisResult.AnalysisType != models.AnalysisTypeAvailability {
+		t.Errorf("expected an under replicated partitions analysis, got %v", analysis)
+	}
+
+	acl, ok := byID["test-cluster/acl/TOPIC/LITERAL/orders"]
+	if !ok || len(acl.RelationshipResults) != 1 {
+		t.Errorf("expected the acl of orders to be related to the topic, got %v", acl)
+	}
+
+	group, ok := byID["test-cluster/group/billing"]
+	if !ok {
+		t.Fatalf("expected the billing consumer group, got %v", lo.Keys(byID))
+	}
+	if group.Status != "Stable" {
+		t.Errorf("expected the group to be stable, got %s", group.Status)
+	}
+	if lag := group.Properties.Find("Lag"); lag == nil || lag.Value != 15 {
+		t.Errorf("expected a lag of 15, got %v", lag)
+	}
+	if len(group.RelationshipResults) != 1 {
+		t.Errorf("expected the group to be related to orders, got %v", group.RelationshipResults)
+	}
+}
+
+func TestSASLMechanism(t *testing.T) {
+	for mechanism, expected := range map[string]string{
+		"":                      v1.KafkaSASLPlain,
+		v1.KafkaSASLScramSHA256: v1.KafkaSASLScramSHA256,
+		v1.KafkaSASLScramSHA512: v1.KafkaSASLScramSHA512,
+		v1.KafkaSASLAWSMSKIAM:   v1.KafkaSASLAWSMSKIAM,
+	} {
+		m, err := saslMechanism(mechanism, "user", "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Name() != expected {
+			t.Errorf("expected %s for %q, got %s", expected, mechanism, m.Name())
+		}
+	}
+
+	if _, err := saslMechanism("GSSAPI", "user", "password"); err == nil {
+		t.Errorf("expected an error for an unsupported mechanism")
+	}
+}
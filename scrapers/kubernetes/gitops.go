@@ -0,0 +1,332 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// gitOpsState is what we extract from ArgoCD and Flux objects on top of the generic kubernetes handling
+type gitOpsState struct {
+	// properties are the sync status and the revision, which change on every sync
+	properties    types.Properties
+	changes       []v1.ChangeResult
+	relationships v1.RelationshipResults
+}
+
+func (s *gitOpsState) property(name, text string) {
+	s.properties = append(s.properties, &types.Property{Name: name, Text: text})
+}
+
+// getGitOpsState returns the sync status, sync operations and managed resources
+// of ArgoCD applications and Flux kustomizations, helm releases and git repositories.
+// It returns nil for any other object.
+func getGitOpsState(obj *unstructured.Unstructured) *gitOpsState {
+	gvk := obj.GroupVersionKind()
+	switch {
+	case gvk.Group == "argoproj.io" && gvk.Kind == "Application":
+		return getArgoApplicationState(obj)
+	case gvk.Group == "argoproj.io" && gvk.Kind == "ApplicationSet":
+		return getArgoApplicationSetState(obj)
+	case gvk.Group == "kustomize.toolkit.fluxcd.io" && gvk.Kind == "Kustomization":
+		return getFluxKustomizationState(obj)
+	case gvk.Group == "helm.toolkit.fluxcd.io" && gvk.Kind == "HelmRelease":
+		return getFluxHelmReleaseState(obj)
+	case gvk.Group == "source.toolkit.fluxcd.io" && gvk.Kind == "GitRepository":
+		return getFluxGitRepositoryState(obj)
+	}
+	return nil
+}
+
+func getArgoApplicationState(obj *unstructured.Unstructured) *gitOpsState {
+	state := &gitOpsState{}
+	if sync, _, _ := unstructured.NestedString(obj.Object, "status", "sync", "status"); sync != "" {
+		state.property("Sync Status", sync)
+	}
+	if revision, _, _ := unstructured.NestedString(obj.Object, "status", "sync", "revision"); revision != "" {
+		state.property("Revision", revision)
+	}
+
+	history, _, _ := unstructured.NestedSlice(obj.Object, "status", "history")
+	for _, h := range history {
+		entry, ok := h.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		revision := nestedString(entry, "revision")
+		if revision == "" {
+			if revisions, _, _ := unstructured.NestedStringSlice(entry, "revisions"); len(revisions) > 0 {
+				revision = strings.Join(revisions, ",")
+			}
+		}
+
+		state.changes = append(state.changes, gitOpsChange(obj, v1.ChangeResult{
+			ExternalChangeID: fmt.Sprintf("%s/%v", obj.GetUID(), entry["id"]),
+			ChangeType:       "Sync",
+			Summary:          fmt.Sprintf("Synced to %s", revision),
+			Severity:         string(models.SeverityInfo),
+			CreatedBy:        argoInitiatedBy(entry),
+			CreatedAt:        parseTime(nestedString(entry, "deployedAt")),
+			Details:          entry,
+		}))
+	}
+
+	// The history only contains successful syncs
+	if operation, ok, _ := unstructured.NestedMap(obj.Object, "status", "operationState"); ok {
+		phase := nestedString(operation, "phase")
+		if phase == "Failed" || phase == "Error" {
+			operationDetails, _, _ := unstructured.NestedMap(operation, "operation")
+			state.changes = append(state.changes, gitOpsChange(obj, v1.ChangeResult{
+				ExternalChangeID: fmt.Sprintf("%s/operation/%s", obj.GetUID(), nestedString(operation, "startedAt")),
+				ChangeType:       "SyncFailed",
+				Summary:          nestedString(operation, "message"),
+				Severity:         string(models.SeverityHigh),
+				CreatedBy:        argoInitiatedBy(operationDetails),
+				CreatedAt:        parseTime(nestedString(operation, "finishedAt")),
+				Details:          operation,
+			}))
+		}
+	}
+
+	state.relationships = argoManagedResources(obj)
+	return state
+}
+
+func getArgoApplicationSetState(obj *unstructured.Unstructured) *gitOpsState {
+	// The generated applications are owned by the application set,
+	// they're related through their owner references already.
+	return &gitOpsState{relationships: argoManagedResources(obj)}
+}
+
+// argoManagedResources relates an application to the resources listed in its status
+func argoManagedResources(obj *unstructured.Unstructured) v1.RelationshipResults {
+	var relationships v1.RelationshipResults
+	resources, _, _ := unstructured.NestedSlice(obj.Object, "status", "resources")
+	for _, r := range resources {
+		resource, ok := r.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		kind, name := nestedString(resource, "kind"), nestedString(resource, "name")
+		if kind == "" || name == "" {
+			continue
+		}
+		relationships = append(relationships, managedResource(obj, kind, nestedString(resource, "namespace"), name))
+	}
+	return relationships
+}
+
+func argoInitiatedBy(operation map[string]any) *string {
+	initiatedBy, ok := operation["initiatedBy"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	if username := nestedString(initiatedBy, "username"); username != "" {
+		return &username
+	}
+	return nil
+}
+
+func getFluxKustomizationState(obj *unstructured.Unstructured) *gitOpsState {
+	state := getFluxReconcileState(obj)
+
+	entries, _, _ := unstructured.NestedSlice(obj.Object, "status", "inventory", "entries")
+	for _, e := range entries {
+		entry, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		// the id of an entry is <namespace>_<name>_<group>_<kind>
+		parts := strings.Split(nestedString(entry, "id"), "_")
+		if len(parts) != 4 {
+			continue
+		}
+		state.relationships = append(state.relationships, managedResource(obj, parts[3], parts[0], parts[1]))
+	}
+
+	if source := fluxSourceRelationship(obj, "spec", "sourceRef"); source != nil {
+		state.relationships = append(state.relationships, *source)
+	}
+	return state
+}
+
+func getFluxHelmReleaseState(obj *unstructured.Unstructured) *gitOpsState {
+	// helm.toolkit.fluxcd.io/v2 keeps the history of the releases
+	history, _, _ := unstructured.NestedSlice(obj.Object, "status", "history")
+	if len(history) == 0 {
+		state := getFluxReconcileState(obj)
+		if source := fluxSourceRelationship(obj, "spec", "chart", "spec", "sourceRef"); source != nil {
+			state.relationships = append(state.relationships, *source)
+		}
+		return state
+	}
+
+	state := &gitOpsState{}
+	for i, h := range history {
+		snapshot, ok := h.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		chart := fmt.Sprintf("%s@%s", nestedString(snapshot, "chartName"), nestedString(snapshot, "chartVersion"))
+		if i == 0 {
+			state.property("Revision", chart)
+		}
+
+		severity := models.SeverityInfo
+		if status := nestedString(snapshot, "status"); status == "failed" {
+			severity = models.SeverityHigh
+		}
+
+		state.changes = append(state.changes, gitOpsChange(obj, v1.ChangeResult{
+			ExternalChangeID: fmt.Sprintf("%s/%v", obj.GetUID(), snapshot["version"]),
+			ChangeType:       "Sync",
+			Summary:          fmt.Sprintf("Release v%v of %s %s", snapshot["version"], chart, nestedString(snapshot, "status")),
+			Severity:         string(severity),
+			CreatedAt:        parseTime(nestedString(snapshot, "lastDeployed")),
+			Details:          snapshot,
+			UpdateExisting:   true,
+		}))
+	}
+
+	if source := fluxSourceRelationship(obj, "spec", "chart", "spec", "sourceRef"); source != nil {
+		state.relationships = append(state.relationships, *source)
+	}
+	return state
+}
+
+func getFluxGitRepositoryState(obj *unstructured.Unstructured) *gitOpsState {
+	state := &gitOpsState{}
+	artifact, ok, _ := unstructured.NestedMap(obj.Object, "status", "artifact")
+	if !ok {
+		return state
+	}
+
+	revision := nestedString(artifact, "revision")
+	if revision == "" {
+		return state
+	}
+
+	state.property("Revision", revision)
+	state.changes = append(state.changes, gitOpsChange(obj, v1.ChangeResult{
+		ExternalChangeID: fmt.Sprintf("%s/%s", obj.GetUID(), revision),
+		ChangeType:       "SourceUpdated",
+		Summary:          fmt.Sprintf("Fetched %s", revision),
+		Severity:         string(models.SeverityInfo),
+		CreatedAt:        parseTime(nestedString(artifact, "lastUpdateTime")),
+		Details:          artifact,
+	}))
+	return state
+}
+
+// getFluxReconcileState turns the last applied revision of a flux object into a sync change,
+// and the last attempted revision into a failed sync when the object isn't ready.
+func getFluxReconcileState(obj *unstructured.Unstructured) *gitOpsState {
+	state := &gitOpsState{}
+	applied, _, _ := unstructured.NestedString(obj.Object, "status", "lastAppliedRevision")
+	attempted, _, _ := unstructured.NestedString(obj.Object, "status", "lastAttemptedRevision")
+	ready := fluxReadyCondition(obj)
+
+	if applied != "" {
+		state.property("Revision", applied)
+		change := v1.ChangeResult{
+			ExternalChangeID: fmt.Sprintf("%s/%s", obj.GetUID(), applied),
+			ChangeType:       "Sync",
+			Summary:          fmt.Sprintf("Applied %s", applied),
+			Severity:         string(models.SeverityInfo),
+		}
+		if ready != nil && nestedString(ready, "status") == "True" {
+			change.CreatedAt = parseTime(nestedString(ready, "lastTransitionTime"))
+		}
+		state.changes = append(state.changes, gitOpsChange(obj, change))
+	}
+
+	if ready != nil && nestedString(ready, "status") == "False" && attempted != "" && attempted != applied {
+		state.changes = append(state.changes, gitOpsChange(obj, v1.ChangeResult{
+			ExternalChangeID: fmt.Sprintf("%s/%s/%s", obj.GetUID(), attempted, nestedString(ready, "reason")),
+			ChangeType:       "SyncFailed",
+			Summary:          nestedString(ready, "message"),
+			Severity:         string(models.SeverityHigh),
+			CreatedAt:        parseTime(nestedString(ready, "lastTransitionTime")),
+			Details:          ready,
+		}))
+	}
+
+	return state
+}
+
+func fluxReadyCondition(obj *unstructured.Unstructured) map[string]any {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		if condition, ok := c.(map[string]any); ok && nestedString(condition, "type") == "Ready" {
+			return condition
+		}
+	}
+	return nil
+}
+
+// fluxSourceRelationship relates a flux object to the source (e.g. GitRepository) it's reconciled from
+func fluxSourceRelationship(obj *unstructured.Unstructured, fields ...string) *v1.RelationshipResult {
+	sourceRef, ok, _ := unstructured.NestedMap(obj.Object, fields...)
+	if !ok {
+		return nil
+	}
+
+	kind, name := nestedString(sourceRef, "kind"), nestedString(sourceRef, "name")
+	if kind == "" || name == "" {
+		return nil
+	}
+	namespace := nestedString(sourceRef, "namespace")
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+
+	return &v1.RelationshipResult{
+		ConfigExternalID:  v1.ExternalID{ExternalID: []string{kubernetesAlias(kind, namespace, name)}, ConfigType: ConfigTypePrefix + kind},
+		RelatedExternalID: v1.ExternalID{ExternalID: []string{string(obj.GetUID())}, ConfigType: ConfigTypePrefix + obj.GetKind()},
+		Relationship:      kind + obj.GetKind(),
+	}
+}
+
+// managedResource relates a gitops object to a resource it manages, by the alias of the resource
+func managedResource(obj *unstructured.Unstructured, kind, namespace, name string) v1.RelationshipResult {
+	return v1.RelationshipResult{
+		ConfigExternalID:  v1.ExternalID{ExternalID: []string{string(obj.GetUID())}, ConfigType: ConfigTypePrefix + obj.GetKind()},
+		RelatedExternalID: v1.ExternalID{ExternalID: []string{kubernetesAlias(kind, namespace, name)}, ConfigType: ConfigTypePrefix + kind},
+		Relationship:      obj.GetKind() + kind,
+	}
+}
+
+func gitOpsChange(obj *unstructured.Unstructured, change v1.ChangeResult) v1.ChangeResult {
+	change.ExternalID = string(obj.GetUID())
+	change.ConfigType = ConfigTypePrefix + obj.GetKind()
+	change.Source = fmt.Sprintf("%s/%s", obj.GetAPIVersion(), obj.GetKind())
+	return change
+}
+
+func kubernetesAlias(kind, namespace, name string) string {
+	return strings.Join([]string{"Kubernetes", kind, namespace, name}, "/")
+}
+
+func nestedString(obj map[string]any, fields ...string) string {
+	value, _, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func parseTime(value string) *time.Time {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t
+	}
+	return nil
+}
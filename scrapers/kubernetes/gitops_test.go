@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGetGitOpsState(t *testing.T) {
+	tests := []struct {
+		name          string
+		object        string
+		properties    map[string]string
+		changes       []string
+		createdBy     []string
+		relationships []string
+	}{
+		{
+			name: "argo application",
+			object: `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata: {name: api, namespace: argocd, uid: app-uid}
+status:
+  sync: {status: OutOfSync, revision: def456}
+  history:
+    - {id: 1, revision: abc123, deployedAt: "2023-01-01T00:00:00Z", initiatedBy: {username: jdoe}}
+    - {id: 2, revision: def456, deployedAt: "2023-01-02T00:00:00Z", initiatedBy: {automated: true}}
+  operationState:
+    phase: Failed
+    message: one or more objects failed to apply
+    startedAt: "2023-01-03T00:00:00Z"
+    finishedAt: "2023-01-03T00:01:00Z"
+    operation: {initiatedBy: {username: asmith}}
+  resources:
+    - {kind: Deployment, namespace: api, name: api, group: apps, version: v1}
+    - {kind: Namespace, name: api, version: v1}
+`,
+			properties:    map[string]string{"Sync Status": "OutOfSync", "Revision": "def456"},
+			changes:       []string{"app-uid/1 Sync", "app-uid/2 Sync", "app-uid/operation/2023-01-03T00:00:00Z SyncFailed"},
+			createdBy:     []string{"jdoe", "", "asmith"},
+			relationships: []string{"app-uid -> Kubernetes/Deployment/api/api", "app-uid -> Kubernetes/Namespace//api"},
+		},
+		{
+			name: "flux kustomization",
+			object: `
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata: {name: apps, namespace: flux-system, uid: ks-uid}
+spec:
+  sourceRef: {kind: GitRepository, name: flux-system}
+status:
+  lastAppliedRevision: main@sha1:abc123
+  lastAttemptedRevision: main@sha1:def456
+  conditions:
+    - {type: Ready, status: "False", reason: ReconciliationFailed, message: "Deployment/api dry-run failed", lastTransitionTime: "2023-01-02T00:00:00Z"}
+  inventory:
+    entries:
+      - {id: api_api_apps_Deployment, v: v1}
+      - {id: _api__Namespace, v: v1}
+`,
+			properties: map[string]string{"Revision": "main@sha1:abc123"},
+			changes:    []string{"ks-uid/main@sha1:abc123 Sync", "ks-uid/main@sha1:def456/ReconciliationFailed SyncFailed"},
+			createdBy:  []string{"", ""},
+			relationships: []string{
+				"ks-uid -> Kubernetes/Deployment/api/api",
+				"ks-uid -> Kubernetes/Namespace//api",
+				"Kubernetes/GitRepository/flux-system/flux-system -> ks-uid",
+			},
+		},
+		{
+			name: "flux helm release",
+			object: `
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata: {name: redis, namespace: cache, uid: hr-uid}
+spec:
+  chart:
+    spec:
+      chart: redis
+      sourceRef: {kind: HelmRepository, name: bitnami, namespace: flux-system}
+status:
+  history:
+    - {chartName: redis, chartVersion: 18.1.0, version: 2, status: deployed, lastDeployed: "2023-01-02T00:00:00Z"}
+    - {chartName: redis, chartVersion: 18.0.0, version: 1, status: superseded, lastDeployed: "2023-01-01T00:00:00Z"}
+`,
+			properties:    map[string]string{"Revision": "redis@18.1.0"},
+			changes:       []string{"hr-uid/2 Sync", "hr-uid/1 Sync"},
+			createdBy:     []string{"", ""},
+			relationships: []string{"Kubernetes/HelmRepository/flux-system/bitnami -> hr-uid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obj unstructured.Unstructured
+			if err := yaml.Unmarshal([]byte(tt.object), &obj.Object); err != nil {
+				t.Fatal(err)
+			}
+
+			state := getGitOpsState(&obj)
+			if state == nil {
+				t.Fatalf("expected a gitops state")
+			}
+
+			properties := map[string]string{}
+			for _, property := range state.properties {
+				properties[property.Name] = property.Text
+			}
+			for k, v := range tt.properties {
+				if properties[k] != v {
+					t.Errorf("expected property %s=%s, got %v", k, v, properties)
+				}
+			}
+
+			if len(state.changes) != len(tt.changes) {
+				t.Fatalf("expected changes %v, got %v", tt.changes, state.changes)
+			}
+			for i, change := range state.changes {
+				if got := change.ExternalChangeID + " " + change.ChangeType; got != tt.changes[i] {
+					t.Errorf("expected change %s, got %s", tt.changes[i], got)
+				}
+				var createdBy string
+				if change.CreatedBy != nil {
+					createdBy = *change.CreatedBy
+				}
+				if createdBy != tt.createdBy[i] {
+					t.Errorf("expected %s to be created by %q, got %q", tt.changes[i], tt.createdBy[i], createdBy)
+				}
+			}
+
+			if len(state.relationships) != len(tt.relationships) {
+				t.Fatalf("expected relationships %v, got %v", tt.relationships, state.relationships)
+			}
+			for i, relationship := range state.relationships {
+				if got := relationship.ConfigExternalID.ExternalID[0] + " -> " + relationship.RelatedExternalID.ExternalID[0]; got != tt.relationships[i] {
+					t.Errorf("expected relationship %s, got %s", tt.relationships[i], got)
+				}
+			}
+		})
+	}
+
+	if state := getGitOpsState(&unstructured.Unstructured{Object: map[string]any{"apiVersion": "apps/v1", "kind": "Deployment"}}); state != nil {
+		t.Errorf("expected no gitops state for a deployment")
+	}
+}
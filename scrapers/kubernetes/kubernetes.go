@@ -12,6 +12,7 @@ import (
 	"github.com/flanksource/commons/collections"
 	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/duty/context"
+	"github.com/flanksource/duty/types"
 	"gopkg.in/flanksource/yaml.v3"
 
 	"github.com/flanksource/config-db/api"
@@ -348,6 +349,14 @@ func extractResults(ctx context.Context, config v1.Kubernetes, objs []*unstructu
 			}
 		}
 
+		var changes []v1.ChangeResult
+		var properties types.Properties
+		if gitops := getGitOpsState(obj); gitops != nil {
+			properties = gitops.properties
+			relationships = append(relationships, gitops.relationships...)
+			changes = gitops.changes
+		}
+
 		// Add health metadata
 		var status, description string
 		if healthStatus, err := health.GetResourceHealth(obj, nil); err == nil && healthStatus != nil {
@@ -396,10 +405,12 @@ func extractResults(ctx context.Context, config v1.Kubernetes, objs []*unstructu
 			Config:              configObj,
 			ID:                  string(obj.GetUID()),
 			Tags:                stripLabels(tags, "-hash"),
+			Properties:          properties,
 			Aliases:             getKubernetesAlias(obj),
 			ParentExternalID:    parentExternalID,
 			ParentType:          ConfigTypePrefix + parentType,
 			RelationshipResults: relationships,
+			Changes:             changes,
 		})
 	}
 
@@ -456,7 +467,7 @@ func getKubernetesParent(obj *unstructured.Unstructured, resourceIDMap map[strin
 }
 
 func getKubernetesAlias(obj *unstructured.Unstructured) []string {
-	return []string{strings.Join([]string{"Kubernetes", obj.GetKind(), obj.GetNamespace(), obj.GetName()}, "/")}
+	return []string{kubernetesAlias(obj.GetKind(), obj.GetNamespace(), obj.GetName())}
 }
 
 func updateOptions(ctx context.Context, opts *options.KetallOptions, config v1.Kubernetes) (*options.KetallOptions, error) {
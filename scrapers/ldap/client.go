@@ -0,0 +1,154 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// pageSize is below the 1000 entries Active Directory returns by default
+const pageSize = 500
+
+// searcher searches the directory, it's implemented by *goldap.Conn
+type searcher interface {
+	SearchWithPaging(req *goldap.SearchRequest, pagingSize uint32) (*goldap.SearchResult, error)
+}
+
+// connect dials & binds to the directory with the connection or the credentials of the scraper.
+// Anonymous binds are used when there's no bind DN.
+func connect(ctx api.ScrapeContext, config v1.LDAP) (*goldap.Conn, error) {
+	url := config.URL
+	insecure := config.InsecureSkipVerify
+	var bindDN, password string
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		bindDN, password = connection.Username, connection.Password
+		insecure = insecure || connection.InsecureTLS
+		if connection.URL != "" {
+			url = connection.URL
+		}
+	} else {
+		if bindDN, err = ctx.GetEnvValueFromCache(config.BindDN); err != nil {
+			return nil, err
+		}
+		if password, err = ctx.GetEnvValueFromCache(config.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	conn, err := goldap.DialURL(url,
+		goldap.DialWithDialer(&net.Dialer{Timeout: config.GetTimeout()}),
+		goldap.DialWithTLSConfig(&tls.Config{InsecureSkipVerify: insecure}), //nolint:gosec
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(config.GetTimeout())
+
+	if bindDN != "" {
+		if err := conn.Bind(bindDN, password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to bind as %s: %w", bindDN, err)
+		}
+	}
+	return conn, nil
+}
+
+func search(s searcher, baseDN, filter string, attributes []string) ([]*goldap.Entry, error) {
+	req := goldap.NewSearchRequest(baseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false, filter, attributes, nil)
+	result, err := s.SearchWithPaging(req, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s for %s: %w", baseDN, filter, err)
+	}
+	return result.Entries, nil
+}
+
+// rangedMembers returns the members of an Active Directory group with more members than the server returns at once
+// (1500 by default). The members of such groups are returned in ranges e.g. member;range=0-1499,
+// the next ranges are read from the group until the last one e.g. member;range=1500-*.
+func rangedMembers(s searcher, entry *goldap.Entry) ([]string, error) {
+	var members []string
+	for {
+		var attribute *goldap.EntryAttribute
+		for _, attr := range entry.Attributes {
+			if strings.HasPrefix(strings.ToLower(attr.Name), "member;range=") {
+				attribute = attr
+			}
+		}
+		if attribute == nil {
+			return members, nil
+		}
+		members = append(members, attribute.Values...)
+
+		_, high, _ := strings.Cut(attribute.Name[len("member;range="):], "-")
+		if high == "*" {
+			return members, nil
+		}
+		end, err := strconv.Atoi(high)
+		if err != nil {
+			return members, fmt.Errorf("invalid range %s", attribute.Name)
+		}
+
+		req := goldap.NewSearchRequest(entry.DN, goldap.ScopeBaseObject, goldap.NeverDerefAliases, 0, 0, false, "(objectClass=*)", []string{fmt.Sprintf("member;range=%d-*", end+1)}, nil)
+		result, err := s.SearchWithPaging(req, pageSize)
+		if err != nil {
+			return members, err
+		}
+		if len(result.Entries) == 0 {
+			return members, nil
+		}
+		entry = result.Entries[0]
+	}
+}
+
+// normalizeDN returns a key to compare DNs, DNs are case insensitive and
+// the spaces around the separators are optional
+func normalizeDN(dn string) string {
+	if parsed, err := goldap.ParseDN(dn); err == nil {
+		return strings.ToLower(parsed.String())
+	}
+	return strings.ToLower(dn)
+}
+
+// parentDN returns the normalized DN of the parent of an entry
+func parentDN(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) < 2 {
+		return ""
+	}
+	return strings.ToLower((&goldap.DN{RDNs: parsed.RDNs[1:]}).String())
+}
+
+// parseTime parses the generalized times of LDAP (e.g. 20240102150405.0Z) and
+// the file times of Active Directory (100ns intervals since 1601, e.g. lastLogonTimestamp)
+func parseTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	if filetime, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// 0 and the max int64 mean never
+		if filetime <= 0 || filetime == 1<<63-1 {
+			return nil
+		}
+		t := time.Unix(filetime/1e7-11644473600, 0).UTC()
+		return &t
+	}
+	t, err := ber.ParseGeneralizedTime([]byte(value))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
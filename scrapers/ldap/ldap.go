@@ -0,0 +1,345 @@
+package ldap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/samber/lo"
+)
+
+const (
+	UserType               = "LDAP::User"
+	GroupType              = "LDAP::Group"
+	OrganizationalUnitType = "LDAP::OrganizationalUnit"
+
+	// accountDisabled is the ACCOUNTDISABLE flag of userAccountControl
+	accountDisabled = 0x2
+)
+
+var (
+	userAttributes = []string{
+		"cn", "uid", "sAMAccountName", "mail", "displayName", "title", "department", "manager",
+		"userAccountControl", "nsAccountLock", "pwdAccountLockedTime", "lastLogonTimestamp", "authTimestamp",
+		"whenCreated", "createTimestamp",
+	}
+	groupAttributes = []string{
+		"cn", "description", "member", "uniqueMember", "memberUid",
+		"whenCreated", "createTimestamp",
+	}
+	ouAttributes = []string{"ou", "description", "whenCreated", "createTimestamp"}
+)
+
+type User struct {
+	DN          string `json:"dn"`
+	Username    string `json:"username,omitempty"`
+	Email       string `json:"email,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Department  string `json:"department,omitempty"`
+	Manager     string `json:"manager,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+	// Groups are the DNs of the groups the user is a direct member of
+	Groups []string `json:"groups,omitempty"`
+
+	// the last logon is updated on every logon, it's only used to find stale accounts
+	lastLogon *time.Time
+}
+
+type Group struct {
+	DN          string `json:"dn"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Members are the DNs of the users & groups that are direct members of the group
+	Members []string `json:"members,omitempty"`
+}
+
+type OrganizationalUnit struct {
+	DN          string `json:"dn"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type LDAPScraper struct {
+}
+
+func (l LDAPScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.LDAP) > 0
+}
+
+// Scrape scrapes the users, groups & organizational units of directories.
+// Users with an email are saved as people so that the changes they make can be attributed to them.
+func (l LDAPScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.LDAP {
+		conn, err := connect(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to connect to %s", config.URL)
+			continue
+		}
+		results = append(results, scrapeDirectory(config, conn)...)
+		conn.Close()
+	}
+	return results
+}
+
+type directory struct {
+	config v1.LDAP
+	ous    map[string]OrganizationalUnit
+	users  map[string]*User
+	groups map[string]*Group
+}
+
+func scrapeDirectory(config v1.LDAP, s searcher) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	d := directory{
+		config: config,
+		ous:    map[string]OrganizationalUnit{},
+		users:  map[string]*User{},
+		groups: map[string]*Group{},
+	}
+
+	ous, err := search(s, config.BaseDN, "(objectClass=organizationalUnit)", ouAttributes)
+	if err != nil {
+		results.Errorf(err, "failed to search organizational units")
+		return results
+	}
+	users, err := search(s, config.BaseDN, config.GetUserFilter(), userAttributes)
+	if err != nil {
+		results.Errorf(err, "failed to search users")
+		return results
+	}
+	groups, err := search(s, config.BaseDN, config.GetGroupFilter(), groupAttributes)
+	if err != nil {
+		results.Errorf(err, "failed to search groups")
+		return results
+	}
+
+	for _, entry := range ous {
+		ou := OrganizationalUnit{
+			DN:          entry.DN,
+			Name:        entry.GetEqualFoldAttributeValue("ou"),
+			Description: entry.GetEqualFoldAttributeValue("description"),
+		}
+		d.ous[normalizeDN(entry.DN)] = ou
+		results = append(results, d.result(OrganizationalUnitType, ou.DN, ou.Name, ou, entry))
+	}
+
+	usernames := map[string]string{}
+	for _, entry := range users {
+		user := newUser(entry)
+		d.users[normalizeDN(user.DN)] = user
+		if uid := entry.GetEqualFoldAttributeValue("uid"); uid != "" {
+			usernames[uid] = normalizeDN(user.DN)
+		}
+	}
+
+	for _, entry := range groups {
+		group := &Group{
+			DN:          entry.DN,
+			Name:        entry.GetEqualFoldAttributeValue("cn"),
+			Description: entry.GetEqualFoldAttributeValue("description"),
+		}
+		key := normalizeDN(group.DN)
+		d.groups[key] = group
+
+		ranged, err := rangedMembers(s, entry)
+		if err != nil {
+			results.Errorf(err, "failed to read the members of %s", entry.DN)
+		}
+
+		var members []string
+		for _, member := range lo.Flatten([][]string{entry.GetEqualFoldAttributeValues("member"), ranged, entry.GetEqualFoldAttributeValues("uniqueMember")}) {
+			// the unique members of groupOfUniqueNames can have a uid suffix, e.g. cn=alice,dc=example,dc=com#'0101'B
+			member, _, _ = strings.Cut(member, "#")
+			members = append(members, normalizeDN(member))
+		}
+		// posixGroups list the usernames of their members
+		for _, uid := range entry.GetEqualFoldAttributeValues("memberUid") {
+			if dn, ok := usernames[uid]; ok {
+				members = append(members, dn)
+			}
+		}
+		group.Members = lo.Uniq(members)
+	}
+
+	// replace the normalized DNs of the members with the DNs of the entries
+	for _, group := range d.groups {
+		for i, member := range group.Members {
+			if user, ok := d.users[member]; ok {
+				group.Members[i] = user.DN
+				user.Groups = append(user.Groups, group.DN)
+			} else if g, ok := d.groups[member]; ok {
+				group.Members[i] = g.DN
+			}
+		}
+		sort.Strings(group.Members)
+	}
+
+	for _, key := range sortedKeys(d.groups) {
+		group := d.groups[key]
+		result := d.result(GroupType, group.DN, group.Name, group, nil)
+		for _, member := range group.Members {
+			relatedType := UserType
+			if _, ok := d.groups[normalizeDN(member)]; ok {
+				relatedType = GroupType
+			} else if _, ok := d.users[normalizeDN(member)]; !ok {
+				// e.g. foreign security principals or members outside of the base DN
+				continue
+			}
+			result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{group.DN}, ConfigType: GroupType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{member}, ConfigType: relatedType},
+				Relationship:      "Group" + strings.TrimPrefix(relatedType, "LDAP::"),
+			})
+		}
+		results = append(results, result)
+	}
+
+	privileged := d.privilegedMembers()
+	for _, entry := range users {
+		user := d.users[normalizeDN(entry.DN)]
+		sort.Strings(user.Groups)
+		result := d.result(UserType, user.DN, firstNonEmpty(user.DisplayName, entry.GetEqualFoldAttributeValue("cn"), user.Username), user, entry)
+		result.Status = lo.Ternary(user.Disabled, "Disabled", "Active")
+		if user.Email != "" {
+			result.Person = &models.Person{Name: result.Name, Email: user.Email, ExternalID: user.DN}
+		}
+		results = append(results, result)
+
+		if groups := privileged[normalizeDN(user.DN)]; len(groups) > 0 {
+			results = append(results, d.analyzePrivileged(user, groups)...)
+		}
+	}
+
+	return results
+}
+
+func newUser(entry *goldap.Entry) *User {
+	user := &User{
+		DN:          entry.DN,
+		Username:    attribute(entry, "sAMAccountName", "uid"),
+		Email:       entry.GetEqualFoldAttributeValue("mail"),
+		DisplayName: entry.GetEqualFoldAttributeValue("displayName"),
+		Title:       entry.GetEqualFoldAttributeValue("title"),
+		Department:  entry.GetEqualFoldAttributeValue("department"),
+		Manager:     entry.GetEqualFoldAttributeValue("manager"),
+		lastLogon:   parseTime(attribute(entry, "lastLogonTimestamp", "authTimestamp")),
+	}
+
+	// Active Directory, 389-ds and the ppolicy overlay of OpenLDAP each disable accounts differently
+	if uac, err := strconv.ParseInt(entry.GetEqualFoldAttributeValue("userAccountControl"), 10, 64); err == nil && uac&accountDisabled != 0 {
+		user.Disabled = true
+	}
+	if strings.EqualFold(entry.GetEqualFoldAttributeValue("nsAccountLock"), "true") || entry.GetEqualFoldAttributeValue("pwdAccountLockedTime") != "" {
+		user.Disabled = true
+	}
+	return user
+}
+
+func (d directory) result(configType, id, name string, config any, entry *goldap.Entry) v1.ScrapeResult {
+	result := v1.ScrapeResult{
+		BaseScraper: d.config.BaseScraper,
+		ID:          id,
+		Name:        name,
+		Type:        configType,
+		ConfigClass: strings.TrimPrefix(configType, "LDAP::"),
+		Config:      config,
+	}
+	if entry != nil {
+		result.CreatedAt = parseTime(attribute(entry, "whenCreated", "createTimestamp"))
+	}
+
+	// the parent is the closest organizational unit
+	for parent := parentDN(id); parent != ""; parent = parentDN(parent) {
+		if ou, ok := d.ous[parent]; ok {
+			result.ParentExternalID = ou.DN
+			result.ParentType = OrganizationalUnitType
+			break
+		}
+	}
+	return result
+}
+
+// privilegedMembers returns the names of the privileged groups of each user (by normalized DN),
+// including the groups the user is a member of through nested groups
+func (d directory) privilegedMembers() map[string][]string {
+	members := map[string][]string{}
+	for _, key := range sortedKeys(d.groups) {
+		root := d.groups[key]
+		if !collections.MatchItems(root.Name, d.config.GetPrivilegedGroups()...) {
+			continue
+		}
+
+		visited := map[string]bool{key: true}
+		queue := []string{key}
+		for len(queue) > 0 {
+			group := d.groups[queue[0]]
+			queue = queue[1:]
+			for _, member := range group.Members {
+				member = normalizeDN(member)
+				if _, ok := d.users[member]; ok && !lo.Contains(members[member], root.Name) {
+					members[member] = append(members[member], root.Name)
+				} else if _, ok := d.groups[member]; ok && !visited[member] {
+					visited[member] = true
+					queue = append(queue, member)
+				}
+			}
+		}
+	}
+	return members
+}
+
+func (d directory) analyzePrivileged(user *User, groups []string) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	if user.Disabled {
+		analysis := results.Analysis("DisabledPrivilegedAccount", UserType, user.DN)
+		analysis.AnalysisType = models.AnalysisTypeSecurity
+		analysis.Severity = models.SeverityHigh
+		analysis.Source = "LDAP"
+		analysis.Summary = fmt.Sprintf("%s is disabled but is a member of %s", firstNonEmpty(user.Username, user.DN), strings.Join(groups, ", "))
+		analysis.Message("Disabled accounts should be removed from privileged groups")
+	}
+	if user.lastLogon != nil && time.Since(*user.lastLogon) > d.config.GetStaleAfter() {
+		analysis := results.Analysis("StalePrivilegedAccount", UserType, user.DN)
+		analysis.AnalysisType = models.AnalysisTypeSecurity
+		analysis.Severity = models.SeverityMedium
+		analysis.Source = "LDAP"
+		analysis.Summary = fmt.Sprintf("%s is a member of %s and hasn't logged on since %s",
+			firstNonEmpty(user.Username, user.DN), strings.Join(groups, ", "), user.lastLogon.Format("2006-01-02"))
+		analysis.Message(fmt.Sprintf("Privileged accounts that haven't been used in %s should be disabled or removed from privileged groups", d.config.GetStaleAfter()))
+	}
+	return results
+}
+
+// attribute returns the first attribute with a value, e.g. the Active Directory attribute or its OpenLDAP equivalent
+func attribute(entry *goldap.Entry, names ...string) string {
+	for _, name := range names {
+		if value := entry.GetEqualFoldAttributeValue(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := lo.Keys(m)
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+package ldap
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/samber/lo"
+)
+
+// fakeSearcher returns the entries of each filter
+type fakeSearcher map[string][]*goldap.Entry
+
+func (f fakeSearcher) SearchWithPaging(req *goldap.SearchRequest, pagingSize uint32) (*goldap.SearchResult, error) {
+	return &goldap.SearchResult{Entries: f[req.Filter]}, nil
+}
+
+// searchFunc answers the searches with a function
+type searchFunc func(req *goldap.SearchRequest) []*goldap.Entry
+
+func (f searchFunc) SearchWithPaging(req *goldap.SearchRequest, pagingSize uint32) (*goldap.SearchResult, error) {
+	return &goldap.SearchResult{Entries: f(req)}, nil
+}
+
+// filetime converts a time to the 100ns intervals since 1601 of Active Directory
+func filetime(t time.Time) string {
+	return strconv.FormatInt((t.Unix()+11644473600)*1e7, 10)
+}
+
+func directoryEntries(adminMembers ...string) fakeSearcher {
+	config := v1.LDAP{}
+	return fakeSearcher{
+		"(objectClass=organizationalUnit)": {
+			goldap.NewEntry("OU=Staff,DC=example,DC=com", map[string][]string{"ou": {"Staff"}}),
+		},
+		config.GetUserFilter(): {
+			goldap.NewEntry("CN=Alice,OU=Staff,DC=example,DC=com", map[string][]string{
+				"sAMAccountName":     {"alice"},
+				"mail":               {"alice@example.com"},
+				"displayName":        {"Alice Smith"},
+				"userAccountControl": {"512"},
+				"lastLogonTimestamp": {filetime(time.Now().Add(-24 * time.Hour))},
+				"whenCreated":        {"20230102150405.0Z"},
+			}),
+			goldap.NewEntry("CN=Bob,OU=Staff,DC=example,DC=com", map[string][]string{
+				"sAMAccountName":     {"bob"},
+				"userAccountControl": {"514"},
+				"lastLogonTimestamp": {filetime(time.Now().Add(-24 * time.Hour))},
+			}),
+			goldap.NewEntry("CN=Carol,OU=Staff,DC=example,DC=com", map[string][]string{
+				"sAMAccountName":     {"carol"},
+				"userAccountControl": {"512"},
+				"lastLogonTimestamp": {filetime(time.Now().Add(-200 * 24 * time.Hour))},
+			}),
+		},
+		config.GetGroupFilter(): {
+			goldap.NewEntry("CN=Domain Admins,CN=Users,DC=example,DC=com", map[string][]string{
+				"cn":     {"Domain Admins"},
+				"member": adminMembers,
+			}),
+			goldap.NewEntry("CN=Ops,OU=Staff,DC=example,DC=com", map[string][]string{
+				"cn":     {"Ops"},
+				"member": {"cn=carol, ou=Staff, dc=example, dc=com"},
+			}),
+		},
+	}
+}
+
+func TestScrapeDirectory(t *testing.T) {
+	config := v1.LDAP{BaseDN: "DC=example,DC=com"}
+
+	results := scrapeDirectory(config, directoryEntries("CN=Alice,OU=Staff,DC=example,DC=com", "CN=Bob,OU=Staff,DC=example,DC=com", "CN=Ops,OU=Staff,DC=example,DC=com"))
+
+	byID := map[string]v1.ScrapeResult{}
+	analysis := map[string]string{}
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		if len(r.Changes) > 0 {
+			t.Errorf("expected no changes on the first run, got %v", r.Changes)
+		}
+		if r.AnalysisResult != nil {
+			analysis[r.AnalysisResult.ExternalID] = r.AnalysisResult.Analyzer
+			continue
+		}
+		byID[r.ID] = r
+	}
+
+	alice := byID["CN=Alice,OU=Staff,DC=example,DC=com"]
+	if alice.Person == nil || alice.Person.Email != "alice@example.com" || alice.Person.Name != "Alice Smith" {
+		t.Errorf("expected alice to be a person, got %v", alice.Person)
+	}
+	if alice.ParentExternalID != "OU=Staff,DC=example,DC=com" {
+		t.Errorf("expected alice to be in the staff ou, got %s", alice.ParentExternalID)
+	}
+	if alice.CreatedAt == nil || alice.CreatedAt.Year() != 2023 {
+		t.Errorf("expected the creation time of alice, got %v", alice.CreatedAt)
+	}
+	if groups := alice.Config.(*User).Groups; len(groups) != 1 || groups[0] != "CN=Domain Admins,CN=Users,DC=example,DC=com" {
+		t.Errorf("unexpected groups of alice: %v", groups)
+	}
+
+	if bob := byID["CN=Bob,OU=Staff,DC=example,DC=com"]; bob.Status != "Disabled" || bob.Person != nil {
+		t.Errorf("expected bob to be disabled without a person, got %s %v", bob.Status, bob.Person)
+	}
+
+	ops := byID["CN=Ops,OU=Staff,DC=example,DC=com"].Config.(*Group)
+	if len(ops.Members) != 1 || ops.Members[0] != "CN=Carol,OU=Staff,DC=example,DC=com" {
+		t.Errorf("expected the members to be matched regardless of case & spaces, got %v", ops.Members)
+	}
+
+	if len(analysis) != 2 ||
+		analysis["CN=Bob,OU=Staff,DC=example,DC=com"] != "DisabledPrivilegedAccount" ||
+		analysis["CN=Carol,OU=Staff,DC=example,DC=com"] != "StalePrivilegedAccount" {
+		t.Errorf("expected bob to be disabled & carol (nested in Ops) to be stale, got %v", analysis)
+	}
+
+	// the changes of the members are recorded as diffs of the groups
+	results = scrapeDirectory(config, directoryEntries("CN=Alice,OU=Staff,DC=example,DC=com", "CN=Carol,OU=Staff,DC=example,DC=com", "CN=Ops,OU=Staff,DC=example,DC=com"))
+	for _, r := range results {
+		if len(r.Changes) > 0 {
+			t.Errorf("expected no membership changes besides the diff of the group, got %v", r.Changes)
+		}
+		if r.ID == "CN=Domain Admins,CN=Users,DC=example,DC=com" {
+			if members := r.Config.(*Group).Members; len(members) != 3 || lo.Contains(members, "CN=Bob,OU=Staff,DC=example,DC=com") {
+				t.Errorf("expected carol to replace bob in the admins, got %v", members)
+			}
+		}
+	}
+}
+
+func TestRangedMembers(t *testing.T) {
+	member := func(i int) string { return "CN=User" + strconv.Itoa(i) + ",OU=Staff,DC=example,DC=com" }
+	members := func(from, to int) []string {
+		var values []string
+		for i := from; i <= to; i++ {
+			values = append(values, member(i))
+		}
+		return values
+	}
+
+	// Active Directory returns the members of large groups in ranges of 1500
+	var requested []string
+	s := searchFunc(func(req *goldap.SearchRequest) []*goldap.Entry {
+		requested = append(requested, req.Attributes...)
+		switch req.Attributes[0] {
+		case "member;range=1500-*":
+			return []*goldap.Entry{goldap.NewEntry(req.BaseDN, map[string][]string{"member;range=1500-2999": members(1500, 2999)})}
+		case "member;range=3000-*":
+			return []*goldap.Entry{goldap.NewEntry(req.BaseDN, map[string][]string{"member;range=3000-*": members(3000, 3100)})}
+		}
+		return nil
+	})
+
+	group := goldap.NewEntry("CN=Everyone,DC=example,DC=com", map[string][]string{"member;range=0-1499": members(0, 1499)})
+	values, err := rangedMembers(s, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3101 || values[3100] != member(3100) {
+		t.Errorf("expected the members of all the ranges, got %d", len(values))
+	}
+	if len(requested) != 2 {
+		t.Errorf("expected the ranges to be read until the last one, got %v", requested)
+	}
+
+	// the members of smaller groups are returned in the member attribute
+	values, err = rangedMembers(s, goldap.NewEntry("CN=Ops,DC=example,DC=com", map[string][]string{"member": {member(1)}}))
+	if err != nil || len(values) != 0 {
+		t.Errorf("expected no ranged members, got %v %v", values, err)
+	}
+}
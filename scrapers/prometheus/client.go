@@ -0,0 +1,194 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// Target is an active scrape target of the Prometheus HTTP API
+type Target struct {
+	Labels     map[string]string `json:"labels"`
+	ScrapePool string            `json:"scrapePool"`
+	ScrapeURL  string            `json:"scrapeUrl"`
+	GlobalURL  string            `json:"globalUrl,omitempty"`
+	Health     string            `json:"health"`
+	LastError  string            `json:"lastError,omitempty"`
+	// ScrapeInterval and ScrapeTimeout are only returned by Prometheus >= 2.33
+	ScrapeInterval string `json:"scrapeInterval,omitempty"`
+	ScrapeTimeout  string `json:"scrapeTimeout,omitempty"`
+}
+
+// RuleGroup is a group of recording and alerting rules.
+// The evaluation results of the rules (e.g. lastEvaluation, alerts) are not decoded as they change on every evaluation.
+type RuleGroup struct {
+	Name     string  `json:"name"`
+	File     string  `json:"file"`
+	Interval float64 `json:"interval"`
+	Rules    []Rule  `json:"rules"`
+}
+
+type Rule struct {
+	Name string `json:"name"`
+	// Type is either alerting or recording
+	Type        string            `json:"type"`
+	Query       string            `json:"query"`
+	Duration    float64           `json:"duration,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Health      string            `json:"health,omitempty"`
+	LastError   string            `json:"lastError,omitempty"`
+}
+
+// Alert is an active alert, of either the Alertmanager (v2) or the Prometheus API
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    *time.Time        `json:"startsAt,omitempty"`
+	// ActiveAt is the equivalent of StartsAt in the Prometheus API
+	ActiveAt     *time.Time `json:"activeAt,omitempty"`
+	Fingerprint  string     `json:"fingerprint,omitempty"`
+	GeneratorURL string     `json:"generatorURL,omitempty"`
+	// State is firing or pending in the Prometheus API
+	State  string `json:"state,omitempty"`
+	Status *struct {
+		State string `json:"state"`
+	} `json:"status,omitempty"`
+}
+
+func (a Alert) Name() string {
+	return a.Labels["alertname"]
+}
+
+func (a Alert) Since() *time.Time {
+	if a.StartsAt != nil {
+		return a.StartsAt
+	}
+	return a.ActiveAt
+}
+
+type PrometheusClient struct {
+	*resty.Client
+	api.ScrapeContext
+	URL          string
+	Alertmanager string
+}
+
+func NewPrometheusClient(ctx api.ScrapeContext, config v1.Prometheus) (*PrometheusClient, error) {
+	var username, password string
+	url := config.URL
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		username, password = connection.Username, connection.Password
+		if connection.URL != "" {
+			url = connection.URL
+		}
+	} else {
+		if username, err = ctx.GetEnvValueFromCache(config.Username); err != nil {
+			return nil, err
+		}
+		if password, err = ctx.GetEnvValueFromCache(config.Password); err != nil {
+			return nil, err
+		}
+	}
+	token, err := ctx.GetEnvValueFromCache(config.BearerToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("prometheus url is required")
+	}
+
+	client := resty.New()
+	if token != "" {
+		client.SetAuthToken(token)
+	} else if username != "" || password != "" {
+		client.SetBasicAuth(username, password)
+	}
+
+	return &PrometheusClient{
+		ScrapeContext: ctx,
+		Client:        client,
+		URL:           strings.TrimSuffix(url, "/"),
+		Alertmanager:  strings.TrimSuffix(config.Alertmanager, "/"),
+	}, nil
+}
+
+// get decodes the data of a Prometheus API response
+func (p *PrometheusClient) get(path string, query map[string]string, data any) error {
+	var response struct {
+		Status string          `json:"status"`
+		Error  string          `json:"error,omitempty"`
+		Data   json.RawMessage `json:"data"`
+	}
+	resp, err := p.R().SetQueryParams(query).Get(p.URL + path)
+	if err != nil {
+		return err
+	}
+	// errors of the API are returned with a 4xx/5xx status code and an error message in the body
+	if err := json.Unmarshal(resp.Body(), &response); err != nil {
+		return fmt.Errorf("received %s from prometheus: %w", resp.Status(), err)
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("received %s from prometheus: %s", resp.Status(), response.Error)
+	}
+	return json.Unmarshal(response.Data, data)
+}
+
+// GetTargets returns the active targets
+func (p *PrometheusClient) GetTargets() ([]Target, error) {
+	var data struct {
+		ActiveTargets []Target `json:"activeTargets"`
+	}
+	err := p.get("/api/v1/targets", map[string]string{"state": "active"}, &data)
+	return data.ActiveTargets, err
+}
+
+// GetRuleGroups returns the recording and alerting rule groups
+func (p *PrometheusClient) GetRuleGroups() ([]RuleGroup, error) {
+	var data struct {
+		Groups []RuleGroup `json:"groups"`
+	}
+	err := p.get("/api/v1/rules", nil, &data)
+	return data.Groups, err
+}
+
+// GetAlerts returns the firing alerts, from Alertmanager when configured.
+// Silenced and inhibited alerts are excluded, as are pending alerts of Prometheus.
+func (p *PrometheusClient) GetAlerts() ([]Alert, error) {
+	if p.Alertmanager == "" {
+		var data struct {
+			Alerts []Alert `json:"alerts"`
+		}
+		if err := p.get("/api/v1/alerts", nil, &data); err != nil {
+			return nil, err
+		}
+		var firing []Alert
+		for _, alert := range data.Alerts {
+			if alert.State == "firing" {
+				firing = append(firing, alert)
+			}
+		}
+		return firing, nil
+	}
+
+	var alerts []Alert
+	resp, err := p.R().
+		SetQueryParams(map[string]string{"active": "true", "silenced": "false", "inhibited": "false"}).
+		SetResult(&alerts).
+		Get(p.Alertmanager + "/api/v2/alerts")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("received non 2xx status code from alertmanager: %s", resp.Status())
+	}
+	return alerts, nil
+}
@@ -0,0 +1,213 @@
+package prometheus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flanksource/commons/logger"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+)
+
+const (
+	TargetType    = "Prometheus::Target"
+	RuleGroupType = "Prometheus::RuleGroup"
+)
+
+// kubernetesLabels are the labels of targets & alerts that identify a kubernetes object, in order of precedence
+var kubernetesLabels = []struct {
+	label string
+	kind  string
+}{
+	{"pod", "Pod"},
+	{"deployment", "Deployment"},
+	{"statefulset", "StatefulSet"},
+	{"daemonset", "DaemonSet"},
+	{"job_name", "Job"},
+	{"service", "Service"},
+}
+
+type PrometheusScraper struct {
+}
+
+func (p PrometheusScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Prometheus) > 0
+}
+
+// Scrape scrapes the targets and rule groups of prometheus servers, firing alerts are reported as analysis
+// of the config they're about. They're resolved when they stop firing, as they're no longer observed.
+func (p PrometheusScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Prometheus {
+		client, err := NewPrometheusClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create prometheus client for %s", config.URL)
+			continue
+		}
+
+		targets, err := client.GetTargets()
+		if err != nil {
+			results.Errorf(err, "failed to get targets of %s", client.URL)
+		}
+		for _, target := range targets {
+			results = append(results, targetResult(config, target))
+		}
+
+		groups, err := client.GetRuleGroups()
+		if err != nil {
+			results.Errorf(err, "failed to get rules of %s", client.URL)
+		}
+		for _, group := range groups {
+			results = append(results, ruleGroupResult(config, group))
+		}
+
+		alerts, err := client.GetAlerts()
+		if err != nil {
+			results.Errorf(err, "failed to get alerts of %s", client.URL)
+			// the alerts that are still firing mustn't be resolved
+			results.KeepAnalysis()
+		}
+		source := "Prometheus"
+		if client.Alertmanager != "" {
+			source = "Alertmanager"
+		}
+		for _, alert := range alerts {
+			configType, id := alertConfig(alert, targets)
+			if id == "" {
+				logger.Debugf("alert %s doesn't match any config: %v", alert.Name(), alert.Labels)
+				continue
+			}
+			addAlertAnalysis(&results, source, configType, id, alert)
+		}
+	}
+	return results
+}
+
+func targetID(target Target) string {
+	return target.ScrapePool + "/" + target.ScrapeURL
+}
+
+func targetResult(config v1.Prometheus, target Target) v1.ScrapeResult {
+	result := v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ID:          targetID(target),
+		Name:        target.ScrapePool + "/" + target.Labels["instance"],
+		Type:        TargetType,
+		ConfigClass: "Target",
+		Config:      target,
+		Status:      target.Health,
+		Tags:        map[string]string{"job": target.Labels["job"]},
+	}
+	if namespace := target.Labels["namespace"]; namespace != "" {
+		result.Tags["namespace"] = namespace
+	}
+
+	if kind, name := kubernetesObject(target.Labels); kind != "" {
+		result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+			ConfigExternalID:  v1.ExternalID{ExternalID: []string{kubernetesAlias(kind, target.Labels["namespace"], name)}, ConfigType: "Kubernetes::" + kind},
+			RelatedExternalID: v1.ExternalID{ExternalID: []string{result.ID}, ConfigType: TargetType},
+			Relationship:      kind + "Target",
+		})
+	}
+	// the pod and service of a target are both linked when available
+	if service := target.Labels["service"]; service != "" && target.Labels["pod"] != "" {
+		result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+			ConfigExternalID:  v1.ExternalID{ExternalID: []string{kubernetesAlias("Service", target.Labels["namespace"], service)}, ConfigType: "Kubernetes::Service"},
+			RelatedExternalID: v1.ExternalID{ExternalID: []string{result.ID}, ConfigType: TargetType},
+			Relationship:      "ServiceTarget",
+		})
+	}
+	return result
+}
+
+func ruleGroupResult(config v1.Prometheus, group RuleGroup) v1.ScrapeResult {
+	status := "ok"
+	for _, rule := range group.Rules {
+		if rule.Health == "err" {
+			status = "err"
+		}
+	}
+	return v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ID:          group.File + "/" + group.Name,
+		Name:        group.Name,
+		Type:        RuleGroupType,
+		ConfigClass: "RuleGroup",
+		Config:      group,
+		Status:      status,
+	}
+}
+
+// kubernetesObject returns the kind & name of the namespaced kubernetes object identified by the labels
+func kubernetesObject(labels map[string]string) (kind, name string) {
+	if labels["namespace"] == "" {
+		return "", ""
+	}
+	for _, l := range kubernetesLabels {
+		if labels[l.label] != "" {
+			return l.kind, labels[l.label]
+		}
+	}
+	return "", ""
+}
+
+// alertConfig returns the config an alert is about: a kubernetes object, a node or the target that fired it
+func alertConfig(alert Alert, targets []Target) (configType, id string) {
+	if kind, name := kubernetesObject(alert.Labels); kind != "" {
+		return "Kubernetes::" + kind, kubernetesAlias(kind, alert.Labels["namespace"], name)
+	}
+	if node := alert.Labels["node"]; node != "" {
+		return "Kubernetes::Node", kubernetesAlias("Node", "", node)
+	}
+	if instance := alert.Labels["instance"]; instance != "" {
+		for _, target := range targets {
+			if target.Labels["instance"] == instance && (alert.Labels["job"] == "" || target.Labels["job"] == alert.Labels["job"]) {
+				return TargetType, targetID(target)
+			}
+		}
+	}
+	return "", ""
+}
+
+func addAlertAnalysis(results *v1.ScrapeResults, source, configType, id string, alert Alert) {
+	analysis := results.Analysis(alert.Name(), configType, id)
+	analysis.AnalysisType = models.AnalysisTypeAvailability
+	analysis.Severity = alertSeverity(alert.Labels["severity"])
+	analysis.Source = source
+	analysis.Status = models.AnalysisStatusOpen
+	analysis.FirstObserved = alert.Since()
+	analysis.Summary = alert.Annotations["summary"]
+	if analysis.Summary == "" {
+		analysis.Summary = fmt.Sprintf("%s is firing", alert.Name())
+	}
+	if description := alert.Annotations["description"]; description != "" {
+		analysis.Message(description)
+	} else if message := alert.Annotations["message"]; message != "" {
+		analysis.Message(message)
+	}
+	analysis.Analysis = map[string]any{
+		"labels":       alert.Labels,
+		"annotations":  alert.Annotations,
+		"generatorURL": alert.GeneratorURL,
+	}
+}
+
+func alertSeverity(severity string) models.Severity {
+	switch strings.ToLower(severity) {
+	case "critical", "page":
+		return models.SeverityCritical
+	case "error", "high":
+		return models.SeverityHigh
+	case "warning", "warn", "medium":
+		return models.SeverityMedium
+	case "info", "none":
+		return models.SeverityInfo
+	default:
+		return models.SeverityLow
+	}
+}
+
+func kubernetesAlias(kind, namespace, name string) string {
+	return strings.Join([]string{"Kubernetes", kind, namespace, name}, "/")
+}
@@ -0,0 +1,184 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+)
+
+func success(data any) map[string]any {
+	return map[string]any{"status": "success", "data": data}
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			_ = json.NewEncoder(w).Encode(success(map[string]any{"activeTargets": []map[string]any{
+				{
+					"labels":     map[string]string{"job": "api", "instance": "10.0.0.5:8080", "namespace": "shop", "pod": "api-7d9f", "service": "api"},
+					"scrapePool": "serviceMonitor/shop/api/0",
+					"scrapeUrl":  "http://10.0.0.5:8080/metrics",
+					"health":     "up",
+					"lastScrape": "2024-01-01T00:00:00Z",
+				},
+				{
+					"labels":     map[string]string{"job": "node", "instance": "node-1:9100"},
+					"scrapePool": "node",
+					"scrapeUrl":  "http://node-1:9100/metrics",
+					"health":     "down",
+					"lastError":  "connection refused",
+				},
+			}}))
+		case "/api/v1/rules":
+			_ = json.NewEncoder(w).Encode(success(map[string]any{"groups": []map[string]any{{
+				"name": "node.rules",
+				"file": "/etc/prometheus/rules/node.yaml",
+				"rules": []map[string]any{
+					{"name": "NodeDown", "type": "alerting", "query": "up{job=\"node\"} == 0", "duration": 300, "health": "ok", "state": "firing"},
+					{"name": "instance:cpu:rate5m", "type": "recording", "query": "rate(cpu[5m])", "health": "err", "lastError": "bad query"},
+				},
+			}}}))
+		case "/api/v1/alerts":
+			_ = json.NewEncoder(w).Encode(success(map[string]any{"alerts": []map[string]any{
+				{"labels": map[string]string{"alertname": "NodeDown", "job": "node", "instance": "node-1:9100", "severity": "critical"}, "state": "firing", "activeAt": "2024-01-01T00:00:00Z"},
+				{"labels": map[string]string{"alertname": "HighLatency", "namespace": "shop", "pod": "api-7d9f"}, "state": "pending"},
+			}}))
+		case "/alertmanager/api/v2/alerts":
+			if r.URL.Query().Get("silenced") != "false" {
+				t.Errorf("expected silenced alerts to be excluded")
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{
+					"labels":      map[string]string{"alertname": "KubePodCrashLooping", "namespace": "shop", "pod": "api-7d9f", "severity": "warning"},
+					"annotations": map[string]string{"summary": "Pod is crash looping", "description": "api-7d9f restarted 5 times"},
+					"startsAt":    "2024-01-01T00:00:00Z",
+					"fingerprint": "abc",
+					"status":      map[string]any{"state": "active"},
+				},
+				{"labels": map[string]string{"alertname": "Watchdog"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func scrape(config v1.Prometheus) v1.ScrapeResults {
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).
+		WithScrapeConfig(&v1.ScrapeConfig{Spec: v1.ScraperSpec{Prometheus: []v1.Prometheus{config}}})
+	return PrometheusScraper{}.Scrape(ctx)
+}
+
+func TestScrape(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	results := scrape(v1.Prometheus{URL: server.URL})
+	var targets, groups, analyses []v1.ScrapeResult
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			t.Fatalf("unexpected error: %v", r.Error)
+		case r.AnalysisResult != nil:
+			analyses = append(analyses, r)
+		case r.Type == TargetType:
+			targets = append(targets, r)
+		case r.Type == RuleGroupType:
+			groups = append(groups, r)
+		}
+	}
+
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(targets))
+	}
+	target := targets[0]
+	if target.ID != "serviceMonitor/shop/api/0/http://10.0.0.5:8080/metrics" || target.Status != "up" || target.Tags["namespace"] != "shop" {
+		t.Errorf("unexpected target %+v", target)
+	}
+	if len(target.RelationshipResults) != 2 ||
+		target.RelationshipResults[0].ConfigExternalID.ExternalID[0] != "Kubernetes/Pod/shop/api-7d9f" ||
+		target.RelationshipResults[1].ConfigExternalID.ExternalID[0] != "Kubernetes/Service/shop/api" {
+		t.Errorf("unexpected relationships %+v", target.RelationshipResults)
+	}
+	if targets[1].Status != "down" || len(targets[1].RelationshipResults) != 0 {
+		t.Errorf("unexpected target %+v", targets[1])
+	}
+
+	if len(groups) != 1 || groups[0].ID != "/etc/prometheus/rules/node.yaml/node.rules" || groups[0].Status != "err" {
+		t.Errorf("unexpected rule groups %+v", groups)
+	}
+
+	// pending alerts are not reported
+	if len(analyses) != 1 {
+		t.Fatalf("expected 1 analysis, got %d", len(analyses))
+	}
+	analysis := analyses[0].AnalysisResult
+	if analysis.Analyzer != "NodeDown" || analysis.ConfigType != TargetType || analysis.ExternalID != "node/http://node-1:9100/metrics" ||
+		analysis.Severity != models.SeverityCritical || analysis.Source != "Prometheus" {
+		t.Errorf("unexpected analysis %+v", analysis)
+	}
+}
+
+func TestScrapeAlertmanager(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	results := scrape(v1.Prometheus{URL: server.URL, Alertmanager: server.URL + "/alertmanager/"})
+	var analyses []*v1.AnalysisResult
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		if r.AnalysisResult != nil {
+			analyses = append(analyses, r.AnalysisResult)
+		}
+	}
+
+	// alerts that don't match a config (e.g. Watchdog) are skipped
+	if len(analyses) != 1 {
+		t.Fatalf("expected 1 analysis, got %d", len(analyses))
+	}
+	analysis := analyses[0]
+	if analysis.Analyzer != "KubePodCrashLooping" || analysis.ConfigType != "Kubernetes::Pod" || analysis.ExternalID != "Kubernetes/Pod/shop/api-7d9f" {
+		t.Errorf("unexpected analysis %+v", analysis)
+	}
+	if analysis.Severity != models.SeverityMedium || analysis.Summary != "Pod is crash looping" || analysis.Messages[0] != "api-7d9f restarted 5 times" {
+		t.Errorf("unexpected analysis %+v", analysis)
+	}
+	if analysis.FirstObserved == nil || analysis.Source != "Alertmanager" {
+		t.Errorf("expected the alert start time & source, got %+v", analysis)
+	}
+}
+
+func TestScrapeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"status":"error","errorType":"unavailable","error":"tsdb not ready"}`))
+	}))
+	defer server.Close()
+
+	results := scrape(v1.Prometheus{URL: server.URL})
+	if errs := results.Errors(); len(errs) != 3 || results[0].Error == nil {
+		t.Fatalf("expected an error for targets, rules & alerts, got %+v", results)
+	}
+}
+
+func TestScrapeAlertsFailure(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	// the analysis aren't resolved when the alerts can't be fetched
+	results := scrape(v1.Prometheus{URL: server.URL, Alertmanager: server.URL + "/missing/"})
+	if !results.HasErr() || results.ResolvesAnalysis() {
+		t.Errorf("expected the analysis to be kept when the alerts can't be fetched")
+	}
+}
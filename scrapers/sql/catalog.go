@@ -0,0 +1,361 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flanksource/commons/logger"
+)
+
+// catalogQueries read the catalog of a database engine. Every query aliases its columns
+// to the same names, so that the results of all the engines are assembled the same way.
+type catalogQueries struct {
+	Engine string
+	// Version returns a single column with the version of the server
+	Version string
+	// Database returns the name, owner, encoding, collation & size of the current database
+	Database string
+	// Schemas returns the name & owner of the schemas
+	Schemas string
+	// Tables returns the table_schema, table_name, kind (table or view), owner, row_estimate, definition & comment of the tables and views
+	Tables string
+	// Columns returns the table_schema, table_name, column_name, ordinal_position, data_type, is_nullable & column_default of the columns
+	Columns string
+	// Indexes returns the table_schema, table_name, index_name & definition of the indexes
+	Indexes string
+	// Constraints returns the table_schema, table_name, constraint_name, constraint_type, definition, referenced_schema & referenced_table of the constraints
+	Constraints string
+	// Roles returns the role_name, superuser, can_login, create_role, create_db, valid_until & member_of (comma separated) of the roles
+	Roles string
+	// Grants returns the grantee, table_schema, table_name & privilege_type of the table privileges
+	Grants string
+	// Extensions returns the extension_name, version & table_schema of the extensions
+	Extensions string
+	// Settings returns the setting_name & setting_value of the server settings that are not defaults
+	Settings string
+}
+
+var postgresCatalog = catalogQueries{
+	Engine:   "Postgres",
+	Version:  `SELECT version()`,
+	Database: `SELECT datname AS name, pg_get_userbyid(datdba) AS owner, pg_encoding_to_char(encoding) AS encoding, datcollate AS collation, pg_database_size(oid) AS size FROM pg_database WHERE datname = current_database()`,
+	Schemas:  `SELECT nspname AS name, pg_get_userbyid(nspowner) AS owner FROM pg_namespace WHERE nspname <> 'information_schema' AND nspname NOT LIKE 'pg\_%'`,
+	Tables: `SELECT n.nspname AS table_schema, c.relname AS table_name,
+		CASE WHEN c.relkind IN ('v', 'm') THEN 'view' ELSE 'table' END AS kind,
+		pg_get_userbyid(c.relowner) AS owner, c.reltuples::bigint AS row_estimate,
+		CASE WHEN c.relkind IN ('v', 'm') THEN pg_get_viewdef(c.oid) END AS definition,
+		obj_description(c.oid, 'pg_class') AS comment
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm') AND n.nspname <> 'information_schema' AND n.nspname NOT LIKE 'pg\_%'`,
+	Columns: `SELECT table_schema, table_name, column_name, ordinal_position, data_type, is_nullable, column_default
+		FROM information_schema.columns WHERE table_schema NOT IN ('information_schema', 'pg_catalog')`,
+	Indexes: `SELECT schemaname AS table_schema, tablename AS table_name, indexname AS index_name, indexdef AS definition
+		FROM pg_indexes WHERE schemaname NOT IN ('information_schema', 'pg_catalog')`,
+	Constraints: `SELECT n.nspname AS table_schema, t.relname AS table_name, c.conname AS constraint_name,
+		CASE c.contype WHEN 'p' THEN 'PRIMARY KEY' WHEN 'f' THEN 'FOREIGN KEY' WHEN 'u' THEN 'UNIQUE' WHEN 'c' THEN 'CHECK' ELSE 'EXCLUDE' END AS constraint_type,
+		pg_get_constraintdef(c.oid) AS definition, rn.nspname AS referenced_schema, rt.relname AS referenced_table
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid JOIN pg_namespace n ON n.oid = t.relnamespace
+		LEFT JOIN pg_class rt ON rt.oid = c.confrelid LEFT JOIN pg_namespace rn ON rn.oid = rt.relnamespace
+		WHERE n.nspname <> 'information_schema' AND n.nspname NOT LIKE 'pg\_%'`,
+	Roles: `SELECT r.rolname AS role_name, r.rolsuper AS superuser, r.rolcanlogin AS can_login, r.rolcreaterole AS create_role,
+		r.rolcreatedb AS create_db, r.rolvaliduntil AS valid_until,
+		array_to_string(ARRAY(SELECT b.rolname FROM pg_auth_members m JOIN pg_roles b ON m.roleid = b.oid WHERE m.member = r.oid), ',') AS member_of
+		FROM pg_roles r WHERE r.rolname NOT LIKE 'pg\_%'`,
+	Grants: `SELECT grantee, table_schema, table_name, privilege_type FROM information_schema.table_privileges
+		WHERE table_schema NOT IN ('information_schema', 'pg_catalog')`,
+	Extensions: `SELECT e.extname AS extension_name, e.extversion AS version, n.nspname AS table_schema
+		FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace`,
+	Settings: `SELECT name AS setting_name, setting || coalesce(unit, '') AS setting_value FROM pg_settings WHERE source NOT IN ('default', 'override')`,
+}
+
+const mysqlSystemSchemas = `('information_schema', 'mysql', 'performance_schema', 'sys')`
+
+var mysqlCatalog = catalogQueries{
+	Engine:   "MySQL",
+	Version:  `SELECT version()`,
+	Database: `SELECT database() AS name, @@character_set_database AS encoding, @@collation_database AS collation`,
+	Schemas: `SELECT schema_name AS name, default_character_set_name AS encoding, default_collation_name AS collation
+		FROM information_schema.schemata WHERE schema_name NOT IN ` + mysqlSystemSchemas,
+	Tables: `SELECT t.table_schema AS table_schema, t.table_name AS table_name,
+		CASE t.table_type WHEN 'VIEW' THEN 'view' ELSE 'table' END AS kind,
+		v.definer AS owner, t.table_rows AS row_estimate, v.view_definition AS definition, t.table_comment AS comment
+		FROM information_schema.tables t
+		LEFT JOIN information_schema.views v ON v.table_schema = t.table_schema AND v.table_name = t.table_name
+		WHERE t.table_schema NOT IN ` + mysqlSystemSchemas,
+	Columns: `SELECT table_schema AS table_schema, table_name AS table_name, column_name AS column_name, ordinal_position AS ordinal_position,
+		column_type AS data_type, is_nullable AS is_nullable, column_default AS column_default
+		FROM information_schema.columns WHERE table_schema NOT IN ` + mysqlSystemSchemas,
+	Indexes: `SELECT table_schema AS table_schema, table_name AS table_name, index_name AS index_name,
+		CONCAT(IF(non_unique = 0, 'UNIQUE ', ''), index_type, ' (', GROUP_CONCAT(column_name ORDER BY seq_in_index), ')') AS definition
+		FROM information_schema.statistics WHERE table_schema NOT IN ` + mysqlSystemSchemas + `
+		GROUP BY table_schema, table_name, index_name, non_unique, index_type`,
+	Constraints: `SELECT tc.table_schema AS table_schema, tc.table_name AS table_name, tc.constraint_name AS constraint_name,
+		tc.constraint_type AS constraint_type, GROUP_CONCAT(kcu.column_name ORDER BY kcu.ordinal_position) AS definition,
+		MAX(kcu.referenced_table_schema) AS referenced_schema, MAX(kcu.referenced_table_name) AS referenced_table
+		FROM information_schema.table_constraints tc
+		LEFT JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema
+			AND kcu.constraint_name = tc.constraint_name AND kcu.table_name = tc.table_name
+		WHERE tc.table_schema NOT IN ` + mysqlSystemSchemas + `
+		GROUP BY tc.table_schema, tc.table_name, tc.constraint_name, tc.constraint_type`,
+	Roles: `SELECT CONCAT(user, '@', host) AS role_name, super_priv AS superuser, IF(account_locked = 'Y', 'N', 'Y') AS can_login,
+		create_user_priv AS create_role, create_priv AS create_db, NULL AS valid_until, '' AS member_of FROM mysql.user`,
+	// grantees are quoted, e.g. 'app'@'%'
+	Grants: `SELECT REPLACE(grantee, '''', '') AS grantee, table_schema AS table_schema, table_name AS table_name, privilege_type AS privilege_type
+		FROM information_schema.table_privileges WHERE table_schema NOT IN ` + mysqlSystemSchemas,
+	// the variables that were not compiled in, without the gtid sets that change with every transaction
+	Settings: `SELECT g.variable_name AS setting_name, g.variable_value AS setting_value
+		FROM performance_schema.global_variables g JOIN performance_schema.variables_info i ON i.variable_name = g.variable_name
+		WHERE i.variable_source <> 'COMPILED' AND g.variable_name NOT IN ('gtid_executed', 'gtid_purged', 'gtid_owned')`,
+}
+
+var sqlServerCatalog = catalogQueries{
+	Engine:   "SQLServer",
+	Version:  `SELECT @@VERSION`,
+	Database: `SELECT name, SUSER_SNAME(owner_sid) AS owner, collation_name AS collation FROM sys.databases WHERE name = DB_NAME()`,
+	Schemas: `SELECT s.name AS name, USER_NAME(s.principal_id) AS owner FROM sys.schemas s
+		WHERE s.name NOT IN ('sys', 'INFORMATION_SCHEMA', 'guest') AND s.name NOT LIKE 'db[_]%'`,
+	Tables: `SELECT s.name AS table_schema, o.name AS table_name, CASE o.type WHEN 'V' THEN 'view' ELSE 'table' END AS kind,
+		NULL AS owner, (SELECT SUM(p.rows) FROM sys.partitions p WHERE p.object_id = o.object_id AND p.index_id IN (0, 1)) AS row_estimate,
+		CASE o.type WHEN 'V' THEN OBJECT_DEFINITION(o.object_id) END AS definition, NULL AS comment
+		FROM sys.objects o JOIN sys.schemas s ON s.schema_id = o.schema_id
+		WHERE o.type IN ('U', 'V') AND o.is_ms_shipped = 0`,
+	Columns: `SELECT table_schema AS table_schema, table_name AS table_name, column_name AS column_name, ordinal_position AS ordinal_position,
+		data_type AS data_type, is_nullable AS is_nullable, column_default AS column_default
+		FROM INFORMATION_SCHEMA.COLUMNS WHERE table_schema NOT IN ('sys', 'INFORMATION_SCHEMA')`,
+	Indexes: `SELECT s.name AS table_schema, t.name AS table_name, i.name AS index_name,
+		CONCAT(CASE WHEN i.is_unique = 1 THEN 'UNIQUE ' ELSE '' END, i.type_desc, ' (',
+			STRING_AGG(c.name, ',') WITHIN GROUP (ORDER BY ic.key_ordinal), ')') AS definition
+		FROM sys.indexes i
+		JOIN sys.tables t ON t.object_id = i.object_id JOIN sys.schemas s ON s.schema_id = t.schema_id
+		JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+		JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+		WHERE i.name IS NOT NULL AND t.is_ms_shipped = 0
+		GROUP BY s.name, t.name, i.name, i.is_unique, i.type_desc`,
+	Constraints: `SELECT tc.table_schema AS table_schema, tc.table_name AS table_name, tc.constraint_name AS constraint_name,
+		tc.constraint_type AS constraint_type, STRING_AGG(kcu.column_name, ',') AS definition,
+		MAX(ref.table_schema) AS referenced_schema, MAX(ref.table_name) AS referenced_table
+		FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+		LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+		LEFT JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc ON rc.constraint_schema = tc.constraint_schema AND rc.constraint_name = tc.constraint_name
+		LEFT JOIN INFORMATION_SCHEMA.TABLE_CONSTRAINTS ref ON ref.constraint_schema = rc.unique_constraint_schema AND ref.constraint_name = rc.unique_constraint_name
+		GROUP BY tc.table_schema, tc.table_name, tc.constraint_name, tc.constraint_type`,
+	// members of db_owner have every permission on the database
+	Roles: `SELECT p.name AS role_name, IS_ROLEMEMBER('db_owner', p.name) AS superuser,
+		CASE WHEN p.type = 'R' THEN 0 ELSE 1 END AS can_login, 0 AS create_role, 0 AS create_db, NULL AS valid_until,
+		(SELECT STRING_AGG(r.name, ',') FROM sys.database_role_members m JOIN sys.database_principals r ON r.principal_id = m.role_principal_id
+			WHERE m.member_principal_id = p.principal_id) AS member_of
+		FROM sys.database_principals p
+		WHERE p.type IN ('S', 'U', 'G', 'R', 'E', 'X') AND p.is_fixed_role = 0 AND p.name NOT IN ('sys', 'INFORMATION_SCHEMA', 'guest', 'public')`,
+	Grants: `SELECT grantee AS grantee, table_schema AS table_schema, table_name AS table_name, privilege_type AS privilege_type
+		FROM INFORMATION_SCHEMA.TABLE_PRIVILEGES`,
+	// sys.configurations has no default values, the options are compared with their documented defaults
+	Settings: `SELECT c.name AS setting_name, CONVERT(nvarchar(4000), c.value_in_use) AS setting_value
+		FROM sys.configurations c JOIN (VALUES ` + sqlServerDefaultSettings() + `) AS d(name, value) ON d.name = c.name
+		WHERE CONVERT(bigint, c.value_in_use) <> d.value`,
+}
+
+// sqlServerDefaults are the default values of the configuration options of SQL Server.
+// The options without a default that is the same on every server e.g. min server memory are not included.
+var sqlServerDefaults = map[string]int64{
+	"access check cache bucket count":    0,
+	"access check cache quota":           0,
+	"Ad Hoc Distributed Queries":         0,
+	"affinity I/O mask":                  0,
+	"affinity mask":                      0,
+	"affinity64 I/O mask":                0,
+	"affinity64 mask":                    0,
+	"allow filesystem enumeration":       1,
+	"allow polybase export":              0,
+	"allow updates":                      0,
+	"automatic soft-NUMA disabled":       0,
+	"backup checksum default":            0,
+	"backup compression default":         0,
+	"blocked process threshold (s)":      0,
+	"c2 audit mode":                      0,
+	"clr enabled":                        0,
+	"clr strict security":                1,
+	"common criteria compliance enabled": 0,
+	"contained database authentication":  0,
+	"cost threshold for parallelism":     5,
+	"cross db ownership chaining":        0,
+	"cursor threshold":                   -1,
+	"Database Mail XPs":                  0,
+	"default full-text language":         1033,
+	"default language":                   0,
+	"default trace enabled":              1,
+	"disallow results from triggers":     0,
+	"EKM provider enabled":               0,
+	"external scripts enabled":           0,
+	"filestream access level":            0,
+	"fill factor (%)":                    0,
+	"ft crawl bandwidth (max)":           100,
+	"ft crawl bandwidth (min)":           0,
+	"ft notify bandwidth (max)":          100,
+	"ft notify bandwidth (min)":          0,
+	"hadoop connectivity":                0,
+	"in-doubt xact resolution":           0,
+	"index create memory (KB)":           0,
+	"lightweight pooling":                0,
+	"locks":                              0,
+	"max degree of parallelism":          0,
+	"max full-text crawl range":          4,
+	"max server memory (MB)":             2147483647,
+	"max text repl size (B)":             65536,
+	"max worker threads":                 0,
+	"media retention":                    0,
+	"min memory per query (KB)":          1024,
+	"nested triggers":                    1,
+	"network packet size (B)":            4096,
+	"Ole Automation Procedures":          0,
+	"open objects":                       0,
+	"optimize for ad hoc workloads":      0,
+	"PH timeout (s)":                     60,
+	"polybase enabled":                   0,
+	"precompute rank":                    0,
+	"priority boost":                     0,
+	"query governor cost limit":          0,
+	"query wait (s)":                     -1,
+	"recovery interval (min)":            0,
+	"remote access":                      1,
+	"remote admin connections":           0,
+	"remote data archive":                0,
+	"remote login timeout (s)":           10,
+	"remote proc trans":                  0,
+	"remote query timeout (s)":           600,
+	"Replication XPs":                    0,
+	"scan for startup procs":             0,
+	"server trigger recursion":           1,
+	"set working set size":               0,
+	"show advanced options":              0,
+	"SMO and DMO XPs":                    1,
+	"transform noise words":              0,
+	"two digit year cutoff":              2049,
+	"user connections":                   0,
+	"user options":                       0,
+	"xp_cmdshell":                        0,
+}
+
+// sqlServerDefaultSettings returns the rows of the default values of the SQL Server options
+func sqlServerDefaultSettings() string {
+	var rows []string
+	for _, name := range sortedKeys(sqlServerDefaults) {
+		rows = append(rows, fmt.Sprintf("('%s', %d)", name, sqlServerDefaults[name]))
+	}
+	return strings.Join(rows, ", ")
+}
+
+func getCatalogQueries(driver string) (*catalogQueries, error) {
+	switch driver {
+	case "postgres", "pgx":
+		return &postgresCatalog, nil
+	case "mysql":
+		return &mysqlCatalog, nil
+	case "sqlserver", "mssql":
+		return &sqlServerCatalog, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %s, expected postgres, mysql or sqlserver", driver)
+}
+
+type catalogRow map[string]string
+
+// catalog is the result of the catalog queries, with lowercase column names
+type catalog struct {
+	Engine      string
+	Version     string
+	Database    catalogRow
+	Schemas     []catalogRow
+	Tables      []catalogRow
+	Columns     []catalogRow
+	Indexes     []catalogRow
+	Constraints []catalogRow
+	Roles       []catalogRow
+	Grants      []catalogRow
+	Extensions  []catalogRow
+	Settings    []catalogRow
+}
+
+// readCatalog runs the catalog queries. The queries of roles, grants, extensions & settings
+// may require privileges the user doesn't have, their failures are logged only.
+func readCatalog(ctx context.Context, db *sql.DB, queries catalogQueries, timeout time.Duration) (*catalog, error) {
+	c := catalog{Engine: queries.Engine}
+	query := func(q string) ([]catalogRow, error) {
+		if q == "" {
+			return nil, nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		details, err := querySQL(ctx, db, q, nil, 1_000_000)
+		if err != nil {
+			return nil, err
+		}
+		var rows []catalogRow
+		for _, r := range details.Rows {
+			out := catalogRow{}
+			for k, v := range r {
+				if v != nil {
+					out[strings.ToLower(k)] = fmt.Sprint(v)
+				}
+			}
+			rows = append(rows, out)
+		}
+		return rows, nil
+	}
+
+	required := []struct {
+		name  string
+		query string
+		out   *[]catalogRow
+	}{
+		{"schemas", queries.Schemas, &c.Schemas},
+		{"tables", queries.Tables, &c.Tables},
+		{"columns", queries.Columns, &c.Columns},
+		{"indexes", queries.Indexes, &c.Indexes},
+		{"constraints", queries.Constraints, &c.Constraints},
+	}
+	optional := []struct {
+		name  string
+		query string
+		out   *[]catalogRow
+	}{
+		{"roles", queries.Roles, &c.Roles},
+		{"grants", queries.Grants, &c.Grants},
+		{"extensions", queries.Extensions, &c.Extensions},
+		{"settings", queries.Settings, &c.Settings},
+	}
+
+	versions, err := query(queries.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+	for _, v := range versions {
+		for _, version := range v {
+			c.Version = version
+		}
+	}
+
+	databases, err := query(queries.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database: %w", err)
+	} else if len(databases) > 0 {
+		c.Database = databases[0]
+	} else {
+		c.Database = catalogRow{}
+	}
+
+	for _, q := range required {
+		if *q.out, err = query(q.query); err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", q.name, err)
+		}
+	}
+	for _, q := range optional {
+		if *q.out, err = query(q.query); err != nil {
+			logger.Warnf("failed to get %s of %s database: %v", q.name, queries.Engine, err)
+		}
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,412 @@
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flanksource/commons/collections"
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/flanksource/duty/types"
+)
+
+type DatabaseScraper struct {
+}
+
+func (d DatabaseScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Database) > 0
+}
+
+// Scrape reads the catalog of the databases and emits their schemas, tables, views, roles & extensions.
+// The changes to the columns, indexes & constraints of the tables are recorded as diffs of their config.
+func (d DatabaseScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	for _, config := range ctx.ScrapeConfig().Spec.Database {
+		results = append(results, scrapeDatabase(ctx, config)...)
+	}
+	return results
+}
+
+func scrapeDatabase(ctx api.ScrapeContext, config v1.Database) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	db, u, err := openDB(ctx, config.Connection)
+	if err != nil {
+		results.Errorf(err, "failed to connect to %s", config.GetEndpoint())
+		return results
+	}
+	defer db.Close()
+
+	queries, err := getCatalogQueries(u.Driver)
+	if err != nil {
+		results.Errorf(err, "failed to scrape %s", config.GetEndpoint())
+		return results
+	}
+
+	c, err := readCatalog(ctx, db, *queries, config.GetTimeout())
+	if err != nil {
+		results.Errorf(err, "failed to read the catalog of %s", config.GetEndpoint())
+		return results
+	}
+
+	id := u.Host
+	if name := c.Database["name"]; name != "" {
+		id += "/" + name
+	}
+
+	results = append(results, c.results(config, id)...)
+	return results
+}
+
+type Column struct {
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default,omitempty"`
+}
+
+type Index struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+type Constraint struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Definition string `json:"definition,omitempty"`
+	References string `json:"references,omitempty"`
+}
+
+type Grant struct {
+	Grantee    string   `json:"grantee"`
+	Privileges []string `json:"privileges"`
+}
+
+type Table struct {
+	Name        string       `json:"name"`
+	Schema      string       `json:"schema"`
+	Owner       string       `json:"owner,omitempty"`
+	Comment     string       `json:"comment,omitempty"`
+	Definition  string       `json:"definition,omitempty"`
+	Columns     []Column     `json:"columns,omitempty"`
+	Indexes     []Index      `json:"indexes,omitempty"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+	Grants      []Grant      `json:"grants,omitempty"`
+}
+
+type Role struct {
+	Name       string   `json:"name"`
+	Superuser  bool     `json:"superuser"`
+	CanLogin   bool     `json:"canLogin"`
+	CreateRole bool     `json:"createRole"`
+	CreateDB   bool     `json:"createDB"`
+	ValidUntil string   `json:"validUntil,omitempty"`
+	MemberOf   []string `json:"memberOf,omitempty"`
+}
+
+// writePrivileges granted to PUBLIC are reported as analysis
+var writePrivileges = []string{"INSERT", "UPDATE", "DELETE", "TRUNCATE", "ALTER", "DROP", "CONTROL"}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "t", "1", "y", "yes":
+		return true
+	}
+	return false
+}
+
+func (c catalog) configType(kind string) string {
+	return c.Engine + "::" + kind
+}
+
+// results assembles the catalog into config items
+func (c catalog) results(config v1.Database, id string) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	settings := map[string]string{}
+	for _, s := range c.Settings {
+		settings[s["setting_name"]] = s["setting_value"]
+	}
+
+	database := map[string]any{
+		"name":     c.Database["name"],
+		"version":  c.Version,
+		"settings": settings,
+	}
+	for _, key := range []string{"owner", "encoding", "collation"} {
+		if c.Database[key] != "" {
+			database[key] = c.Database[key]
+		}
+	}
+
+	dbResult := v1.ScrapeResult{
+		BaseScraper: config.BaseScraper,
+		ID:          id,
+		Name:        c.Database["name"],
+		Type:        c.configType("Database"),
+		ConfigClass: "Database",
+		Config:      database,
+	}
+	if dbResult.Name == "" {
+		dbResult.Name = id
+	}
+	if size, err := strconv.ParseInt(c.Database["size"], 10, 64); err == nil {
+		dbResult.Properties = append(dbResult.Properties, &types.Property{Name: "Size", Value: size, Unit: "bytes"})
+	}
+	results = append(results, dbResult)
+
+	schemas := map[string]bool{}
+	for _, s := range c.Schemas {
+		name := s["name"]
+		if len(config.Schemas) > 0 && !collections.MatchItems(name, config.Schemas...) {
+			continue
+		}
+		schemas[name] = true
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ID:               id + "/" + name,
+			Name:             name,
+			Type:             c.configType("Schema"),
+			ConfigClass:      "Schema",
+			Config:           s,
+			ParentExternalID: id,
+			ParentType:       dbResult.Type,
+		})
+	}
+
+	for _, e := range c.Extensions {
+		results = append(results, v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ID:               id + "/extension/" + e["extension_name"],
+			Name:             e["extension_name"],
+			Type:             c.configType("Extension"),
+			ConfigClass:      "Extension",
+			Config:           map[string]string{"name": e["extension_name"], "version": e["version"], "schema": e["table_schema"]},
+			ParentExternalID: id,
+			ParentType:       dbResult.Type,
+		})
+	}
+
+	tables, views := c.tables(schemas)
+	rowEstimates := c.rowEstimates()
+
+	roles := map[string]bool{}
+	for _, r := range c.Roles {
+		roles[r["role_name"]] = true
+	}
+
+	for _, tableID := range sortedKeys(tables) {
+		table := tables[tableID]
+		configType := c.configType("Table")
+		if views[tableID] {
+			configType = c.configType("View")
+		}
+
+		result := v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ID:               id + "/" + tableID,
+			Name:             table.Name,
+			Type:             configType,
+			ConfigClass:      strings.TrimPrefix(configType, c.Engine+"::"),
+			Config:           *table,
+			ParentExternalID: id + "/" + table.Schema,
+			ParentType:       c.configType("Schema"),
+			Tags:             map[string]string{"schema": table.Schema},
+		}
+
+		for _, constraint := range table.Constraints {
+			if constraint.References == "" {
+				continue
+			}
+			result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ConfigType: configType, ExternalID: []string{result.ID}},
+				RelatedExternalID: v1.ExternalID{ConfigType: c.configType("Table"), ExternalID: []string{id + "/" + constraint.References}},
+				Relationship:      "TableTable",
+			})
+		}
+
+		for _, grant := range table.Grants {
+			if strings.EqualFold(grant.Grantee, "PUBLIC") {
+				if risky := intersect(grant.Privileges, writePrivileges); len(risky) > 0 {
+					analysis := results.Analysis("PublicWriteAccess", configType, result.ID)
+					analysis.AnalysisType = models.AnalysisTypeSecurity
+					analysis.Severity = models.SeverityHigh
+					analysis.Source = c.Engine
+					analysis.Summary = fmt.Sprintf("PUBLIC can %s %s", strings.Join(risky, ", "), tableID)
+					analysis.Message(fmt.Sprintf("Every role of the database is granted %s on %s", strings.Join(risky, ", "), tableID))
+				}
+			} else if roles[grant.Grantee] {
+				result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+					ConfigExternalID:  v1.ExternalID{ConfigType: c.configType("Role"), ExternalID: []string{id + "/role/" + grant.Grantee}},
+					RelatedExternalID: v1.ExternalID{ConfigType: configType, ExternalID: []string{result.ID}},
+					Relationship:      "RoleTable",
+				})
+			}
+		}
+
+		if table.Definition == "" {
+			result.Properties = append(result.Properties, &types.Property{Name: "Rows", Value: rowEstimates[tableID]})
+		}
+		results = append(results, result)
+	}
+
+	for _, r := range c.Roles {
+		role := Role{
+			Name:       r["role_name"],
+			Superuser:  isTrue(r["superuser"]),
+			CanLogin:   isTrue(r["can_login"]),
+			CreateRole: isTrue(r["create_role"]),
+			CreateDB:   isTrue(r["create_db"]),
+			ValidUntil: r["valid_until"],
+		}
+		if r["member_of"] != "" {
+			role.MemberOf = strings.Split(r["member_of"], ",")
+		}
+
+		result := v1.ScrapeResult{
+			BaseScraper:      config.BaseScraper,
+			ID:               id + "/role/" + role.Name,
+			Name:             role.Name,
+			Type:             c.configType("Role"),
+			ConfigClass:      "Role",
+			Config:           role,
+			ParentExternalID: id,
+			ParentType:       dbResult.Type,
+		}
+		for _, group := range role.MemberOf {
+			result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ConfigType: result.Type, ExternalID: []string{id + "/role/" + group}},
+				RelatedExternalID: v1.ExternalID{ConfigType: result.Type, ExternalID: []string{result.ID}},
+				Relationship:      "RoleRole",
+			})
+		}
+		results = append(results, result)
+
+		if role.Superuser {
+			analysis := results.Analysis("SuperuserRole", result.Type, result.ID)
+			analysis.AnalysisType = models.AnalysisTypeSecurity
+			analysis.Severity = models.SeverityMedium
+			analysis.Source = c.Engine
+			analysis.Summary = fmt.Sprintf("%s is a superuser", role.Name)
+			if role.CanLogin {
+				analysis.Message(fmt.Sprintf("%s can login and bypasses all permission checks", role.Name))
+			}
+		}
+	}
+
+	return results
+}
+
+// tables groups the columns, indexes, constraints & grants by table (schema/name) of the given schemas
+func (c catalog) tables(schemas map[string]bool) (map[string]*Table, map[string]bool) {
+	tables := map[string]*Table{}
+	views := map[string]bool{}
+	for _, t := range c.Tables {
+		if !schemas[t["table_schema"]] {
+			continue
+		}
+		key := t["table_schema"] + "/" + t["table_name"]
+		tables[key] = &Table{
+			Name:       t["table_name"],
+			Schema:     t["table_schema"],
+			Owner:      t["owner"],
+			Comment:    t["comment"],
+			Definition: strings.TrimSpace(t["definition"]),
+		}
+		if t["kind"] == "view" {
+			views[key] = true
+		}
+	}
+
+	for _, col := range c.Columns {
+		if table, ok := tables[col["table_schema"]+"/"+col["table_name"]]; ok {
+			position, _ := strconv.Atoi(col["ordinal_position"])
+			table.Columns = append(table.Columns, Column{
+				Name:     col["column_name"],
+				Position: position,
+				Type:     col["data_type"],
+				Nullable: isTrue(col["is_nullable"]),
+				Default:  col["column_default"],
+			})
+		}
+	}
+
+	for _, index := range c.Indexes {
+		if table, ok := tables[index["table_schema"]+"/"+index["table_name"]]; ok {
+			table.Indexes = append(table.Indexes, Index{Name: index["index_name"], Definition: index["definition"]})
+		}
+	}
+
+	for _, con := range c.Constraints {
+		if table, ok := tables[con["table_schema"]+"/"+con["table_name"]]; ok {
+			constraint := Constraint{Name: con["constraint_name"], Type: con["constraint_type"], Definition: con["definition"]}
+			if con["referenced_table"] != "" {
+				constraint.References = con["referenced_schema"] + "/" + con["referenced_table"]
+			}
+			table.Constraints = append(table.Constraints, constraint)
+		}
+	}
+
+	grants := map[string]map[string][]string{}
+	for _, g := range c.Grants {
+		key := g["table_schema"] + "/" + g["table_name"]
+		if _, ok := tables[key]; !ok {
+			continue
+		}
+		if grants[key] == nil {
+			grants[key] = map[string][]string{}
+		}
+		if !collections.Contains(grants[key][g["grantee"]], g["privilege_type"]) {
+			grants[key][g["grantee"]] = append(grants[key][g["grantee"]], g["privilege_type"])
+		}
+	}
+
+	// the order of the catalog rows isn't guaranteed, sort them to not record diffs between runs
+	for key, table := range tables {
+		sort.Slice(table.Columns, func(i, j int) bool { return table.Columns[i].Position < table.Columns[j].Position })
+		sort.Slice(table.Indexes, func(i, j int) bool { return table.Indexes[i].Name < table.Indexes[j].Name })
+		sort.Slice(table.Constraints, func(i, j int) bool { return table.Constraints[i].Name < table.Constraints[j].Name })
+		for _, grantee := range sortedKeys(grants[key]) {
+			privileges := grants[key][grantee]
+			sort.Strings(privileges)
+			table.Grants = append(table.Grants, Grant{Grantee: grantee, Privileges: privileges})
+		}
+	}
+
+	return tables, views
+}
+
+// rowEstimates returns the estimated number of rows of the tables by schema/name
+func (c catalog) rowEstimates() map[string]int64 {
+	estimates := map[string]int64{}
+	for _, t := range c.Tables {
+		// postgres estimates tables that were never analyzed as -1
+		if rows, err := strconv.ParseFloat(t["row_estimate"], 64); err == nil && rows > 0 {
+			estimates[t["table_schema"]+"/"+t["table_name"]] = int64(rows)
+		}
+	}
+	return estimates
+}
+
+func intersect(values, with []string) []string {
+	var out []string
+	for _, v := range values {
+		if collections.Contains(with, strings.ToUpper(v)) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
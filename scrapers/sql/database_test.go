@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"testing"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func testCatalog() catalog {
+	return catalog{
+		Engine:   "Postgres",
+		Version:  "PostgreSQL 16.1",
+		Database: catalogRow{"name": "shop", "owner": "postgres", "size": "8192"},
+		Schemas:  []catalogRow{{"name": "public", "owner": "postgres"}, {"name": "audit", "owner": "postgres"}},
+		Tables: []catalogRow{
+			{"table_schema": "public", "table_name": "orders", "kind": "table", "row_estimate": "1200"},
+			{"table_schema": "public", "table_name": "customers", "kind": "table", "row_estimate": "-1"},
+			{"table_schema": "public", "table_name": "open_orders", "kind": "view", "definition": " SELECT * FROM orders"},
+			{"table_schema": "audit", "table_name": "events", "kind": "table"},
+		},
+		Columns: []catalogRow{
+			{"table_schema": "public", "table_name": "orders", "column_name": "customer_id", "ordinal_position": "2", "data_type": "integer", "is_nullable": "YES"},
+			{"table_schema": "public", "table_name": "orders", "column_name": "id", "ordinal_position": "1", "data_type": "integer", "is_nullable": "NO"},
+			{"table_schema": "public", "table_name": "customers", "column_name": "id", "ordinal_position": "1", "data_type": "integer", "is_nullable": "NO"},
+		},
+		Indexes: []catalogRow{
+			{"table_schema": "public", "table_name": "orders", "index_name": "orders_pkey", "definition": "CREATE UNIQUE INDEX orders_pkey ON public.orders USING btree (id)"},
+		},
+		Constraints: []catalogRow{
+			{"table_schema": "public", "table_name": "orders", "constraint_name": "orders_customer_id_fkey", "constraint_type": "FOREIGN KEY",
+				"definition": "FOREIGN KEY (customer_id) REFERENCES customers(id)", "referenced_schema": "public", "referenced_table": "customers"},
+		},
+		Roles: []catalogRow{
+			{"role_name": "postgres", "superuser": "true", "can_login": "true"},
+			{"role_name": "app", "superuser": "false", "can_login": "true", "member_of": "readers"},
+		},
+		Grants: []catalogRow{
+			{"grantee": "app", "table_schema": "public", "table_name": "orders", "privilege_type": "SELECT"},
+			{"grantee": "PUBLIC", "table_schema": "public", "table_name": "customers", "privilege_type": "SELECT"},
+			{"grantee": "PUBLIC", "table_schema": "public", "table_name": "customers", "privilege_type": "UPDATE"},
+		},
+		Extensions: []catalogRow{{"extension_name": "pgcrypto", "version": "1.3", "table_schema": "public"}},
+		Settings:   []catalogRow{{"setting_name": "max_connections", "setting_value": "200"}},
+	}
+}
+
+func TestCatalogResults(t *testing.T) {
+	results := testCatalog().results(v1.Database{Schemas: []string{"public"}}, "db:5432/shop")
+
+	items := map[string]v1.ScrapeResult{}
+	var analyzers []string
+	for _, result := range results {
+		if result.AnalysisResult != nil {
+			analyzers = append(analyzers, result.AnalysisResult.Analyzer+" "+result.AnalysisResult.ExternalID)
+		} else if len(result.Changes) > 0 {
+			t.Errorf("expected the schema changes to be recorded as diffs, got %v", result.Changes)
+		} else {
+			items[result.Type+" "+result.ID] = result
+		}
+	}
+
+	for _, expected := range []string{
+		"Postgres::Database db:5432/shop",
+		"Postgres::Schema db:5432/shop/public",
+		"Postgres::Extension db:5432/shop/extension/pgcrypto",
+		"Postgres::Table db:5432/shop/public/orders",
+		"Postgres::Table db:5432/shop/public/customers",
+		"Postgres::View db:5432/shop/public/open_orders",
+		"Postgres::Role db:5432/shop/role/app",
+	} {
+		if _, ok := items[expected]; !ok {
+			t.Errorf("expected %s to be scraped", expected)
+		}
+	}
+	if _, ok := items["Postgres::Schema db:5432/shop/audit"]; ok {
+		t.Errorf("expected the audit schema to be excluded")
+	}
+
+	orders := items["Postgres::Table db:5432/shop/public/orders"]
+	table := orders.Config.(Table)
+	if len(table.Columns) != 2 || table.Columns[0].Name != "id" || table.Columns[0].Nullable {
+		t.Errorf("expected the columns to be ordered by position, got %v", table.Columns)
+	}
+	if len(orders.RelationshipResults) != 2 {
+		t.Errorf("expected a foreign key and a grant relationship, got %v", orders.RelationshipResults)
+	}
+	if orders.Properties[0].Value != 1200 {
+		t.Errorf("expected the row estimate as a property, got %v", orders.Properties[0])
+	}
+
+	if len(analyzers) != 2 || analyzers[0] != "PublicWriteAccess db:5432/shop/public/customers" || analyzers[1] != "SuperuserRole db:5432/shop/role/postgres" {
+		t.Errorf("unexpected analysis %v", analyzers)
+	}
+}
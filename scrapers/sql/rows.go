@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+)
+
+// bindCursor replaces the :param references of the cursor in the query with the placeholders of the driver,
+// returning the args to bind. Postgres casts (e.g. ::text) are left as is.
+func bindCursor(driver, query string, cursor *queryCursor) (string, []any) {
+	if cursor == nil {
+		return query, nil
+	}
+
+	var args []any
+	re := regexp.MustCompile(`(^|[^:]):` + regexp.QuoteMeta(cursor.GetParam()) + `\b`)
+	query = re.ReplaceAllStringFunc(query, func(match string) string {
+		prefix := strings.TrimSuffix(match, ":"+cursor.GetParam())
+		args = append(args, cursor.value)
+		switch driver {
+		case "postgres", "pgx":
+			return fmt.Sprintf("%s$%d", prefix, len(args))
+		case "sqlserver", "mssql":
+			return fmt.Sprintf("%s@p%d", prefix, len(args))
+		}
+		return prefix + "?"
+	})
+	return query, args
+}
+
+var timeFormats = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+func parseTime(value string) *time.Time {
+	for _, format := range timeFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// greater compares cursor values as numbers or timestamps when possible
+func greater(a, b string) bool {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			return x > y
+		}
+	}
+	if x, y := parseTime(a), parseTime(b); x != nil && y != nil {
+		return x.After(*y)
+	}
+	return a > b
+}
+
+// popString removes a column from the row, returning its value
+func popString(row map[string]any, column string) string {
+	value, ok := row[column]
+	if !ok {
+		return ""
+	}
+	delete(row, column)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// popJSON removes a column of JSON objects from the row
+func popJSON(row map[string]any, column string) map[string]any {
+	value := popString(row, column)
+	if value == "" {
+		return map[string]any{}
+	}
+	var out map[string]any
+	if err := json.Unmarshal([]byte(value), &out); err != nil {
+		return map[string]any{column: value}
+	}
+	return out
+}
+
+func copyRow(row map[string]any) map[string]any {
+	out := make(map[string]any, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	return out
+}
+
+func changeFromRow(_row map[string]any) v1.ChangeResult {
+	row := copyRow(_row)
+	change := v1.ChangeResult{
+		ExternalID:       popString(row, "external_id"),
+		ConfigType:       popString(row, "config_type"),
+		ExternalChangeID: popString(row, "external_change_id"),
+		ChangeType:       popString(row, "change_type"),
+		Summary:          popString(row, "summary"),
+		Severity:         popString(row, "severity"),
+		Source:           popString(row, "source"),
+		CreatedAt:        parseTime(popString(row, "created_at")),
+	}
+	if createdBy := popString(row, "created_by"); createdBy != "" {
+		change.CreatedBy = &createdBy
+	}
+
+	change.Details = popJSON(row, "details")
+	for k, v := range row {
+		change.Details[k] = v
+	}
+	return change
+}
+
+func analysisFromRow(results *v1.ScrapeResults, _row map[string]any) {
+	row := copyRow(_row)
+	analysis := results.Analysis(popString(row, "analyzer"), popString(row, "config_type"), popString(row, "external_id"))
+	analysis.AnalysisType = models.AnalysisType(popString(row, "analysis_type"))
+	analysis.Severity = models.Severity(popString(row, "severity"))
+	analysis.Summary = popString(row, "summary")
+	analysis.Source = popString(row, "source")
+	analysis.Status = popString(row, "status")
+	if messages := popString(row, "messages"); messages != "" {
+		if err := json.Unmarshal([]byte(messages), &analysis.Messages); err != nil {
+			analysis.Messages = []string{messages}
+		}
+	}
+
+	analysis.Analysis = popJSON(row, "analysis")
+	for k, v := range row {
+		analysis.Analysis[k] = v
+	}
+}
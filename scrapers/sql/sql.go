@@ -1,12 +1,15 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 
+	"github.com/flanksource/commons/logger"
 	"github.com/flanksource/config-db/api"
 	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/config-db/utils"
 	"github.com/xo/dburl"
 
 	//drivers
@@ -15,6 +18,8 @@ import (
 	_ "github.com/microsoft/go-mssqldb"
 )
 
+const cursorType = "sql"
+
 type SqlScraper struct {
 }
 
@@ -24,80 +29,221 @@ func (s SqlScraper) CanScrape(configs v1.ScraperSpec) bool {
 
 func (s SqlScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
 	var results v1.ScrapeResults
-	for _, _config := range ctx.ScrapeConfig().Spec.SQL {
-		var (
-			config     = _config
-			err        error
-			connection = config.Connection.GetModel()
-		)
-
-		if strings.HasPrefix(config.Connection.Connection, "connection://") {
-			connection, err = ctx.HydrateConnection(config.Connection.Connection)
-			if err != nil {
-				results.Errorf(err, "failed to find connection name %s", config.Connection.Connection)
-				continue
-			}
-		} else {
-			connection, err = ctx.HydrateConnectionModel(*connection)
-			if err != nil {
-				results.Errorf(err, "failed to hydrate connection for %s", config.Connection)
-				continue
-			}
-		}
+	for _, config := range ctx.ScrapeConfig().Spec.SQL {
+		results = append(results, scrape(ctx, config)...)
+	}
+
+	return results
+}
+
+// openDB hydrates the connection and opens it, returning the parsed connection url
+func openDB(ctx api.ScrapeContext, conn v1.Connection) (*sql.DB, *dburl.URL, error) {
+	var (
+		err        error
+		connection = conn.GetModel()
+	)
 
-		db, err := dburl.Open(connection.URL)
+	if strings.HasPrefix(conn.Connection, "connection://") {
+		connection, err = ctx.HydrateConnection(conn.Connection)
 		if err != nil {
-			results.Errorf(err, "failed to open connection to %s", config.GetEndpoint())
-			continue
+			return nil, nil, fmt.Errorf("failed to find connection name %s: %w", conn.Connection, err)
 		}
-		defer db.Close()
-
-		rows, err := querySQL(db, config.Query)
+	} else {
+		connection, err = ctx.HydrateConnectionModel(*connection)
 		if err != nil {
-			results.Errorf(err, "failed to query %s", config.GetEndpoint())
-			continue
+			return nil, nil, fmt.Errorf("failed to hydrate connection for %s: %w", conn.GetEndpoint(), err)
 		}
+	}
+
+	u, err := dburl.Parse(connection.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse connection to %s: %w", conn.GetEndpoint(), err)
+	}
+
+	db, err := sql.Open(u.Driver, u.DSN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open connection to %s: %w", conn.GetEndpoint(), err)
+	}
+	return db, u, nil
+}
 
-		for _, _row := range rows.Rows {
-			var row = _row
-			var item interface{}
-			item = row
-			if len(rows.Columns) == 1 {
-				// if there is only a single column, return the value of that column
-				item = row[rows.Columns[0]]
+// scrape runs the queries of a config, closing the connection once done
+func scrape(ctx api.ScrapeContext, config v1.SQL) v1.ScrapeResults {
+	var results v1.ScrapeResults
+
+	db, u, err := openDB(ctx, config.Connection)
+	if err != nil {
+		results.Errorf(err, "failed to connect to %s", config.GetEndpoint())
+		return results
+	}
+	defer db.Close()
+
+	run := func(query string) (*SQLDetails, error) {
+		var cursor *queryCursor
+		if config.Cursor != nil {
+			if cursor, err = getCursor(ctx, config, query); err != nil {
+				return nil, fmt.Errorf("failed to get cursor: %w", err)
 			}
-			results = append(results, v1.ScrapeResult{
-				BaseScraper: config.BaseScraper,
-				Config:      item,
+			query = cursor.orderBy(query)
+		}
+
+		query, args := bindCursor(u.Driver, query, cursor)
+		timeout, cancel := context.WithTimeout(ctx, config.GetTimeout())
+		defer cancel()
+		rows, err := querySQL(timeout, db, query, args, config.GetMaxRows())
+		if err != nil {
+			return nil, err
+		}
+		if rows.Truncated {
+			logger.Warnf("only the first %d rows of a query of %s were read", config.GetMaxRows(), config.GetEndpoint())
+		}
+
+		if err := cursor.update(rows.Rows, rows.Truncated); err != nil {
+			return nil, fmt.Errorf("%w, raise maxRows to read past them", err)
+		}
+		if cursor != nil && cursor.next != cursor.value {
+			// the cursor is only saved once the rows are, so that failed runs are retried
+			results.OnSave(func() error {
+				if err := ctx.SaveCursor(cursorType, cursor.scope, cursor.next); err != nil {
+					return fmt.Errorf("failed to save cursor of %s: %w", config.GetEndpoint(), err)
+				}
+				return nil
 			})
 		}
+		return rows, nil
+	}
+
+	rows, err := run(config.Query)
+	if err != nil {
+		results.Errorf(err, "failed to query %s", config.GetEndpoint())
+		return results
+	}
+
+	for _, _row := range rows.Rows {
+		var row = _row
+		var item interface{}
+		item = row
+		if len(rows.Columns) == 1 {
+			// if there is only a single column, return the value of that column
+			item = row[rows.Columns[0]]
+		}
+		results = append(results, v1.ScrapeResult{
+			BaseScraper: config.BaseScraper,
+			Config:      item,
+		})
+	}
 
+	if config.Changes != "" {
+		rows, err := run(config.Changes)
+		if err != nil {
+			results.Errorf(err, "failed to query changes of %s", config.GetEndpoint())
+			return results
+		}
+		for _, row := range rows.Rows {
+			results.AddChange(changeFromRow(row))
+		}
+	}
+
+	if config.Analysis != "" {
+		rows, err := run(config.Analysis)
+		if err != nil {
+			results.Errorf(err, "failed to query analysis of %s", config.GetEndpoint())
+			return results
+		}
+		for _, row := range rows.Rows {
+			analysisFromRow(&results, row)
+		}
 	}
 
 	return results
 }
 
+// queryCursor is the value bound to the cursor param of the queries,
+// and the greatest value of the cursor column seen during this run.
+type queryCursor struct {
+	v1.SQLCursor
+	scope string
+	value string
+	next  string
+}
+
+func getCursor(ctx api.ScrapeContext, config v1.SQL, query string) (*queryCursor, error) {
+	// each query keeps its own cursor, editing a query resets it
+	scope, err := utils.Hash([]string{config.GetEndpoint(), query})
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ctx.GetCursor(cursorType, scope)
+	if err != nil {
+		return nil, err
+	} else if value == "" {
+		value = config.Cursor.GetInitial()
+	}
+
+	return &queryCursor{SQLCursor: *config.Cursor, scope: scope, value: value, next: value}, nil
+}
+
+// orderBy orders the rows of the query by the cursor column,
+// so that the rows left unread when the query is truncated come after the cursor.
+// The query is wrapped as a subquery, so on SQL Server it can't have a top-level ORDER BY or a WITH clause.
+func (c *queryCursor) orderBy(query string) string {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	return fmt.Sprintf("SELECT * FROM (%s) cursor_rows ORDER BY %s", query, c.Column)
+}
+
+// update advances the cursor to the greatest value of the rows.
+// When the rows are truncated, the unread rows may have the same value as the last row read,
+// so the cursor stops before it.
+// It fails when all the rows of a truncated query have the same value, as the cursor can't advance.
+func (c *queryCursor) update(rows []map[string]any, truncated bool) error {
+	if c == nil {
+		return nil
+	}
+
+	var last string
+	if truncated && len(rows) > 0 {
+		last, _ = rows[len(rows)-1][c.Column].(string)
+	}
+	for _, row := range rows {
+		if value, ok := row[c.Column].(string); ok && greater(value, c.next) && (last == "" || greater(last, value)) {
+			c.next = value
+		}
+	}
+	if truncated && last != "" && c.next == c.value {
+		return fmt.Errorf("all the %d rows read have the %s %s", len(rows), c.Column, last)
+	}
+	return nil
+}
+
 type SQLDetails struct {
 	Columns []string
 	Rows    []map[string]interface{} `json:"rows,omitempty"`
 	Count   int                      `json:"count,omitempty"`
+	// Truncated is true when the query returned more rows than were read
+	Truncated bool `json:"truncated,omitempty"`
 }
 
-// Connects to a db using the specified `driver` and `connectionstring`
-// Performs the test query given in `query`.
-// Gives the single row test query result as result.
-func querySQL(db *sql.DB, query string) (*SQLDetails, error) {
-	rows, err := db.Query(query)
-	result := SQLDetails{}
-	if err != nil || rows.Err() != nil {
-		return nil, fmt.Errorf("failed to query db: %s", err.Error())
+// Performs the query given in `query` with the bound `args`,
+// reading at most `maxRows` rows.
+func querySQL(ctx context.Context, db *sql.DB, query string, args []any, maxRows int) (*SQLDetails, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query db: %w", err)
 	}
+	defer rows.Close()
+
+	result := SQLDetails{}
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get columns: %s", err.Error())
 	}
 	result.Columns = columns
 	for rows.Next() {
+		if len(result.Rows) >= maxRows {
+			result.Truncated = true
+			break
+		}
 		var rowValues = make([]interface{}, len(columns))
 		for i := range rowValues {
 			var s sql.NullString
@@ -117,6 +263,9 @@ func querySQL(db *sql.DB, query string) (*SQLDetails, error) {
 		}
 		result.Rows = append(result.Rows, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query db: %w", err)
+	}
 	result.Count = len(result.Rows)
 	return &result, nil
 }
@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"testing"
+
+	v1 "github.com/flanksource/config-db/api/v1"
+)
+
+func TestBindCursor(t *testing.T) {
+	cursor := &queryCursor{SQLCursor: v1.SQLCursor{Column: "updated_at"}, value: "2024-01-01T00:00:00Z"}
+	query := "SELECT id::text FROM items WHERE updated_at > :last_updated OR created_at > :last_updated ORDER BY updated_at"
+
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"postgres", "SELECT id::text FROM items WHERE updated_at > $1 OR created_at > $2 ORDER BY updated_at"},
+		{"sqlserver", "SELECT id::text FROM items WHERE updated_at > @p1 OR created_at > @p2 ORDER BY updated_at"},
+		{"mysql", "SELECT id::text FROM items WHERE updated_at > ? OR created_at > ? ORDER BY updated_at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			got, args := bindCursor(tt.driver, query, cursor)
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if len(args) != 2 || args[0] != cursor.value {
+				t.Errorf("expected the cursor to be bound twice, got %v", args)
+			}
+		})
+	}
+
+	if got, args := bindCursor("postgres", query, nil); got != query || args != nil {
+		t.Errorf("expected the query to be unchanged without a cursor")
+	}
+}
+
+func TestCursorUpdate(t *testing.T) {
+	cursor := &queryCursor{SQLCursor: v1.SQLCursor{Column: "version"}, value: "9", next: "9"}
+	if err := cursor.update([]map[string]any{{"version": "10"}, {"version": "2"}, {"version": nil}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if cursor.next != "10" {
+		t.Errorf("expected numeric cursors to be compared as numbers, got %s", cursor.next)
+	}
+
+	cursor = &queryCursor{SQLCursor: v1.SQLCursor{Column: "updated_at"}, next: "2024-01-01T10:00:00+02:00"}
+	if err := cursor.update([]map[string]any{{"updated_at": "2024-01-01T09:00:00Z"}, {"updated_at": "2024-01-01T07:00:00Z"}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if cursor.next != "2024-01-01T09:00:00Z" {
+		t.Errorf("expected timestamps to be compared as times, got %s", cursor.next)
+	}
+
+	// the rows of a truncated query are ordered, more rows may have the last value
+	cursor = &queryCursor{SQLCursor: v1.SQLCursor{Column: "version"}, value: "1", next: "1"}
+	if err := cursor.update([]map[string]any{{"version": "2"}, {"version": "3"}, {"version": "3"}}, true); err != nil {
+		t.Fatal(err)
+	}
+	if cursor.next != "2" {
+		t.Errorf("expected the cursor to stop before the last value of truncated rows, got %s", cursor.next)
+	}
+
+	// the cursor can't advance when all the rows read have the same value
+	stuck := &queryCursor{SQLCursor: v1.SQLCursor{Column: "version"}, value: "1", next: "1"}
+	if err := stuck.update([]map[string]any{{"version": "3"}, {"version": "3"}}, true); err == nil {
+		t.Errorf("expected an error when the truncated rows all have the same value")
+	}
+	if err := stuck.update([]map[string]any{{"version": "3"}, {"version": "3"}}, false); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	query := cursor.orderBy("SELECT * FROM items WHERE version > :last_updated;")
+	if query != "SELECT * FROM (SELECT * FROM items WHERE version > :last_updated) cursor_rows ORDER BY version" {
+		t.Errorf("unexpected ordered query %s", query)
+	}
+}
+
+func TestRows(t *testing.T) {
+	change := changeFromRow(map[string]any{
+		"external_id": "db/orders",
+		"config_type": "SQL::Table",
+		"change_type": "Migration",
+		"created_by":  "admin",
+		"created_at":  "2024-01-02 03:04:05",
+		"details":     `{"version": 3}`,
+		"script":      "V3__orders.sql",
+	})
+	if change.ExternalID != "db/orders" || change.ChangeType != "Migration" || *change.CreatedBy != "admin" {
+		t.Errorf("unexpected change %+v", change)
+	}
+	if change.CreatedAt == nil || change.CreatedAt.Hour() != 3 {
+		t.Errorf("expected created_at to be parsed, got %v", change.CreatedAt)
+	}
+	if change.Details["version"] != float64(3) || change.Details["script"] != "V3__orders.sql" {
+		t.Errorf("expected details to include the other columns, got %v", change.Details)
+	}
+
+	var results v1.ScrapeResults
+	analysisFromRow(&results, map[string]any{
+		"external_id":   "db/orders",
+		"config_type":   "SQL::Table",
+		"analyzer":      "missing-primary-key",
+		"analysis_type": "integration",
+		"severity":      "medium",
+		"messages":      `["orders has no primary key"]`,
+		"rows":          "1200",
+	})
+	if len(results) != 1 || results[0].AnalysisResult == nil {
+		t.Fatalf("expected an analysis, got %v", results)
+	}
+	analysis := results[0].AnalysisResult
+	if analysis.Analyzer != "missing-primary-key" || analysis.Severity != "medium" || len(analysis.Messages) != 1 || analysis.Analysis["rows"] != "1200" {
+		t.Errorf("unexpected analysis %+v", analysis)
+	}
+}
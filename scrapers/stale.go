@@ -24,7 +24,7 @@ func DeleteStaleConfigItems(ctx api.ScrapeContext, scraperID uuid.UUID) error {
 	}
 
 	staleTimeout := ctx.ScrapeConfig().Spec.Retention.StaleItemAge
-	if staleTimeout == "keep" {
+	if staleTimeout == "keep" || ctx.ScrapeConfig().Spec.IsIncremental() {
 		return nil
 	} else if staleTimeout == "" {
 		if defaultVal, exists := ctx.DutyContext().Properties()["config.retention.stale_item_age"]; exists {
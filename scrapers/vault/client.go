@@ -0,0 +1,202 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// Mount is a secrets engine or an auth method
+type Mount struct {
+	Type        string            `json:"type"`
+	Description string            `json:"description,omitempty"`
+	Accessor    string            `json:"accessor,omitempty"`
+	Local       bool              `json:"local,omitempty"`
+	SealWrap    bool              `json:"seal_wrap,omitempty"`
+	Options     map[string]string `json:"options,omitempty"`
+	Config      map[string]any    `json:"config,omitempty"`
+}
+
+// IsKV2 returns true for version 2 of the KV secrets engine, the only engine with secret metadata
+func (m Mount) IsKV2() bool {
+	return m.Type == "kv" && m.Options["version"] == "2"
+}
+
+// SecretMetadata is the metadata of a KV v2 secret, it doesn't contain the value of the secret
+type SecretMetadata struct {
+	CreatedTime        time.Time                `json:"created_time"`
+	UpdatedTime        time.Time                `json:"updated_time"`
+	CurrentVersion     int                      `json:"current_version"`
+	OldestVersion      int                      `json:"oldest_version"`
+	MaxVersions        int                      `json:"max_versions"`
+	CasRequired        bool                     `json:"cas_required"`
+	DeleteVersionAfter string                   `json:"delete_version_after,omitempty"`
+	CustomMetadata     map[string]string        `json:"custom_metadata,omitempty"`
+	Versions           map[string]SecretVersion `json:"versions,omitempty"`
+}
+
+type SecretVersion struct {
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime string    `json:"deletion_time,omitempty"`
+	Destroyed    bool      `json:"destroyed"`
+}
+
+type VaultClient struct {
+	*resty.Client
+	api.ScrapeContext
+	URL string
+}
+
+func NewVaultClient(ctx api.ScrapeContext, config v1.Vault) (*VaultClient, error) {
+	var token string
+	url := config.URL
+	if connection, err := ctx.HydrateConnection(config.ConnectionName); err != nil {
+		return nil, err
+	} else if connection != nil {
+		token = connection.Password
+		if connection.URL != "" {
+			url = connection.URL
+		}
+	} else if token, err = ctx.GetEnvValueFromCache(config.Token); err != nil {
+		return nil, err
+	}
+
+	if url == "" {
+		return nil, fmt.Errorf("vault url is required")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("vault token is required")
+	}
+
+	client := resty.New().SetHeader("X-Vault-Token", token)
+	if config.Namespace != "" {
+		client.SetHeader("X-Vault-Namespace", config.Namespace)
+	}
+
+	return &VaultClient{
+		ScrapeContext: ctx,
+		Client:        client,
+		URL:           strings.TrimSuffix(url, "/"),
+	}, nil
+}
+
+// get decodes the data of a response, paths that don't exist (e.g. an empty list) are returned as nil data
+func (v *VaultClient) get(path string, list bool, data any) error {
+	req := v.R()
+	if list {
+		req.SetQueryParam("list", "true")
+	}
+	resp, err := req.Get(v.URL + "/v1/" + strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil
+	}
+	if resp.IsError() {
+		var response struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(resp.Body(), &response)
+		return fmt.Errorf("received %s from vault for %s: %s", resp.Status(), path, strings.Join(response.Errors, ", "))
+	}
+
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body(), &response); err != nil {
+		return err
+	}
+	if len(response.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Data, data)
+}
+
+func (v *VaultClient) list(path string) ([]string, error) {
+	var data struct {
+		Keys []string `json:"keys"`
+	}
+	return data.Keys, v.get(path, true, &data)
+}
+
+// GetMounts returns the secrets engines by path
+func (v *VaultClient) GetMounts() (map[string]Mount, error) {
+	var mounts map[string]Mount
+	return mounts, v.get("sys/mounts", false, &mounts)
+}
+
+// GetAuthMethods returns the auth methods by path
+func (v *VaultClient) GetAuthMethods() (map[string]Mount, error) {
+	var methods map[string]Mount
+	return methods, v.get("sys/auth", false, &methods)
+}
+
+// GetPolicies returns the ACL policies by name
+func (v *VaultClient) GetPolicies() (map[string]string, error) {
+	names, err := v.list("sys/policies/acl")
+	if err != nil {
+		return nil, err
+	}
+	policies := map[string]string{}
+	for _, name := range names {
+		var policy struct {
+			Policy string `json:"policy"`
+		}
+		if err := v.get("sys/policies/acl/"+name, false, &policy); err != nil {
+			return nil, err
+		}
+		policies[name] = policy.Policy
+	}
+	return policies, nil
+}
+
+// GetRoles returns the roles of an auth method by name
+func (v *VaultClient) GetRoles(path string) (map[string]map[string]any, error) {
+	names, err := v.list("auth/" + path + "role")
+	if err != nil {
+		return nil, err
+	}
+	roles := map[string]map[string]any{}
+	for _, name := range names {
+		var role map[string]any
+		if err := v.get("auth/"+path+"role/"+name, false, &role); err != nil {
+			return nil, err
+		}
+		roles[name] = role
+	}
+	return roles, nil
+}
+
+// ListSecrets returns the paths of the secrets under a path of a KV v2 mount, recursively
+func (v *VaultClient) ListSecrets(mount, path string) ([]string, error) {
+	keys, err := v.list(mount + "metadata/" + path)
+	if err != nil {
+		return nil, err
+	}
+	var secrets []string
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			nested, err := v.ListSecrets(mount, path+key)
+			if err != nil {
+				return nil, err
+			}
+			secrets = append(secrets, nested...)
+		} else {
+			secrets = append(secrets, path+key)
+		}
+	}
+	return secrets, nil
+}
+
+// GetSecretMetadata returns the metadata of a KV v2 secret
+func (v *VaultClient) GetSecretMetadata(mount, path string) (*SecretMetadata, error) {
+	var metadata SecretMetadata
+	return &metadata, v.get(mount+"metadata/"+path, false, &metadata)
+}
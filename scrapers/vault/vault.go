@@ -0,0 +1,218 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/models"
+	"github.com/samber/lo"
+)
+
+const (
+	SecretsEngineType = "Vault::SecretsEngine"
+	AuthMethodType    = "Vault::AuthMethod"
+	PolicyType        = "Vault::Policy"
+	RoleType          = "Vault::Role"
+	SecretType        = "Vault::Secret"
+)
+
+// roleAuthMethods are the auth methods whose roles are listed under auth/<path>/role
+var roleAuthMethods = []string{"approle", "kubernetes", "jwt", "oidc", "aws", "azure", "gcp", "alicloud", "oci"}
+
+type VaultScraper struct {
+}
+
+func (v VaultScraper) CanScrape(spec v1.ScraperSpec) bool {
+	return len(spec.Vault) > 0
+}
+
+// Scrape scrapes the metadata of vault servers, secrets that haven't been updated for longer than StaleAfter are reported as analysis
+func (v VaultScraper) Scrape(ctx api.ScrapeContext) v1.ScrapeResults {
+	results := v1.ScrapeResults{}
+	for _, config := range ctx.ScrapeConfig().Spec.Vault {
+		client, err := NewVaultClient(ctx, config)
+		if err != nil {
+			results.Errorf(err, "failed to create vault client for %s", config.URL)
+			continue
+		}
+		s := scraper{client: client, config: config}
+
+		mounts, err := client.GetMounts()
+		if err != nil {
+			results.Errorf(err, "failed to get secrets engines of %s", client.URL)
+		}
+		for _, path := range sortedKeys(mounts) {
+			results = append(results, s.result(path, path, SecretsEngineType, "SecretsEngine", mounts[path]))
+		}
+
+		methods, err := client.GetAuthMethods()
+		if err != nil {
+			results.Errorf(err, "failed to get auth methods of %s", client.URL)
+		}
+		for _, path := range sortedKeys(methods) {
+			results = append(results, s.result("auth/"+path, path, AuthMethodType, "AuthMethod", methods[path]))
+			if lo.Contains(roleAuthMethods, methods[path].Type) {
+				results = append(results, s.roles(path)...)
+			}
+		}
+
+		policies, err := client.GetPolicies()
+		if err != nil {
+			results.Errorf(err, "failed to get policies of %s", client.URL)
+		}
+		for _, name := range sortedKeys(policies) {
+			// the root policy is built-in and has no rules
+			if name == "root" {
+				continue
+			}
+			// the rules are split into lines, so that changes to a policy are diffed line by line
+			results = append(results, s.result("policy/"+name, name, PolicyType, "Policy", map[string]any{
+				"name":  name,
+				"rules": strings.Split(strings.TrimSpace(policies[name]), "\n"),
+			}))
+		}
+
+		paths := config.Secrets
+		if len(paths) == 0 {
+			for _, path := range sortedKeys(mounts) {
+				if mounts[path].IsKV2() {
+					paths = append(paths, path)
+				}
+			}
+		}
+		for _, path := range paths {
+			results = append(results, s.secrets(mounts, path)...)
+		}
+	}
+	return results
+}
+
+type scraper struct {
+	client *VaultClient
+	config v1.Vault
+}
+
+func (s scraper) id(path string) string {
+	if s.config.Namespace != "" {
+		return s.config.Namespace + "/" + path
+	}
+	return path
+}
+
+func (s scraper) result(path, name, configType, class string, config any) v1.ScrapeResult {
+	return v1.ScrapeResult{
+		BaseScraper: s.config.BaseScraper,
+		ID:          s.id(path),
+		Name:        name,
+		Type:        configType,
+		ConfigClass: class,
+		Config:      config,
+	}
+}
+
+// roles scrapes the roles of an auth method and links them to their policies
+func (s scraper) roles(path string) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	roles, err := s.client.GetRoles(path)
+	if err != nil {
+		results.Errorf(err, "failed to get roles of auth/%s", path)
+		return results
+	}
+
+	for _, name := range sortedKeys(roles) {
+		result := s.result("auth/"+path+"role/"+name, name, RoleType, "Role", roles[name])
+		result.ParentExternalID = s.id("auth/" + path)
+		result.ParentType = AuthMethodType
+
+		var policies []string
+		for _, field := range []string{"token_policies", "policies"} {
+			if list, ok := roles[name][field].([]any); ok {
+				for _, policy := range list {
+					policies = append(policies, fmt.Sprint(policy))
+				}
+			}
+		}
+		for _, policy := range lo.Uniq(policies) {
+			result.RelationshipResults = append(result.RelationshipResults, v1.RelationshipResult{
+				ConfigExternalID:  v1.ExternalID{ExternalID: []string{s.id("policy/" + policy)}, ConfigType: PolicyType},
+				RelatedExternalID: v1.ExternalID{ExternalID: []string{result.ID}, ConfigType: RoleType},
+				Relationship:      "PolicyRole",
+			})
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// secrets scrapes the metadata of the secrets under a path of a KV v2 mount, their versions are scraped as changes
+func (s scraper) secrets(mounts map[string]Mount, path string) v1.ScrapeResults {
+	var results v1.ScrapeResults
+	mount, prefix := "", ""
+	for m := range mounts {
+		if strings.HasPrefix(path, m) && len(m) > len(mount) {
+			mount, prefix = m, strings.TrimPrefix(path, m)
+		}
+	}
+	if mount == "" || !mounts[mount].IsKV2() {
+		results.Errorf(fmt.Errorf("%s is not a KV v2 secrets engine", path), "failed to scrape secrets")
+		return results
+	}
+
+	secrets, err := s.client.ListSecrets(mount, prefix)
+	if err != nil {
+		results.Errorf(err, "failed to list secrets of %s", path)
+		return results
+	}
+
+	staleAfter := s.config.GetStaleAfter()
+	for _, secret := range secrets {
+		metadata, err := s.client.GetSecretMetadata(mount, secret)
+		if err != nil {
+			results.Errorf(err, "failed to get metadata of %s%s", mount, secret)
+			continue
+		}
+
+		versions := metadata.Versions
+		// versions are scraped as changes
+		metadata.Versions = nil
+		result := s.result(mount+secret, secret, SecretType, "Secret", metadata)
+		result.ParentExternalID = s.id(mount)
+		result.ParentType = SecretsEngineType
+		result.CreatedAt = &metadata.CreatedTime
+
+		for _, number := range sortedKeys(versions) {
+			version := versions[number]
+			result.Changes = append(result.Changes, v1.ChangeResult{
+				ExternalID:       result.ID,
+				ConfigType:       SecretType,
+				ExternalChangeID: result.ID + "@" + number,
+				ChangeType:       "SecretVersionCreated",
+				Summary:          fmt.Sprintf("version %s of %s created", number, secret),
+				Source:           "Vault",
+				CreatedAt:        lo.ToPtr(version.CreatedTime),
+				Details:          map[string]any{"version": number, "destroyed": version.Destroyed, "deletion_time": version.DeletionTime},
+			})
+		}
+		results = append(results, result)
+
+		if age := time.Since(metadata.UpdatedTime); age > staleAfter {
+			analysis := results.Analysis("StaleSecret", SecretType, result.ID)
+			analysis.AnalysisType = models.AnalysisTypeSecurity
+			analysis.Severity = models.SeverityMedium
+			analysis.Source = "Vault"
+			analysis.Summary = fmt.Sprintf("%s hasn't been updated in %d days", mount+secret, int(age.Hours()/24))
+			analysis.Message(fmt.Sprintf("%s was last updated on %s, secrets should be rotated every %d days", mount+secret, metadata.UpdatedTime.Format(time.DateOnly), int(staleAfter.Hours()/24)))
+		}
+	}
+	return results
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := lo.Keys(m)
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flanksource/config-db/api"
+	v1 "github.com/flanksource/config-db/api/v1"
+	"github.com/flanksource/duty/types"
+)
+
+func data(d any) map[string]any {
+	return map[string]any{"data": d}
+}
+
+func newServer(t *testing.T, updated time.Time) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+		// secret values must never be read
+		if strings.Contains(r.URL.Path, "/data/") {
+			t.Errorf("unexpected request for a secret value: %s", r.URL.Path)
+		}
+		list := r.URL.Query().Get("list") == "true"
+		switch {
+		case r.URL.Path == "/v1/sys/mounts":
+			_ = json.NewEncoder(w).Encode(data(map[string]any{
+				"secret/":    map[string]any{"type": "kv", "options": map[string]string{"version": "2"}},
+				"sys/":       map[string]any{"type": "system"},
+				"transit/":   map[string]any{"type": "transit"},
+				"legacy-kv/": map[string]any{"type": "kv", "options": map[string]string{"version": "1"}},
+			}))
+		case r.URL.Path == "/v1/sys/auth":
+			_ = json.NewEncoder(w).Encode(data(map[string]any{
+				"token/":      map[string]any{"type": "token"},
+				"kubernetes/": map[string]any{"type": "kubernetes"},
+			}))
+		case r.URL.Path == "/v1/auth/kubernetes/role" && list:
+			_ = json.NewEncoder(w).Encode(data(map[string]any{"keys": []string{"web"}}))
+		case r.URL.Path == "/v1/auth/kubernetes/role/web":
+			_ = json.NewEncoder(w).Encode(data(map[string]any{"bound_service_account_names": []string{"web"}, "token_policies": []string{"web", "default"}}))
+		case r.URL.Path == "/v1/sys/policies/acl" && list:
+			_ = json.NewEncoder(w).Encode(data(map[string]any{"keys": []string{"default", "root", "web"}}))
+		case strings.HasPrefix(r.URL.Path, "/v1/sys/policies/acl/"):
+			name := strings.TrimPrefix(r.URL.Path, "/v1/sys/policies/acl/")
+			_ = json.NewEncoder(w).Encode(data(map[string]any{"name": name, "policy": "path \"secret/data/" + name + "/*\" {\n  capabilities = [\"read\"]\n}\n"}))
+		case r.URL.Path == "/v1/secret/metadata/" && list:
+			_ = json.NewEncoder(w).Encode(data(map[string]any{"keys": []string{"web/", "db"}}))
+		case r.URL.Path == "/v1/secret/metadata/web/" && list:
+			_ = json.NewEncoder(w).Encode(data(map[string]any{"keys": []string{"api-key"}}))
+		case r.URL.Path == "/v1/secret/metadata/web/api-key" || r.URL.Path == "/v1/secret/metadata/db":
+			_ = json.NewEncoder(w).Encode(data(map[string]any{
+				"created_time":    "2023-01-01T00:00:00Z",
+				"updated_time":    updated,
+				"current_version": 2,
+				"versions": map[string]any{
+					"1": map[string]any{"created_time": "2023-01-01T00:00:00Z"},
+					"2": map[string]any{"created_time": updated},
+				},
+			}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{"errors": []string{}})
+		}
+	}))
+}
+
+func scrape(config v1.Vault) (map[string]v1.ScrapeResult, []*v1.AnalysisResult, error) {
+	ctx := api.NewScrapeContext(context.TODO(), nil, nil).
+		WithScrapeConfig(&v1.ScrapeConfig{Spec: v1.ScraperSpec{Vault: []v1.Vault{config}}})
+	items := map[string]v1.ScrapeResult{}
+	var analyses []*v1.AnalysisResult
+	for _, r := range (VaultScraper{}).Scrape(ctx) {
+		if r.Error != nil {
+			return nil, nil, r.Error
+		}
+		if r.AnalysisResult != nil {
+			analyses = append(analyses, r.AnalysisResult)
+		} else {
+			items[r.Type+"/"+r.ID] = r
+		}
+	}
+	return items, analyses, nil
+}
+
+func TestScrape(t *testing.T) {
+	server := newServer(t, time.Now().Add(-200*24*time.Hour))
+	defer server.Close()
+
+	items, analyses, err := scrape(v1.Vault{URL: server.URL, Token: types.EnvVar{ValueStatic: "root"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{
+		"Vault::SecretsEngine/secret/", "Vault::SecretsEngine/sys/", "Vault::SecretsEngine/transit/", "Vault::SecretsEngine/legacy-kv/",
+		"Vault::AuthMethod/auth/token/", "Vault::AuthMethod/auth/kubernetes/",
+		"Vault::Role/auth/kubernetes/role/web",
+		"Vault::Policy/policy/default", "Vault::Policy/policy/web",
+		"Vault::Secret/secret/web/api-key", "Vault::Secret/secret/db",
+	} {
+		if _, ok := items[id]; !ok {
+			t.Errorf("expected %s to be scraped", id)
+		}
+	}
+	if len(items) != 11 {
+		t.Errorf("expected 11 items, got %d", len(items))
+	}
+
+	role := items["Vault::Role/auth/kubernetes/role/web"]
+	if role.ParentExternalID != "auth/kubernetes/" || len(role.RelationshipResults) != 2 {
+		t.Errorf("unexpected role %+v", role)
+	}
+
+	policy := items["Vault::Policy/policy/web"].Config.(map[string]any)
+	if rules := policy["rules"].([]string); len(rules) != 3 || rules[0] != `path "secret/data/web/*" {` {
+		t.Errorf("unexpected policy rules %v", rules)
+	}
+
+	secret := items["Vault::Secret/secret/web/api-key"]
+	metadata := secret.Config.(*SecretMetadata)
+	if metadata.CurrentVersion != 2 || metadata.Versions != nil || secret.ParentExternalID != "secret/" {
+		t.Errorf("unexpected secret %+v", secret)
+	}
+	if len(secret.Changes) != 2 || secret.Changes[1].ExternalChangeID != "secret/web/api-key@2" {
+		t.Errorf("unexpected changes %+v", secret.Changes)
+	}
+
+	if len(analyses) != 2 || analyses[0].Analyzer != "StaleSecret" || !strings.Contains(analyses[0].Summary, "200 days") {
+		t.Errorf("expected both secrets to be stale, got %+v", analyses)
+	}
+}
+
+func TestScrapeRecentSecrets(t *testing.T) {
+	server := newServer(t, time.Now().Add(-24*time.Hour))
+	defer server.Close()
+
+	items, analyses, err := scrape(v1.Vault{URL: server.URL, Token: types.EnvVar{ValueStatic: "root"}, Secrets: []string{"secret/web/"}, StaleAfter: "30d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := items["Vault::Secret/secret/db"]; ok {
+		t.Errorf("expected only the secrets under secret/web/ to be scraped")
+	}
+	if len(analyses) != 0 {
+		t.Errorf("expected no stale secrets, got %+v", analyses)
+	}
+}
+
+func TestScrapeKV1(t *testing.T) {
+	server := newServer(t, time.Now())
+	defer server.Close()
+
+	if _, _, err := scrape(v1.Vault{URL: server.URL, Token: types.EnvVar{ValueStatic: "root"}, Secrets: []string{"legacy-kv/"}}); err == nil {
+		t.Errorf("expected an error for KV v1 secrets engines")
+	}
+}